@@ -6,13 +6,30 @@ let authToken: string | null = null;
 export class ApiError extends Error {
   status: number;
   body: string;
-  constructor(status: number, body: string) {
-    super(`API error: ${status} ${body}`);
+  code?: string;
+  details?: Record<string, unknown>;
+  constructor(status: number, body: string, code?: string, details?: Record<string, unknown>) {
+    super(`API error: ${status} ${code ? `${code}: ` : ""}${body}`);
     this.status = status;
     this.body = body;
+    this.code = code;
+    this.details = details;
   }
 }
 
+// REST errors are JSON: { error: { code, message, details } }.
+function parseApiError(status: number, bodyText: string, statusText: string): ApiError {
+  try {
+    const { error } = JSON.parse(bodyText);
+    if (error && typeof error.message === "string") {
+      return new ApiError(status, error.message, error.code, error.details);
+    }
+  } catch {
+    // not JSON; fall through
+  }
+  return new ApiError(status, bodyText || statusText);
+}
+
 const api = {
   setAuthToken(token: string) {
     authToken = token;
@@ -34,7 +51,7 @@ const api = {
 
     if (!response.ok) {
       const bodyText = await response.text().catch(() => "");
-      throw new ApiError(response.status, bodyText || response.statusText);
+      throw parseApiError(response.status, bodyText, response.statusText);
     }
 
     return response.json();
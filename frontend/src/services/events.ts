@@ -9,6 +9,7 @@ export type WorkspaceEvent = {
     | "file.moved"
     | "dir.created"
     | "dir.deleted"
+    | "dir.moved"
     | "presence.join"
     | "presence.leave"
     | "lock.acquired"
@@ -1,27 +1,52 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"mcp-workspace-manager/pkg/events"
 	"mcp-workspace-manager/pkg/mcpsdk"
 	"mcp-workspace-manager/pkg/workspace"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // Config holds the application configuration.
 type Config struct {
-	WorkspacesRoot string
-	Transport      string
-	Host           string
-	Port           int
-	LogFormat      string
-	LogLevel       slog.Level
-	AuthTokens     []string
+	WorkspacesRoot   string
+	Transport        string
+	Host             string
+	Port             int
+	LogFormat        string
+	LogLevel         slog.Level
+	AuthTokens       []string
+	CORSOrigins      []string
+	TLSCertFile      string
+	TLSKeyFile       string
+	TempDir          string
+	TemplatesDir     string
+	SlugStrategy     workspace.SlugStrategy
+	RateLimit        float64
+	RateBurst        int
+	EventsLogDir     string
+	MaxResponseBytes int
+	MaxRequestBytes  int64
+	MaxMediaBytes    int64
+	MaxReadBytes     int64
+	QuotaBytes       int64
+	ProtectedNames   []string
+	ProtectedGlobs   []string
+	EventsBuffer     int
+	EventsHeartbeat  time.Duration
+	Metrics          bool
+	Ephemeral        bool
 }
 
 func main() {
@@ -50,10 +75,56 @@ func main() {
 
 	var authTokensCSV string
 	var authTokenSingle string
-	flag.StringVar(&authTokensCSV, "auth-tokens", os.Getenv("AUTH_BEARER_TOKENS"), "Comma-separated list of Bearer tokens for HTTP auth (env: AUTH_BEARER_TOKENS)")
+	flag.StringVar(&authTokensCSV, "auth-tokens", os.Getenv("AUTH_BEARER_TOKENS"), "Comma-separated list of Bearer tokens for HTTP auth; suffix a token with :ro for read-only or :rw (default) (env: AUTH_BEARER_TOKENS)")
 	flag.StringVar(&authTokenSingle, "auth-token", os.Getenv("AUTH_BEARER_TOKEN"), "Single Bearer token for HTTP auth (env: AUTH_BEARER_TOKEN)")
 
+	var corsOriginsCSV string
+	flag.StringVar(&corsOriginsCSV, "cors-origins", os.Getenv("CORS_ORIGINS"), "Comma-separated list of origins allowed for CORS, or '*' for any (env: CORS_ORIGINS)")
+
+	flag.StringVar(&cfg.TLSCertFile, "tls-cert", os.Getenv("TLS_CERT_FILE"), "Path to a PEM certificate; serves HTTPS when set with --tls-key (env: TLS_CERT_FILE)")
+	flag.StringVar(&cfg.TLSKeyFile, "tls-key", os.Getenv("TLS_KEY_FILE"), "Path to the PEM private key for --tls-cert (env: TLS_KEY_FILE)")
+
+	flag.StringVar(&cfg.TempDir, "temp-dir", os.Getenv("TEMP_DIR"), "Directory for atomic-write temp files; must be on the same filesystem as --workspaces-root (env: TEMP_DIR, default: inside each workspace's .git)")
+
+	flag.StringVar(&cfg.TemplatesDir, "templates-dir", os.Getenv("TEMPLATES_DIR"), "Directory whose subdirectories are templates for workspace_create_from_template; disabled when empty (env: TEMPLATES_DIR)")
+
+	flag.Float64Var(&cfg.RateLimit, "rate-limit", envFloat("RATE_LIMIT"), "Requests per second allowed per token (or client IP without auth) on /mcp and /api/tools; 0 disables (env: RATE_LIMIT)")
+	flag.IntVar(&cfg.RateBurst, "rate-burst", int(envFloat("RATE_BURST")), "Burst size for --rate-limit; defaults to the rate rounded up (env: RATE_BURST)")
+
+	flag.IntVar(&cfg.EventsBuffer, "events-buffer", envInt("EVENTS_BUFFER", events.DefaultRingCapacity), "Events kept per workspace for replay (since/Last-Event-ID); older events are dropped unless --events-log-dir is set (env: EVENTS_BUFFER)")
+	flag.DurationVar(&cfg.EventsHeartbeat, "events-heartbeat", envDuration("EVENTS_HEARTBEAT", events.DefaultHeartbeat), "Interval between SSE heartbeat comments and WebSocket pings (env: EVENTS_HEARTBEAT)")
+	flag.StringVar(&cfg.EventsLogDir, "events-log-dir", os.Getenv("EVENTS_LOG_DIR"), "Directory for persistent per-workspace event logs so SSE replay survives restarts; disabled when empty (env: EVENTS_LOG_DIR)")
+
+	flag.Int64Var(&cfg.MaxRequestBytes, "max-request-bytes", int64(envInt("MAX_REQUEST_BYTES", mcpsdk.DefaultMaxRequestBytes)), "Maximum request body size on /mcp and /api/tools; larger bodies fail with 413; negative disables (env: MAX_REQUEST_BYTES)")
+
+	flag.Int64Var(&cfg.MaxMediaBytes, "max-media-bytes", int64(envInt("MAX_MEDIA_BYTES", mcpsdk.DefaultMaxMediaBytes)), "Largest file fs_read_media_file returns as base64; larger files fail with UNSUPPORTED (use GET /api/workspaces/{id}/raw) (env: MAX_MEDIA_BYTES)")
+	flag.Int64Var(&cfg.MaxReadBytes, "max-read-bytes", int64(envInt("MAX_READ_BYTES", mcpsdk.DefaultMaxReadBytes)), "Default and largest maxBytes of fs_read_text_file and fs_read_multiple_files; longer files are returned truncated (env: MAX_READ_BYTES)")
+
+	flag.Int64Var(&cfg.QuotaBytes, "workspace-quota-bytes", int64(envInt("WORKSPACE_QUOTA_BYTES", 0)), "Maximum size of each workspace's files (.git excluded); writes and copies that would exceed it fail with QUOTA_EXCEEDED (HTTP 413); 0 disables (env: WORKSPACE_QUOTA_BYTES)")
+
+	var protectedNamesCSV, protectedGlobsCSV string
+	flag.StringVar(&protectedNamesCSV, "protected-names", os.Getenv("PROTECTED_NAMES"), "Comma-separated file or directory names, besides .git, .gitkeep and .trash, that tools refuse to read or modify and hide from listings wherever they occur (env: PROTECTED_NAMES)")
+	flag.StringVar(&protectedGlobsCSV, "protected-globs", os.Getenv("PROTECTED_GLOBS"), "Comma-separated glob patterns protected like --protected-names; a pattern without '/' matches names at any depth (e.g. '*.env'), one with '/' matches workspace-relative paths and everything below them (e.g. 'secrets/*') (env: PROTECTED_GLOBS)")
+
+	flag.BoolVar(&cfg.Metrics, "metrics", envBool("METRICS"), "Expose Prometheus metrics at /metrics (unauthenticated) in HTTP mode (env: METRICS)")
+	flag.BoolVar(&cfg.Ephemeral, "ephemeral", envBool("EPHEMERAL"), "Keep workspaces in memory under --workspaces-root instead of on disk; they have no git history, are lost on exit, and tools that need disk or history fail with UNSUPPORTED (env: EPHEMERAL)")
+
+	flag.IntVar(&cfg.MaxResponseBytes, "max-response-bytes", int(envFloat("MAX_RESPONSE_BYTES")), "Maximum encoded size of a tool result; larger results fail with TOO_LARGE (HTTP 413); 0 disables (env: MAX_RESPONSE_BYTES)")
+
+	var slugStrategy string
+	flag.StringVar(&slugStrategy, "slug-strategy", os.Getenv("SLUG_STRATEGY"), "Workspace id strategy: 'slug' (default), 'slug-date' or 'uuid' (env: SLUG_STRATEGY)")
+
 	flag.Parse()
+	cfg.ProtectedNames = splitCSV(protectedNamesCSV)
+	cfg.ProtectedGlobs = splitCSV(protectedGlobsCSV)
+
+	if st, err := workspace.ParseSlugStrategy(slugStrategy); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: --slug-strategy: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	} else {
+		cfg.SlugStrategy = st
+	}
 
 	if err := validateConfig(cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
@@ -64,6 +135,7 @@ func main() {
 	setupLogger(cfg)
 
 	cfg.AuthTokens = collectAuthTokens(authTokensCSV, authTokenSingle)
+	cfg.CORSOrigins = splitCSV(corsOriginsCSV)
 
 	slog.Info("Starting MCP Workspace Manager",
 		"version", "0.1.0",
@@ -73,10 +145,28 @@ func main() {
 		"workspaces-root", cfg.WorkspacesRoot,
 		"auth_enabled", len(cfg.AuthTokens) > 0,
 		"auth_tokens", len(cfg.AuthTokens),
+		"cors_origins", cfg.CORSOrigins,
+		"tls_enabled", cfg.TLSCertFile != "",
+		"ephemeral", cfg.Ephemeral,
 	)
 
 	// --- Initialize Managers and Services ---
-	workspaceManager, err := workspace.NewManager(cfg.WorkspacesRoot)
+	managerOpts := []workspace.Option{workspace.WithSlugStrategy(cfg.SlugStrategy)}
+	if cfg.TempDir != "" {
+		managerOpts = append(managerOpts, workspace.WithTempDir(cfg.TempDir))
+	}
+	if cfg.TemplatesDir != "" {
+		managerOpts = append(managerOpts, workspace.WithTemplatesDir(cfg.TemplatesDir))
+	}
+	if cfg.QuotaBytes > 0 {
+		managerOpts = append(managerOpts, workspace.WithQuota(cfg.QuotaBytes))
+	}
+	protected, _ := workspace.NewProtectedPaths(cfg.ProtectedNames, cfg.ProtectedGlobs) // checked by validateConfig
+	managerOpts = append(managerOpts, workspace.WithProtectedPaths(protected))
+	if cfg.Ephemeral {
+		managerOpts = append(managerOpts, workspace.WithFS(workspace.NewMemFS()))
+	}
+	workspaceManager, err := workspace.NewManager(cfg.WorkspacesRoot, managerOpts...)
 	if err != nil {
 		slog.Error("Failed to initialize workspace manager", "error", err)
 		os.Exit(1)
@@ -84,7 +174,12 @@ func main() {
 
 	// Using MCP SDK server; tool registration happens inside mcpsdk.buildServer.
 
+	// Stop cleanly on SIGINT/SIGTERM (e.g. container orchestration).
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// --- Start Transport Listener (MCP SDK) ---
+	var runErr error
 	if cfg.Transport == "http" {
 		fsys, err := fs.Sub(embeddedFiles, "frontend/dist")
 		if err != nil {
@@ -92,10 +187,37 @@ func main() {
 			os.Exit(1)
 		}
 		rootHandler := http.FileServer(http.FS(fsys))
-		mcpsdk.RunHTTP(cfg.Host, cfg.Port, workspaceManager, cfg.AuthTokens, rootHandler)
+		runErr = mcpsdk.RunHTTP(ctx, workspaceManager, mcpsdk.HTTPOptions{
+			Host:             cfg.Host,
+			Port:             cfg.Port,
+			AuthTokens:       cfg.AuthTokens,
+			CORSOrigins:      cfg.CORSOrigins,
+			TLSCertFile:      cfg.TLSCertFile,
+			TLSKeyFile:       cfg.TLSKeyFile,
+			RateLimit:        cfg.RateLimit,
+			RateBurst:        cfg.RateBurst,
+			EventsLogDir:     cfg.EventsLogDir,
+			MaxResponseBytes: cfg.MaxResponseBytes,
+			MaxRequestBytes:  cfg.MaxRequestBytes,
+			EventsBuffer:     cfg.EventsBuffer,
+			EventsHeartbeat:  cfg.EventsHeartbeat,
+			Metrics:          cfg.Metrics,
+			MaxMediaBytes:    cfg.MaxMediaBytes,
+			MaxReadBytes:     cfg.MaxReadBytes,
+		}, rootHandler)
 	} else {
-		mcpsdk.RunStdio(workspaceManager)
+		runErr = mcpsdk.RunStdio(ctx, workspaceManager, mcpsdk.StdioOptions{
+			MaxResponseBytes: cfg.MaxResponseBytes,
+			MaxMediaBytes:    cfg.MaxMediaBytes,
+			MaxReadBytes:     cfg.MaxReadBytes,
+		})
+	}
+	if runErr != nil {
+		slog.Error("Server stopped with error", "error", runErr)
+		stop()
+		os.Exit(1)
 	}
+	slog.Info("Server stopped")
 }
 
 func validateConfig(cfg *Config) error {
@@ -108,6 +230,21 @@ func validateConfig(cfg *Config) error {
 	if cfg.Transport != "stdio" && cfg.Transport != "http" {
 		return fmt.Errorf("--transport must be 'stdio' or 'http'")
 	}
+	if cfg.MaxResponseBytes < 0 {
+		return fmt.Errorf("--max-response-bytes must not be negative")
+	}
+	if cfg.MaxMediaBytes < 0 {
+		return fmt.Errorf("--max-media-bytes must not be negative")
+	}
+	if cfg.MaxReadBytes < 0 {
+		return fmt.Errorf("--max-read-bytes must not be negative")
+	}
+	if cfg.QuotaBytes < 0 {
+		return fmt.Errorf("--workspace-quota-bytes must not be negative")
+	}
+	if _, err := workspace.NewProtectedPaths(cfg.ProtectedNames, cfg.ProtectedGlobs); err != nil {
+		return fmt.Errorf("--protected-names/--protected-globs: %v", err)
+	}
 	if cfg.Transport == "http" {
 		if cfg.Host == "" {
 			return fmt.Errorf("--host is required for HTTP transport")
@@ -115,6 +252,28 @@ func validateConfig(cfg *Config) error {
 		if cfg.Port <= 0 || cfg.Port > 65535 {
 			return fmt.Errorf("--port must be between 1 and 65535")
 		}
+		if cfg.RateLimit < 0 || cfg.RateBurst < 0 {
+			return fmt.Errorf("--rate-limit and --rate-burst must not be negative")
+		}
+		if cfg.EventsBuffer <= 0 {
+			return fmt.Errorf("--events-buffer must be positive")
+		}
+		if cfg.EventsHeartbeat <= 0 {
+			return fmt.Errorf("--events-heartbeat must be positive")
+		}
+		if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+			return fmt.Errorf("--tls-cert and --tls-key must be set together")
+		}
+		for _, f := range []struct{ flag, path string }{{"--tls-cert", cfg.TLSCertFile}, {"--tls-key", cfg.TLSKeyFile}} {
+			if f.path == "" {
+				continue
+			}
+			if info, err := os.Stat(f.path); err != nil {
+				return fmt.Errorf("%s: cannot read %q: %v", f.flag, f.path, err)
+			} else if info.IsDir() {
+				return fmt.Errorf("%s: %q is a directory", f.flag, f.path)
+			}
+		}
 	}
 	return nil
 }
@@ -166,3 +325,48 @@ func collectAuthTokens(csv string, single string) []string {
 	}
 	return out
 }
+
+// splitCSV splits a comma-separated flag value, dropping empty entries.
+func splitCSV(csv string) []string {
+	var out []string
+	for _, part := range strings.Split(csv, ",") {
+		if p := strings.TrimSpace(part); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// envFloat parses a numeric environment variable, returning 0 when unset or invalid.
+func envFloat(name string) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// envInt reads an integer environment variable, returning def when unset or invalid.
+func envInt(name string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// envBool reads a boolean environment variable, returning false when unset or invalid.
+func envBool(name string) bool {
+	v, _ := strconv.ParseBool(os.Getenv(name))
+	return v
+}
+
+// envDuration reads a duration (e.g. "10s") environment variable, returning def
+// when unset or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -12,20 +12,32 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/require"
 )
 
 // Minimal shape for events coming from /events SSE
 type sseWorkspaceEvent struct {
-	ID          int64  `json:"id"`
-	WorkspaceID string `json:"workspaceId"`
-	Type        string `json:"type"`
-	Path        string `json:"path"`
-	IsDir       bool   `json:"isDir"`
+	ID          int64   `json:"id"`
+	TS          string  `json:"ts"`
+	WorkspaceID string  `json:"workspaceId"`
+	Type        string  `json:"type"`
+	Path        string  `json:"path"`
+	PrevPath    *string `json:"prevPath"`
+	IsDir       bool    `json:"isDir"`
+	Commit      *string `json:"commit"`
+	Actor       *struct {
+		Kind string  `json:"kind"`
+		ID   *string `json:"id"`
+	} `json:"actor"`
+	Viewers  *int  `json:"viewers"`
+	GlobalID int64 `json:"globalId"`
 }
 
 // Helpers
@@ -82,6 +94,59 @@ func mustJSON[T any](t *testing.T, r io.Reader, out *T) {
 	require.NoError(t, json.Unmarshal(b, out), "body=%s", string(b))
 }
 
+// startTestServer builds the binary, starts it on the given port with a fresh
+// workspaces root, and returns the base URL along with the root directory.
+func startTestServer(t *testing.T, port string, args ...string) (string, string) {
+	bin := buildBinary(t)
+	wsRoot, err := os.MkdirTemp("", "mcp-ws-root-"+port)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(wsRoot) })
+	host := "127.0.0.1"
+	_ = startServer(t, bin, wsRoot, host, port, args...)
+	return fmt.Sprintf("http://%s:%s", host, port), wsRoot
+}
+
+// callTool POSTs body to the REST mirror for toolName, asserts the status and decodes into out (if non-nil).
+func callTool(t *testing.T, base, toolName string, body any, wantStatus int, out any) {
+	t.Helper()
+	resp := restPOST(t, base+"/api/tools/"+toolName, body)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	require.Equal(t, wantStatus, resp.StatusCode, "tool=%s body=%s", toolName, string(b))
+	if out != nil {
+		require.NoError(t, json.Unmarshal(b, out), "body=%s", string(b))
+	}
+}
+
+// callToolAs is callTool with an Authorization: Bearer token.
+func callToolAs(t *testing.T, base, token, toolName string, body any, wantStatus int, out any) {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, base+"/api/tools/"+toolName, bytes.NewReader(b))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	rb, _ := io.ReadAll(resp.Body)
+	require.Equal(t, wantStatus, resp.StatusCode, "tool=%s body=%s", toolName, string(rb))
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rb, out), "body=%s", string(rb))
+	}
+}
+
+// createWorkspace creates a workspace via REST and returns its id.
+func createWorkspace(t *testing.T, base, name string) string {
+	t.Helper()
+	var ws struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	callTool(t, base, "workspace_create", map[string]any{"name": name}, http.StatusOK, &ws)
+	return ws.WorkspaceID
+}
+
 func openSSE(t *testing.T, url string) (*http.Response, *bufio.Reader) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	require.NoError(t, err)
@@ -307,3 +372,396 @@ func TestHTTP_REST_Preconditions_EtagConflict409(t *testing.T) {
 	defer respW3.Body.Close()
 	require.Equal(t, http.StatusConflict, respW3.StatusCode)
 }
+
+func TestHTTP_SSE_PathSubscription(t *testing.T) {
+	base, _ := startTestServer(t, "18106")
+	wsID := createWorkspace(t, base, "Path Sub Test")
+
+	stream, rd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s&path=notes/a.txt", base, wsID))
+	defer stream.Body.Close()
+
+	// Unrelated file: must not be delivered
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "other"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "content": "mine"}, http.StatusOK, nil)
+
+	evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "file.created", evt.Type)
+	require.Equal(t, "notes/a.txt", evt.Path)
+
+	// Moving the watched file away matches on prevPath
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "other 2"}, http.StatusOK, nil)
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "notes/a.txt", "destination": "c.txt"}, http.StatusOK, nil)
+
+	evt, err = readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "file.moved", evt.Type)
+	require.Equal(t, "c.txt", evt.Path)
+	require.NotNil(t, evt.PrevPath)
+	require.Equal(t, "notes/a.txt", *evt.PrevPath)
+
+	_, err = readNextWorkspaceEvent(rd, 800*time.Millisecond)
+	require.Error(t, err, "expected no further events for the subscribed path")
+
+	// Replay honours the path filter as well
+	replay, rrd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s&path=notes&since=0", base, wsID))
+	defer replay.Body.Close()
+	evt, err = readNextWorkspaceEvent(rrd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "notes/a.txt", evt.Path)
+	evt, err = readNextWorkspaceEvent(rrd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "file.moved", evt.Type)
+}
+
+func TestHTTP_SSE_RESTWriteActorKind(t *testing.T) {
+	base, _ := startTestServer(t, "18107")
+	wsID := createWorkspace(t, base, "Actor Test")
+
+	stream, rd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s", base, wsID))
+	defer stream.Body.Close()
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "hi"}, http.StatusOK, nil)
+
+	evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "file.created", evt.Type)
+	require.NotNil(t, evt.Actor)
+	require.Equal(t, "api", evt.Actor.Kind)
+}
+
+func TestHTTP_SSE_ReplaySinceTimestamp(t *testing.T) {
+	base, _ := startTestServer(t, "18109")
+	wsID := createWorkspace(t, base, "SinceTs Test")
+
+	stream, rd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s", base, wsID))
+	for _, p := range []string{"a.txt", "b.txt"} {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": p, "content": p}, http.StatusOK, nil)
+	}
+	var last *sseWorkspaceEvent
+	for i := 0; i < 2; i++ {
+		evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+		require.NoError(t, err)
+		last = evt
+	}
+	stream.Body.Close()
+	require.Equal(t, "b.txt", last.Path)
+	require.NotEmpty(t, last.TS)
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "c.txt", "content": "c"}, http.StatusOK, nil)
+
+	// Resume from b.txt's timestamp: only c.txt replays
+	resumed, rrd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s&sinceTs=%s", base, wsID, last.TS))
+	defer resumed.Body.Close()
+	evt, err := readNextWorkspaceEvent(rrd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "c.txt", evt.Path)
+	_, err = readNextWorkspaceEvent(rrd, 800*time.Millisecond)
+	require.Error(t, err, "expected no older events to replay")
+
+	// A timestamp older than the buffer replays everything
+	old := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	full, frd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s&sinceTs=%s", base, wsID, old))
+	defer full.Body.Close()
+	evt, err = readNextWorkspaceEvent(frd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", evt.Path)
+
+	resp, err := http.Get(fmt.Sprintf("%s/events?workspaceId=%s&sinceTs=yesterday", base, wsID))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHTTP_GracefulShutdown_OnSIGTERM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM is not deliverable on windows")
+	}
+	bin := buildBinary(t)
+	wsRoot := t.TempDir()
+	host, port := "127.0.0.1", "18112"
+	server := startServer(t, bin, wsRoot, host, port)
+	base := fmt.Sprintf("http://%s:%s", host, port)
+	wsID := createWorkspace(t, base, "Shutdown Test")
+
+	stream, rd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s", base, wsID))
+	defer stream.Body.Close()
+
+	exited := make(chan error, 1)
+	go func() { exited <- server.Wait() }()
+	require.NoError(t, server.Process.Signal(syscall.SIGTERM))
+
+	// SSE clients get a clean EOF rather than a hung connection
+	streamDone := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, rd)
+		streamDone <- err
+	}()
+	select {
+	case err := <-streamDone:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SSE stream was not closed on shutdown")
+	}
+
+	select {
+	case err := <-exited:
+		require.NoError(t, err, "server should exit with status 0")
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not exit after SIGTERM")
+	}
+}
+
+func TestHTTP_SSE_CreateDirectoryTwice_SingleEvent(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18124")
+	wsID := createWorkspace(t, base, "Mkdir Twice")
+
+	stream, rd := openSSE(t, base+"/events?workspaceId="+wsID)
+	defer stream.Body.Close()
+
+	type mkdirOut struct {
+		Created bool   `json:"created"`
+		Commit  string `json:"commit"`
+	}
+	var first, second mkdirOut
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "docs"}, http.StatusOK, &first)
+	require.True(t, first.Created)
+	require.NotEmpty(t, first.Commit)
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "docs"}, http.StatusOK, &second)
+	require.False(t, second.Created)
+	require.Empty(t, second.Commit)
+
+	// A later write marks the end of the events caused by the two creates
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/a.txt", "content": "x"}, http.StatusOK, nil)
+
+	evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "dir.created", evt.Type)
+	require.Equal(t, "docs", evt.Path)
+	evt, err = readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "file.created", evt.Type, "second create must not emit dir.created")
+
+	// A directory that has files is a no-op too: it needs no .gitkeep
+	var third mkdirOut
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "docs"}, http.StatusOK, &third)
+	require.False(t, third.Created)
+	require.Empty(t, third.Commit)
+	_, err = os.Stat(filepath.Join(wsRoot, wsID, "docs", ".gitkeep"))
+	require.True(t, os.IsNotExist(err), "no marker is added next to docs/a.txt")
+}
+
+func TestHTTP_SSE_GlobalStream(t *testing.T) {
+	base, _ := startTestServer(t, "18132", "--auth-tokens=secret")
+	var a, b struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	callToolAs(t, base, "secret", "workspace_create", map[string]any{"name": "Global A"}, http.StatusOK, &a)
+	callToolAs(t, base, "secret", "workspace_create", map[string]any{"name": "Global B"}, http.StatusOK, &b)
+
+	// The global stream is authenticated like per-workspace streams
+	resp, err := http.Get(base + "/events?workspaceId=*")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	stream, rd := openSSE(t, base+"/events?workspaceId=*&token=secret")
+	defer stream.Body.Close()
+
+	callToolAs(t, base, "secret", "fs_write_file", map[string]any{"workspaceId": a.WorkspaceID, "path": "a.txt", "content": "a"}, http.StatusOK, nil)
+	callToolAs(t, base, "secret", "fs_write_file", map[string]any{"workspaceId": b.WorkspaceID, "path": "b.txt", "content": "b"}, http.StatusOK, nil)
+
+	seen := map[string]string{}
+	for len(seen) < 2 {
+		evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+		require.NoError(t, err)
+		seen[evt.WorkspaceID] = evt.Path
+	}
+	require.Equal(t, map[string]string{a.WorkspaceID: "a.txt", b.WorkspaceID: "b.txt"}, seen)
+
+	// Resuming uses global ids: A's ids run ahead of B's, and B's next event
+	// must still be replayed after A's last one
+	for _, p := range []string{"a2.txt", "a3.txt"} {
+		callToolAs(t, base, "secret", "fs_write_file", map[string]any{"workspaceId": a.WorkspaceID, "path": p, "content": "a"}, http.StatusOK, nil)
+	}
+	var lastA *sseWorkspaceEvent
+	for lastA == nil || lastA.Path != "a3.txt" {
+		evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+		require.NoError(t, err)
+		lastA = evt
+	}
+	callToolAs(t, base, "secret", "fs_write_file", map[string]any{"workspaceId": b.WorkspaceID, "path": "b2.txt", "content": "b"}, http.StatusOK, nil)
+	live, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "b2.txt", live.Path)
+	require.Less(t, live.ID, lastA.ID, "B's own ids lag behind A's")
+
+	resumed, rd2 := openSSE(t, base+"/events?workspaceId=*&token=secret&since="+strconv.FormatInt(lastA.GlobalID, 10))
+	defer resumed.Body.Close()
+	evt, err := readNextWorkspaceEvent(rd2, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, b.WorkspaceID, evt.WorkspaceID)
+	require.Equal(t, "b2.txt", evt.Path)
+	require.Equal(t, live.GlobalID, evt.GlobalID)
+}
+
+func TestHTTP_WS_Events_ReplayThenLive(t *testing.T) {
+	base, _ := startTestServer(t, "18134", "--auth-tokens=secret")
+	var ws struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	callToolAs(t, base, "secret", "workspace_create", map[string]any{"name": "WS Events"}, http.StatusOK, &ws)
+	callToolAs(t, base, "secret", "fs_write_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt", "content": "a"}, http.StatusOK, nil)
+
+	wsURL := "ws" + strings.TrimPrefix(base, "http") + "/ws/events?workspaceId=" + ws.WorkspaceID + "&since=0"
+
+	// Same auth as SSE
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"&token=secret", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	readEvent := func() sseWorkspaceEvent {
+		t.Helper()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+		msgType, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, websocket.TextMessage, msgType)
+		var evt sseWorkspaceEvent
+		require.NoError(t, json.Unmarshal(data, &evt))
+		return evt
+	}
+
+	// Replayed from the buffer
+	evt := readEvent()
+	require.Equal(t, "file.created", evt.Type)
+	require.Equal(t, "a.txt", evt.Path)
+	require.Equal(t, ws.WorkspaceID, evt.WorkspaceID)
+
+	// Then live
+	callToolAs(t, base, "secret", "fs_write_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt", "content": "b"}, http.StatusOK, nil)
+	live := readEvent()
+	require.Equal(t, "file.updated", live.Type)
+	require.Greater(t, live.ID, evt.ID)
+}
+
+func TestHTTP_SSE_PresenceJoinLeave(t *testing.T) {
+	base, _ := startTestServer(t, "18137")
+	wsID := createWorkspace(t, base, "Presence")
+
+	first, rd := openSSE(t, base+"/events?workspaceId="+wsID+"&clientId=alice")
+	defer first.Body.Close()
+	evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "presence.join", evt.Type)
+	require.NotNil(t, evt.Actor)
+	require.NotNil(t, evt.Actor.ID)
+	require.Equal(t, "alice", *evt.Actor.ID)
+	require.Equal(t, 1, *evt.Viewers)
+
+	second, _ := openSSE(t, base+"/events?workspaceId="+wsID+"&clientId=bob")
+	evt, err = readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "presence.join", evt.Type)
+	require.Equal(t, "bob", *evt.Actor.ID)
+	require.Equal(t, 2, *evt.Viewers)
+
+	// Streams without a clientId do not take part in presence
+	anon, _ := openSSE(t, base+"/events?workspaceId="+wsID)
+	defer anon.Body.Close()
+
+	second.Body.Close()
+	evt, err = readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "presence.leave", evt.Type)
+	require.Equal(t, "bob", *evt.Actor.ID)
+	require.Equal(t, 1, *evt.Viewers)
+}
+
+func TestHTTP_REST_FSWaitForChange(t *testing.T) {
+	base, _ := startTestServer(t, "18138")
+	wsID := createWorkspace(t, base, "Wait")
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		resp := restPOST(t, base+"/api/tools/fs_write_file", map[string]any{"workspaceId": wsID, "path": "out/build.log", "content": "done"})
+		resp.Body.Close()
+	}()
+
+	var out struct {
+		Event sseWorkspaceEvent `json:"event"`
+	}
+	callTool(t, base, "fs_wait_for_change", map[string]any{"workspaceId": wsID, "path": "out/build.log", "timeoutMs": 5000}, http.StatusOK, &out)
+	require.Equal(t, "file.created", out.Event.Type)
+	require.Equal(t, "out/build.log", out.Event.Path)
+	require.Equal(t, wsID, out.Event.WorkspaceID)
+
+	// No change within the timeout
+	resp := restPOST(t, base+"/api/tools/fs_wait_for_change", map[string]any{"workspaceId": wsID, "path": "out/build.log", "timeoutMs": 200})
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	require.Equal(t, http.StatusRequestTimeout, resp.StatusCode, string(body))
+	require.Contains(t, string(body), "TIMEOUT")
+}
+
+func TestHTTP_SSE_EventsBufferAndHeartbeat(t *testing.T) {
+	base, _ := startTestServer(t, "18140", "--events-buffer=2", "--events-heartbeat=200ms")
+	wsID := createWorkspace(t, base, "Small Buffer")
+	for _, p := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": p, "content": p}, http.StatusOK, nil)
+	}
+
+	// Resuming from the first event replays only the two newest
+	resp, rd := openSSE(t, base+"/events?workspaceId="+wsID+"&since=1")
+	defer resp.Body.Close()
+	var paths []string
+	for range 2 {
+		evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+		require.NoError(t, err)
+		paths = append(paths, evt.Path)
+	}
+	require.Equal(t, []string{"c.txt", "d.txt"}, paths)
+
+	// Heartbeat comments arrive at the configured interval
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		require.True(t, time.Now().Before(deadline), "no heartbeat received")
+		line, err := rd.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, ": ping") {
+			break
+		}
+	}
+}
+
+func TestHTTP_SSE_APIWriteReportedOnce(t *testing.T) {
+	base, _ := startTestServer(t, "18142")
+	wsID := createWorkspace(t, base, "No Echo")
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "src"}, http.StatusOK, nil)
+
+	resp, rd := openSSE(t, base+"/events?workspaceId="+wsID)
+	defer resp.Body.Close()
+	evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "dir.created", evt.Type) // replayed
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "src/new.txt", "content": "hi"}, http.StatusOK, nil)
+	evt, err = readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "file.created", evt.Type)
+	require.Equal(t, "src/new.txt", evt.Path)
+	require.NotNil(t, evt.Actor)
+	require.NotEqual(t, "fswatch", evt.Actor.Kind)
+
+	// The watcher sees the same change on disk but must not report it again: the
+	// next event is the one for a later marker write
+	time.Sleep(1500 * time.Millisecond)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "marker.txt", "content": "m"}, http.StatusOK, nil)
+	evt, err = readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "marker.txt", evt.Path, "unexpected echo: %s %s (%s)", evt.Type, evt.Path, evt.Actor.Kind)
+}
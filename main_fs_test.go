@@ -0,0 +1,1963 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP_REST_FSDiffFiles(t *testing.T) {
+	base, _ := startTestServer(t, "18100")
+	wsID := createWorkspace(t, base, "Diff Test")
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "one\ntwo\nthree\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "one\n2\nthree\nfour\n"}, http.StatusOK, nil)
+
+	var out struct {
+		Diff    string `json:"diff"`
+		Added   int    `json:"added"`
+		Removed int    `json:"removed"`
+	}
+	callTool(t, base, "fs_diff_files", map[string]any{"workspaceId": wsID, "pathA": "a.txt", "pathB": "b.txt"}, http.StatusOK, &out)
+	assert.Equal(t, 2, out.Added)
+	assert.Equal(t, 1, out.Removed)
+	assert.True(t, strings.HasPrefix(out.Diff, "--- a/a.txt\n+++ b/b.txt\n@@ -1,3 +1,4 @@\n"), out.Diff)
+	assert.Contains(t, out.Diff, "-two\n+2\n")
+
+	// Inline content on the B side
+	callTool(t, base, "fs_diff_files", map[string]any{"workspaceId": wsID, "pathA": "a.txt", "contentB": "one\ntwo\nthree\n"}, http.StatusOK, &out)
+	require.Equal(t, "", out.Diff)
+
+	callTool(t, base, "fs_diff_files", map[string]any{"workspaceId": wsID, "pathA": "a.txt", "pathB": "missing.txt"}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_diff_files", map[string]any{"workspaceId": wsID, "pathA": ".gitkeep", "contentB": "x"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_FSJSONSet_NestedKey(t *testing.T) {
+	base, _ := startTestServer(t, "18101")
+	wsID := createWorkspace(t, base, "JSON Set Test")
+
+	orig := "{\n  \"name\": \"app\",\n  \"build\": {\n    \"target\": \"es2020\"\n  }\n}\n"
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "config.json", "content": orig}, http.StatusOK, nil)
+
+	var setOut struct {
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_json_set", map[string]any{
+		"workspaceId": wsID, "path": "config.json", "pointer": "/build/options/minify", "value": true,
+	}, http.StatusOK, &setOut)
+	require.NotEmpty(t, setOut.Commit)
+
+	var readOut struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "config.json"}, http.StatusOK, &readOut)
+	want := "{\n  \"name\": \"app\",\n  \"build\": {\n    \"target\": \"es2020\",\n    \"options\": {\n      \"minify\": true\n    }\n  }\n}\n"
+	assert.Equal(t, want, readOut.Content)
+
+	// Traversing into a scalar is rejected
+	callTool(t, base, "fs_json_set", map[string]any{
+		"workspaceId": wsID, "path": "config.json", "pointer": "/name/x", "value": 1,
+	}, http.StatusBadRequest, nil)
+
+	// null is a value; only a missing one is rejected
+	callTool(t, base, "fs_json_set", map[string]any{
+		"workspaceId": wsID, "path": "config.json", "pointer": "/build/options", "value": nil,
+	}, http.StatusOK, nil)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "config.json"}, http.StatusOK, &readOut)
+	assert.Contains(t, readOut.Content, "\"options\": null")
+	callTool(t, base, "fs_json_set", map[string]any{
+		"workspaceId": wsID, "path": "config.json", "pointer": "/name",
+	}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_FSSetMtime(t *testing.T) {
+	base, _ := startTestServer(t, "18103")
+	wsID := createWorkspace(t, base, "Mtime Test")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "out.bin", "content": "x"}, http.StatusOK, nil)
+
+	const when = "2021-06-01T12:30:00Z"
+	callTool(t, base, "fs_set_mtime", map[string]any{"workspaceId": wsID, "path": "out.bin", "mtime": when}, http.StatusOK, nil)
+
+	var info struct {
+		Mtime string `json:"mtime"`
+	}
+	callTool(t, base, "fs_get_file_info", map[string]any{"workspaceId": wsID, "path": "out.bin"}, http.StatusOK, &info)
+	assert.Equal(t, when, info.Mtime)
+
+	callTool(t, base, "fs_set_mtime", map[string]any{"workspaceId": wsID, "path": "out.bin", "mtime": "yesterday"}, http.StatusBadRequest, nil)
+	callTool(t, base, "fs_set_mtime", map[string]any{"workspaceId": wsID, "path": ".gitkeep", "mtime": when}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_FSEstimateRead(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18110")
+	wsID := createWorkspace(t, base, "Estimate Test")
+
+	content := strings.Repeat("0123456789\n", 40) + "tail without newline"
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/big.txt", "content": content}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/nested/small.txt", "content": "a\nb\n"}, http.StatusOK, nil)
+
+	type estimate struct {
+		Files           int   `json:"files"`
+		Bytes           int64 `json:"bytes"`
+		Lines           int   `json:"lines"`
+		EstimatedTokens int64 `json:"estimatedTokens"`
+	}
+	var out estimate
+	callTool(t, base, "fs_estimate_read", map[string]any{"workspaceId": wsID, "path": "docs/big.txt"}, http.StatusOK, &out)
+	onDisk, err := os.ReadFile(filepath.Join(wsRoot, wsID, "docs", "big.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(onDisk)), out.Bytes)
+	assert.Equal(t, len(strings.Split(string(onDisk), "\n")), out.Lines)
+	assert.Equal(t, 1, out.Files)
+	assert.Equal(t, (out.Bytes+3)/4, out.EstimatedTokens)
+
+	// Non-recursive directory estimate only counts direct children
+	callTool(t, base, "fs_estimate_read", map[string]any{"workspaceId": wsID, "path": "docs"}, http.StatusOK, &out)
+	assert.Equal(t, 1, out.Files)
+
+	callTool(t, base, "fs_estimate_read", map[string]any{"workspaceId": wsID, "path": "docs", "recursive": true}, http.StatusOK, &out)
+	assert.Equal(t, 2, out.Files)
+	assert.Equal(t, int64(len(onDisk)+4), out.Bytes)
+	assert.Equal(t, 41+2, out.Lines)
+
+	callTool(t, base, "fs_estimate_read", map[string]any{"workspaceId": wsID, "path": "missing.txt"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_AtomicWrite_ConfiguredTempDir(t *testing.T) {
+	tempDir := t.TempDir()
+	orphan := filepath.Join(tempDir, "mcp-write-orphan")
+	require.NoError(t, os.WriteFile(orphan, []byte("partial"), 0o644))
+
+	base, wsRoot := startTestServer(t, "18111", "--temp-dir="+tempDir)
+	wsID := createWorkspace(t, base, "Atomic Test")
+
+	_, err := os.Stat(orphan)
+	require.True(t, os.IsNotExist(err), "orphaned temp file should be removed on startup")
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "dir/a.txt", "content": "first"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "dir/a.txt", "content": "second"}, http.StatusOK, nil)
+
+	got, err := os.ReadFile(filepath.Join(wsRoot, wsID, "dir", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(got))
+
+	leftovers, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, leftovers, "temp files should be renamed into place")
+}
+
+func TestHTTP_REST_FSSearchAndRead_JSON(t *testing.T) {
+	base, _ := startTestServer(t, "18113")
+	wsID := createWorkspace(t, base, "Search Read Test")
+
+	files := map[string]string{
+		"package.json":         `{"name":"app"}`,
+		"config/tsconfig.json": `{"strict":true}`,
+		"README.md":            "# readme",
+	}
+	for p, c := range files {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": p, "content": c}, http.StatusOK, nil)
+	}
+
+	var out struct {
+		Files []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"files"`
+		Truncated bool `json:"truncated"`
+	}
+	callTool(t, base, "fs_search_and_read", map[string]any{"workspaceId": wsID, "pattern": "*.json"}, http.StatusOK, &out)
+	require.Len(t, out.Files, 2)
+	assert.False(t, out.Truncated)
+	got := map[string]string{}
+	for _, f := range out.Files {
+		got[f.Path] = f.Content
+	}
+	assert.Equal(t, files["package.json"], got["package.json"])
+	assert.Equal(t, files["config/tsconfig.json"], got["config/tsconfig.json"])
+
+	// Content filter and count cap
+	callTool(t, base, "fs_search_and_read", map[string]any{"workspaceId": wsID, "pattern": "*.json", "content": "strict"}, http.StatusOK, &out)
+	require.Len(t, out.Files, 1)
+	assert.Equal(t, "config/tsconfig.json", out.Files[0].Path)
+
+	callTool(t, base, "fs_search_and_read", map[string]any{"workspaceId": wsID, "pattern": "*.json", "maxFiles": 1}, http.StatusOK, &out)
+	require.Len(t, out.Files, 1)
+	assert.True(t, out.Truncated)
+
+	// Large files are read only up to the cap, which never splits a character;
+	// the content filter still sees all of them
+	big := strings.Repeat("é", 100000) + "needle"
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "big.txt", "content": big}, http.StatusOK, nil)
+	var capped struct {
+		Files []struct {
+			Path      string `json:"path"`
+			Content   string `json:"content"`
+			Size      int64  `json:"size"`
+			Truncated bool   `json:"truncated"`
+		} `json:"files"`
+	}
+	callTool(t, base, "fs_search_and_read", map[string]any{"workspaceId": wsID, "pattern": "*.txt", "content": "needle", "maxBytesPerFile": 5}, http.StatusOK, &capped)
+	require.Len(t, capped.Files, 1)
+	assert.Equal(t, "éé", capped.Files[0].Content)
+	assert.Equal(t, int64(len(big)), capped.Files[0].Size)
+	assert.True(t, capped.Files[0].Truncated)
+}
+
+func TestHTTP_REST_FSRestoreFromSnapshot(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18118")
+	wsID := createWorkspace(t, base, "Restore")
+
+	var w struct {
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "content": "original\n"}, http.StatusOK, &w)
+
+	// Snapshot the current state as a tag
+	repo, err := git.PlainOpen(filepath.Join(wsRoot, wsID))
+	require.NoError(t, err)
+	_, err = repo.CreateTag("before-edit", plumbing.NewHash(w.Commit), nil)
+	require.NoError(t, err)
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "content": "edited\n"}, http.StatusOK, nil)
+
+	var out struct {
+		Path           string `json:"path"`
+		SnapshotCommit string `json:"snapshotCommit"`
+		Commit         string `json:"commit"`
+	}
+	callTool(t, base, "fs_restore_from_snapshot", map[string]any{"workspaceId": wsID, "snapshot": "before-edit", "path": "notes/a.txt"}, http.StatusOK, &out)
+	assert.Equal(t, w.Commit, out.SnapshotCommit)
+	assert.NotEmpty(t, out.Commit)
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "original\n", read.Content)
+
+	// Restoring again is a no-op
+	callTool(t, base, "fs_restore_from_snapshot", map[string]any{"workspaceId": wsID, "snapshot": "before-edit", "path": "notes/a.txt"}, http.StatusOK, &out)
+	assert.Empty(t, out.Commit)
+
+	callTool(t, base, "fs_restore_from_snapshot", map[string]any{"workspaceId": wsID, "snapshot": "no-such-tag", "path": "notes/a.txt"}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_restore_from_snapshot", map[string]any{"workspaceId": wsID, "snapshot": "before-edit", "path": "missing.txt"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_FSGetCommitHistory_Paging(t *testing.T) {
+	base, _ := startTestServer(t, "18119")
+	wsID := createWorkspace(t, base, "History Paging")
+
+	// Initial commit plus five writes: six commits in total
+	for i := 0; i < 5; i++ {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": strings.Repeat("x", i+1)}, http.StatusOK, nil)
+	}
+
+	type page struct {
+		Log []struct {
+			Commit string `json:"commit"`
+			Parent string `json:"parent"`
+		} `json:"log"`
+		NextCursor string `json:"nextCursor"`
+	}
+	var all page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "limit": 10}, http.StatusOK, &all)
+	require.Len(t, all.Log, 6)
+	assert.Empty(t, all.NextCursor)
+
+	var first page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "limit": 4}, http.StatusOK, &first)
+	require.Len(t, first.Log, 4)
+	require.Equal(t, first.Log[3].Commit, first.NextCursor)
+
+	var second page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "limit": 4, "before": first.NextCursor}, http.StatusOK, &second)
+	require.Len(t, second.Log, 2)
+	assert.Empty(t, second.NextCursor)
+
+	// The two pages together are the full history: no overlap, no gaps
+	var paged []string
+	for _, c := range append(first.Log, second.Log...) {
+		paged = append(paged, c.Commit)
+	}
+	var full []string
+	for _, c := range all.Log {
+		full = append(full, c.Commit)
+	}
+	assert.Equal(t, full, paged)
+	assert.Equal(t, first.Log[3].Parent, second.Log[0].Commit)
+
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "before": strings.Repeat("0", 40)}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_FSGetCommitHistory_TimeWindow(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18187")
+	wsID := createWorkspace(t, base, "History Window")
+
+	// Seed one commit a week through January 2024, besides the initial commit made now
+	repo, err := git.PlainOpen(filepath.Join(wsRoot, wsID))
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "a.txt"), []byte(strings.Repeat("x", i+1)), 0644))
+		_, err := wt.Add("a.txt")
+		require.NoError(t, err)
+		_, err = wt.Commit(fmt.Sprintf("week %d", i+1), &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: start.AddDate(0, 0, 7*i)},
+		})
+		require.NoError(t, err)
+	}
+
+	type page struct {
+		Log []struct {
+			Message string `json:"message"`
+			Date    string `json:"date"`
+		} `json:"log"`
+		NextCursor string `json:"nextCursor"`
+	}
+	messages := func(p page) []string {
+		var out []string
+		for _, c := range p.Log {
+			out = append(out, c.Message)
+		}
+		return out
+	}
+
+	// Both bounds are inclusive
+	var window page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "since": "2024-01-08T12:00:00Z", "until": "2024-01-15T12:00:00Z"}, http.StatusOK, &window)
+	assert.Equal(t, []string{"week 3", "week 2"}, messages(window))
+
+	var since page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "since": "2024-01-10T00:00:00Z"}, http.StatusOK, &since)
+	assert.Equal(t, []string{"week 4", "week 3", "Initial commit"}, messages(since))
+
+	// limit still applies, and paging continues within the window
+	var first page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "until": "2024-02-01T00:00:00Z", "limit": 3}, http.StatusOK, &first)
+	assert.Equal(t, []string{"week 4", "week 3", "week 2"}, messages(first))
+	require.NotEmpty(t, first.NextCursor)
+	var second page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "until": "2024-02-01T00:00:00Z", "limit": 3, "before": first.NextCursor}, http.StatusOK, &second)
+	assert.Equal(t, []string{"week 1"}, messages(second))
+	assert.Empty(t, second.NextCursor)
+
+	// Per-file history is filtered too
+	var file page
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "path": "a.txt", "until": "2024-01-02T00:00:00Z"}, http.StatusOK, &file)
+	assert.Equal(t, []string{"week 1"}, messages(file))
+
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "since": "last week"}, http.StatusBadRequest, nil)
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "since": "2024-02-01T00:00:00Z", "until": "2024-01-01T00:00:00Z"}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_FSManifest(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18121")
+	wsID := createWorkspace(t, base, "Manifest")
+
+	files := map[string]string{
+		"b.txt":         "bravo\n",
+		"a.txt":         "alpha\n",
+		"dir/z.txt":     strings.Repeat("z", 100000),
+		"dir/sub/c.txt": "",
+	}
+	for p, c := range files {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": p, "content": c}, http.StatusOK, nil)
+	}
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "empty"}, http.StatusOK, nil)
+
+	type manifest struct {
+		Files []struct {
+			Path   string `json:"path"`
+			Size   int64  `json:"size"`
+			SHA256 string `json:"sha256"`
+		} `json:"files"`
+	}
+	var m manifest
+	callTool(t, base, "fs_manifest", map[string]any{"workspaceId": wsID}, http.StatusOK, &m)
+
+	var paths []string
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+		b, err := os.ReadFile(filepath.Join(wsRoot, wsID, filepath.FromSlash(f.Path)))
+		require.NoError(t, err)
+		sum := sha256.Sum256(b)
+		assert.Equal(t, hex.EncodeToString(sum[:]), f.SHA256, f.Path)
+		assert.EqualValues(t, len(b), f.Size, f.Path)
+	}
+	assert.Equal(t, []string{"a.txt", "b.txt", "dir/sub/c.txt", "dir/z.txt"}, paths)
+
+	// Deterministic across calls; subtree paths stay workspace-relative
+	var again manifest
+	callTool(t, base, "fs_manifest", map[string]any{"workspaceId": wsID}, http.StatusOK, &again)
+	assert.Equal(t, m, again)
+
+	var sub manifest
+	callTool(t, base, "fs_manifest", map[string]any{"workspaceId": wsID, "path": "dir"}, http.StatusOK, &sub)
+	require.Len(t, sub.Files, 2)
+	assert.Equal(t, "dir/sub/c.txt", sub.Files[0].Path)
+
+	callTool(t, base, "fs_manifest", map[string]any{"workspaceId": wsID, "path": "nope"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_FSCopyBetweenWorkspaces(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18122")
+	srcWS := createWorkspace(t, base, "Copy Source")
+	dstWS := createWorkspace(t, base, "Copy Dest")
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": srcWS, "path": "src/main.go", "content": "package main\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": srcWS, "path": "src/lib/util.go", "content": "package lib\n"}, http.StatusOK, nil)
+
+	var out struct {
+		DestPath string `json:"destPath"`
+		IsDir    bool   `json:"isDir"`
+		Commit   string `json:"commit"`
+	}
+	callTool(t, base, "fs_copy_between_workspaces", map[string]any{
+		"sourceWorkspaceId": srcWS, "sourcePath": "src",
+		"destWorkspaceId": dstWS, "destPath": "vendor/src",
+	}, http.StatusOK, &out)
+	assert.True(t, out.IsDir)
+	require.NotEmpty(t, out.Commit)
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": dstWS, "path": "vendor/src/lib/util.go"}, http.StatusOK, &read)
+	assert.Equal(t, "package lib\n", read.Content)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": dstWS, "path": "vendor/src/main.go"}, http.StatusOK, &read)
+	assert.Equal(t, "package main\n", read.Content)
+
+	// The copy is committed in the destination, and the source is untouched
+	repo, err := git.PlainOpen(filepath.Join(wsRoot, dstWS))
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, out.Commit, head.Hash().String())
+	c, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	_, err = c.File("vendor/src/lib/util.go")
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(wsRoot, srcWS, "vendor"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Never overwrite; protected paths and missing sources are rejected
+	callTool(t, base, "fs_copy_between_workspaces", map[string]any{
+		"sourceWorkspaceId": srcWS, "sourcePath": "src/main.go",
+		"destWorkspaceId": dstWS, "destPath": "vendor/src/main.go",
+	}, http.StatusConflict, nil)
+	callTool(t, base, "fs_copy_between_workspaces", map[string]any{
+		"sourceWorkspaceId": srcWS, "sourcePath": ".git",
+		"destWorkspaceId": dstWS, "destPath": "stolen-git",
+	}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_copy_between_workspaces", map[string]any{
+		"sourceWorkspaceId": srcWS, "sourcePath": "missing",
+		"destWorkspaceId": dstWS, "destPath": "x",
+	}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_FSGetDirectorySize(t *testing.T) {
+	base, _ := startTestServer(t, "18123")
+	wsID := createWorkspace(t, base, "Dir Size")
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "12345"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "d/b.txt", "content": "123"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "d/e/c.txt", "content": "12"}, http.StatusOK, nil)
+
+	type size struct {
+		CombinedSize int64 `json:"combinedSize"`
+		Files        int   `json:"files"`
+		Directories  int   `json:"directories"`
+		Truncated    bool  `json:"truncated"`
+	}
+	var all size
+	callTool(t, base, "fs_get_directory_size", map[string]any{"workspaceId": wsID, "path": "."}, http.StatusOK, &all)
+	assert.Equal(t, size{CombinedSize: 10, Files: 3, Directories: 2}, all)
+
+	var shallow size
+	callTool(t, base, "fs_get_directory_size", map[string]any{"workspaceId": wsID, "path": ".", "maxDepth": 2}, http.StatusOK, &shallow)
+	assert.Equal(t, size{CombinedSize: 8, Files: 2, Directories: 2, Truncated: true}, shallow)
+
+	callTool(t, base, "fs_get_directory_size", map[string]any{"workspaceId": wsID, "path": "a.txt"}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_WorkspaceFindFiles(t *testing.T) {
+	base, _ := startTestServer(t, "18125")
+	wsA := createWorkspace(t, base, "Find A")
+	wsB := createWorkspace(t, base, "Find B")
+	wsC := createWorkspace(t, base, "Find C")
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsA, "path": "config/app.yaml", "content": "env: prod\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsB, "path": "app.yaml", "content": "env: dev\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsC, "path": "other.txt", "content": "app.yaml"}, http.StatusOK, nil)
+
+	type found struct {
+		Workspaces []struct {
+			WorkspaceID string   `json:"workspaceId"`
+			Matches     []string `json:"matches"`
+		} `json:"workspaces"`
+	}
+	var all found
+	callTool(t, base, "workspace_find_files", map[string]any{"pattern": "app.yaml"}, http.StatusOK, &all)
+	require.Len(t, all.Workspaces, 2)
+	assert.Equal(t, wsA, all.Workspaces[0].WorkspaceID)
+	assert.Equal(t, []string{"config/app.yaml"}, all.Workspaces[0].Matches)
+	assert.Equal(t, wsB, all.Workspaces[1].WorkspaceID)
+	assert.Equal(t, []string{"app.yaml"}, all.Workspaces[1].Matches)
+
+	var prod found
+	callTool(t, base, "workspace_find_files", map[string]any{"pattern": "*.yaml", "content": "prod"}, http.StatusOK, &prod)
+	require.Len(t, prod.Workspaces, 1)
+	assert.Equal(t, wsA, prod.Workspaces[0].WorkspaceID)
+
+	// Files are scanned in chunks; a match across a chunk boundary still counts
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsC, "path": "big.yaml", "content": strings.Repeat("x", 64<<10-2) + "prod\n"}, http.StatusOK, nil)
+	callTool(t, base, "workspace_find_files", map[string]any{"pattern": "*.yaml", "content": "prod", "workspaceIds": []string{wsC}}, http.StatusOK, &prod)
+	require.Len(t, prod.Workspaces, 1)
+	assert.Equal(t, []string{"big.yaml"}, prod.Workspaces[0].Matches)
+
+	var subset found
+	callTool(t, base, "workspace_find_files", map[string]any{"pattern": "app.yaml", "workspaceIds": []string{wsB, wsC}}, http.StatusOK, &subset)
+	require.Len(t, subset.Workspaces, 1)
+	assert.Equal(t, wsB, subset.Workspaces[0].WorkspaceID)
+
+	// Protected files are never reported
+	var keep found
+	callTool(t, base, "workspace_find_files", map[string]any{"pattern": ".gitkeep"}, http.StatusOK, &keep)
+	assert.Empty(t, keep.Workspaces)
+
+	callTool(t, base, "workspace_find_files", map[string]any{"pattern": "app.yaml", "workspaceIds": []string{"nope"}}, http.StatusNotFound, nil)
+}
+
+func findNode(nodes []treeNode, name string) *treeNode {
+	for i := range nodes {
+		if nodes[i].Name == name {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+func writeDeepTree(t *testing.T, base, wsID string) {
+	for _, p := range []string{"top.txt", "a/one.txt", "a/b/two.txt", "a/b/c/three.txt"} {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": p, "content": p}, http.StatusOK, nil)
+	}
+}
+
+func TestHTTP_REST_FSDirectoryTree_MaxDepth(t *testing.T) {
+	base, _ := startTestServer(t, "18126")
+	wsID := createWorkspace(t, base, "Tree Depth")
+	writeDeepTree(t, base, wsID)
+
+	var out dirTreeResp
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": ".", "maxDepth": 2}, http.StatusOK, &out)
+
+	require.Len(t, out.Tree, 2)
+	a := findNode(out.Tree, "a")
+	require.NotNil(t, a)
+	assert.False(t, a.Truncated)
+	require.NotNil(t, a.Children)
+	b := findNode(*a.Children, "b")
+	require.NotNil(t, b)
+	assert.True(t, b.Truncated, "directory at maxDepth should be marked truncated")
+	assert.Nil(t, b.Children, "children omitted at maxDepth")
+	assert.NotNil(t, findNode(*a.Children, "one.txt"))
+
+	// Unlimited depth reaches the deepest file and never hides protected names
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": "."}, http.StatusOK, &out)
+	assert.Nil(t, findNode(out.Tree, ".git"))
+	assert.Nil(t, findNode(out.Tree, ".gitkeep"))
+	c := findNode(*findNode(*findNode(out.Tree, "a").Children, "b").Children, "c")
+	require.NotNil(t, c)
+	assert.False(t, c.Truncated)
+	require.NotNil(t, c.Children)
+	assert.NotNil(t, findNode(*c.Children, "three.txt"))
+}
+
+func TestHTTP_REST_FSDirectoryTree_DirsOnlyTruncation(t *testing.T) {
+	base, _ := startTestServer(t, "18127")
+	wsID := createWorkspace(t, base, "Tree Dirs Only")
+	writeDeepTree(t, base, wsID)
+
+	var out dirTreeResp
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": ".", "maxDepth": 1, "dirsOnly": true}, http.StatusOK, &out)
+	require.Len(t, out.Tree, 1)
+	assert.Equal(t, "a", out.Tree[0].Name)
+	assert.Equal(t, "directory", out.Tree[0].Type)
+	assert.True(t, out.Tree[0].Truncated)
+	assert.Nil(t, out.Tree[0].Children)
+
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": ".", "maxDepth": -1}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_FSDirectoryTree_IncludeStats(t *testing.T) {
+	base, _ := startTestServer(t, "18181")
+	wsID := createWorkspace(t, base, "Tree Stats")
+	writeDeepTree(t, base, wsID)
+
+	var out dirTreeResp
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": ".", "maxDepth": 2, "includeStats": true}, http.StatusOK, &out)
+	top := findNode(out.Tree, "top.txt")
+	require.NotNil(t, top)
+	require.NotNil(t, top.Size)
+	assert.Equal(t, int64(len("top.txt")), *top.Size)
+	_, err := time.Parse(time.RFC3339, top.Mtime)
+	assert.NoError(t, err)
+	assert.Nil(t, top.ChildCount)
+
+	a := findNode(out.Tree, "a")
+	require.NotNil(t, a)
+	require.NotNil(t, a.ChildCount)
+	assert.Equal(t, 2, *a.ChildCount)
+	assert.Nil(t, a.Size)
+	b := findNode(*a.Children, "b")
+	require.NotNil(t, b)
+	assert.True(t, b.Truncated)
+	require.NotNil(t, b.ChildCount, "truncated directories still report their child count")
+	assert.Equal(t, 2, *b.ChildCount)
+
+	// The default tree stays lean
+	var lean dirTreeResp
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": "."}, http.StatusOK, &lean)
+	top = findNode(lean.Tree, "top.txt")
+	require.NotNil(t, top)
+	assert.Nil(t, top.Size)
+	assert.Empty(t, top.Mtime)
+	assert.Nil(t, findNode(lean.Tree, "a").ChildCount)
+}
+
+func TestHTTP_REST_FSReadTextFile_SliceEtag(t *testing.T) {
+	base, _ := startTestServer(t, "18128")
+	wsID := createWorkspace(t, base, "Slice Etag")
+	content := "line1\nline2\nline3\nline4\n"
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": content}, http.StatusOK, nil)
+
+	var out struct {
+		Content   string `json:"content"`
+		Etag      string `json:"etag"`
+		SliceEtag string `json:"sliceEtag"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "head": 2}, http.StatusOK, &out)
+	require.Equal(t, "line1\nline2", out.Content)
+
+	sliceSum := sha256.Sum256([]byte(out.Content))
+	assert.Equal(t, hex.EncodeToString(sliceSum[:]), out.SliceEtag)
+	fileSum := sha256.Sum256([]byte(content))
+	assert.Equal(t, hex.EncodeToString(fileSum[:]), out.Etag)
+	assert.NotEqual(t, out.Etag, out.SliceEtag)
+
+	// A full read's slice is the whole file
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt"}, http.StatusOK, &out)
+	assert.Equal(t, out.Etag, out.SliceEtag)
+}
+
+func TestHTTP_REST_FSReadTextFile_Conditional(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18189")
+	wsID := createWorkspace(t, base, "Conditional Read")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "one\n"}, http.StatusOK, nil)
+
+	type readResp struct {
+		Content     string `json:"content"`
+		Etag        string `json:"etag"`
+		Mtime       string `json:"mtime"`
+		Size        int64  `json:"size"`
+		NotModified bool   `json:"notModified"`
+	}
+	var first readResp
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt"}, http.StatusOK, &first)
+	require.Equal(t, "one\n", first.Content)
+	require.False(t, first.NotModified)
+
+	// Re-reading with the returned etag skips the content
+	var again readResp
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "ifNoneMatchEtag": first.Etag}, http.StatusOK, &again)
+	assert.True(t, again.NotModified)
+	assert.Empty(t, again.Content)
+	assert.Equal(t, first.Etag, again.Etag)
+	assert.Equal(t, int64(4), again.Size)
+
+	// So does the returned mtime, until the file changes
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "ifModifiedSince": first.Mtime}, http.StatusOK, &again)
+	assert.True(t, again.NotModified)
+	assert.Empty(t, again.Content)
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "two\n"}, http.StatusOK, nil)
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(wsRoot, wsID, "a.txt"), later, later))
+	var changed readResp
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "ifNoneMatchEtag": first.Etag}, http.StatusOK, &changed)
+	assert.False(t, changed.NotModified)
+	assert.Equal(t, "two\n", changed.Content)
+	assert.NotEqual(t, first.Etag, changed.Etag)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "ifModifiedSince": first.Mtime}, http.StatusOK, &changed)
+	assert.False(t, changed.NotModified)
+	assert.Equal(t, "two\n", changed.Content)
+
+	// The etag wins over the timestamp, as in HTTP
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "ifNoneMatchEtag": changed.Etag, "ifModifiedSince": first.Mtime}, http.StatusOK, &again)
+	assert.True(t, again.NotModified)
+
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "ifModifiedSince": "yesterday"}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_RespectGitignore(t *testing.T) {
+	base, _ := startTestServer(t, "18129")
+	wsID := createWorkspace(t, base, "Gitignore")
+	// .gitignore goes last: once it exists, ignored files are not committed
+	for _, f := range [][2]string{
+		{"src/app.js", "src"},
+		{"dist/app.js", "built"},
+		{"dist/vendor/x.js", "built"},
+		{"debug.log", "log"},
+		{".gitignore", "dist/\n*.log\n"},
+	} {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": f[0], "content": f[1]}, http.StatusOK, nil)
+	}
+
+	var all, filtered searchFilesResp
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": wsID, "path": ".", "pattern": "*.js"}, http.StatusOK, &all)
+	assert.Len(t, all.Matches, 3, "gitignore is off by default")
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": wsID, "path": ".", "pattern": "*.js", "respectGitignore": true}, http.StatusOK, &filtered)
+	assert.Equal(t, []string{"src/app.js"}, filtered.Matches)
+
+	var tree dirTreeResp
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": ".", "respectGitignore": true}, http.StatusOK, &tree)
+	assert.Nil(t, findNode(tree.Tree, "dist"))
+	assert.Nil(t, findNode(tree.Tree, "debug.log"))
+	assert.NotNil(t, findNode(tree.Tree, "src"))
+	assert.NotNil(t, findNode(tree.Tree, ".gitignore"))
+
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": "."}, http.StatusOK, &tree)
+	assert.NotNil(t, findNode(tree.Tree, "dist"))
+}
+
+func TestHTTP_REST_FSMoveFile_DirectoryWithGitkeep(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18130")
+	wsID := createWorkspace(t, base, "Move Gitkeep")
+
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "assets/empty"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "assets/logo.txt", "content": "logo"}, http.StatusOK, nil)
+
+	var out struct {
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "assets", "destination": "static"}, http.StatusOK, &out)
+	require.NotEmpty(t, out.Commit)
+
+	// The marker moved along, so the empty directory is still tracked
+	_, err := os.Stat(filepath.Join(wsRoot, wsID, "static", "empty", ".gitkeep"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(wsRoot, wsID, "assets"))
+	assert.True(t, os.IsNotExist(err))
+	repo, err := git.PlainOpen(filepath.Join(wsRoot, wsID))
+	require.NoError(t, err)
+	c, err := repo.CommitObject(plumbing.NewHash(out.Commit))
+	require.NoError(t, err)
+	_, err = c.File("static/empty/.gitkeep")
+	assert.NoError(t, err)
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "static/logo.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "logo", read.Content)
+}
+
+func TestHTTP_REST_FSMoveFile_ProtectedForbidden(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18131")
+	wsID := createWorkspace(t, base, "Move Protected")
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "docs"}, http.StatusOK, nil)
+
+	resp := restPOST(t, base+"/api/tools/fs_move_file", map[string]any{"workspaceId": wsID, "source": ".git", "destination": "git-backup"})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "FORBIDDEN")
+	assert.Contains(t, string(body), "protected")
+
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "docs/.gitkeep", "destination": "keep"}, http.StatusForbidden, nil)
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "docs", "destination": ".git/docs"}, http.StatusForbidden, nil)
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": ".", "destination": "root"}, http.StatusForbidden, nil)
+
+	_, err := os.Stat(filepath.Join(wsRoot, wsID, ".git", "HEAD"))
+	assert.NoError(t, err, ".git must stay in place")
+}
+
+func TestHTTP_REST_ConfiguredProtectedPaths(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18191", "--protected-names=.github", "--protected-globs=*.env,secrets/*")
+	wsID := createWorkspace(t, base, "Protected Config")
+	ws := filepath.Join(wsRoot, wsID)
+	require.NoError(t, os.MkdirAll(filepath.Join(ws, "secrets"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(ws, "config"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(ws, "secrets", "key.pem"), []byte("KEY"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(ws, "config", "prod.env"), []byte("TOKEN=x"), 0o644))
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "config/app.yaml", "content": "a: 1"}, http.StatusOK, nil)
+
+	// Reads of protected files behave as if they did not exist
+	for _, p := range []string{"config/prod.env", "secrets/key.pem"} {
+		callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": p}, http.StatusNotFound, nil)
+	}
+	// ...and writes are refused without touching the disk
+	for _, p := range []string{"local.env", "secrets/new.pem", ".github/workflows/ci.yml"} {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": p, "content": "x"}, http.StatusNotFound, nil)
+		_, err := os.Stat(filepath.Join(ws, filepath.FromSlash(p)))
+		assert.True(t, os.IsNotExist(err), p)
+	}
+	data, err := os.ReadFile(filepath.Join(ws, "config", "prod.env"))
+	require.NoError(t, err)
+	assert.Equal(t, "TOKEN=x", string(data))
+
+	// Listings leave them out
+	var list struct {
+		Entries []string `json:"entries"`
+	}
+	callTool(t, base, "fs_list_directory", map[string]any{"workspaceId": wsID, "path": "config"}, http.StatusOK, &list)
+	assert.Equal(t, []string{"[FILE] app.yaml"}, list.Entries)
+	callTool(t, base, "fs_list_directory", map[string]any{"workspaceId": wsID, "path": "secrets"}, http.StatusOK, &list)
+	assert.Empty(t, list.Entries)
+
+	// Their parent directories cannot carry them elsewhere or delete them
+	otherID := createWorkspace(t, base, "Protected Target")
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "secrets", "destination": "public"}, http.StatusForbidden, nil)
+	callTool(t, base, "fs_copy_between_workspaces", map[string]any{"sourceWorkspaceId": wsID, "sourcePath": "config", "destWorkspaceId": otherID, "destPath": "config"}, http.StatusForbidden, nil)
+	for _, soft := range []bool{false, true} {
+		callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "secrets", "softDelete": soft}, http.StatusForbidden, nil)
+	}
+	for _, p := range []string{"secrets/key.pem", "config/prod.env"} {
+		_, err := os.Stat(filepath.Join(ws, filepath.FromSlash(p)))
+		assert.NoError(t, err, p)
+	}
+	_, err = os.Stat(filepath.Join(wsRoot, otherID, "config"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Other servers keep the default set
+	base2, wsRoot2 := startTestServer(t, "18192")
+	wsID2 := createWorkspace(t, base2, "Default Protection")
+	callTool(t, base2, "fs_write_file", map[string]any{"workspaceId": wsID2, "path": "local.env", "content": "x"}, http.StatusOK, nil)
+	_, err = os.Stat(filepath.Join(wsRoot2, wsID2, "local.env"))
+	assert.NoError(t, err)
+}
+
+func TestHTTP_REST_FSMergeContent(t *testing.T) {
+	base, _ := startTestServer(t, "18135")
+	wsID := createWorkspace(t, base, "Merge")
+	orig := "alpha\nbeta\ngamma\ndelta\nepsilon\n"
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": orig}, http.StatusOK, nil)
+
+	// Another agent edits the end of the file after our read
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "alpha\nbeta\ngamma\ndelta\nEPSILON\n"}, http.StatusOK, nil)
+
+	type mergeResp struct {
+		Clean     bool   `json:"clean"`
+		Merged    string `json:"merged"`
+		Etag      string `json:"etag"`
+		Conflicts []struct {
+			Line   int    `json:"line"`
+			Base   string `json:"base"`
+			Theirs string `json:"theirs"`
+		} `json:"conflicts"`
+	}
+
+	// Our edit touches the start, so both changes survive
+	var clean mergeResp
+	callTool(t, base, "fs_merge_content", map[string]any{"workspaceId": wsID, "path": "doc.txt", "base": orig, "theirs": "ALPHA\nbeta\ngamma\ndelta\nepsilon\n"}, http.StatusOK, &clean)
+	require.True(t, clean.Clean)
+	assert.Equal(t, "ALPHA\nbeta\ngamma\ndelta\nEPSILON\n", clean.Merged)
+	assert.Empty(t, clean.Conflicts)
+
+	// The merge does not write; the etag allows a conditional write of the result
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": clean.Merged, "ifMatchFileEtag": clean.Etag}, http.StatusOK, nil)
+
+	// Editing the same line as the other agent is a conflict
+	var conflict mergeResp
+	callTool(t, base, "fs_merge_content", map[string]any{"workspaceId": wsID, "path": "doc.txt", "base": orig, "theirs": "alpha\nbeta\ngamma\ndelta\nEpsilon!\n"}, http.StatusOK, &conflict)
+	require.False(t, conflict.Clean)
+	assert.Empty(t, conflict.Merged)
+	require.Len(t, conflict.Conflicts, 1)
+	assert.Equal(t, 5, conflict.Conflicts[0].Line)
+	assert.Contains(t, conflict.Conflicts[0].Base, "epsilon")
+	assert.Contains(t, conflict.Conflicts[0].Theirs, "Epsilon!")
+
+	// Conflicting hunks longer than the 32-byte match window are reported too
+	longBase := "head\n" + strings.Repeat("a", 120) + "\ntail\n"
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "long.txt", "content": "head\n" + strings.Repeat("b", 120) + "\ntail\n"}, http.StatusOK, nil)
+	var long mergeResp
+	callTool(t, base, "fs_merge_content", map[string]any{"workspaceId": wsID, "path": "long.txt", "base": longBase, "theirs": "head\n" + strings.Repeat("c", 120) + "\ntail\n"}, http.StatusOK, &long)
+	require.False(t, long.Clean)
+	require.NotEmpty(t, long.Conflicts)
+	assert.Equal(t, 2, long.Conflicts[0].Line)
+	assert.Contains(t, long.Conflicts[0].Theirs, "ccc")
+
+	callTool(t, base, "fs_merge_content", map[string]any{"workspaceId": wsID, "path": "missing.txt", "base": "", "theirs": "x"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_MaxResponseBytes(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18136", "--max-response-bytes=4096")
+	wsID := createWorkspace(t, base, "Big Tree")
+	dir := filepath.Join(wsRoot, wsID, "big")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for i := 0; i < 200; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file-with-a-fairly-long-name-%03d.txt", i)), []byte("x"), 0644))
+	}
+
+	resp := restPOST(t, base+"/api/tools/fs_directory_tree", map[string]any{"workspaceId": wsID, "path": "."})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "TOO_LARGE")
+	assert.Contains(t, string(body), "maxDepth")
+
+	// Narrower requests still succeed
+	callTool(t, base, "fs_directory_tree", map[string]any{"workspaceId": wsID, "path": ".", "dirsOnly": true}, http.StatusOK, nil)
+
+	// The cap applies to a batch response as a whole
+	resp2 := restPOST(t, base+"/api/tools/batch", []map[string]any{
+		{"tool": "fs_list_directory", "params": map[string]any{"workspaceId": wsID, "path": "big"}},
+	})
+	defer resp2.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp2.StatusCode)
+}
+
+func TestHTTP_REST_CaseOnlyRenameAndCollisions(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18141")
+	wsID := createWorkspace(t, base, "Case Test")
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/file.txt", "content": "x"}, http.StatusOK, nil)
+
+	// A case-only rename succeeds whether or not the filesystem folds case
+	var mv struct {
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "docs/file.txt", "destination": "docs/File.txt"}, http.StatusOK, &mv)
+	entries, err := os.ReadDir(filepath.Join(wsRoot, wsID, "docs"))
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"File.txt"}, names)
+	repo, err := git.PlainOpen(filepath.Join(wsRoot, wsID))
+	require.NoError(t, err)
+	c, err := repo.CommitObject(plumbing.NewHash(mv.Commit))
+	require.NoError(t, err)
+	_, err = c.File("docs/File.txt")
+	assert.NoError(t, err)
+	_, err = c.File("docs/file.txt")
+	assert.Error(t, err)
+
+	// Writes and directories that would collide by case are reported as conflicts
+	for _, p := range []string{"docs/file.txt", "Docs/other.txt"} {
+		resp := restPOST(t, base+"/api/tools/fs_write_file", map[string]any{"workspaceId": wsID, "path": p, "content": "y"})
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.Equal(t, http.StatusConflict, resp.StatusCode, p)
+		assert.Contains(t, string(b), "differs only in case")
+	}
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "DOCS"}, http.StatusConflict, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "other.txt", "content": "z"}, http.StatusOK, nil)
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "other.txt", "destination": "docs/FILE.TXT"}, http.StatusConflict, nil)
+	b, err := os.ReadFile(filepath.Join(wsRoot, wsID, "docs", "File.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "x", string(b))
+}
+
+func TestHTTP_REST_FSPatch(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18143")
+	wsID := createWorkspace(t, base, "Patch")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "alpha\nbeta\ngamma\ndelta\n"}, http.StatusOK, nil)
+
+	type patchResp struct {
+		Applies  bool   `json:"applies"`
+		Conflict string `json:"conflict"`
+		Hunks    int    `json:"hunks"`
+		Commit   string `json:"commit"`
+	}
+	patch := "--- a/doc.txt\n+++ b/doc.txt\n@@ -1,3 +1,3 @@\n alpha\n-beta\n+BETA\n gamma\n"
+
+	// A dry run checks the patch without writing
+	var dry patchResp
+	callTool(t, base, "fs_patch", map[string]any{"workspaceId": wsID, "path": "doc.txt", "patch": patch, "dryRun": true}, http.StatusOK, &dry)
+	assert.True(t, dry.Applies)
+	assert.Empty(t, dry.Commit)
+	b, err := os.ReadFile(filepath.Join(wsRoot, wsID, "doc.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "alpha\nbeta\ngamma\ndelta\n", string(b))
+
+	var applied patchResp
+	callTool(t, base, "fs_patch", map[string]any{"workspaceId": wsID, "path": "doc.txt", "patch": patch}, http.StatusOK, &applied)
+	assert.True(t, applied.Applies)
+	assert.Equal(t, 1, applied.Hunks)
+	assert.NotEmpty(t, applied.Commit)
+	b, err = os.ReadFile(filepath.Join(wsRoot, wsID, "doc.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "alpha\nBETA\ngamma\ndelta\n", string(b))
+
+	// The same patch no longer matches: the hunk is rejected and nothing changes
+	resp := restPOST(t, base+"/api/tools/fs_patch", map[string]any{"workspaceId": wsID, "path": "doc.txt", "patch": patch})
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "CONFLICT")
+	callTool(t, base, "fs_patch", map[string]any{"workspaceId": wsID, "path": "doc.txt", "patch": patch, "dryRun": true}, http.StatusOK, &dry)
+	assert.False(t, dry.Applies)
+	assert.Contains(t, dry.Conflict, "line 2")
+
+	// A diff against /dev/null creates the file
+	callTool(t, base, "fs_patch", map[string]any{"workspaceId": wsID, "path": "new.txt", "patch": "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n\\ No newline at end of file\n"}, http.StatusOK, nil)
+	b, err = os.ReadFile(filepath.Join(wsRoot, wsID, "new.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo", string(b))
+
+	callTool(t, base, "fs_patch", map[string]any{"workspaceId": wsID, "path": "doc.txt", "patch": "not a diff"}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_FSEditFile_MatchGuards(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18144")
+	wsID := createWorkspace(t, base, "Edit Guards")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "foo bar foo\nbaz\n"}, http.StatusOK, nil)
+
+	edit := func(edits []map[string]any, extra map[string]any, wantStatus int, out any) {
+		body := map[string]any{"workspaceId": wsID, "path": "a.txt", "edits": edits}
+		for k, v := range extra {
+			body[k] = v
+		}
+		callTool(t, base, "fs_edit_file", body, wantStatus, out)
+	}
+
+	// An oldText that does not occur fails unless allowNoMatch is set
+	edit([]map[string]any{{"oldText": "qux", "newText": "x"}}, nil, http.StatusConflict, nil)
+	edit([]map[string]any{{"oldText": "", "newText": "x"}}, nil, http.StatusBadRequest, nil)
+	var res struct {
+		EditMatches []int  `json:"editMatches"`
+		Commit      string `json:"commit"`
+	}
+	edit([]map[string]any{{"oldText": "qux", "newText": "x"}, {"oldText": "baz", "newText": "BAZ"}}, map[string]any{"allowNoMatch": true}, http.StatusOK, &res)
+	assert.Equal(t, []int{0, 1}, res.EditMatches)
+	assert.NotEmpty(t, res.Commit)
+
+	// expectSingleMatch rejects an oldText occurring twice and leaves the file alone
+	edit([]map[string]any{{"oldText": "foo", "newText": "FOO"}}, map[string]any{"expectSingleMatch": true}, http.StatusConflict, nil)
+	b, err := os.ReadFile(filepath.Join(wsRoot, wsID, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo bar foo\nBAZ\n", string(b))
+
+	// Overlapping edits are rejected; later edits never see earlier replacements
+	edit([]map[string]any{{"oldText": "foo", "newText": "x"}, {"oldText": "foo", "newText": "y"}}, nil, http.StatusBadRequest, nil)
+	edit([]map[string]any{{"oldText": "bar", "newText": "baz"}, {"oldText": "baz", "newText": "qux"}}, map[string]any{"allowNoMatch": true}, http.StatusOK, &res)
+	assert.Equal(t, []int{1, 0}, res.EditMatches)
+	b, err = os.ReadFile(filepath.Join(wsRoot, wsID, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo baz foo\nBAZ\n", string(b))
+}
+
+func TestHTTP_REST_CommitAuthor(t *testing.T) {
+	base, _ := startTestServer(t, "18145")
+	wsID := createWorkspace(t, base, "Authors")
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "1"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "2", "authorName": "Alice", "authorEmail": "alice@example.com"}, http.StatusOK, nil)
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "a.txt", "destination": "b.txt", "authorName": "Bob"}, http.StatusOK, nil)
+	callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "authorName": "Eve <evil>"}, http.StatusBadRequest, nil)
+
+	var hist struct {
+		Log []struct {
+			Author  string `json:"author"`
+			Message string `json:"message"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "limit": 3}, http.StatusOK, &hist)
+	require.Len(t, hist.Log, 3)
+	assert.Equal(t, "Bob <mcp-server@localhost>", hist.Log[0].Author)
+	assert.Equal(t, "Alice <alice@example.com>", hist.Log[1].Author)
+	assert.Equal(t, "mcp-client <mcp-server@localhost>", hist.Log[2].Author)
+}
+
+func TestHTTP_REST_FSEditFile_IncludeDiff(t *testing.T) {
+	base, _ := startTestServer(t, "18146")
+	wsID := createWorkspace(t, base, "Edit Diff")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "hello world\n"}, http.StatusOK, nil)
+
+	type editResp struct {
+		Commit string  `json:"commit"`
+		Diff   *string `json:"diff"`
+	}
+	var lean editResp
+	callTool(t, base, "fs_edit_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "edits": []map[string]any{{"oldText": "hello", "newText": "hi"}}}, http.StatusOK, &lean)
+	assert.NotEmpty(t, lean.Commit)
+	assert.Nil(t, lean.Diff)
+
+	var withDiff editResp
+	callTool(t, base, "fs_edit_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "edits": []map[string]any{{"oldText": "world", "newText": "there"}}, "includeDiff": true}, http.StatusOK, &withDiff)
+	assert.NotEmpty(t, withDiff.Commit)
+	require.NotNil(t, withDiff.Diff)
+	assert.Contains(t, *withDiff.Diff, "hi ")
+}
+
+func TestHTTP_REST_FSReadFileAtCommit(t *testing.T) {
+	base, _ := startTestServer(t, "18153")
+	wsID := createWorkspace(t, base, "history")
+	var v1, v2 writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "version one\n"}, http.StatusOK, &v1)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "version two\n"}, http.StatusOK, &v2)
+
+	var out struct {
+		Content string `json:"content"`
+		Commit  string `json:"commit"`
+	}
+	callTool(t, base, "fs_read_file_at_commit", map[string]any{"workspaceId": wsID, "path": "doc.txt", "commit": v1.Commit}, http.StatusOK, &out)
+	assert.Equal(t, "version one\n", out.Content)
+	assert.Equal(t, v1.Commit, out.Commit)
+	callTool(t, base, "fs_read_file_at_commit", map[string]any{"workspaceId": wsID, "path": "doc.txt", "commit": v2.Commit}, http.StatusOK, &out)
+	assert.Equal(t, "version two\n", out.Content)
+
+	callTool(t, base, "fs_read_file_at_commit", map[string]any{"workspaceId": wsID, "path": "missing.txt", "commit": v1.Commit}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_read_file_at_commit", map[string]any{"workspaceId": wsID, "path": ".git/HEAD", "commit": v1.Commit}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_read_file_at_commit", map[string]any{"workspaceId": wsID, "path": "doc.txt"}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_FSMoveFile_ParentsAndOverwrite(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18156")
+	wsID := createWorkspace(t, base, "move")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "a"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "b"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "c.txt", "content": "c"}, http.StatusOK, nil)
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "dir"}, http.StatusOK, nil)
+
+	// Missing parents of the destination are created
+	var mv struct {
+		Overwritten bool   `json:"overwritten"`
+		Commit      string `json:"commit"`
+	}
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "a.txt", "destination": "deep/nested/a.txt"}, http.StatusOK, &mv)
+	assert.False(t, mv.Overwritten)
+	data, err := os.ReadFile(filepath.Join(wsRoot, wsID, "deep", "nested", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+
+	// Existing destinations are kept unless overwrite is set
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "b.txt", "destination": "c.txt"}, http.StatusConflict, nil)
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "b.txt", "destination": "dir", "overwrite": true}, http.StatusConflict, nil)
+
+	stream, rd := openSSE(t, fmt.Sprintf("%s/events?workspaceId=%s", base, wsID))
+	defer stream.Body.Close()
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "b.txt", "destination": "c.txt", "overwrite": true}, http.StatusOK, &mv)
+	assert.True(t, mv.Overwritten)
+	data, err = os.ReadFile(filepath.Join(wsRoot, wsID, "c.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+	_, err = os.Stat(filepath.Join(wsRoot, wsID, "b.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// The stream replays earlier events first; the move is the last one
+	var evt *sseWorkspaceEvent
+	for evt == nil || evt.PrevPath == nil || *evt.PrevPath != "b.txt" {
+		evt, err = readNextWorkspaceEvent(rd, 3*time.Second)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "file.updated", evt.Type)
+	assert.Equal(t, "c.txt", evt.Path)
+	require.NotNil(t, evt.PrevPath)
+	assert.Equal(t, "b.txt", *evt.PrevPath)
+}
+
+func TestHTTP_REST_FSWriteFile_NormalizeNewlines(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18158")
+	wsID := createWorkspace(t, base, "newlines")
+
+	// CRLF and lone CR input is stored with LF line endings
+	var out writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "crlf.txt", "content": "one\r\ntwo\rthree\r\n", "normalizeNewlines": true}, http.StatusOK, &out)
+	assert.Equal(t, len("one\ntwo\nthree\n"), out.BytesWritten)
+	assert.NotEmpty(t, out.Commit)
+	data, err := os.ReadFile(filepath.Join(wsRoot, wsID, "crlf.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\nthree\n", string(data))
+
+	// Rewriting the same CRLF content is a no-op once normalized
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "crlf.txt", "content": "one\r\ntwo\rthree\r\n", "normalizeNewlines": true}, http.StatusOK, &out)
+	assert.Equal(t, 0, out.BytesWritten)
+	assert.Empty(t, out.Commit)
+
+	// A missing final newline is added
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "tail.txt", "content": "no newline", "ensureTrailingNewline": true}, http.StatusOK, &out)
+	assert.NotEmpty(t, out.Commit)
+	data, err = os.ReadFile(filepath.Join(wsRoot, wsID, "tail.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "no newline\n", string(data))
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "tail.txt", "content": "no newline", "ensureTrailingNewline": true}, http.StatusOK, &out)
+	assert.Empty(t, out.Commit)
+
+	// Without the flags content is written as-is
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "raw.txt", "content": "a\r\nb"}, http.StatusOK, &out)
+	data, err = os.ReadFile(filepath.Join(wsRoot, wsID, "raw.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\r\nb", string(data))
+}
+
+func TestHTTP_REST_FSGetFileInfo_MimeType(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18159")
+	wsID := createWorkspace(t, base, "mime")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes.txt", "content": "plain text\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "dir"}, http.StatusOK, nil)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), 0, 0, 0, 13, 'I', 'H', 'D', 'R', 0, 0, 0, 1, 0, 0, 0, 1, 8, 6, 0, 0, 0)
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "pixel.png"), png, 0644))
+
+	type infoOut struct {
+		Type     string `json:"type"`
+		MimeType string `json:"mimeType"`
+		IsBinary bool   `json:"isBinary"`
+	}
+	var info infoOut
+	callTool(t, base, "fs_get_file_info", map[string]any{"workspaceId": wsID, "path": "pixel.png"}, http.StatusOK, &info)
+	assert.Equal(t, "image/png", info.MimeType)
+	assert.True(t, info.IsBinary)
+
+	info = infoOut{}
+	callTool(t, base, "fs_get_file_info", map[string]any{"workspaceId": wsID, "path": "notes.txt"}, http.StatusOK, &info)
+	assert.True(t, strings.HasPrefix(info.MimeType, "text/plain"), info.MimeType)
+	assert.False(t, info.IsBinary)
+
+	info = infoOut{}
+	callTool(t, base, "fs_get_file_info", map[string]any{"workspaceId": wsID, "path": "dir"}, http.StatusOK, &info)
+	assert.Equal(t, "directory", info.Type)
+	assert.Empty(t, info.MimeType)
+}
+
+func TestHTTP_REST_FSChmod(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on Windows")
+	}
+	base, _ := startTestServer(t, "18162")
+	wsID := createWorkspace(t, base, "chmod")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "run.sh", "content": "#!/bin/sh\necho hi\n"}, http.StatusOK, nil)
+
+	type chmodOut struct {
+		Mode        string `json:"mode"`
+		Permissions string `json:"permissions"`
+		Commit      string `json:"commit"`
+	}
+	var out chmodOut
+	callTool(t, base, "fs_chmod", map[string]any{"workspaceId": wsID, "path": "run.sh", "mode": "0755"}, http.StatusOK, &out)
+	assert.Equal(t, "0755", out.Mode)
+	assert.NotEmpty(t, out.Commit, "the executable bit is tracked by git")
+
+	var info struct {
+		Permissions string `json:"permissions"`
+	}
+	callTool(t, base, "fs_get_file_info", map[string]any{"workspaceId": wsID, "path": "run.sh"}, http.StatusOK, &info)
+	assert.Equal(t, "-rwxr-xr-x", info.Permissions)
+	assert.Equal(t, info.Permissions, out.Permissions)
+
+	// Git does not see group/other changes that keep the executable bit
+	out = chmodOut{}
+	callTool(t, base, "fs_chmod", map[string]any{"workspaceId": wsID, "path": "run.sh", "mode": "750"}, http.StatusOK, &out)
+	assert.Equal(t, "-rwxr-x---", out.Permissions)
+	assert.Empty(t, out.Commit)
+
+	for _, mode := range []string{"", "rwx", "0999", "04755", "0200"} {
+		callTool(t, base, "fs_chmod", map[string]any{"workspaceId": wsID, "path": "run.sh", "mode": mode}, http.StatusBadRequest, nil)
+	}
+	callTool(t, base, "fs_chmod", map[string]any{"workspaceId": wsID, "path": ".git/config", "mode": "0644"}, http.StatusForbidden, nil)
+	callTool(t, base, "fs_chmod", map[string]any{"workspaceId": wsID, "path": "missing.sh", "mode": "0644"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_ConcurrentWritesCommitSeparately(t *testing.T) {
+	base, _ := startTestServer(t, "18165")
+	wsID := createWorkspace(t, base, "concurrent")
+
+	const n = 24
+	statuses := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, _ := json.Marshal(map[string]any{"workspaceId": wsID, "path": fmt.Sprintf("f%02d.txt", i), "content": fmt.Sprintf("content %d\n", i)})
+			resp, err := http.Post(base+"/api/tools/fs_write_file", "application/json", bytes.NewReader(body))
+			if err != nil {
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	for i, s := range statuses {
+		require.Equal(t, http.StatusOK, s, "write %d", i)
+	}
+
+	for i := 0; i < n; i++ {
+		var out struct {
+			Content string `json:"content"`
+		}
+		callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": fmt.Sprintf("f%02d.txt", i)}, http.StatusOK, &out)
+		assert.Equal(t, fmt.Sprintf("content %d\n", i), out.Content)
+	}
+
+	// One commit per write (plus the initial commit), each adding exactly its own file
+	var history struct {
+		Log []struct {
+			Commit  string `json:"commit"`
+			Message string `json:"message"`
+			Parent  string `json:"parent"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "limit": 100}, http.StatusOK, &history)
+	require.Len(t, history.Log, n+1)
+	for _, c := range history.Log[:n] {
+		var diff struct {
+			Changes []struct {
+				Path string `json:"path"`
+			} `json:"changes"`
+		}
+		callTool(t, base, "workspace_diff", map[string]any{"workspaceId": wsID, "from": c.Parent, "to": c.Commit}, http.StatusOK, &diff)
+		require.Len(t, diff.Changes, 1, "commit %q", c.Message)
+		assert.Contains(t, c.Message, diff.Changes[0].Path)
+	}
+}
+
+func TestHTTP_REST_ReadMultipleFilesKeepsOrder(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18166")
+	wsID := createWorkspace(t, base, "readmany")
+
+	// Sizes vary so reads finish out of order; missing and protected paths
+	// are interleaved to check that their errors stay in place.
+	var paths []string
+	for i := range 40 {
+		p := fmt.Sprintf("f%02d.txt", i)
+		switch i % 10 {
+		case 3:
+			p = fmt.Sprintf("missing%02d.txt", i)
+		case 7:
+			p = ".git/HEAD"
+		default:
+			content := strings.Repeat(p+"\n", (40-i)*2000)
+			require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, p), []byte(content), 0o644))
+		}
+		paths = append(paths, p)
+	}
+
+	for _, workers := range []int{0, 1, 8, 100} {
+		var out struct {
+			Results []struct {
+				Path    string  `json:"path"`
+				OK      bool    `json:"ok"`
+				Content *string `json:"content"`
+				Error   *string `json:"error"`
+			} `json:"results"`
+		}
+		callTool(t, base, "fs_read_multiple_files", map[string]any{"workspaceId": wsID, "paths": paths, "maxConcurrency": workers}, http.StatusOK, &out)
+		require.Len(t, out.Results, len(paths))
+		for i, r := range out.Results {
+			assert.Equal(t, paths[i], r.Path, "maxConcurrency %d", workers)
+			switch i % 10 {
+			case 3, 7:
+				assert.False(t, r.OK, r.Path)
+				require.NotNil(t, r.Error, r.Path)
+			default:
+				assert.True(t, r.OK, r.Path)
+				require.NotNil(t, r.Content, r.Path)
+				assert.True(t, strings.HasPrefix(*r.Content, r.Path+"\n"), r.Path)
+			}
+		}
+	}
+
+	callTool(t, base, "fs_read_multiple_files", map[string]any{"workspaceId": wsID, "paths": paths, "maxConcurrency": -1}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_ReadMaxBytesTruncates(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18167", "--max-read-bytes=4096")
+	wsID := createWorkspace(t, base, "bigread")
+
+	var sb strings.Builder
+	for i := range 10000 {
+		fmt.Fprintf(&sb, "line %05d é\n", i)
+	}
+	big := sb.String()
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "big.log"), []byte(big), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "small.txt"), []byte("tiny\n"), 0o644))
+	sum := sha256.Sum256([]byte(big))
+
+	type readOut struct {
+		Content    string `json:"content"`
+		TotalLines int    `json:"totalLines"`
+		Etag       string `json:"etag"`
+		Size       int64  `json:"size"`
+		Truncated  bool   `json:"truncated"`
+	}
+	var out readOut
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "big.log", "maxBytes": 1000}, http.StatusOK, &out)
+	assert.True(t, out.Truncated)
+	assert.Equal(t, int64(len(big)), out.Size)
+	assert.LessOrEqual(t, len(out.Content), 1000)
+	assert.True(t, strings.HasPrefix(big, out.Content))
+	assert.True(t, utf8.ValidString(out.Content))
+	assert.Zero(t, out.TotalLines)
+	assert.Equal(t, hex.EncodeToString(sum[:]), out.Etag, "etag covers the whole file")
+
+	out = readOut{}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "big.log", "maxBytes": 1000, "tail": 3}, http.StatusOK, &out)
+	assert.False(t, out.Truncated, "the last lines fit in the window")
+	assert.Equal(t, "line 09998 é\nline 09999 é\n", out.Content)
+
+	// Without maxBytes the server cap applies; requests cannot raise it.
+	for _, maxBytes := range []int{0, 1 << 20} {
+		out = readOut{}
+		callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "big.log", "maxBytes": maxBytes}, http.StatusOK, &out)
+		assert.True(t, out.Truncated)
+		assert.LessOrEqual(t, len(out.Content), 4096)
+	}
+
+	out = readOut{}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "small.txt", "maxBytes": 1000}, http.StatusOK, &out)
+	assert.False(t, out.Truncated)
+	assert.Equal(t, "tiny\n", out.Content)
+	assert.Equal(t, int64(5), out.Size)
+
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "big.log", "maxBytes": -1}, http.StatusBadRequest, nil)
+
+	var many struct {
+		Results []struct {
+			Path      string `json:"path"`
+			Content   string `json:"content"`
+			Size      int64  `json:"size"`
+			Truncated bool   `json:"truncated"`
+		} `json:"results"`
+	}
+	callTool(t, base, "fs_read_multiple_files", map[string]any{"workspaceId": wsID, "paths": []string{"big.log", "small.txt"}, "maxBytes": 500}, http.StatusOK, &many)
+	require.Len(t, many.Results, 2)
+	assert.True(t, many.Results[0].Truncated)
+	assert.Equal(t, int64(len(big)), many.Results[0].Size)
+	assert.LessOrEqual(t, len(many.Results[0].Content), 500)
+	assert.False(t, many.Results[1].Truncated)
+	assert.Equal(t, "tiny\n", many.Results[1].Content)
+}
+
+func TestHTTP_REST_FSStatMultiple(t *testing.T) {
+	base, _ := startTestServer(t, "18168")
+	wsID := createWorkspace(t, base, "stats")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/a.txt", "content": "hello"}, http.StatusOK, nil)
+
+	paths := []string{"docs/a.txt", "missing.txt", ".git/HEAD", "docs", "docs/.gitkeep", "../escape"}
+	var out struct {
+		Results []struct {
+			Path     string  `json:"path"`
+			OK       bool    `json:"ok"`
+			Size     int64   `json:"size"`
+			Mtime    string  `json:"mtime"`
+			Type     string  `json:"type"`
+			MimeType string  `json:"mimeType"`
+			Error    *string `json:"error"`
+		} `json:"results"`
+	}
+	callTool(t, base, "fs_stat_multiple", map[string]any{"workspaceId": wsID, "paths": paths}, http.StatusOK, &out)
+	require.Len(t, out.Results, len(paths))
+	for i, r := range out.Results {
+		assert.Equal(t, paths[i], r.Path)
+	}
+
+	file := out.Results[0]
+	assert.True(t, file.OK)
+	assert.Equal(t, int64(5), file.Size)
+	assert.Equal(t, "file", file.Type)
+	assert.NotEmpty(t, file.Mtime)
+	assert.Equal(t, "text/plain; charset=utf-8", file.MimeType)
+	assert.Nil(t, file.Error)
+
+	dir := out.Results[3]
+	assert.True(t, dir.OK)
+	assert.Equal(t, "directory", dir.Type)
+
+	for _, i := range []int{1, 2, 4} {
+		r := out.Results[i]
+		assert.False(t, r.OK, r.Path)
+		require.NotNil(t, r.Error, r.Path)
+		assert.True(t, strings.HasPrefix(*r.Error, "NOT_FOUND:"), *r.Error)
+		assert.Empty(t, r.Type, r.Path)
+	}
+	require.NotNil(t, out.Results[5].Error)
+	assert.True(t, strings.HasPrefix(*out.Results[5].Error, "OUT_OF_BOUNDS:"), *out.Results[5].Error)
+
+	callTool(t, base, "fs_stat_multiple", map[string]any{"workspaceId": wsID}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_TextEncodingRoundTrip(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18169")
+	wsID := createWorkspace(t, base, "encodings")
+	text := "Grüße, 世界 😀\nsecond line\n"
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "u16.txt", "content": text, "encoding": "utf-16le"}, http.StatusOK, nil)
+	onDisk, err := os.ReadFile(filepath.Join(wsRoot, wsID, "u16.txt"))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(onDisk, []byte{0xFF, 0xFE}), "UTF-16 is written with a byte order mark")
+	assert.Equal(t, byte('G'), onDisk[2])
+	assert.Equal(t, byte(0), onDisk[3])
+
+	type readOut struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+		Size     int64  `json:"size"`
+	}
+	var out readOut
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "u16.txt", "encoding": "auto"}, http.StatusOK, &out)
+	assert.Equal(t, text, out.Content)
+	assert.Equal(t, "utf-16le", out.Encoding)
+	assert.Equal(t, int64(len(onDisk)), out.Size)
+
+	out = readOut{}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "u16.txt", "encoding": "auto", "tail": 2}, http.StatusOK, &out)
+	assert.Equal(t, "second line\n", out.Content)
+
+	// A truncated window never splits a code unit or a surrogate pair.
+	out = readOut{}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "u16.txt", "encoding": "auto", "maxBytes": 25}, http.StatusOK, &out)
+	assert.Equal(t, "Grüße, 世界 ", out.Content)
+
+	// auto keeps the file's encoding on rewrite.
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "u16.txt", "content": "ünïcode\n", "encoding": "auto"}, http.StatusOK, nil)
+	onDisk, err = os.ReadFile(filepath.Join(wsRoot, wsID, "u16.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xFF, 0xFE, 0xFC, 0x00}, onDisk[:4])
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "latin.txt", "content": "café\n", "encoding": "latin1"}, http.StatusOK, nil)
+	onDisk, err = os.ReadFile(filepath.Join(wsRoot, wsID, "latin.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("caf\xe9\n"), onDisk)
+	out = readOut{}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "latin.txt", "encoding": "latin1"}, http.StatusOK, &out)
+	assert.Equal(t, "café\n", out.Content)
+
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "latin.txt", "content": "世界", "encoding": "latin1"}, http.StatusBadRequest, nil)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "latin.txt", "encoding": "ebcdic"}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_FSWriteFileDryRun(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18170")
+	wsID := createWorkspace(t, base, "writedry")
+	var first struct {
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes.txt", "content": "alpha\nbeta\n"}, http.StatusOK, &first)
+
+	type writeOut struct {
+		DryRun       bool   `json:"dryRun"`
+		BytesWritten int    `json:"bytesWritten"`
+		Overwritten  bool   `json:"overwritten"`
+		Commit       string `json:"commit"`
+		Diff         string `json:"diff"`
+	}
+	var out writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes.txt", "content": "alpha\ngamma\n", "dryRun": true}, http.StatusOK, &out)
+	assert.True(t, out.DryRun)
+	assert.True(t, out.Overwritten)
+	assert.Equal(t, len("alpha\ngamma\n"), out.BytesWritten)
+	assert.Empty(t, out.Commit)
+	assert.Contains(t, out.Diff, "--- a/notes.txt\n+++ b/notes.txt\n")
+	assert.Contains(t, out.Diff, "-beta\n+gamma\n")
+
+	onDisk, err := os.ReadFile(filepath.Join(wsRoot, wsID, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "alpha\nbeta\n", string(onDisk))
+	var history struct {
+		Log []struct {
+			Commit string `json:"commit"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID}, http.StatusOK, &history)
+	require.NotEmpty(t, history.Log)
+	assert.Equal(t, first.Commit, history.Log[0].Commit, "a dry run makes no commit")
+
+	// New files are not created; unchanged content is still a no-op.
+	out = writeOut{}
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "new.txt", "content": "fresh\n", "dryRun": true}, http.StatusOK, &out)
+	assert.False(t, out.Overwritten)
+	assert.Equal(t, 6, out.BytesWritten)
+	assert.Contains(t, out.Diff, "--- /dev/null\n")
+	assert.Contains(t, out.Diff, "+fresh\n")
+	_, err = os.Stat(filepath.Join(wsRoot, wsID, "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	out = writeOut{}
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes.txt", "content": "alpha\nbeta\n", "dryRun": true}, http.StatusOK, &out)
+	assert.True(t, out.DryRun)
+	assert.Zero(t, out.BytesWritten)
+	assert.Empty(t, out.Diff)
+
+	stale := "0000"
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes.txt", "content": "x", "dryRun": true, "ifMatchFileEtag": stale}, http.StatusConflict, nil)
+}
+
+func TestHTTP_REST_FSRestoreFile(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18173")
+	wsID := createWorkspace(t, base, "restorefile")
+
+	var v1, v2 struct {
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "first\n"}, http.StatusOK, &v1)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "second\n"}, http.StatusOK, &v2)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "other.txt", "content": "untouched\n"}, http.StatusOK, nil)
+
+	type restoreOut struct {
+		Path         string `json:"path"`
+		RestoredFrom string `json:"restoredFrom"`
+		Commit       string `json:"commit"`
+	}
+	var out restoreOut
+	callTool(t, base, "fs_restore_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "commit": v1.Commit[:10]}, http.StatusOK, &out)
+	assert.Equal(t, v1.Commit, out.RestoredFrom)
+	require.NotEmpty(t, out.Commit)
+
+	onDisk, err := os.ReadFile(filepath.Join(wsRoot, wsID, "doc.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(onDisk))
+	onDisk, err = os.ReadFile(filepath.Join(wsRoot, wsID, "other.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "untouched\n", string(onDisk), "only the one file is restored")
+
+	var history struct {
+		Log []struct {
+			Commit  string `json:"commit"`
+			Message string `json:"message"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID, "limit": 1}, http.StatusOK, &history)
+	require.Len(t, history.Log, 1)
+	assert.Equal(t, out.Commit, history.Log[0].Commit)
+	assert.Contains(t, history.Log[0].Message, "mcp/fs_restore_file: Restore doc.txt")
+
+	// Restoring again is a no-op.
+	out = restoreOut{}
+	callTool(t, base, "fs_restore_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "commit": v1.Commit}, http.StatusOK, &out)
+	assert.Empty(t, out.Commit)
+
+	callTool(t, base, "fs_restore_file", map[string]any{"workspaceId": wsID, "path": "other.txt", "commit": v1.Commit}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_restore_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "commit": "0123456789abcdef0123456789abcdef01234567"}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_restore_file", map[string]any{"workspaceId": wsID, "path": "doc.txt"}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_SoftDeleteAndRestore(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18179")
+	wsID := createWorkspace(t, base, "Trash")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "content": "keep me"}, http.StatusOK, nil)
+
+	var del struct {
+		Commit  string `json:"commit"`
+		TrashID string `json:"trashId"`
+	}
+	callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "softDelete": true}, http.StatusOK, &del)
+	require.NotEmpty(t, del.TrashID)
+	assert.NotEmpty(t, del.Commit)
+	_, err := os.Stat(filepath.Join(wsRoot, wsID, "notes", "a.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	var list struct {
+		Entries []string `json:"entries"`
+	}
+	callTool(t, base, "fs_list_directory", map[string]any{"workspaceId": wsID, "path": "."}, http.StatusOK, &list)
+	for _, e := range list.Entries {
+		assert.NotContains(t, e, ".trash")
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": ".trash/" + del.TrashID + ".json"}, http.StatusNotFound, nil)
+
+	// The deletion is committed; the trash itself is not
+	repo, err := git.PlainOpen(filepath.Join(wsRoot, wsID))
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), "%v", status)
+
+	var restored struct {
+		Path    string `json:"path"`
+		TrashID string `json:"trashId"`
+		Commit  string `json:"commit"`
+	}
+	callTool(t, base, "fs_restore_from_trash", map[string]any{"workspaceId": wsID, "path": "notes/a.txt"}, http.StatusOK, &restored)
+	assert.Equal(t, "notes/a.txt", restored.Path)
+	assert.Equal(t, del.TrashID, restored.TrashID)
+	assert.NotEmpty(t, restored.Commit)
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "keep me", read.Content)
+
+	callTool(t, base, "fs_restore_from_trash", map[string]any{"workspaceId": wsID, "trashId": del.TrashID}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "missing.txt", "softDelete": true}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_restore_from_trash", map[string]any{"workspaceId": wsID}, http.StatusBadRequest, nil)
+
+	// Restoring never overwrites a file recreated in the meantime
+	callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "softDelete": true}, http.StatusOK, &del)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "content": "new"}, http.StatusOK, nil)
+	callTool(t, base, "fs_restore_from_trash", map[string]any{"workspaceId": wsID, "trashId": del.TrashID}, http.StatusConflict, nil)
+}
+
+func TestHTTP_REST_FSReadLines(t *testing.T) {
+	base, _ := startTestServer(t, "18180")
+	wsID := createWorkspace(t, base, "Read Lines")
+	var content strings.Builder
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&content, "line %d\r\n", i)
+	}
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": content.String()}, http.StatusOK, nil)
+
+	type numbered struct {
+		LineNumber int    `json:"lineNumber"`
+		Text       string `json:"text"`
+	}
+	var out struct {
+		Lines      []numbered `json:"lines"`
+		TotalLines int        `json:"totalLines"`
+	}
+	callTool(t, base, "fs_read_lines", map[string]any{"workspaceId": wsID, "path": "a.txt", "startLine": 5, "count": 6}, http.StatusOK, &out)
+	assert.Equal(t, 12, out.TotalLines)
+	require.Len(t, out.Lines, 6)
+	for i, l := range out.Lines {
+		assert.Equal(t, numbered{LineNumber: 5 + i, Text: fmt.Sprintf("line %d", 5+i)}, l)
+	}
+
+	// The window is clamped to the file
+	callTool(t, base, "fs_read_lines", map[string]any{"workspaceId": wsID, "path": "a.txt", "startLine": 11, "count": 100}, http.StatusOK, &out)
+	assert.Equal(t, []numbered{{11, "line 11"}, {12, "line 12"}}, out.Lines)
+	callTool(t, base, "fs_read_lines", map[string]any{"workspaceId": wsID, "path": "a.txt", "startLine": 20}, http.StatusOK, &out)
+	assert.Empty(t, out.Lines)
+	assert.Equal(t, 12, out.TotalLines)
+
+	callTool(t, base, "fs_read_lines", map[string]any{"workspaceId": wsID, "path": ".git/HEAD"}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_read_lines", map[string]any{"workspaceId": wsID, "path": "missing.txt"}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_read_lines", map[string]any{"workspaceId": wsID, "path": "a.txt", "count": -1}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_FSWriteFiles_SingleCommit(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18182")
+	wsID := createWorkspace(t, base, "Write Files")
+	stream, rd := openSSE(t, base+"/events?workspaceId="+wsID)
+	defer stream.Body.Close()
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "old"}, http.StatusOK, nil)
+	_, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+
+	type history struct {
+		Log []struct {
+			Commit  string `json:"commit"`
+			Message string `json:"message"`
+		} `json:"log"`
+	}
+	var before history
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID}, http.StatusOK, &before)
+
+	files := []map[string]any{
+		{"path": "a.txt", "content": "new"},
+		{"path": "b.txt", "content": "b"},
+		{"path": "src/c.go", "content": "package c"},
+		{"path": "src/d.go", "content": "package d"},
+		{"path": "docs/e.md", "content": "# e"},
+	}
+	var out struct {
+		Files []struct {
+			Path         string `json:"path"`
+			BytesWritten int    `json:"bytesWritten"`
+			Overwritten  bool   `json:"overwritten"`
+		} `json:"files"`
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_write_files", map[string]any{"workspaceId": wsID, "files": files}, http.StatusOK, &out)
+	require.NotEmpty(t, out.Commit)
+	require.Len(t, out.Files, 5)
+	assert.True(t, out.Files[0].Overwritten)
+	assert.Equal(t, "src/c.go", out.Files[2].Path)
+	assert.Equal(t, len("package c"), out.Files[2].BytesWritten)
+
+	var after history
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID}, http.StatusOK, &after)
+	require.Len(t, after.Log, len(before.Log)+1, "exactly one new commit")
+	assert.Equal(t, out.Commit, after.Log[0].Commit)
+	assert.Contains(t, after.Log[0].Message, "mcp/fs_write_files: Write 5 files")
+
+	repo, err := git.PlainOpen(filepath.Join(wsRoot, wsID))
+	require.NoError(t, err)
+	c, err := repo.CommitObject(plumbing.NewHash(out.Commit))
+	require.NoError(t, err)
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Len(t, stats, 5, "the commit covers every file")
+
+	seen := map[string]string{}
+	for len(seen) < 5 {
+		evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, evt.Commit)
+		assert.Equal(t, out.Commit, *evt.Commit)
+		seen[evt.Path] = evt.Type
+	}
+	assert.Equal(t, "file.updated", seen["a.txt"])
+	assert.Equal(t, "file.created", seen["docs/e.md"])
+
+	// A failed write rolls back the files written before it: "x" is written
+	// as a file, so "x/y.txt" cannot be created below it.
+	failing := []map[string]any{
+		{"path": "a.txt", "content": "clobbered"},
+		{"path": "n/new.txt", "content": "n"},
+		{"path": "x", "content": "x"},
+		{"path": "x/y.txt", "content": "y"},
+	}
+	callTool(t, base, "fs_write_files", map[string]any{"workspaceId": wsID, "files": failing}, http.StatusInternalServerError, nil)
+	b, err := os.ReadFile(filepath.Join(wsRoot, wsID, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(b))
+	for _, p := range []string{"n", "x"} {
+		_, err := os.Stat(filepath.Join(wsRoot, wsID, p))
+		assert.True(t, os.IsNotExist(err), "%s should be rolled back", p)
+	}
+	var final history
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID}, http.StatusOK, &final)
+	assert.Len(t, final.Log, len(after.Log))
+
+	callTool(t, base, "fs_write_files", map[string]any{"workspaceId": wsID, "files": []map[string]any{{"path": ".git/config", "content": ""}}}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_write_files", map[string]any{"workspaceId": wsID, "files": []map[string]any{{"path": "d.txt"}, {"path": "D.txt"}}}, http.StatusConflict, nil)
+	callTool(t, base, "fs_write_files", map[string]any{"workspaceId": wsID}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_GitkeepRemovedWhenDirectoryGainsFile(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18183")
+	wsID := createWorkspace(t, base, "Gitkeep")
+	wsPath := filepath.Join(wsRoot, wsID)
+
+	var mk struct {
+		Commit string `json:"commit"`
+	}
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "docs/api"}, http.StatusOK, &mk)
+	require.FileExists(t, filepath.Join(wsPath, "docs", "api", ".gitkeep"))
+
+	var w writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/api/index.md", "content": "# API"}, http.StatusOK, &w)
+	_, err := os.Stat(filepath.Join(wsPath, "docs", "api", ".gitkeep"))
+	assert.True(t, os.IsNotExist(err), "the marker is removed once the directory has a file")
+
+	repo, err := git.PlainOpen(wsPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), "the removal is part of the write's commit: %v", status)
+
+	// History is intact: the directory's commit still has the marker
+	created, err := repo.CommitObject(plumbing.NewHash(mk.Commit))
+	require.NoError(t, err)
+	_, err = created.File("docs/api/.gitkeep")
+	assert.NoError(t, err)
+	written, err := repo.CommitObject(plumbing.NewHash(w.Commit))
+	require.NoError(t, err)
+	_, err = written.File("docs/api/.gitkeep")
+	assert.Error(t, err)
+	_, err = written.File("docs/api/index.md")
+	assert.NoError(t, err)
+
+	// Moves into a directory drop its marker too, but never the root's
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "archive"}, http.StatusOK, nil)
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "docs/api/index.md", "destination": "archive/index.md"}, http.StatusOK, nil)
+	_, err = os.Stat(filepath.Join(wsPath, "archive", ".gitkeep"))
+	assert.True(t, os.IsNotExist(err))
+	assert.FileExists(t, filepath.Join(wsPath, ".gitkeep"))
+}
+
+func TestHTTP_REST_FSSearchFiles_MaxResults(t *testing.T) {
+	base, _ := startTestServer(t, "18184")
+	wsID := createWorkspace(t, base, "Search Limit")
+	var files []map[string]any
+	for i := 0; i < 60; i++ {
+		files = append(files, map[string]any{"path": fmt.Sprintf("dir%d/f%02d.txt", i%6, i), "content": "x"})
+	}
+	callTool(t, base, "fs_write_files", map[string]any{"workspaceId": wsID, "files": files}, http.StatusOK, nil)
+
+	var out searchFilesResp
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": wsID, "path": ".", "pattern": "*.txt", "maxResults": 5}, http.StatusOK, &out)
+	assert.Len(t, out.Matches, 5, "the walk stops at maxResults")
+	assert.True(t, out.Truncated)
+
+	var all searchFilesResp
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": wsID, "path": ".", "pattern": "*.txt", "maxResults": 60, "timeoutMs": 30000}, http.StatusOK, &all)
+	assert.Len(t, all.Matches, 60)
+	assert.False(t, all.Truncated, "exactly maxResults matches is not truncated")
+
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": wsID, "path": ".", "pattern": "*.txt", "maxResults": -1}, http.StatusBadRequest, nil)
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": wsID, "path": ".", "pattern": "*.txt", "timeoutMs": 600000}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_WorkspaceQuota(t *testing.T) {
+	base, _ := startTestServer(t, "18185", "--workspace-quota-bytes=200")
+	wsID := createWorkspace(t, base, "Quota")
+	otherWS := createWorkspace(t, base, "Quota Source")
+	write := func(path string, n, status int) {
+		t.Helper()
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": path, "content": strings.Repeat("x", n)}, status, nil)
+	}
+
+	write("a.txt", 150, http.StatusOK)
+	write("b.txt", 50, http.StatusOK)
+	resp := restPOST(t, base+"/api/tools/fs_write_file", map[string]any{"workspaceId": wsID, "path": "c.txt", "content": "x"})
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Contains(t, string(body), "QUOTA_EXCEEDED")
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "c.txt"}, http.StatusNotFound, nil)
+
+	// Copies into the workspace count too
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": otherWS, "path": "big.txt", "content": strings.Repeat("y", 100)}, http.StatusOK, nil)
+	callTool(t, base, "fs_copy_between_workspaces", map[string]any{
+		"sourceWorkspaceId": otherWS, "sourcePath": "big.txt",
+		"destWorkspaceId": wsID, "destPath": "big.txt",
+	}, http.StatusRequestEntityTooLarge, nil)
+
+	// Deleting and shrinking files frees space
+	callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "b.txt"}, http.StatusOK, nil)
+	write("a.txt", 50, http.StatusOK)
+	callTool(t, base, "fs_copy_between_workspaces", map[string]any{
+		"sourceWorkspaceId": otherWS, "sourcePath": "big.txt",
+		"destWorkspaceId": wsID, "destPath": "big.txt",
+	}, http.StatusOK, nil)
+	write("c.txt", 51, http.StatusRequestEntityTooLarge)
+	write("c.txt", 50, http.StatusOK)
+
+	// New workspaces are held to the quota too
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	f, err := zw.Create("big.txt")
+	require.NoError(t, err)
+	_, err = f.Write([]byte(strings.Repeat("z", 300)))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	callTool(t, base, "workspace_import", map[string]any{"name": "Too Big", "archiveBase64": base64.StdEncoding.EncodeToString(zbuf.Bytes())}, http.StatusRequestEntityTooLarge, nil)
+	var list struct {
+		Workspaces []struct {
+			Name string `json:"name"`
+		} `json:"workspaces"`
+	}
+	callTool(t, base, "workspace_list", map[string]any{}, http.StatusOK, &list)
+	assert.Len(t, list.Workspaces, 2, "a rejected import leaves no workspace behind")
+}
@@ -1,16 +1,24 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -199,13 +207,18 @@ type searchFilesReq struct {
 	ExcludePatterns []string `json:"excludePatterns,omitempty"`
 }
 type searchFilesResp struct {
-	Matches []string `json:"matches"`
+	Matches   []string `json:"matches"`
+	Truncated bool     `json:"truncated"`
 }
 
 type treeNode struct {
-	Name     string      `json:"name"`
-	Type     string      `json:"type"`
-	Children *[]treeNode `json:"children,omitempty"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Children   *[]treeNode `json:"children,omitempty"`
+	Truncated  bool        `json:"truncated,omitempty"`
+	Size       *int64      `json:"size,omitempty"`
+	Mtime      string      `json:"mtime,omitempty"`
+	ChildCount *int        `json:"childCount,omitempty"`
 }
 type dirTreeResp struct {
 	Tree []treeNode `json:"tree"`
@@ -475,3 +488,1044 @@ func TestHTTP_REST_SearchAndTree_SkipProtected(t *testing.T) {
 	assert.False(t, containsSpecial(tOut.Tree, ".git"), "tree must not include .git")
 	assert.False(t, containsSpecial(tOut.Tree, ".gitkeep"), "tree must not include .gitkeep")
 }
+
+func TestHTTP_REST_OpenAPISpec(t *testing.T) {
+	base, _ := startTestServer(t, "18105")
+
+	resp, err := http.Get(base + "/api/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]struct {
+			Post struct {
+				OperationID string `json:"operationId"`
+				RequestBody struct {
+					Content map[string]struct {
+						Schema struct {
+							Type       string                     `json:"type"`
+							Properties map[string]json.RawMessage `json:"properties"`
+							Required   []string                   `json:"required"`
+						} `json:"schema"`
+					} `json:"content"`
+				} `json:"requestBody"`
+				Responses map[string]any `json:"responses"`
+			} `json:"post"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&spec))
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+
+	op, ok := spec.Paths["/api/tools/fs_write_file"]
+	require.True(t, ok, "fs_write_file path missing from spec")
+	assert.Equal(t, "fs_write_file", op.Post.OperationID)
+	schema := op.Post.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, "object", schema.Type)
+	for _, field := range []string{"workspaceId", "path", "content"} {
+		assert.Contains(t, schema.Properties, field)
+	}
+	for _, status := range []string{"200", "400", "404", "409", "500"} {
+		assert.Contains(t, op.Post.Responses, status)
+	}
+
+	// Every tool in the catalog has a path, and no path is left without a tool
+	catResp, err := http.Get(base + "/api/tools")
+	require.NoError(t, err)
+	defer catResp.Body.Close()
+	var catalog []struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, json.NewDecoder(catResp.Body).Decode(&catalog))
+	require.NotEmpty(t, catalog)
+	var fromCatalog, fromSpec []string
+	for _, tool := range catalog {
+		fromCatalog = append(fromCatalog, "/api/tools/"+tool.Name)
+	}
+	for path, item := range spec.Paths {
+		fromSpec = append(fromSpec, path)
+		assert.NotEmpty(t, item.Post.OperationID, path)
+	}
+	assert.ElementsMatch(t, fromCatalog, fromSpec)
+	for _, tool := range []string{"workspace_create", "workspace_list", "fs_read_text_file", "fs_stat_multiple", "fs_restore_file", "workspace_undo_last_commit", "workspace_create_tag"} {
+		assert.Contains(t, spec.Paths, "/api/tools/"+tool)
+	}
+}
+
+func TestHTTP_REST_ToolCatalog(t *testing.T) {
+	base, _ := startTestServer(t, "18176")
+
+	resp, err := http.Get(base + "/api/tools")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var catalog []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		InputSchema struct {
+			Type       string                     `json:"type"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"inputSchema"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&catalog))
+	names := map[string]bool{}
+	for _, tool := range catalog {
+		names[tool.Name] = true
+		if tool.Name != "fs_write_file" {
+			continue
+		}
+		assert.NotEmpty(t, tool.Description)
+		assert.Equal(t, "object", tool.InputSchema.Type)
+		for _, field := range []string{"workspaceId", "path", "content"} {
+			assert.Contains(t, tool.InputSchema.Properties, field)
+		}
+	}
+	assert.True(t, names["fs_write_file"], "fs_write_file missing from catalog")
+	assert.True(t, names["workspace_create"], "workspace_create missing from catalog")
+}
+
+func TestHTTP_CORS_PreflightAndSSE(t *testing.T) {
+	base, _ := startTestServer(t, "18108", "--cors-origins=https://app.example.com", "--auth-token=secret")
+	const origin = "https://app.example.com"
+
+	// Preflight is answered without credentials
+	req, err := http.NewRequest(http.MethodOptions, base+"/api/tools/fs_write_file", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, origin, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Content-Type")
+
+	// SSE responses carry the header too
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, base+"/events?workspaceId=x&token=secret", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", origin)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, origin, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// Unlisted origins get no CORS grant
+	req, err = http.NewRequest(http.MethodGet, base+"/healthz", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp2, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp2.Body.Close()
+	assert.Empty(t, resp2.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestHTTP_REST_ReadOnlyTokenScope(t *testing.T) {
+	base, _ := startTestServer(t, "18114", "--auth-tokens=writer:rw,reader:ro")
+
+	var ws struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	callToolAs(t, base, "writer", "workspace_create", map[string]any{"name": "Scopes"}, http.StatusOK, &ws)
+	callToolAs(t, base, "writer", "fs_write_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt", "content": "hello"}, http.StatusOK, nil)
+
+	// Read-only token can list and read
+	callToolAs(t, base, "reader", "workspace_list", map[string]any{}, http.StatusOK, nil)
+	callToolAs(t, base, "reader", "fs_list_directory", map[string]any{"workspaceId": ws.WorkspaceID, "path": "."}, http.StatusOK, nil)
+	var read struct {
+		Content string `json:"content"`
+	}
+	callToolAs(t, base, "reader", "fs_read_text_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "hello", read.Content)
+
+	// ...but every mutation is forbidden
+	callToolAs(t, base, "reader", "fs_write_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt", "content": "nope"}, http.StatusForbidden, nil)
+	callToolAs(t, base, "reader", "fs_delete_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt"}, http.StatusForbidden, nil)
+	callToolAs(t, base, "reader", "workspace_create", map[string]any{"name": "Nope"}, http.StatusForbidden, nil)
+
+	callToolAs(t, base, "reader", "fs_read_text_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "hello", read.Content)
+}
+
+func TestHTTP_RateLimit_429WithRetryAfter(t *testing.T) {
+	const burst = 3
+	base, _ := startTestServer(t, "18116", "--rate-limit=1", fmt.Sprintf("--rate-burst=%d", burst))
+
+	var limited *http.Response
+	var body []byte
+	for i := 0; i < burst+2; i++ {
+		resp := restPOST(t, base+"/api/tools/workspace_list", map[string]any{})
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			limited = resp
+			break
+		}
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	require.NotNil(t, limited, "expected a 429 after exceeding the burst")
+	assert.NotEmpty(t, limited.Header.Get("Retry-After"))
+	assert.Equal(t, "application/json", limited.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), `"code":"RATE_LIMITED"`)
+
+	// Health checks are not rate limited
+	resp, err := http.Get(base + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestHTTP_REST_BatchTools(t *testing.T) {
+	base, _ := startTestServer(t, "18117")
+	wsID := createWorkspace(t, base, "Batch")
+
+	type result struct {
+		OK     bool            `json:"ok"`
+		Status int             `json:"status"`
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	calls := []map[string]any{
+		{"tool": "fs_write_file", "params": map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "A"}},
+		{"tool": "fs_read_text_file", "params": map[string]any{"workspaceId": wsID, "path": "missing.txt"}},
+		{"tool": "fs_write_file", "params": map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "B"}},
+		{"tool": "fs_read_text_file", "params": map[string]any{"workspaceId": wsID, "path": "a.txt"}},
+	}
+
+	// Without stopOnError every call runs and results come back in order
+	resp := restPOST(t, base+"/api/tools/batch", calls)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var results []result
+	mustJSON(t, resp.Body, &results)
+	require.Len(t, results, 4)
+	assert.True(t, results[0].OK)
+	assert.False(t, results[1].OK)
+	assert.Equal(t, http.StatusNotFound, results[1].Status)
+	assert.Contains(t, results[1].Error, "NOT_FOUND")
+	assert.True(t, results[2].OK)
+	require.True(t, results[3].OK)
+	var read struct {
+		Content string `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(results[3].Result, &read))
+	assert.Equal(t, "A", read.Content)
+
+	// With stopOnError execution ends at the first failure
+	calls[0]["params"].(map[string]any)["path"] = "c.txt"
+	calls[2]["params"].(map[string]any)["path"] = "d.txt"
+	resp2 := restPOST(t, base+"/api/tools/batch?stopOnError=true", calls)
+	defer resp2.Body.Close()
+	require.Equal(t, http.StatusOK, resp2.StatusCode)
+	var stopped []result
+	mustJSON(t, resp2.Body, &stopped)
+	require.Len(t, stopped, 2)
+	assert.True(t, stopped[0].OK)
+	assert.False(t, stopped[1].OK)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "d.txt"}, http.StatusNotFound, nil)
+
+	// Unknown tools fail per call rather than failing the batch
+	resp3 := restPOST(t, base+"/api/tools/batch", []map[string]any{{"tool": "nope", "params": map[string]any{}}})
+	defer resp3.Body.Close()
+	var unknown []result
+	mustJSON(t, resp3.Body, &unknown)
+	require.Len(t, unknown, 1)
+	assert.Equal(t, http.StatusNotFound, unknown[0].Status)
+}
+
+func TestHTTP_REST_GetReadOnlyTools(t *testing.T) {
+	base, _ := startTestServer(t, "18120")
+	wsID := createWorkspace(t, base, "Get Tools")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/a.txt", "content": "one\ntwo\nthree\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/skip.log", "content": "x"}, http.StatusOK, nil)
+
+	get := func(tool string, q url.Values, wantStatus int, out any) *http.Response {
+		t.Helper()
+		resp, err := http.Get(base + "/api/tools/" + tool + "?" + q.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, wantStatus, resp.StatusCode)
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp
+	}
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	get("fs_read_text_file", url.Values{"workspaceId": {wsID}, "path": {"docs/a.txt"}, "head": {"1"}}, http.StatusOK, &read)
+	assert.Contains(t, read.Content, "one")
+	assert.NotContains(t, read.Content, "two")
+
+	var list struct {
+		Entries []string `json:"entries"`
+	}
+	get("fs_list_directory", url.Values{"workspaceId": {wsID}, "path": {"docs"}}, http.StatusOK, &list)
+	assert.Len(t, list.Entries, 2)
+
+	var info struct {
+		Size int64 `json:"size"`
+	}
+	get("fs_get_file_info", url.Values{"workspaceId": {wsID}, "path": {"docs/a.txt"}}, http.StatusOK, &info)
+	assert.EqualValues(t, 14, info.Size)
+
+	var tree struct {
+		Tree json.RawMessage `json:"tree"`
+	}
+	get("fs_directory_tree", url.Values{"workspaceId": {wsID}, "path": {"docs"}, "excludePatterns": {"*.log"}}, http.StatusOK, &tree)
+	assert.Contains(t, string(tree.Tree), "a.txt")
+	assert.NotContains(t, string(tree.Tree), "skip.log")
+
+	var wsList struct {
+		Workspaces []struct {
+			Name string `json:"name"`
+		} `json:"workspaces"`
+	}
+	get("workspace_list", nil, http.StatusOK, &wsList)
+	require.Len(t, wsList.Workspaces, 1)
+
+	// Errors map as for POST; bad query values are INVALID_INPUT
+	get("fs_read_text_file", url.Values{"workspaceId": {wsID}, "path": {"missing.txt"}}, http.StatusNotFound, nil)
+	get("fs_read_text_file", url.Values{"workspaceId": {wsID}, "path": {"docs/a.txt"}, "head": {"one"}}, http.StatusBadRequest, nil)
+
+	// Mutating tools stay POST-only
+	resp := get("fs_write_file", url.Values{"workspaceId": {wsID}, "path": {"b.txt"}, "content": {"x"}}, http.StatusMethodNotAllowed, nil)
+	assert.Equal(t, "POST", resp.Header.Get("Allow"))
+	get("fs_read_text_file", url.Values{"workspaceId": {wsID}, "path": {"b.txt"}}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "docs/a.txt"}, http.StatusOK, nil)
+
+	// GET operations are described in the OpenAPI document
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	specResp, err := http.Get(base + "/api/openapi.json")
+	require.NoError(t, err)
+	defer specResp.Body.Close()
+	mustJSON(t, specResp.Body, &spec)
+	assert.Contains(t, spec.Paths["/api/tools/fs_read_text_file"], "get")
+	assert.NotContains(t, spec.Paths["/api/tools/fs_write_file"], "get")
+}
+
+func TestHTTP_REST_ShareLinkToken(t *testing.T) {
+	base, _ := startTestServer(t, "18133", "--auth-tokens=admin")
+
+	var ws, other struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	callToolAs(t, base, "admin", "workspace_create", map[string]any{"name": "Shared"}, http.StatusOK, &ws)
+	callToolAs(t, base, "admin", "workspace_create", map[string]any{"name": "Private"}, http.StatusOK, &other)
+	callToolAs(t, base, "admin", "fs_write_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt", "content": "hello"}, http.StatusOK, nil)
+	callToolAs(t, base, "admin", "fs_write_file", map[string]any{"workspaceId": other.WorkspaceID, "path": "b.txt", "content": "secret"}, http.StatusOK, nil)
+
+	var link struct {
+		Token       string `json:"token"`
+		WorkspaceID string `json:"workspaceId"`
+		ExpiresAt   string `json:"expiresAt"`
+		EventsURL   string `json:"eventsUrl"`
+	}
+	callToolAs(t, base, "admin", "workspace_create_share_link", map[string]any{"workspaceId": ws.WorkspaceID}, http.StatusOK, &link)
+	require.NotEmpty(t, link.Token)
+	assert.Equal(t, ws.WorkspaceID, link.WorkspaceID)
+	exp, err := time.Parse(time.RFC3339, link.ExpiresAt)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), exp, time.Minute)
+
+	// Reads of the shared workspace work, over POST and GET
+	var read struct {
+		Content string `json:"content"`
+	}
+	callToolAs(t, base, link.Token, "fs_read_text_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "hello", read.Content)
+	req, err := http.NewRequest(http.MethodGet, base+"/api/tools/fs_read_text_file?workspaceId="+ws.WorkspaceID+"&path=a.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+link.Token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Writes, other workspaces, cross-workspace tools and new share links are denied
+	callToolAs(t, base, link.Token, "fs_write_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt", "content": "nope"}, http.StatusForbidden, nil)
+	callToolAs(t, base, link.Token, "fs_read_text_file", map[string]any{"workspaceId": other.WorkspaceID, "path": "b.txt"}, http.StatusForbidden, nil)
+	callToolAs(t, base, link.Token, "workspace_list", map[string]any{}, http.StatusForbidden, nil)
+	callToolAs(t, base, link.Token, "workspace_create_share_link", map[string]any{"workspaceId": ws.WorkspaceID}, http.StatusForbidden, nil)
+	// workspace_find_files searches workspaceIds (or every workspace), so a
+	// matching workspaceId must not let it reach the private one
+	for _, params := range []map[string]any{
+		{"pattern": "*", "content": "secret", "workspaceId": ws.WorkspaceID},
+		{"pattern": "*", "content": "secret", "workspaceIds": []string{other.WorkspaceID}},
+	} {
+		callToolAs(t, base, link.Token, "workspace_find_files", params, http.StatusForbidden, nil)
+	}
+	var batch []struct {
+		Status int `json:"status"`
+	}
+	callToolAs(t, base, link.Token, "batch", []map[string]any{
+		{"tool": "workspace_find_files", "params": map[string]any{"pattern": "*", "content": "secret", "workspaceId": ws.WorkspaceID}},
+	}, http.StatusOK, &batch)
+	require.Len(t, batch, 1)
+	assert.Equal(t, http.StatusForbidden, batch[0].Status)
+
+	// The token also authorizes the shared workspace's event stream only
+	resp, err = http.Get(base + link.EventsURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(base + "/events?workspaceId=" + other.WorkspaceID + "&token=" + url.QueryEscape(link.Token))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Revoked tokens are rejected
+	var revoked struct {
+		Revoked bool `json:"revoked"`
+	}
+	callToolAs(t, base, "admin", "workspace_revoke_share_link", map[string]any{"token": link.Token}, http.StatusOK, &revoked)
+	assert.True(t, revoked.Revoked)
+	callToolAs(t, base, link.Token, "fs_read_text_file", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt"}, http.StatusUnauthorized, nil)
+
+	// ...and so are expired ones
+	callToolAs(t, base, "admin", "workspace_create_share_link", map[string]any{"workspaceId": ws.WorkspaceID, "ttlSeconds": 1}, http.StatusOK, &link)
+	callToolAs(t, base, link.Token, "fs_get_file_info", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt"}, http.StatusOK, nil)
+	time.Sleep(2 * time.Second)
+	callToolAs(t, base, link.Token, "fs_get_file_info", map[string]any{"workspaceId": ws.WorkspaceID, "path": "a.txt"}, http.StatusUnauthorized, nil)
+
+	// Links die with their workspace: a new workspace under the shared id,
+	// here after a rename freed it, is not shared with them
+	callToolAs(t, base, "admin", "workspace_create_share_link", map[string]any{"workspaceId": ws.WorkspaceID}, http.StatusOK, &link)
+	callToolAs(t, base, "admin", "workspace_rename", map[string]any{"workspaceId": ws.WorkspaceID, "name": "Shared Moved"}, http.StatusOK, nil)
+	var reused struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	callToolAs(t, base, "admin", "workspace_create", map[string]any{"name": "Shared"}, http.StatusOK, &reused)
+	require.Equal(t, ws.WorkspaceID, reused.WorkspaceID)
+	callToolAs(t, base, "admin", "fs_write_file", map[string]any{"workspaceId": reused.WorkspaceID, "path": "a.txt", "content": "new owner"}, http.StatusOK, nil)
+	callToolAs(t, base, link.Token, "fs_read_text_file", map[string]any{"workspaceId": reused.WorkspaceID, "path": "a.txt"}, http.StatusUnauthorized, nil)
+}
+
+func TestHTTP_REST_WorkspaceImportZip(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18139")
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	for name, content := range map[string]string{
+		"README.md":          "# imported\n",
+		"src/main.go":        "package main\n",
+		"src/pkg/util/a.txt": "nested\n",
+		".git/config":        "[core]\n",
+	} {
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	_, err := zw.Create("empty/")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	// Multipart upload
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("name", "Imported"))
+	fw, err := mw.CreateFormFile("file", "project.zip")
+	require.NoError(t, err)
+	_, err = fw.Write(zbuf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+	resp, err := http.Post(base+"/api/workspaces/import", mw.FormDataContentType(), &body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(b))
+
+	var out struct {
+		WorkspaceID string `json:"workspaceId"`
+		Files       int    `json:"files"`
+		Directories int    `json:"directories"`
+		Skipped     int    `json:"skipped"`
+		Commit      string `json:"commit"`
+	}
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, 3, out.Files)
+	assert.Equal(t, 1, out.Directories)
+	assert.Equal(t, 1, out.Skipped)
+	require.NotEmpty(t, out.Commit)
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": out.WorkspaceID, "path": "src/pkg/util/a.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "nested\n", read.Content)
+	assert.DirExists(t, filepath.Join(wsRoot, out.WorkspaceID, "empty"))
+	cfg, err := os.ReadFile(filepath.Join(wsRoot, out.WorkspaceID, ".git", "config"))
+	require.NoError(t, err)
+	assert.NotEqual(t, "[core]\n", string(cfg), "archive must not overwrite the repository's .git")
+
+	// The import is committed on top of the initial commit
+	var hist struct {
+		Log []struct {
+			Commit  string `json:"commit"`
+			Message string `json:"message"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": out.WorkspaceID, "limit": 10}, http.StatusOK, &hist)
+	require.Len(t, hist.Log, 2)
+	assert.Equal(t, out.Commit, hist.Log[0].Commit)
+	assert.Contains(t, hist.Log[0].Message, "mcp/workspace_import")
+
+	// Base64 via the tool; traversal is rejected and leaves no workspace behind
+	callTool(t, base, "workspace_import", map[string]any{"name": "Again", "archiveBase64": base64.StdEncoding.EncodeToString(zbuf.Bytes())}, http.StatusOK, nil)
+	var evil bytes.Buffer
+	zw = zip.NewWriter(&evil)
+	_, err = zw.Create("../escape.txt")
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	callTool(t, base, "workspace_import", map[string]any{"name": "Evil", "archiveBase64": base64.StdEncoding.EncodeToString(evil.Bytes())}, http.StatusBadRequest, nil)
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(wsRoot), "escape.txt"))
+	var list struct {
+		Workspaces []struct {
+			DisplayName string `json:"displayName"`
+		} `json:"workspaces"`
+	}
+	callTool(t, base, "workspace_list", map[string]any{}, http.StatusOK, &list)
+	assert.Len(t, list.Workspaces, 2)
+
+	callTool(t, base, "workspace_import", map[string]any{"name": "Junk", "archiveBase64": base64.StdEncoding.EncodeToString([]byte("not an archive"))}, http.StatusBadRequest, nil)
+}
+
+// readTarGz returns the entry names of a tar.gz archive mapped to file contents
+// (directories map to "").
+func readTarGz(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	out := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		b, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		out[hdr.Name] = string(b)
+	}
+	return out
+}
+
+func TestHTTP_REST_WorkspaceExport(t *testing.T) {
+	base, _ := startTestServer(t, "18148")
+	wsID := createWorkspace(t, base, "Export Me")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "README.md", "content": "# hi\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "src/main.go", "content": "package main\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "empty"}, http.StatusOK, nil)
+
+	// Streaming download
+	resp, err := http.Get(base + "/api/workspaces/" + wsID + "/archive")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/gzip", resp.Header.Get("Content-Type"))
+	assert.Equal(t, map[string]string{
+		"README.md":   "# hi\n",
+		"src/":        "",
+		"src/main.go": "package main\n",
+		"empty/":      "",
+	}, readTarGz(t, resp.Body))
+
+	// includeGit adds the repository
+	resp2, err := http.Get(base + "/api/workspaces/" + wsID + "/archive?includeGit=true")
+	require.NoError(t, err)
+	defer resp2.Body.Close()
+	withGit := readTarGz(t, resp2.Body)
+	assert.Contains(t, withGit, ".git/HEAD")
+	assert.Contains(t, withGit, "empty/.gitkeep")
+
+	// The tool returns the same archive inline
+	var out struct {
+		ArchiveBase64 string `json:"archiveBase64"`
+		Files         int    `json:"files"`
+		Directories   int    `json:"directories"`
+	}
+	callTool(t, base, "workspace_export", map[string]any{"workspaceId": wsID}, http.StatusOK, &out)
+	assert.Equal(t, 2, out.Files)
+	assert.Equal(t, 2, out.Directories)
+	data, err := base64.StdEncoding.DecodeString(out.ArchiveBase64)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", readTarGz(t, bytes.NewReader(data))["src/main.go"])
+
+	resp3, err := http.Get(base + "/api/workspaces/missing/archive")
+	require.NoError(t, err)
+	resp3.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp3.StatusCode)
+}
+
+func TestHTTP_REST_WorkspaceImportTarGz(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18149")
+	stream, rd := openSSE(t, base+"/events?workspaceId=*")
+	defer stream.Body.Close()
+
+	type entry struct {
+		name, body string
+		typ        byte
+	}
+	tarGz := func(entries ...entry) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, e := range entries {
+			hdr := &tar.Header{Name: e.name, Typeflag: e.typ, Mode: 0644, Size: int64(len(e.body))}
+			switch e.typ {
+			case tar.TypeDir:
+				hdr.Mode, hdr.Size = 0755, 0
+			case tar.TypeSymlink:
+				hdr.Linkname, hdr.Size = "/etc/passwd", 0
+			}
+			require.NoError(t, tw.WriteHeader(hdr))
+			_, err := tw.Write([]byte(e.body))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, gz.Close())
+		return buf.Bytes()
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("name", "From Tar"))
+	fw, err := mw.CreateFormFile("file", "project.tar.gz")
+	require.NoError(t, err)
+	_, err = fw.Write(tarGz(
+		entry{name: "docs/", typ: tar.TypeDir},
+		entry{name: "docs/guide.md", body: "# guide\n", typ: tar.TypeReg},
+		entry{name: "main.go", body: "package main\n", typ: tar.TypeReg},
+	))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+	resp, err := http.Post(base+"/api/workspaces/import", mw.FormDataContentType(), &body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(b))
+	var out struct {
+		WorkspaceID string `json:"workspaceId"`
+		Files       int    `json:"files"`
+		Commit      string `json:"commit"`
+	}
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, 2, out.Files)
+	assert.NotEmpty(t, out.Commit)
+
+	// Imports announce the new workspace like workspace_create does
+	for {
+		evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+		require.NoError(t, err)
+		if evt.Type == "workspace.created" {
+			assert.Equal(t, out.WorkspaceID, evt.WorkspaceID)
+			require.NotNil(t, evt.Commit)
+			assert.Equal(t, out.Commit, *evt.Commit)
+			break
+		}
+	}
+
+	var matches struct {
+		Matches []string `json:"matches"`
+	}
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": out.WorkspaceID, "path": ".", "pattern": "*"}, http.StatusOK, &matches)
+	assert.ElementsMatch(t, []string{filepath.Join("docs", "guide.md"), "main.go"}, matches.Matches)
+
+	// Symlinks and entries escaping the workspace are rejected
+	for name, archive := range map[string][]byte{
+		"Link":   tarGz(entry{name: "passwd", typ: tar.TypeSymlink}),
+		"Escape": tarGz(entry{name: "../escape.txt", body: "x", typ: tar.TypeReg}),
+		"Abs":    tarGz(entry{name: "/tmp/abs.txt", body: "x", typ: tar.TypeReg}),
+	} {
+		callTool(t, base, "workspace_import", map[string]any{"name": name, "archiveBase64": base64.StdEncoding.EncodeToString(archive)}, http.StatusBadRequest, nil)
+	}
+
+	// Failed imports leave nothing behind, so their names are free again
+	entries, err := os.ReadDir(wsRoot)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	var again struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	archive := tarGz(entry{name: "ok.txt", body: "x", typ: tar.TypeReg})
+	callTool(t, base, "workspace_import", map[string]any{"name": "Escape", "archiveBase64": base64.StdEncoding.EncodeToString(archive)}, http.StatusOK, &again)
+	assert.Equal(t, "escape", again.WorkspaceID)
+}
+
+func TestHTTP_Metrics(t *testing.T) {
+	base, _ := startTestServer(t, "18154", "--metrics")
+	scrape := func() string {
+		resp, err := http.Get(base + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	wsID := createWorkspace(t, base, "metrics")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "a"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "b"}, http.StatusOK, nil)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "missing.txt"}, http.StatusNotFound, nil)
+	callTool(t, base, "no_such_tool", map[string]any{}, http.StatusNotFound, nil)
+
+	body := scrape()
+	assert.Contains(t, body, `mcp_workspace_tool_calls_total{tool="fs_write_file",transport="rest"} 2`)
+	assert.Contains(t, body, `mcp_workspace_tool_calls_total{tool="workspace_create",transport="rest"} 1`)
+	assert.Contains(t, body, `mcp_workspace_tool_errors_total{code="NOT_FOUND",tool="fs_read_text_file",transport="rest"} 1`)
+	assert.Contains(t, body, `mcp_workspace_tool_calls_total{tool="unknown",transport="rest"} 1`)
+	assert.NotContains(t, body, "no_such_tool")
+	assert.Contains(t, body, `mcp_workspace_events_published_total{type="file.created"} 2`)
+}
+
+func TestHTTP_REST_RequestBodyLimit(t *testing.T) {
+	base, _ := startTestServer(t, "18155", "--max-request-bytes=1024")
+	wsID := createWorkspace(t, base, "limits")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "small.txt", "content": "ok"}, http.StatusOK, nil)
+
+	big, err := json.Marshal(map[string]any{"workspaceId": wsID, "path": "big.txt", "content": strings.Repeat("x", 4096)})
+	require.NoError(t, err)
+	post := func(url string, body io.Reader) (int, string) {
+		resp, err := http.Post(url, "application/json", body)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	// Rejected up front from Content-Length...
+	status, body := post(base+"/api/tools/fs_write_file", bytes.NewReader(big))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.Contains(t, body, "TOO_LARGE")
+	// ...and while reading a chunked body of unknown length
+	status, body = post(base+"/api/tools/fs_write_file", io.MultiReader(bytes.NewReader(big)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.Contains(t, body, "TOO_LARGE")
+	batch := `[{"tool":"fs_write_file","params":` + string(big) + `}]`
+	status, _ = post(base+"/api/tools/batch", io.MultiReader(strings.NewReader(batch)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "big.txt"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_RawFileStreaming(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18160")
+	wsID := createWorkspace(t, base, "raw")
+
+	// An 11 MiB PNG, over the default fs_read_media_file cap
+	big := make([]byte, 11<<20)
+	copy(big, "\x89PNG\r\n\x1a\n")
+	for i := 8; i < len(big); i++ {
+		big[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "big.png"), big, 0644))
+
+	var out map[string]any
+	callTool(t, base, "fs_read_media_file", map[string]any{"workspaceId": wsID, "path": "big.png"}, http.StatusUnprocessableEntity, nil)
+
+	resp, err := http.Get(base + "/api/workspaces/" + wsID + "/raw?path=big.png")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(len(big)), resp.Header.Get("Content-Length"))
+	got, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(big, got), "streamed content differs")
+
+	// Small files still go through the tool
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "hello"}, http.StatusOK, nil)
+	callTool(t, base, "fs_read_media_file", map[string]any{"workspaceId": wsID, "path": "a.txt"}, http.StatusOK, &out)
+
+	for path, want := range map[string]int{
+		"":          http.StatusBadRequest,
+		"missing":   http.StatusNotFound,
+		".git/HEAD": http.StatusNotFound,
+		"../x":      http.StatusBadRequest,
+	} {
+		resp, err := http.Get(base + "/api/workspaces/" + wsID + "/raw?path=" + url.QueryEscape(path))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, want, resp.StatusCode, "path=%q", path)
+	}
+
+	// Uploaded files cannot run scripts on the server's origin
+	for path, attachment := range map[string]bool{
+		"page.html": true,
+		"logo.svg":  true,
+		"feed.xml":  true,
+		"a.txt":     false,
+	} {
+		if path != "a.txt" {
+			content := "<html><script>alert(1)</script></html>"
+			if path == "logo.svg" {
+				content = `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"/>`
+			}
+			callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": path, "content": content}, http.StatusOK, nil)
+		}
+		resp, err := http.Get(base + "/api/workspaces/" + wsID + "/raw?path=" + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"), path)
+		assert.Equal(t, "sandbox", resp.Header.Get("Content-Security-Policy"), path)
+		assert.Equal(t, attachment, strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment"), path)
+	}
+}
+
+func TestHTTP_REST_ETagRevalidation(t *testing.T) {
+	base, _ := startTestServer(t, "18177")
+	wsID := createWorkspace(t, base, "etags")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "hello"}, http.StatusOK, nil)
+
+	get := func(url, ifNoneMatch string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, url := range []string{
+		base + "/api/tools/fs_read_text_file?workspaceId=" + wsID + "&path=a.txt",
+		base + "/api/workspaces/" + wsID + "/raw?path=a.txt",
+	} {
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "hello"}, http.StatusOK, nil)
+		first := get(url, "")
+		require.Equal(t, http.StatusOK, first.StatusCode, url)
+		etag := first.Header.Get("ETag")
+		require.NotEmpty(t, etag, url)
+		assert.True(t, strings.HasPrefix(etag, `"`), etag)
+
+		again := get(url, etag)
+		assert.Equal(t, http.StatusNotModified, again.StatusCode, url)
+		assert.Equal(t, etag, again.Header.Get("ETag"), url)
+		assert.Equal(t, http.StatusNotModified, get(url, `"other", W/`+etag).StatusCode, url)
+
+		callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "changed"}, http.StatusOK, nil)
+		changed := get(url, etag)
+		assert.Equal(t, http.StatusOK, changed.StatusCode, url)
+		assert.NotEqual(t, etag, changed.Header.Get("ETag"), url)
+	}
+
+	// The tool result also carries workspaceHead, so a commit elsewhere in the
+	// workspace invalidates it even though the file is unchanged
+	url := base + "/api/tools/fs_read_text_file?workspaceId=" + wsID + "&path=a.txt"
+	etag := get(url, "").Header.Get("ETag")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "other"}, http.StatusOK, nil)
+	resp := get(url, etag)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+	// POST is not cached
+	resp = restPOST(t, base+"/api/tools/fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("ETag"))
+}
+
+func TestHTTP_REST_MaxMediaBytes(t *testing.T) {
+	base, _ := startTestServer(t, "18161", "--max-media-bytes=16")
+	wsID := createWorkspace(t, base, "media")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "small.txt", "content": "0123456789"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "large.txt", "content": strings.Repeat("x", 17)}, http.StatusOK, nil)
+	callTool(t, base, "fs_read_media_file", map[string]any{"workspaceId": wsID, "path": "small.txt"}, http.StatusOK, nil)
+	callTool(t, base, "fs_read_media_file", map[string]any{"workspaceId": wsID, "path": "large.txt"}, http.StatusUnprocessableEntity, nil)
+}
+
+func TestHTTP_Readyz(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18174", "--auth-tokens=secret")
+
+	type readiness struct {
+		Status string `json:"status"`
+		Checks []struct {
+			Name  string `json:"name"`
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		} `json:"checks"`
+	}
+	ready := func(wantStatus int) readiness {
+		t.Helper()
+		resp, err := http.Get(base + "/readyz")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, wantStatus, resp.StatusCode)
+		var out readiness
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+
+	out := ready(http.StatusOK)
+	assert.Equal(t, "ok", out.Status)
+	require.Len(t, out.Checks, 2)
+	for _, c := range out.Checks {
+		assert.True(t, c.OK, c.Name)
+	}
+	entries, err := os.ReadDir(wsRoot)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the probe file is removed")
+
+	if os.Geteuid() != 0 {
+		// Root ignores permission bits, so this only fails for other users.
+		require.NoError(t, os.Chmod(wsRoot, 0o555))
+		out = ready(http.StatusServiceUnavailable)
+		require.NoError(t, os.Chmod(wsRoot, 0o755))
+		assert.Equal(t, "unavailable", out.Status)
+		assert.Equal(t, "workspaces_root", out.Checks[0].Name)
+		assert.False(t, out.Checks[0].OK)
+		assert.Contains(t, out.Checks[0].Error, "not writable")
+	}
+
+	moved := wsRoot + "-moved"
+	require.NoError(t, os.Rename(wsRoot, moved))
+	t.Cleanup(func() { _ = os.RemoveAll(moved) })
+	out = ready(http.StatusServiceUnavailable)
+	assert.Equal(t, "unavailable", out.Status)
+	assert.False(t, out.Checks[0].OK)
+	assert.NotEmpty(t, out.Checks[0].Error)
+	assert.True(t, out.Checks[1].OK, "the event hub is still open")
+
+	require.NoError(t, os.Rename(moved, wsRoot))
+	ready(http.StatusOK)
+}
+
+func TestHTTP_ToolCallLogging(t *testing.T) {
+	bin := buildBinary(t)
+	wsRoot := t.TempDir()
+	logFile, err := os.Create(filepath.Join(t.TempDir(), "server.log"))
+	require.NoError(t, err)
+	defer logFile.Close()
+
+	host, port := "127.0.0.1", "18175"
+	server := exec.Command(bin, "--transport=http", "--host="+host, "--port="+port,
+		"--workspaces-root="+wsRoot, "--log-format=json", "--log-level=debug")
+	server.Stderr = logFile
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { _ = server.Process.Kill() })
+	time.Sleep(750 * time.Millisecond) // allow bind
+	base := fmt.Sprintf("http://%s:%s", host, port)
+
+	wsID := createWorkspace(t, base, "logging")
+	const secret = "do-not-log-this-content"
+	var out writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes.txt", "content": secret}, http.StatusOK, &out)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "missing.txt"}, http.StatusNotFound, nil)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "notes.txt"}, http.StatusOK, nil)
+
+	data, err := os.ReadFile(logFile.Name())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), secret)
+
+	var started, finished, failed map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec map[string]any
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			continue
+		}
+		switch {
+		case rec["msg"] == "Tool call started" && rec["tool"] == "fs_write_file":
+			started = rec
+		case rec["msg"] == "Tool call finished" && rec["tool"] == "fs_write_file":
+			finished = rec
+		case rec["msg"] == "Tool call failed" && rec["tool"] == "fs_read_text_file":
+			failed = rec
+		}
+	}
+	require.NotNil(t, started, "log=%s", data)
+	assert.Equal(t, "DEBUG", started["level"])
+	assert.Equal(t, wsID, started["workspaceId"])
+	assert.Equal(t, "notes.txt", started["path"])
+
+	require.NotNil(t, finished, "log=%s", data)
+	assert.Equal(t, "DEBUG", finished["level"])
+	assert.Equal(t, "rest", finished["transport"])
+	assert.Equal(t, wsID, finished["workspaceId"])
+	assert.Equal(t, "notes.txt", finished["path"])
+	assert.Equal(t, float64(len(secret)), finished["bytes"])
+	assert.Equal(t, out.Commit, finished["commit"])
+
+	require.NotNil(t, failed, "log=%s", data)
+	assert.Equal(t, "WARN", failed["level"])
+	assert.Equal(t, "NOT_FOUND", failed["code"])
+	assert.Equal(t, "missing.txt", failed["path"])
+}
+
+func TestHTTP_REST_StructuredErrors(t *testing.T) {
+	base, _ := startTestServer(t, "18186")
+	wsID := createWorkspace(t, base, "Errors")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "one"}, http.StatusOK, nil)
+	var read struct {
+		Etag string `json:"etag"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.txt"}, http.StatusOK, &read)
+	require.NotEmpty(t, read.Etag)
+
+	type restError struct {
+		Error struct {
+			Code    string         `json:"code"`
+			Message string         `json:"message"`
+			Details map[string]any `json:"details"`
+		} `json:"error"`
+	}
+	call := func(tool string, body any, wantStatus int) restError {
+		t.Helper()
+		resp := restPOST(t, base+"/api/tools/"+tool, body)
+		defer resp.Body.Close()
+		require.Equal(t, wantStatus, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var out restError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+
+	conflict := call("fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "two", "ifMatchFileEtag": "stale"}, http.StatusConflict)
+	assert.Equal(t, "CONFLICT", conflict.Error.Code)
+	assert.Equal(t, "file etag mismatch", conflict.Error.Message)
+	assert.Equal(t, read.Etag, conflict.Error.Details["currentEtag"])
+
+	notFound := call("fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "missing.txt"}, http.StatusNotFound)
+	assert.Equal(t, "NOT_FOUND", notFound.Error.Code)
+	assert.NotEmpty(t, notFound.Error.Message)
+	assert.Nil(t, notFound.Error.Details)
+
+	badJSON := call("fs_read_text_file", "not an object", http.StatusBadRequest)
+	assert.Equal(t, "INVALID_INPUT", badJSON.Error.Code)
+
+	// Errors raised before a tool runs use the same body
+	decode := func(resp *http.Response, wantStatus int) restError {
+		t.Helper()
+		defer resp.Body.Close()
+		require.Equal(t, wantStatus, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var out restError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+	resp, err := http.Get(base + "/api/tools/fs_write_file")
+	require.NoError(t, err)
+	assert.Equal(t, "METHOD_NOT_ALLOWED", decode(resp, http.StatusMethodNotAllowed).Error.Code)
+
+	authBase, _ := startTestServer(t, "18193", "--auth-tokens=secret")
+	unauthorized := decode(restPOST(t, authBase+"/api/tools/workspace_list", map[string]any{}), http.StatusUnauthorized)
+	assert.Equal(t, "UNAUTHORIZED", unauthorized.Error.Code)
+}
@@ -5,16 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"mcp-workspace-manager/pkg/events"
 )
 
 type wsCreateOut struct {
@@ -159,3 +163,187 @@ func TestHTTP_Streamable_WorkspaceCreate_SDK(t *testing.T) {
 	_, err = os.Stat(filepath.Join(wsRoot, "my-http-workspace"))
 	require.NoError(t, err, "workspace directory should exist")
 }
+
+func TestValidateConfig_TLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "cert.pem")
+	key := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(cert, []byte("cert"), 0o600))
+	require.NoError(t, os.WriteFile(key, []byte("key"), 0o600))
+
+	base := Config{WorkspacesRoot: dir, Transport: "http", Host: "127.0.0.1", Port: 8080,
+		EventsBuffer: events.DefaultRingCapacity, EventsHeartbeat: events.DefaultHeartbeat}
+
+	cfg := base
+	require.NoError(t, validateConfig(&cfg), "plain HTTP remains the default")
+
+	cfg = base
+	cfg.TLSCertFile, cfg.TLSKeyFile = cert, key
+	require.NoError(t, validateConfig(&cfg))
+
+	cfg = base
+	cfg.TLSCertFile = cert
+	assert.ErrorContains(t, validateConfig(&cfg), "must be set together")
+
+	cfg = base
+	cfg.TLSCertFile, cfg.TLSKeyFile = cert, filepath.Join(dir, "missing.pem")
+	assert.ErrorContains(t, validateConfig(&cfg), "--tls-key")
+}
+
+// bearerTransport adds an Authorization header to every request.
+type bearerTransport struct{ token string }
+
+func (b bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+b.token)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestHTTP_Streamable_ReadOnlyTokenScope(t *testing.T) {
+	base, _ := startTestServer(t, "18115", "--auth-tokens=reader:ro")
+
+	transport := &sdkmcp.StreamableClientTransport{
+		Endpoint:   base + "/mcp",
+		HTTPClient: &http.Client{Transport: bearerTransport{token: "reader"}},
+	}
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	defer cancel()
+	session, err := client.Connect(ctx, transport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "workspace_list", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+
+	_, err = session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "workspace_create", Arguments: map[string]any{"name": "Nope"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "FORBIDDEN")
+}
+
+func TestHTTP_Streamable_JSONSetNull(t *testing.T) {
+	base, _ := startTestServer(t, "18195")
+	wsID := createWorkspace(t, base, "JSON Null")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.json", "content": "{\"x\": 1}"}, http.StatusOK, nil)
+
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	defer cancel()
+	session, err := client.Connect(ctx, &sdkmcp.StreamableClientTransport{Endpoint: base + "/mcp"}, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	for _, v := range []any{nil, map[string]any{"a": 1, "b": 2}} {
+		res, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "fs_json_set", Arguments: map[string]any{"workspaceId": wsID, "path": "a.json", "pointer": "/x", "value": v}})
+		require.NoError(t, err)
+		require.False(t, res.IsError, "%v", res.Content)
+	}
+	var readOut struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a.json"}, http.StatusOK, &readOut)
+	assert.Equal(t, `{"x":{"a":1,"b":2}}`, readOut.Content)
+}
+
+func TestHTTP_Streamable_WorkspaceResources(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18163")
+	wsID := createWorkspace(t, base, "Resources")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "README.md", "content": "# hello\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "docs/my notes.txt", "content": "notes\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "empty"}, http.StatusOK, nil)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), 0, 0, 0, 13, 'I', 'H', 'D', 'R')
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "pixel.png"), png, 0644))
+
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	defer cancel()
+	session, err := client.Connect(ctx, &sdkmcp.StreamableClientTransport{Endpoint: base + "/mcp"}, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	list, err := session.ListResources(ctx, nil)
+	require.NoError(t, err)
+	var uris []string
+	for _, r := range list.Resources {
+		uris = append(uris, r.URI)
+	}
+	assert.Equal(t, []string{
+		"workspace://" + wsID + "/README.md",
+		"workspace://" + wsID + "/docs/my%20notes.txt",
+		"workspace://" + wsID + "/pixel.png",
+	}, uris, ".git and .gitkeep must not be listed")
+
+	res, err := session.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: "workspace://" + wsID + "/docs/my%20notes.txt"})
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	assert.Equal(t, "notes\n", res.Contents[0].Text)
+	assert.Equal(t, "text/plain; charset=utf-8", res.Contents[0].MIMEType)
+
+	res, err = session.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: "workspace://" + wsID + "/pixel.png"})
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", res.Contents[0].MIMEType)
+	assert.Equal(t, png, res.Contents[0].Blob)
+
+	_, err = session.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: "workspace://" + wsID + "/.git/HEAD"})
+	assert.Error(t, err)
+	_, err = session.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: "workspace://" + wsID + "/missing.txt"})
+	assert.Error(t, err)
+}
+
+func TestHTTP_Streamable_DirectoryTreeProgress(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18164")
+	wsID := createWorkspace(t, base, "Progress")
+	for i := 0; i < 50; i++ {
+		dir := filepath.Join(wsRoot, wsID, fmt.Sprintf("dir%02d", i))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		for j := 0; j < 20; j++ {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%02d.txt", j)), []byte("x"), 0644))
+		}
+	}
+
+	var mu sync.Mutex
+	var progress []*sdkmcp.ProgressNotificationParams
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "v0.0.0"}, &sdkmcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *sdkmcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, req.Params)
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	defer cancel()
+	session, err := client.Connect(ctx, &sdkmcp.StreamableClientTransport{Endpoint: base + "/mcp"}, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	// Set Meta directly: SetProgressToken loses the token when Meta is nil
+	params := &sdkmcp.CallToolParams{
+		Meta:      sdkmcp.Meta{"progressToken": "tree-1"},
+		Name:      "fs_directory_tree",
+		Arguments: map[string]any{"workspaceId": wsID, "path": "."},
+	}
+	res, err := session.CallTool(ctx, params)
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+
+	// Notifications may arrive just after the result
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(progress) > 0
+	}, 2*time.Second, 20*time.Millisecond)
+	mu.Lock()
+	first, n := progress[0], len(progress)
+	mu.Unlock()
+	assert.Equal(t, "tree-1", first.ProgressToken)
+	assert.Positive(t, first.Progress)
+
+	// Without a token no notifications are sent
+	_, err = session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "fs_directory_tree", Arguments: map[string]any{"workspaceId": wsID, "path": "."}})
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, progress, n)
+}
@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type writeOut struct {
+	Path         string `json:"path"`
+	BytesWritten int    `json:"bytesWritten"`
+	Overwritten  bool   `json:"overwritten"`
+	Commit       string `json:"commit"`
+}
+
+func TestHTTP_REST_WorkspaceDiff(t *testing.T) {
+	base, _ := startTestServer(t, "18102")
+	wsID := createWorkspace(t, base, "Diff Commits")
+
+	var w1, w2 writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "v1\n"}, http.StatusOK, &w1)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "hello\n"}, http.StatusOK, &w2)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "v2\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "b.txt"}, http.StatusOK, nil)
+
+	type diffOut struct {
+		To      string `json:"to"`
+		Changes []struct {
+			Path   string `json:"path"`
+			Action string `json:"action"`
+			Patch  string `json:"patch"`
+		} `json:"changes"`
+	}
+
+	// w1..w2: b.txt added, patch requested
+	var d1 diffOut
+	callTool(t, base, "workspace_diff", map[string]any{"workspaceId": wsID, "from": w1.Commit, "to": w2.Commit, "includePatch": true}, http.StatusOK, &d1)
+	require.Len(t, d1.Changes, 1)
+	assert.Equal(t, "b.txt", d1.Changes[0].Path)
+	assert.Equal(t, "added", d1.Changes[0].Action)
+	assert.Contains(t, d1.Changes[0].Patch, "+hello")
+
+	// w1..HEAD: only a.txt modified (b.txt added then deleted); patches omitted by default
+	var d2 diffOut
+	callTool(t, base, "workspace_diff", map[string]any{"workspaceId": wsID, "from": w1.Commit}, http.StatusOK, &d2)
+	assert.Equal(t, "HEAD", d2.To)
+	require.Len(t, d2.Changes, 1)
+	assert.Equal(t, "a.txt", d2.Changes[0].Path)
+	assert.Equal(t, "modified", d2.Changes[0].Action)
+	assert.Empty(t, d2.Changes[0].Patch)
+
+	callTool(t, base, "workspace_diff", map[string]any{"workspaceId": wsID, "from": "0123456789abcdef0123456789abcdef01234567"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_WorkspaceRevert(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18104")
+	wsID := createWorkspace(t, base, "Revert Test")
+
+	var v1, v2 writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "v1"}, http.StatusOK, &v1)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "v2"}, http.StatusOK, &v2)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "extra.txt", "content": "later"}, http.StatusOK, nil)
+
+	var out struct {
+		Commit  string `json:"commit"`
+		Changes []struct {
+			Path   string `json:"path"`
+			Action string `json:"action"`
+		} `json:"changes"`
+	}
+	callTool(t, base, "workspace_revert", map[string]any{"workspaceId": wsID, "commit": v1.Commit}, http.StatusOK, &out)
+	require.NotEmpty(t, out.Commit)
+	assert.NotEqual(t, v1.Commit, out.Commit, "revert must record a fresh commit")
+	assert.NotEqual(t, v2.Commit, out.Commit)
+	assert.Len(t, out.Changes, 2)
+
+	var read struct {
+		Content       string `json:"content"`
+		WorkspaceHead string `json:"workspaceHead"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "doc.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "v1", read.Content)
+	assert.Equal(t, out.Commit, read.WorkspaceHead)
+	_, err := os.Stat(filepath.Join(wsRoot, wsID, "extra.txt"))
+	assert.True(t, os.IsNotExist(err), "file added after the target commit should be removed")
+	_, err = os.Stat(filepath.Join(wsRoot, wsID, ".git"))
+	assert.NoError(t, err)
+
+	var hist struct {
+		Log []struct {
+			Message string `json:"message"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID}, http.StatusOK, &hist)
+	require.NotEmpty(t, hist.Log)
+	assert.Equal(t, "mcp/workspace_revert: Revert to "+v1.Commit, strings.TrimSpace(hist.Log[0].Message))
+
+	// Uncommitted edits made outside the API are reverted too, although HEAD already matches
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "doc.txt"), []byte("dirty"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "stray.txt"), []byte("stray"), 0o644))
+	callTool(t, base, "workspace_revert", map[string]any{"workspaceId": wsID, "commit": v1.Commit}, http.StatusOK, &out)
+	assert.ElementsMatch(t, []string{"doc.txt", "stray.txt"}, []string{out.Changes[0].Path, out.Changes[1].Path})
+	b, err := os.ReadFile(filepath.Join(wsRoot, wsID, "doc.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(b))
+	_, err = os.Stat(filepath.Join(wsRoot, wsID, "stray.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	callTool(t, base, "workspace_revert", map[string]any{"workspaceId": wsID, "commit": "deadbeef"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_WorkspaceUndoLastCommit(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18171")
+	wsID := createWorkspace(t, base, "Undo Test")
+
+	var v1, v2, extra writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "v1"}, http.StatusOK, &v1)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "v2"}, http.StatusOK, &v2)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "extra.txt", "content": "later"}, http.StatusOK, &extra)
+
+	type undoOut struct {
+		Commit  string `json:"commit"`
+		Undone  string `json:"undone"`
+		Mode    string `json:"mode"`
+		Changes []struct {
+			Path   string `json:"path"`
+			Action string `json:"action"`
+		} `json:"changes"`
+	}
+	type readOut struct {
+		Content       string `json:"content"`
+		WorkspaceHead string `json:"workspaceHead"`
+	}
+
+	// Soft: HEAD moves back, extra.txt stays on disk.
+	var soft undoOut
+	callTool(t, base, "workspace_undo_last_commit", map[string]any{"workspaceId": wsID}, http.StatusOK, &soft)
+	assert.Equal(t, v2.Commit, soft.Commit)
+	assert.Equal(t, extra.Commit, soft.Undone)
+	assert.Equal(t, "soft", soft.Mode)
+	assert.Empty(t, soft.Changes)
+	var read readOut
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "extra.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "later", read.Content)
+	assert.Equal(t, v2.Commit, read.WorkspaceHead)
+
+	// Hard: the v2 change is discarded.
+	var hard undoOut
+	callTool(t, base, "workspace_undo_last_commit", map[string]any{"workspaceId": wsID, "mode": "hard"}, http.StatusOK, &hard)
+	assert.Equal(t, v1.Commit, hard.Commit)
+	assert.Equal(t, v2.Commit, hard.Undone)
+	require.Len(t, hard.Changes, 1)
+	assert.Equal(t, "doc.txt", hard.Changes[0].Path)
+	assert.Equal(t, "modified", hard.Changes[0].Action)
+	read = readOut{}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "doc.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "v1", read.Content)
+	assert.Equal(t, v1.Commit, read.WorkspaceHead)
+
+	// Hard removes files the undone commit added.
+	var added writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "nested/new.txt", "content": "n"}, http.StatusOK, &added)
+	hard = undoOut{}
+	callTool(t, base, "workspace_undo_last_commit", map[string]any{"workspaceId": wsID, "mode": "hard"}, http.StatusOK, &hard)
+	assert.Equal(t, v1.Commit, hard.Commit)
+	_, err := os.Stat(filepath.Join(wsRoot, wsID, "nested", "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	callTool(t, base, "workspace_undo_last_commit", map[string]any{"workspaceId": wsID, "mode": "mixed"}, http.StatusBadRequest, nil)
+	callTool(t, base, "workspace_undo_last_commit", map[string]any{"workspaceId": wsID, "mode": "hard"}, http.StatusOK, nil)
+	callTool(t, base, "workspace_undo_last_commit", map[string]any{"workspaceId": wsID}, http.StatusConflict, nil)
+}
+
+func TestHTTP_REST_WorkspaceTags(t *testing.T) {
+	base, _ := startTestServer(t, "18172")
+	wsID := createWorkspace(t, base, "Tag Test")
+
+	var v1, v2 writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "v1"}, http.StatusOK, &v1)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "doc.txt", "content": "v2"}, http.StatusOK, &v2)
+
+	type tagInfo struct {
+		Name    string `json:"name"`
+		Commit  string `json:"commit"`
+		Message string `json:"message"`
+	}
+	var created tagInfo
+	callTool(t, base, "workspace_create_tag", map[string]any{"workspaceId": wsID, "name": "release-1", "message": "First release", "commit": v1.Commit}, http.StatusOK, &created)
+	assert.Equal(t, tagInfo{Name: "release-1", Commit: v1.Commit, Message: "First release"}, created)
+	created = tagInfo{}
+	callTool(t, base, "workspace_create_tag", map[string]any{"workspaceId": wsID, "name": "checkpoint"}, http.StatusOK, &created)
+	assert.Equal(t, v2.Commit, created.Commit, "tags HEAD by default")
+
+	var list struct {
+		Tags []tagInfo `json:"tags"`
+	}
+	callTool(t, base, "workspace_list_tags", map[string]any{"workspaceId": wsID}, http.StatusOK, &list)
+	assert.Equal(t, []tagInfo{
+		{Name: "checkpoint", Commit: v2.Commit},
+		{Name: "release-1", Commit: v1.Commit, Message: "First release"},
+	}, list.Tags)
+
+	var diff struct {
+		Changes []struct {
+			Path string `json:"path"`
+		} `json:"changes"`
+	}
+	callTool(t, base, "workspace_diff", map[string]any{"workspaceId": wsID, "from": "release-1", "to": "checkpoint"}, http.StatusOK, &diff)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "doc.txt", diff.Changes[0].Path)
+
+	callTool(t, base, "workspace_create_tag", map[string]any{"workspaceId": wsID, "name": "release-1"}, http.StatusConflict, nil)
+	callTool(t, base, "workspace_create_tag", map[string]any{"workspaceId": wsID, "name": "bad name"}, http.StatusBadRequest, nil)
+	callTool(t, base, "workspace_create_tag", map[string]any{"workspaceId": wsID, "name": "x", "commit": "0123456789abcdef0123456789abcdef01234567"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_WorkspaceClone(t *testing.T) {
+	base, _ := startTestServer(t, "18178")
+	wsID := createWorkspace(t, base, "Clone Source")
+	var w writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "one"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "dir/b.txt", "content": "two"}, http.StatusOK, &w)
+
+	stream, rd := openSSE(t, base+"/events?workspaceId=*")
+	defer stream.Body.Close()
+
+	var out struct {
+		WorkspaceID       string `json:"workspaceId"`
+		SourceWorkspaceID string `json:"sourceWorkspaceId"`
+		HeadCommit        string `json:"headCommit"`
+	}
+	callTool(t, base, "workspace_clone", map[string]any{"workspaceId": wsID, "name": "Clone Source"}, http.StatusOK, &out)
+	assert.Equal(t, wsID+"-2", out.WorkspaceID, "the colliding id is suffixed")
+	assert.Equal(t, wsID, out.SourceWorkspaceID)
+	assert.Equal(t, w.Commit, out.HeadCommit)
+
+	evt, err := readNextWorkspaceEvent(rd, 3*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "workspace.created", evt.Type)
+	assert.Equal(t, out.WorkspaceID, evt.WorkspaceID)
+
+	type history struct {
+		Log []struct {
+			Commit  string `json:"commit"`
+			Message string `json:"message"`
+		} `json:"log"`
+	}
+	var srcHist, cloneHist history
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID}, http.StatusOK, &srcHist)
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": out.WorkspaceID}, http.StatusOK, &cloneHist)
+	require.Len(t, srcHist.Log, 3, "initial commit plus two writes")
+	assert.Equal(t, srcHist, cloneHist)
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": out.WorkspaceID, "path": "dir/b.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "two", read.Content)
+
+	callTool(t, base, "workspace_clone", map[string]any{"workspaceId": "missing", "name": "x"}, http.StatusNotFound, nil)
+	callTool(t, base, "workspace_clone", map[string]any{"workspaceId": wsID}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_WorkspaceRename(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18147")
+	wsID := createWorkspace(t, base, "Old Name")
+	other := createWorkspace(t, base, "Taken")
+	var w writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "notes/a.txt", "content": "kept\n"}, http.StatusOK, &w)
+
+	var out struct {
+		WorkspaceID     string `json:"workspaceId"`
+		PrevWorkspaceID string `json:"prevWorkspaceId"`
+	}
+	callTool(t, base, "workspace_rename", map[string]any{"workspaceId": wsID, "name": "New Name"}, http.StatusOK, &out)
+	assert.Equal(t, "new-name", out.WorkspaceID)
+	assert.Equal(t, wsID, out.PrevWorkspaceID)
+	_, err := os.Stat(filepath.Join(wsRoot, wsID))
+	assert.True(t, os.IsNotExist(err))
+
+	// Files and history moved with it
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": out.WorkspaceID, "path": "notes/a.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "kept\n", read.Content)
+	var hist struct {
+		Log []struct {
+			Commit string `json:"commit"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": out.WorkspaceID}, http.StatusOK, &hist)
+	require.NotEmpty(t, hist.Log)
+	assert.Equal(t, w.Commit, hist.Log[0].Commit)
+
+	var list struct {
+		Workspaces []struct {
+			Name        string `json:"name"`
+			DisplayName string `json:"displayName"`
+		} `json:"workspaces"`
+	}
+	callTool(t, base, "workspace_list", map[string]any{}, http.StatusOK, &list)
+	found := false
+	for _, ws := range list.Workspaces {
+		if ws.Name == out.WorkspaceID {
+			found = true
+			assert.Equal(t, "New Name", ws.DisplayName)
+		}
+	}
+	assert.True(t, found)
+
+	callTool(t, base, "workspace_rename", map[string]any{"workspaceId": out.WorkspaceID, "name": other}, http.StatusConflict, nil)
+	callTool(t, base, "workspace_rename", map[string]any{"workspaceId": wsID, "name": "Whatever"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_WorkspaceCreateFromTemplate(t *testing.T) {
+	templates := t.TempDir()
+	tpl := filepath.Join(templates, "go-service")
+	require.NoError(t, os.MkdirAll(filepath.Join(tpl, "cmd"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tpl, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tpl, "README.md"), []byte("# service\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tpl, "cmd", "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tpl, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644))
+	// Templates kept in git often carry a root .gitkeep, which every workspace already has
+	require.NoError(t, os.WriteFile(filepath.Join(tpl, ".gitkeep"), nil, 0644))
+
+	base, wsRoot := startTestServer(t, "18150", "--templates-dir="+templates)
+	var out struct {
+		WorkspaceID string `json:"workspaceId"`
+		Commit      string `json:"commit"`
+	}
+	callTool(t, base, "workspace_create_from_template", map[string]any{"name": "My Service", "template": "go-service"}, http.StatusOK, &out)
+	assert.Equal(t, "my-service", out.WorkspaceID)
+	assert.NotEmpty(t, out.Commit)
+
+	for rel, want := range map[string]string{"README.md": "# service\n", "cmd/main.go": "package main\n"} {
+		var read struct {
+			Content string `json:"content"`
+		}
+		callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": out.WorkspaceID, "path": rel}, http.StatusOK, &read)
+		assert.Equal(t, want, read.Content, rel)
+	}
+	// The template's .git is not copied over the workspace's own repository
+	head, err := os.ReadFile(filepath.Join(wsRoot, out.WorkspaceID, ".git", "HEAD"))
+	require.NoError(t, err)
+	assert.NotEqual(t, "ref: refs/heads/main\n", string(head))
+
+	var hist struct {
+		Log []struct {
+			Commit string `json:"commit"`
+		} `json:"log"`
+	}
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": out.WorkspaceID}, http.StatusOK, &hist)
+	require.NotEmpty(t, hist.Log)
+	assert.Equal(t, out.Commit, hist.Log[0].Commit)
+
+	callTool(t, base, "workspace_create_from_template", map[string]any{"name": "x", "template": "../" + filepath.Base(templates)}, http.StatusBadRequest, nil)
+	callTool(t, base, "workspace_create_from_template", map[string]any{"name": "x", "template": "missing"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_WorkspaceListPaging(t *testing.T) {
+	base, _ := startTestServer(t, "18151")
+	for _, name := range []string{"delta", "alpha", "charlie", "bravo", "Alpha Two"} {
+		createWorkspace(t, base, name)
+	}
+
+	type listOut struct {
+		Workspaces []struct {
+			Name         string `json:"name"`
+			HeadCommit   string `json:"headCommit"`
+			LastModified string `json:"lastModified"`
+		} `json:"workspaces"`
+		Total int `json:"total"`
+	}
+	names := func(l listOut) []string {
+		var out []string
+		for _, w := range l.Workspaces {
+			out = append(out, w.Name)
+		}
+		return out
+	}
+
+	var page listOut
+	callTool(t, base, "workspace_list", map[string]any{"limit": 2}, http.StatusOK, &page)
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, []string{"alpha", "alpha-two"}, names(page))
+	for _, w := range page.Workspaces {
+		assert.NotEmpty(t, w.HeadCommit)
+		_, err := time.Parse(time.RFC3339, w.LastModified)
+		assert.NoError(t, err)
+	}
+
+	callTool(t, base, "workspace_list", map[string]any{"limit": 2, "offset": 2}, http.StatusOK, &page)
+	assert.Equal(t, []string{"bravo", "charlie"}, names(page))
+	callTool(t, base, "workspace_list", map[string]any{"limit": 2, "offset": 4}, http.StatusOK, &page)
+	assert.Equal(t, []string{"delta"}, names(page))
+	callTool(t, base, "workspace_list", map[string]any{"offset": 10}, http.StatusOK, &page)
+	assert.Empty(t, page.Workspaces)
+	assert.Equal(t, 5, page.Total)
+
+	callTool(t, base, "workspace_list", map[string]any{"nameContains": "ALPHA"}, http.StatusOK, &page)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, []string{"alpha", "alpha-two"}, names(page))
+
+	// Sorted by display name, not id: "echo (1)" sorts before "echo 0", "echo-1" after "echo-0"
+	createWorkspace(t, base, "Echo 0")
+	createWorkspace(t, base, "Echo (1)")
+	callTool(t, base, "workspace_list", map[string]any{"nameContains": "echo"}, http.StatusOK, &page)
+	assert.Equal(t, []string{"echo-1", "echo-0"}, names(page))
+
+	callTool(t, base, "workspace_list", map[string]any{"limit": -1}, http.StatusBadRequest, nil)
+}
+
+func TestHTTP_REST_WorkspaceListLastCommit(t *testing.T) {
+	base, _ := startTestServer(t, "18152")
+	wsID := createWorkspace(t, base, "Activity")
+	var w writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "a\n"}, http.StatusOK, &w)
+
+	var list struct {
+		Workspaces []struct {
+			Name              string `json:"name"`
+			HeadCommit        string `json:"headCommit"`
+			LastModified      string `json:"lastModified"`
+			LastCommitMessage string `json:"lastCommitMessage"`
+		} `json:"workspaces"`
+	}
+	callTool(t, base, "workspace_list", map[string]any{"nameContains": wsID}, http.StatusOK, &list)
+	require.Len(t, list.Workspaces, 1)
+	ws := list.Workspaces[0]
+	assert.Equal(t, w.Commit, ws.HeadCommit)
+	assert.Contains(t, ws.LastCommitMessage, "mcp/fs_write_file")
+	assert.NotContains(t, ws.LastCommitMessage, "\n")
+	date, err := time.Parse(time.RFC3339, ws.LastModified)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), date, time.Minute)
+}
+
+func TestHTTP_REST_WorkspaceInfo(t *testing.T) {
+	base, _ := startTestServer(t, "18157")
+	wsID := createWorkspace(t, base, "Overview")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "12345"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "src/b.go", "content": "package b\n"}, http.StatusOK, nil)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "src/lib/c.go", "content": "c"}, http.StatusOK, nil)
+	var last writeOut
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "empty"}, http.StatusOK, &last)
+
+	var info struct {
+		WorkspaceID  string `json:"workspaceId"`
+		DisplayName  string `json:"displayName"`
+		Files        int    `json:"files"`
+		Directories  int    `json:"directories"`
+		CombinedSize int64  `json:"combinedSize"`
+		HeadCommit   string `json:"headCommit"`
+		Branch       string `json:"branch"`
+		CommitCount  int    `json:"commitCount"`
+		CreatedAt    string `json:"createdAt"`
+	}
+	callTool(t, base, "workspace_info", map[string]any{"workspaceId": wsID}, http.StatusOK, &info)
+	assert.Equal(t, wsID, info.WorkspaceID)
+	assert.Equal(t, "Overview", info.DisplayName)
+	assert.Equal(t, 3, info.Files)
+	assert.Equal(t, 3, info.Directories) // src, src/lib, empty
+	assert.Equal(t, int64(5+len("package b\n")+1), info.CombinedSize)
+	assert.Equal(t, last.Commit, info.HeadCommit)
+	assert.Equal(t, "master", info.Branch)
+	assert.Equal(t, 5, info.CommitCount) // initial commit + 4 tool commits
+	created, err := time.Parse(time.RFC3339, info.CreatedAt)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), created, time.Minute)
+
+	callTool(t, base, "workspace_info", map[string]any{"workspaceId": "no-such-workspace"}, http.StatusNotFound, nil)
+	callTool(t, base, "workspace_info", map[string]any{"workspaceId": ".."}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_WorkspaceStatus(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18188")
+	wsID := createWorkspace(t, base, "Pending")
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a.txt", "content": "one"}, http.StatusOK, nil)
+
+	type status struct {
+		Clean bool `json:"clean"`
+		Files []struct {
+			Path   string `json:"path"`
+			Status string `json:"status"`
+			Staged bool   `json:"staged"`
+		} `json:"files"`
+	}
+	var clean status
+	callTool(t, base, "workspace_status", map[string]any{"workspaceId": wsID}, http.StatusOK, &clean)
+	assert.True(t, clean.Clean)
+	assert.Empty(t, clean.Files)
+
+	// Files edited behind the server's back are pending until the next commit
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "out-of-band.txt"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(wsRoot, wsID, "a.txt"), []byte("two"), 0644))
+	var pending status
+	callTool(t, base, "workspace_status", map[string]any{"workspaceId": wsID}, http.StatusOK, &pending)
+	assert.False(t, pending.Clean)
+	require.Len(t, pending.Files, 2)
+	assert.Equal(t, "a.txt", pending.Files[0].Path)
+	assert.Equal(t, "modified", pending.Files[0].Status)
+	assert.Equal(t, "out-of-band.txt", pending.Files[1].Path)
+	assert.Equal(t, "untracked", pending.Files[1].Status)
+	assert.False(t, pending.Files[1].Staged)
+
+	// GET works as for other read-only tools
+	resp, err := http.Get(base + "/api/tools/workspace_status?workspaceId=" + wsID)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The next tool commit records them
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "b.txt", "content": "b"}, http.StatusOK, nil)
+	callTool(t, base, "workspace_status", map[string]any{"workspaceId": wsID}, http.StatusOK, &clean)
+	assert.True(t, clean.Clean)
+
+	callTool(t, base, "workspace_status", map[string]any{"workspaceId": "missing"}, http.StatusNotFound, nil)
+}
+
+func TestHTTP_REST_WorkspaceRenameDuringWrites(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18190")
+	wsID := createWorkspace(t, base, "Busy")
+
+	// Writers race a rename: each write either lands before it or fails
+	// cleanly, and none resurrects the old workspace
+	var wg sync.WaitGroup
+	statuses := make(chan int, 64)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 16; i++ {
+				b, _ := json.Marshal(map[string]any{"workspaceId": wsID, "path": fmt.Sprintf("w%d/%d.txt", w, i), "content": "x"})
+				resp, err := http.Post(base+"/api/tools/fs_write_file", "application/json", bytes.NewReader(b))
+				if !assert.NoError(t, err) {
+					return
+				}
+				resp.Body.Close()
+				statuses <- resp.StatusCode
+			}
+		}(w)
+	}
+	time.Sleep(20 * time.Millisecond)
+	var renamed struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	callTool(t, base, "workspace_rename", map[string]any{"workspaceId": wsID, "name": "Busy Renamed"}, http.StatusOK, &renamed)
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		assert.Contains(t, []int{http.StatusOK, http.StatusBadRequest, http.StatusNotFound}, status)
+	}
+
+	_, err := os.Stat(filepath.Join(wsRoot, wsID))
+	assert.True(t, os.IsNotExist(err), "the old workspace directory must stay gone")
+	var st struct {
+		Clean bool `json:"clean"`
+	}
+	callTool(t, base, "workspace_status", map[string]any{"workspaceId": renamed.WorkspaceID}, http.StatusOK, &st)
+	assert.True(t, st.Clean, "every accepted write was committed")
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": renamed.WorkspaceID}, http.StatusOK, nil)
+}
+
+func TestHTTP_REST_EphemeralWorkspaces(t *testing.T) {
+	base, wsRoot := startTestServer(t, "18194", "--ephemeral")
+	wsID := createWorkspace(t, base, "Ephemeral")
+
+	var w writeOut
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "a/b.txt", "content": "hello\nworld"}, http.StatusOK, &w)
+	assert.False(t, w.Overwritten)
+	assert.Empty(t, w.Commit, "there is no git history in memory")
+
+	var read struct {
+		Content string `json:"content"`
+	}
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a/b.txt"}, http.StatusOK, &read)
+	assert.Equal(t, "hello\nworld", read.Content)
+	callTool(t, base, "fs_read_text_file", map[string]any{"workspaceId": wsID, "path": "a/b.txt", "maxBytes": 3}, http.StatusOK, &read)
+	assert.Equal(t, "hel", read.Content)
+	var lines struct {
+		Lines []struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+	}
+	callTool(t, base, "fs_read_lines", map[string]any{"workspaceId": wsID, "path": "a/b.txt", "startLine": 2}, http.StatusOK, &lines)
+	require.Len(t, lines.Lines, 1)
+	assert.Equal(t, "world", lines.Lines[0].Text)
+
+	var mkdir struct {
+		Created bool `json:"created"`
+	}
+	callTool(t, base, "fs_create_directory", map[string]any{"workspaceId": wsID, "path": "docs"}, http.StatusOK, &mkdir)
+	assert.True(t, mkdir.Created)
+	callTool(t, base, "fs_write_file", map[string]any{"workspaceId": wsID, "path": "A/b.txt", "content": "x"}, http.StatusConflict, nil)
+
+	var info struct {
+		Size int64  `json:"size"`
+		Type string `json:"type"`
+	}
+	callTool(t, base, "fs_get_file_info", map[string]any{"workspaceId": wsID, "path": "a/b.txt"}, http.StatusOK, &info)
+	assert.Equal(t, int64(11), info.Size)
+	assert.Equal(t, "file", info.Type)
+
+	callTool(t, base, "fs_move_file", map[string]any{"workspaceId": wsID, "source": "a/b.txt", "destination": "docs/b.txt"}, http.StatusOK, nil)
+	var list struct {
+		Entries []string `json:"entries"`
+	}
+	callTool(t, base, "fs_list_directory", map[string]any{"workspaceId": wsID, "path": "docs"}, http.StatusOK, &list)
+	assert.Equal(t, []string{"[FILE] b.txt"}, list.Entries, "the directory's .gitkeep went with its first file")
+
+	callTool(t, base, "fs_delete_file", map[string]any{"workspaceId": wsID, "path": "docs/b.txt"}, http.StatusOK, nil)
+	callTool(t, base, "fs_get_file_info", map[string]any{"workspaceId": wsID, "path": "docs/b.txt"}, http.StatusNotFound, nil)
+
+	// Tools that still need the disk or history are refused, not run against it
+	callTool(t, base, "fs_search_files", map[string]any{"workspaceId": wsID, "path": ".", "pattern": "*"}, http.StatusUnprocessableEntity, nil)
+	callTool(t, base, "fs_get_commit_history", map[string]any{"workspaceId": wsID}, http.StatusUnprocessableEntity, nil)
+
+	entries, err := os.ReadDir(wsRoot)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing was written to disk")
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HubOption configures a Hub.
+type HubOption func(*Hub)
+
+// WithLogDir enables a persistent, append-only JSON-lines event log per workspace
+// in dir (<dir>/<workspaceId>.jsonl). Event ids continue from the last logged id
+// after a restart, and subscribers resuming from an id older than the ring buffer
+// are replayed from the log. A log is created by the first event published for
+// its workspace, never by subscribers, and once it holds as many events as the
+// ring buffer it is rotated to <workspaceId>.jsonl.1 (replacing the previous
+// one), so at least the last ring-buffer's worth of events is kept on disk. The
+// directory must exist.
+func WithLogDir(dir string) HubOption {
+	return func(h *Hub) { h.logDir = dir }
+}
+
+// logPath returns the log file for a workspace, or "" when logging is disabled or
+// the id is not usable as a file name.
+func (h *Hub) logPath(workspaceID string) string {
+	if h.logDir == "" || workspaceID == "" || workspaceID == "." || workspaceID == ".." ||
+		strings.ContainsAny(workspaceID, `/\`) {
+		return ""
+	}
+	return filepath.Join(h.logDir, workspaceID+".jsonl")
+}
+
+// rotatedLogPath is where a full log is moved by rotation.
+func rotatedLogPath(path string) string {
+	return path + ".1"
+}
+
+// loadLog returns the last id in a workspace's log (looking at the rotated log
+// when the current one is empty) and the number of events in the current log.
+// A missing log is not an error.
+func loadLog(path string) (last int64, lines int, err error) {
+	err = scanLog(path, func(e WorkspaceEvent) bool {
+		last = e.ID
+		lines++
+		return true
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return 0, 0, err
+	}
+	if lines == 0 {
+		err = scanLog(rotatedLogPath(path), func(e WorkspaceEvent) bool {
+			last = e.ID
+			return true
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return 0, 0, err
+		}
+	}
+	return last, lines, nil
+}
+
+// flushLog appends the events queued by Publish to the workspace log, opening it
+// on first use and rotating it when it reaches ringCap events. Publish queues
+// events in id order under h.mu and the whole queue is taken at once under
+// logMu, so lines are written in id order without holding h.mu during I/O.
+func (ws *workspaceState) flushLog() {
+	ws.logMu.Lock()
+	defer ws.logMu.Unlock()
+	ws.queueMu.Lock()
+	queue := ws.logQueue
+	ws.logQueue = nil
+	ws.queueMu.Unlock()
+	if ws.logClosed {
+		return
+	}
+	for _, evt := range queue {
+		if ws.log == nil {
+			f, err := os.OpenFile(ws.logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				slog.Warn("events: failed to open event log", "workspaceId", evt.WorkspaceID, "error", err)
+				return
+			}
+			ws.log = f
+		}
+		b, err := json.Marshal(evt)
+		if err != nil {
+			slog.Warn("events: failed to marshal event for log", "error", err)
+			continue
+		}
+		if _, err := ws.log.Write(append(b, '\n')); err != nil {
+			slog.Warn("events: failed to append to event log", "workspaceId", evt.WorkspaceID, "error", err)
+			continue
+		}
+		ws.logLines++
+		if ws.logLines >= ws.ringCap {
+			_ = ws.log.Close()
+			ws.log, ws.logLines = nil, 0
+			if err := os.Rename(ws.logPath, rotatedLogPath(ws.logPath)); err != nil {
+				slog.Warn("events: failed to rotate event log", "workspaceId", evt.WorkspaceID, "error", err)
+			}
+		}
+	}
+}
+
+// closeLog flushes and closes the workspace log; later events are not logged.
+func (ws *workspaceState) closeLog() {
+	ws.flushLog()
+	ws.logMu.Lock()
+	defer ws.logMu.Unlock()
+	ws.logClosed = true
+	if ws.log != nil {
+		_ = ws.log.Close()
+		ws.log = nil
+	}
+}
+
+// readLog returns logged events with sinceID < ID < beforeID that pass filter,
+// from the rotated log and then the current one.
+func readLog(path string, sinceID, beforeID int64, filter Filter) []WorkspaceEvent {
+	var out []WorkspaceEvent
+	done := false
+	for _, p := range []string{rotatedLogPath(path), path} {
+		if done {
+			break
+		}
+		err := scanLog(p, func(e WorkspaceEvent) bool {
+			if e.ID >= beforeID {
+				done = true
+				return false
+			}
+			if e.ID > sinceID && (filter == nil || filter(e)) {
+				out = append(out, e)
+			}
+			return true
+		})
+		if err != nil && !os.IsNotExist(err) {
+			slog.Warn("events: failed to read event log", "path", p, "error", err)
+		}
+	}
+	return out
+}
+
+// scanLog calls fn for each decodable line of the log until fn returns false.
+// A torn final line (e.g. from a crash mid-write) is skipped.
+func scanLog(path string, fn func(WorkspaceEvent) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e WorkspaceEvent
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		if !fn(e) {
+			return nil
+		}
+	}
+	return sc.Err()
+}
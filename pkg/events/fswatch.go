@@ -1,6 +1,7 @@
 package events
 
 import (
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -9,24 +10,38 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"mcp-workspace-manager/pkg/workspace"
 )
 
 // StartFSWatcher watches the workspaces root for external file changes (not going through API/MCP)
 // and publishes normalized WorkspaceEvents to the hub.
-// Best-effort recursive watching: we watch each workspace root directory under root, and dynamically
-// add watchers for newly created top-level workspace directories. For nested subdirectories, we
-// attempt to detect creation and add a watcher lazily; however, this is not guaranteed on all OSes.
-// This is an MVP that covers most typical workflows for small workspaces.
-func StartFSWatcher(root string, hub *Hub) (func(), error) {
+// fsnotify is not recursive, so every directory below root (except .git) is watched individually:
+// existing ones are walked at startup, directories created (or moved in) later are walked when
+// their Create event arrives, and deleted ones are dropped from the watch set. A directory created
+// and filled faster than its Create event is handled may still miss events for its first entries.
+// A directory renamed within a workspace moves its watches with it and is reported as dir.moved.
+// Paths protected by protected (workspace.DefaultProtectedPaths when nil) are neither watched nor
+// reported. The returned function stops the watcher; once it returns nothing more is published, and calling
+// it again is a no-op.
+func StartFSWatcher(root string, hub *Hub, protected *workspace.ProtectedPaths) (func(), error) {
 	if hub == nil {
 		return func() {}, nil
 	}
+	if protected == nil {
+		protected = workspace.DefaultProtectedPaths()
+	}
 
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
 
+	// Files seen by the watcher (path -> last stat), so a removed path can still be
+	// identified when correlating moves (see below); guarded by debMu.
+	var debMu sync.Mutex
+	known := map[string]os.FileInfo{}
+
 	// Track watched directories to avoid duplicate Add calls
 	var mu sync.Mutex
 	watched := map[string]struct{}{}
@@ -43,70 +58,188 @@ func StartFSWatcher(root string, hub *Hub) (func(), error) {
 		}
 		watched[dir] = struct{}{}
 		slog.Debug("fswatch: watching dir", "dir", dir)
-	}
 
-	// Seed: watch the workspaces root and each top-level workspace directory
-	addWatch(root)
-	entries, _ := os.ReadDir(root)
-	for _, e := range entries {
-		if e.IsDir() {
-			addWatch(filepath.Join(root, e.Name()))
+		if entries, err := os.ReadDir(dir); err == nil {
+			debMu.Lock()
+			if info, err := os.Stat(dir); err == nil {
+				known[dir] = info
+			}
+			for _, e := range entries {
+				if !e.Type().IsRegular() {
+					continue
+				}
+				if info, err := e.Info(); err == nil {
+					known[filepath.Join(dir, e.Name())] = info
+				}
+			}
+			debMu.Unlock()
 		}
 	}
+	// addWatchTree watches dir and every directory below it, skipping protected ones
+	addWatchTree := func(dir string) {
+		_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if protected.IsProtectedName(d.Name()) {
+				return filepath.SkipDir
+			}
+			// Below a workspace directory, path globs apply too
+			if rel, err := filepath.Rel(root, p); err == nil {
+				if parts := strings.SplitN(rel, string(os.PathSeparator), 2); len(parts) == 2 && protected.IsProtectedPath(parts[1]) {
+					return filepath.SkipDir
+				}
+			}
+			addWatch(p)
+			return nil
+		})
+	}
+	// unwatch forgets a deleted directory and everything below it, reporting
+	// whether it was watched (i.e. the removed path was a directory). A removal is
+	// reported both by the parent's watch and the directory's own, so unwatched
+	// directories are remembered for a moment to classify the second one too.
+	gone := map[string]time.Time{}
+	unwatch := func(dir string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		for d, t := range gone {
+			if now.Sub(t) > time.Second {
+				delete(gone, d)
+			}
+		}
+		if _, ok := gone[dir]; ok {
+			return true
+		}
+		if _, ok := watched[dir]; !ok {
+			return false
+		}
+		prefix := dir + string(os.PathSeparator)
+		for d := range watched {
+			if d == dir || strings.HasPrefix(d, prefix) {
+				delete(watched, d)
+				gone[d] = now
+				// The kernel usually drops the watch with the directory already
+				_ = w.Remove(d)
+			}
+		}
+		return true
+	}
+
+	// Seed: watch the workspaces root and every directory in each workspace
+	addWatchTree(root)
 
 	// Debounce burst events (per workspace/path key)
 	type key struct {
 		wsID string
 		path string
 		typ  string
+		prev string // previous path of file.moved and dir.moved
 	}
-	var debMu sync.Mutex
 	debounced := map[key]time.Time{}
 	const debounceWindow = 200 * time.Millisecond
 
-	flush := func(wsID, relPath, evtType string, isDir bool) {
+	// Move correlation: fsnotify reports a rename as Rename (or Remove) of the old
+	// path and Create of the new one, with nothing linking them. The removal is
+	// held for moveWindow; a file created in the same workspace within the window
+	// that is the same file (inode), or has the same base name and size, turns the
+	// pair into one file.moved. Unmatched removals become file.deleted as before.
+	// Directories are correlated the same way into dir.moved.
+	type pendingRemoval struct {
+		wsID string
+		path string
+		abs  string
+		info os.FileInfo // nil if the file was never seen
+		dir  bool
+		at   time.Time
+	}
+	var pending []pendingRemoval // guarded by debMu
+	const moveWindow = 300 * time.Millisecond
+
+	sameFile := func(p pendingRemoval, abs string, info os.FileInfo) bool {
+		if p.dir != info.IsDir() {
+			return false
+		}
+		if p.info != nil && os.SameFile(p.info, info) {
+			return true
+		}
+		if p.dir && p.info != nil {
+			// A directory's size says nothing about its content; trust the inode
+			return false
+		}
+		if filepath.Base(p.abs) != filepath.Base(abs) {
+			return false
+		}
+		return p.info == nil || p.info.Size() == info.Size()
+	}
+
+	flush := func(wsID, relPath, prevPath, evtType string, isDir bool, observed time.Time) {
 		if wsID == "" || relPath == "" {
 			return
 		}
 		// Ignore protected paths (any segment like .git or .gitkeep)
-		if isProtectedPath(relPath) {
+		if protected.IsProtectedPath(relPath) {
 			return
 		}
-		// Suppress duplicate fs events shortly after an API-originated publish
-		// (suppress if same type OR any event for this path was just published)
-		// Use a wider window to avoid duplicate echoes racing in after API-originated publishes.
+		// Skip changes the API registered before making them (see Hub.Expect)
+		if hub.Expected(wsID, relPath, evtType, observed) {
+			return
+		}
+		// Fallback for mutations that did not register: suppress duplicate fs events
+		// shortly after an API-originated publish (same type OR any event for this path)
 		if hub.RecentlyPublished(wsID, relPath, evtType, 1*time.Second) ||
 			hub.RecentlyPublishedForPath(wsID, relPath, 1*time.Second) {
 			return
 		}
-		hub.Publish(wsID, WorkspaceEvent{
+		evt := WorkspaceEvent{
 			Type:  evtType,
 			Path:  relPath,
 			IsDir: isDir,
 			Actor: &Actor{Kind: "fswatch"},
-		})
+		}
+		if prevPath != "" {
+			evt.PrevPath = &prevPath
+		}
+		hub.Publish(wsID, evt)
 	}
 
 	coalescer := time.NewTicker(100 * time.Millisecond)
 	stop := make(chan struct{})
+	var running sync.WaitGroup
+	running.Add(2)
 
 	go func() {
+		defer running.Done()
 		defer coalescer.Stop()
 		for {
 			select {
 			case <-coalescer.C:
 				now := time.Now()
-				var toSend []key
+				toSend := map[key]time.Time{}
 				debMu.Lock()
+				// Removals that found no matching create are plain deletes
+				kept := pending[:0]
+				for _, p := range pending {
+					if now.Sub(p.at) >= moveWindow {
+						typ := "file.deleted"
+						if p.dir {
+							typ = "dir.deleted"
+						}
+						debounced[key{wsID: p.wsID, path: p.path, typ: typ}] = p.at
+					} else {
+						kept = append(kept, p)
+					}
+				}
+				pending = kept
 				for k, t := range debounced {
 					if now.Sub(t) >= debounceWindow {
-						toSend = append(toSend, k)
+						toSend[k] = t
 						delete(debounced, k)
 					}
 				}
 				debMu.Unlock()
-				for _, k := range toSend {
-					flush(k.wsID, k.path, k.typ, strings.HasSuffix(k.typ, ".created") || strings.HasSuffix(k.typ, ".deleted") && strings.HasSuffix(strings.ToLower(k.path), "/"))
+				for k, t := range toSend {
+					flush(k.wsID, k.path, k.prev, k.typ, strings.HasPrefix(k.typ, "dir.") || strings.HasSuffix(k.typ, ".created") || strings.HasSuffix(k.typ, ".deleted") && strings.HasSuffix(strings.ToLower(k.path), "/"), t)
 				}
 			case <-stop:
 				return
@@ -134,38 +267,89 @@ func StartFSWatcher(root string, hub *Hub) (func(), error) {
 	}
 
 	go func() {
+		defer running.Done()
 		for {
 			select {
+			case <-stop:
+				return
 			case ev, ok := <-w.Events:
 				if !ok {
 					return
 				}
-				// Dynamically add watchers for newly created directories (best-effort)
+				// Watch newly created directories, including new workspaces and
+				// directory trees moved in from elsewhere
 				if ev.Op&fsnotify.Create == fsnotify.Create {
 					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
-						addWatch(ev.Name)
+						addWatchTree(ev.Name)
 					}
 				}
 
+				// Classify event
+				evtType := ""
+				isDir := false
+				// Try to stat to determine if dir (fails for remove; a removed
+				// directory is recognised by having been watched)
+				info, statErr := os.Stat(ev.Name)
+				if statErr == nil {
+					isDir = info.IsDir()
+				} else if ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+					isDir = unwatch(ev.Name)
+				}
+
 				wsID, rel := splitPath(ev.Name)
 				if wsID == "" || rel == "" {
-					// It may be a create of a new top-level workspace directory
-					if ev.Op&fsnotify.Create == fsnotify.Create {
-						// attempt to add watch
-						if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
-							addWatch(ev.Name)
-						}
-					}
 					continue
 				}
 
-				// Classify event
-				evtType := ""
-				isDir := false
-				// Try to stat to determine if dir (may fail for remove)
-				if info, err := os.Stat(ev.Name); err == nil {
-					isDir = info.IsDir()
+				// Correlate removals and creates into moves. A removed directory is
+				// reported by its parent's watch and its own, but held only once;
+				// its watches are gone (see unwatch) and its new location, if any,
+				// was watched above.
+				if !protected.IsProtectedPath(rel) {
+					removed := ev.Op&(fsnotify.Rename|fsnotify.Remove) != 0 && statErr != nil
+					debMu.Lock()
+					switch {
+					case removed:
+						held := false
+						for _, p := range pending {
+							held = held || isDir && p.abs == ev.Name
+						}
+						if !held {
+							pending = append(pending, pendingRemoval{wsID: wsID, path: rel, abs: ev.Name, info: known[ev.Name], dir: isDir, at: time.Now()})
+						}
+						delete(known, ev.Name)
+						if isDir {
+							prefix := ev.Name + string(os.PathSeparator)
+							for p := range known {
+								if strings.HasPrefix(p, prefix) {
+									delete(known, p)
+								}
+							}
+						}
+						debMu.Unlock()
+						continue
+					case statErr == nil && ev.Op&fsnotify.Create == fsnotify.Create:
+						known[ev.Name] = info
+						for i, p := range pending {
+							if p.wsID == wsID && p.abs != ev.Name && sameFile(p, ev.Name, info) {
+								pending = append(pending[:i], pending[i+1:]...)
+								evtType = "file.moved"
+								if isDir {
+									evtType = "dir.moved"
+								}
+								debounced[key{wsID: wsID, path: rel, typ: evtType, prev: p.path}] = time.Now()
+								break
+							}
+						}
+					case statErr == nil:
+						known[ev.Name] = info
+					}
+					debMu.Unlock()
+					if evtType != "" {
+						continue
+					}
 				}
+
 				switch {
 				case ev.Op&fsnotify.Create == fsnotify.Create:
 					if isDir {
@@ -180,7 +364,7 @@ func StartFSWatcher(root string, hub *Hub) (func(), error) {
 						evtType = "file.deleted"
 					}
 				case ev.Op&fsnotify.Rename == fsnotify.Rename:
-					// Without destination we treat as delete; actual move may be observed as create elsewhere
+					// Not correlated above (directory or protected path): treat as delete
 					if isDir {
 						evtType = "dir.deleted"
 					} else {
@@ -206,28 +390,13 @@ func StartFSWatcher(root string, hub *Hub) (func(), error) {
 		}
 	}()
 
+	var stopOnce sync.Once
 	stopFn := func() {
-		close(stop)
-		_ = w.Close()
+		stopOnce.Do(func() {
+			close(stop)
+			_ = w.Close()
+			running.Wait()
+		})
 	}
 	return stopFn, nil
 }
-
-// local copy of protected path logic; keep in sync with mcpsdk/tools.go
-func isProtectedName(name string) bool {
-	return name == ".git" || name == ".gitkeep"
-}
-
-// isProtectedPath returns true if any segment of rel equals a protected name.
-func isProtectedPath(rel string) bool {
-	cleaned := filepath.Clean(rel)
-	for _, seg := range strings.Split(cleaned, string(os.PathSeparator)) {
-		if seg == "" {
-			continue
-		}
-		if isProtectedName(seg) {
-			return true
-		}
-	}
-	return false
-}
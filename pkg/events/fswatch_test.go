@@ -0,0 +1,172 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSWatcher_RenameIsMove(t *testing.T) {
+	root := t.TempDir()
+	wsDir := filepath.Join(root, "ws")
+	require.NoError(t, os.MkdirAll(filepath.Join(wsDir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "a.txt"), []byte("hello"), 0o644))
+
+	hub := NewHub(16)
+	defer hub.Close()
+	ch, unsub := hub.Subscribe("ws", 0, 16)
+	defer unsub()
+	// Deferred last so it runs first: the watcher stops publishing before
+	// the subscription is closed
+	stop, err := StartFSWatcher(root, hub, nil)
+	require.NoError(t, err)
+	defer stop()
+
+	// A rename of a file that existed before the watcher started
+	require.NoError(t, os.Rename(filepath.Join(wsDir, "a.txt"), filepath.Join(wsDir, "b.txt")))
+	evt := collect(t, ch, 1)[0]
+	require.Equal(t, "file.moved", evt.Type)
+	require.Equal(t, "b.txt", evt.Path)
+	require.NotNil(t, evt.PrevPath)
+	require.Equal(t, "a.txt", *evt.PrevPath)
+
+	// A plain delete is still reported as one
+	require.NoError(t, os.Remove(filepath.Join(wsDir, "b.txt")))
+	evt = collect(t, ch, 1)[0]
+	require.Equal(t, "file.deleted", evt.Type)
+	require.Equal(t, "b.txt", evt.Path)
+	require.Nil(t, evt.PrevPath)
+
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected event %s %s", e.Type, e.Path)
+	case <-time.After(600 * time.Millisecond):
+	}
+}
+
+func TestFSWatcher_WatchesExistingSubdirectories(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "ws", "src", "app")
+	require.NoError(t, os.MkdirAll(app, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(app, "main.go"), []byte("package main\n"), 0o644))
+
+	hub := NewHub(16)
+	defer hub.Close()
+	ch, unsub := hub.Subscribe("ws", 0, 16)
+	defer unsub()
+	// Deferred last so it runs first: the watcher stops publishing before
+	// the subscription is closed
+	stop, err := StartFSWatcher(root, hub, nil)
+	require.NoError(t, err)
+	defer stop()
+
+	// An edit in a directory that existed before the watcher started
+	f, err := os.OpenFile(filepath.Join(app, "main.go"), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("func main() {}\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	evt := collect(t, ch, 1)[0]
+	require.Equal(t, "file.updated", evt.Type)
+	require.Equal(t, filepath.Join("src", "app", "main.go"), evt.Path)
+
+	// A deleted directory is reported as such and watched again once recreated
+	require.NoError(t, os.RemoveAll(app))
+	got := map[string]string{}
+	for _, e := range collect(t, ch, 2) {
+		got[e.Path] = e.Type
+	}
+	require.Equal(t, map[string]string{
+		filepath.Join("src", "app", "main.go"): "file.deleted",
+		filepath.Join("src", "app"):            "dir.deleted",
+	}, got)
+
+	require.NoError(t, os.Mkdir(app, 0o755))
+	evt = collect(t, ch, 1)[0]
+	require.Equal(t, "dir.created", evt.Type)
+	require.NoError(t, os.WriteFile(filepath.Join(app, "new.go"), []byte("package app\n"), 0o644))
+	evt = collect(t, ch, 1)[0]
+	require.Equal(t, filepath.Join("src", "app", "new.go"), evt.Path)
+}
+
+func TestFSWatcher_StopEndsPublishing(t *testing.T) {
+	root := t.TempDir()
+	wsDir := filepath.Join(root, "ws")
+	require.NoError(t, os.MkdirAll(wsDir, 0o755))
+
+	hub := NewHub(16)
+	defer hub.Close()
+	stop, err := StartFSWatcher(root, hub, nil)
+	require.NoError(t, err)
+	ch, unsub := hub.Subscribe("ws", 0, 16)
+	defer unsub()
+
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "before.txt"), []byte("x"), 0o644))
+	evt := collect(t, ch, 1)[0]
+	require.Equal(t, "before.txt", evt.Path)
+	// Drain the rest of the write's burst (create and write are separate events)
+	for drained := false; !drained; {
+		select {
+		case <-ch:
+		case <-time.After(400 * time.Millisecond):
+			drained = true
+		}
+	}
+
+	// A change still being debounced is dropped along with later ones
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "pending.txt"), []byte("x"), 0o644))
+	stop()
+	stop() // stopping twice is harmless
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "after.txt"), []byte("x"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(wsDir, "before.txt")))
+
+	select {
+	case e := <-ch:
+		t.Fatalf("event after stop: %s %s", e.Type, e.Path)
+	case <-time.After(600 * time.Millisecond):
+	}
+}
+
+func TestFSWatcher_DirectoryRenameMovesWatches(t *testing.T) {
+	root := t.TempDir()
+	wsDir := filepath.Join(root, "ws")
+	require.NoError(t, os.MkdirAll(filepath.Join(wsDir, "src", "old", "deep"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "src", "old", "deep", "main.go"), []byte("package deep\n"), 0o644))
+
+	hub := NewHub(16)
+	defer hub.Close()
+	ch, unsub := hub.Subscribe("ws", 0, 16)
+	defer unsub()
+	// Deferred last so it runs first: the watcher stops publishing before
+	// the subscription is closed
+	stop, err := StartFSWatcher(root, hub, nil)
+	require.NoError(t, err)
+	defer stop()
+
+	require.NoError(t, os.Rename(filepath.Join(wsDir, "src", "old"), filepath.Join(wsDir, "src", "new")))
+	evt := collect(t, ch, 1)[0]
+	require.Equal(t, "dir.moved", evt.Type)
+	require.Equal(t, filepath.Join("src", "new"), evt.Path)
+	require.True(t, evt.IsDir)
+	require.NotNil(t, evt.PrevPath)
+	require.Equal(t, filepath.Join("src", "old"), *evt.PrevPath)
+
+	// Edits below the new location are still observed
+	f, err := os.OpenFile(filepath.Join(wsDir, "src", "new", "deep", "main.go"), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("func main() {}\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	evt = collect(t, ch, 1)[0]
+	require.Equal(t, "file.updated", evt.Type)
+	require.Equal(t, filepath.Join("src", "new", "deep", "main.go"), evt.Path)
+
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected event %s %s", e.Type, e.Path)
+	case <-time.After(600 * time.Millisecond):
+	}
+}
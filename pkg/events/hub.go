@@ -1,6 +1,12 @@
 package events
 
 import (
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -14,10 +20,10 @@ type Actor struct {
 
 // WorkspaceEvent is the normalized change notification delivered to frontends.
 type WorkspaceEvent struct {
-	ID            int64   `json:"id"`                      // monotonically increasing per workspace
+	ID            int64   `json:"id"`                      // monotonically increasing per workspace; 0 for presence events (see Join)
 	TS            string  `json:"ts"`                      // RFC3339 timestamp
 	WorkspaceID   string  `json:"workspaceId"`             // workspace scope
-	Type          string  `json:"type"`                    // "file.created" | "file.updated" | "file.deleted" | "file.moved" | "dir.created" | "dir.deleted" | "presence.join" | "presence.leave" | "lock.acquired" | "lock.released"
+	Type          string  `json:"type"`                    // "file.created" | "file.updated" | "file.deleted" | "file.moved" | "dir.created" | "dir.deleted" | "dir.moved" | "presence.join" | "presence.leave" | "lock.acquired" | "lock.released" | "workspace.created" | "workspace.renamed"
 	Path          string  `json:"path"`                    // canonical path (workspace-relative)
 	PrevPath      *string `json:"prevPath,omitempty"`      // for moves/renames
 	IsDir         bool    `json:"isDir"`                   // whether Path is a directory
@@ -26,11 +32,45 @@ type WorkspaceEvent struct {
 	Actor         *Actor  `json:"actor,omitempty"`         // event initiator
 	Commit        *string `json:"commit,omitempty"`        // workspace HEAD after mutation
 	CorrelationID *string `json:"correlationId,omitempty"` // request correlation ID if provided
+	Viewers       *int    `json:"viewers,omitempty"`       // presence events: active streams on the workspace after the change
+	RenamedTo     *string `json:"renamedTo,omitempty"`     // workspace.renamed: the workspace's new id
+	GlobalID      int64   `json:"globalId,omitempty"`      // monotonically increasing across workspaces (see SubscribeAll)
+}
+
+// Filter reports whether an event should be delivered to a subscriber.
+type Filter func(WorkspaceEvent) bool
+
+// PathFilter matches events whose path (or, for moves, previous path) equals p
+// or is nested under it. An empty or root path matches every event.
+func PathFilter(p string) Filter {
+	p = normalizeEventPath(p)
+	if p == "" {
+		return nil
+	}
+	matches := func(candidate string) bool {
+		c := normalizeEventPath(candidate)
+		return c == p || strings.HasPrefix(c, p+"/")
+	}
+	return func(e WorkspaceEvent) bool {
+		if matches(e.Path) {
+			return true
+		}
+		return e.PrevPath != nil && matches(*e.PrevPath)
+	}
+}
+
+func normalizeEventPath(p string) string {
+	p = strings.TrimSpace(strings.ReplaceAll(p, "\\", "/"))
+	if p == "" {
+		return ""
+	}
+	return strings.Trim(path.Clean("/"+p), "/")
 }
 
 type subscriber struct {
-	id int
-	ch chan WorkspaceEvent
+	id     int
+	ch     chan WorkspaceEvent
+	filter Filter
 }
 
 type workspaceState struct {
@@ -40,6 +80,15 @@ type workspaceState struct {
 	ringStart int // index of oldest
 	subs      map[int]subscriber
 	nextSubID int
+
+	// Persistent event log (see WithLogDir); logPath is "" when disabled.
+	logPath   string
+	queueMu   sync.Mutex       // guards logQueue; taken under h.mu by Publish
+	logQueue  []WorkspaceEvent // published events not yet written
+	logMu     sync.Mutex       // serializes flushLog; guards the fields below
+	log       *os.File         // nil until the first write after opening or rotation
+	logLines  int              // events in the current log file
+	logClosed bool
 }
 
 type Hub struct {
@@ -47,44 +96,96 @@ type Hub struct {
 	ws     map[string]*workspaceState
 	cap    int
 	closed bool
+	logDir string // see WithLogDir
+
+	// all holds subscribers to every workspace's events (see SubscribeAll).
+	all       map[int]subscriber
+	nextAllID int
+	allSeq    int64 // last GlobalID
+
+	// viewers counts active event streams per workspace (see Join).
+	viewers map[string]int
+
+	// published counts events published since start, by type (see Stats).
+	published map[string]int64
 
 	// recent holds timestamps of recently published events keyed by workspace|type|path.
 	recent map[string]time.Time
 	// recentPath holds timestamps keyed by workspace|path regardless of type (to suppress fs echoes).
 	recentPath map[string]time.Time
+	// expected holds API changes registered by Expect, keyed like recent, with
+	// the time of registration.
+	expected map[string]time.Time
 }
 
+// DefaultRingCapacity is the per-workspace replay buffer used when NewHub is
+// given a non-positive capacity.
+const DefaultRingCapacity = 200
+
 // NewHub creates an in-memory event hub with a per-workspace ring buffer capacity.
-func NewHub(ringCapacity int) *Hub {
+// Once a workspace has more than ringCapacity events the oldest are dropped from
+// memory (and, without WithLogDir, can no longer be replayed).
+func NewHub(ringCapacity int, opts ...HubOption) *Hub {
 	if ringCapacity <= 0 {
-		ringCapacity = 200
+		ringCapacity = DefaultRingCapacity
 	}
-	return &Hub{
+	h := &Hub{
 		ws:         make(map[string]*workspaceState),
 		cap:        ringCapacity,
+		all:        make(map[int]subscriber),
+		nextAllID:  1,
+		viewers:    make(map[string]int),
+		published:  make(map[string]int64),
 		recent:     make(map[string]time.Time),
 		recentPath: make(map[string]time.Time),
+		expected:   make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 func (h *Hub) getOrCreateWS(id string) *workspaceState {
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	if h.closed {
+		h.mu.Unlock()
 		return nil
 	}
-	st, ok := h.ws[id]
-	if !ok {
-		st = &workspaceState{
-			seq:       0,
-			ring:      make([]WorkspaceEvent, 0, h.cap),
-			ringCap:   h.cap,
-			ringStart: 0,
-			subs:      make(map[int]subscriber),
-			nextSubID: 1,
+	if st, ok := h.ws[id]; ok {
+		h.mu.Unlock()
+		return st
+	}
+	h.mu.Unlock()
+
+	st := &workspaceState{
+		seq:       0,
+		ring:      make([]WorkspaceEvent, 0, h.cap),
+		ringCap:   h.cap,
+		ringStart: 0,
+		subs:      make(map[int]subscriber),
+		nextSubID: 1,
+	}
+	// Read any existing log without holding the hub lock; the file itself is
+	// only created once an event is published
+	if p := h.logPath(id); p != "" {
+		last, lines, err := loadLog(p)
+		if err != nil {
+			slog.Warn("events: failed to read event log; continuing in memory only", "workspaceId", id, "error", err)
+		} else {
+			st.logPath, st.seq, st.logLines = p, last, lines
 		}
-		h.ws[id] = st
 	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	if existing, ok := h.ws[id]; ok {
+		return existing
+	}
+	h.ws[id] = st
 	return st
 }
 
@@ -98,14 +199,22 @@ func (h *Hub) Publish(workspaceID string, evt WorkspaceEvent) {
 
 	// Fill defaults
 	if evt.TS == "" {
-		evt.TS = time.Now().UTC().Format(time.RFC3339)
+		// Sub-second precision lets clients resume by timestamp (see SinceIDForTime).
+		evt.TS = time.Now().UTC().Format(time.RFC3339Nano)
 	}
 	evt.WorkspaceID = workspaceID
 
-	// Mutate state under lock, then fanout
 	h.mu.Lock()
 	ws.seq++
 	evt.ID = ws.seq
+	h.allSeq++
+	evt.GlobalID = h.allSeq
+	if ws.logPath != "" {
+		ws.queueMu.Lock()
+		ws.logQueue = append(ws.logQueue, evt)
+		ws.queueMu.Unlock()
+	}
+	h.published[evt.Type]++
 
 	// Append to ring buffer (circular)
 	if len(ws.ring) < ws.ringCap {
@@ -116,41 +225,85 @@ func (h *Hub) Publish(workspaceID string, evt WorkspaceEvent) {
 		ws.ringStart = (ws.ringStart + 1) % ws.ringCap
 	}
 
-	// Mark as recently published to suppress duplicate fswatch echoes
-	now := time.Now()
-	h.recent[makeRecentKey(workspaceID, evt.Type, evt.Path)] = now
-	h.recentPath[makeRecentPathKey(workspaceID, evt.Path)] = now
+	// Mark as recently published to suppress duplicate fswatch echoes (the
+	// watcher's own events must not suppress later ones)
+	if evt.Actor == nil || evt.Actor.Kind != "fswatch" {
+		now := time.Now()
+		h.recent[makeRecentKey(workspaceID, evt.Type, evt.Path)] = now
+		h.recentPath[makeRecentPathKey(workspaceID, evt.Path)] = now
+	}
 
-	// Snapshot subscribers to avoid holding lock during sends
-	subs := make([]subscriber, 0, len(ws.subs))
+	// Fan out while still holding the lock, so unsubscribe and Close cannot
+	// close a channel mid-send; sends never block, so the lock is held briefly.
 	for _, s := range ws.subs {
-		subs = append(subs, s)
+		deliver(s, evt)
+	}
+	for _, s := range h.all {
+		deliver(s, evt)
 	}
 	h.mu.Unlock()
 
-	// Non-blocking fanout; drop if buffer is full to avoid backpressure issues
-	for _, s := range subs {
+	if ws.logPath != "" {
+		ws.flushLog()
+	}
+}
+
+// broadcast delivers an ephemeral event to the current subscribers only: unlike
+// Publish it takes no id, is not buffered for replay or logged, and does not
+// suppress fswatch echoes.
+func (h *Hub) broadcast(workspaceID string, evt WorkspaceEvent) {
+	evt.TS = time.Now().UTC().Format(time.RFC3339Nano)
+	evt.WorkspaceID = workspaceID
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.published[evt.Type]++
+	if ws, ok := h.ws[workspaceID]; ok {
+		for _, s := range ws.subs {
+			deliver(s, evt)
+		}
+	}
+	for _, s := range h.all {
+		deliver(s, evt)
+	}
+}
+
+// deliver sends evt to s if its filter accepts it, without blocking: when the
+// buffer is full the oldest event is dropped to make room, and if that still
+// fails evt is skipped. The caller holds h.mu, which keeps s.ch open.
+func deliver(s subscriber, evt WorkspaceEvent) {
+	if s.filter != nil && !s.filter(evt) {
+		return
+	}
+	select {
+	case s.ch <- evt:
+	default:
+		select {
+		case <-s.ch:
+		default:
+		}
 		select {
 		case s.ch <- evt:
 		default:
-			// Drop oldest by draining one, then try again once
-			select {
-			case <-s.ch:
-			default:
-			}
-			select {
-			case s.ch <- evt:
-			default:
-				// Still blocked; skip
-			}
+			// Still blocked; skip
 		}
 	}
 }
 
 // Subscribe registers a new subscriber for a workspace. If sinceID > 0,
-// the hub will replay buffered events with ID > sinceID before delivering live events.
+// the hub will replay buffered events with ID > sinceID before delivering live events;
+// with an event log (WithLogDir), ids older than the ring buffer are replayed from disk.
 // Returns a receive-only channel and an unsubscribe function.
 func (h *Hub) Subscribe(workspaceID string, sinceID int64, buffer int) (<-chan WorkspaceEvent, func()) {
+	return h.SubscribeFiltered(workspaceID, sinceID, buffer, nil)
+}
+
+// SubscribeFiltered is like Subscribe but only delivers events (live and replayed)
+// for which filter returns true. A nil filter delivers everything.
+func (h *Hub) SubscribeFiltered(workspaceID string, sinceID int64, buffer int, filter Filter) (<-chan WorkspaceEvent, func()) {
 	if buffer <= 0 {
 		buffer = 64
 	}
@@ -165,20 +318,36 @@ func (h *Hub) Subscribe(workspaceID string, sinceID int64, buffer int) (<-chan W
 	}
 	id := ws.nextSubID
 	ws.nextSubID++
-	ws.subs[id] = subscriber{id: id, ch: ch}
+	ws.subs[id] = subscriber{id: id, ch: ch, filter: filter}
 
 	// Collect replay slice
-	replay := h.collectSinceLocked(ws, sinceID)
+	replay := h.collectSinceLocked(ws, sinceID, filter)
+	oldest := ws.seq + 1
+	if len(ws.ring) > 0 {
+		oldest = ws.ring[ws.ringStart].ID
+	}
+	logPath := ws.logPath
 	h.mu.Unlock()
 
 	// Deliver replay asynchronously
 	go func() {
+		if logPath != "" && sinceID > 0 && sinceID+1 < oldest {
+			// Events between sinceID and the ring are only on disk
+			replay = append(readLog(logPath, sinceID, oldest, filter), replay...)
+		}
 		for _, e := range replay {
+			// Hold the read lock so unsubscribe/Close cannot close ch mid-send
+			h.mu.RLock()
+			if _, live := ws.subs[id]; !live {
+				h.mu.RUnlock()
+				return
+			}
 			select {
 			case ch <- e:
 			default:
 				// Drop if subscriber is too slow during replay
 			}
+			h.mu.RUnlock()
 		}
 	}()
 
@@ -195,7 +364,78 @@ func (h *Hub) Subscribe(workspaceID string, sinceID int64, buffer int) (<-chan W
 	return ch, unsub
 }
 
-func (h *Hub) collectSinceLocked(ws *workspaceState, sinceID int64) []WorkspaceEvent {
+// SubscribeAll registers a subscriber for the events of every workspace, including
+// workspaces that first publish after the subscription. Event ids are per workspace,
+// so sinceID is a GlobalID instead: the buffered events of every workspace with a
+// larger GlobalID are replayed in GlobalID order. GlobalIDs restart with the hub,
+// so a sinceID beyond the newest one replays every buffer; the persistent event
+// log is not consulted.
+func (h *Hub) SubscribeAll(sinceID int64, buffer int) (<-chan WorkspaceEvent, func()) {
+	return h.SubscribeAllFiltered(sinceID, buffer, nil)
+}
+
+// SubscribeAllFiltered is like SubscribeAll but only delivers events for which
+// filter returns true. A nil filter delivers everything.
+func (h *Hub) SubscribeAllFiltered(sinceID int64, buffer int, filter Filter) (<-chan WorkspaceEvent, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan WorkspaceEvent, buffer)
+
+	h.mu.Lock()
+	if h.closed {
+		close(ch)
+		h.mu.Unlock()
+		return ch, func() {}
+	}
+	id := h.nextAllID
+	h.nextAllID++
+	h.all[id] = subscriber{id: id, ch: ch, filter: filter}
+
+	var replay []WorkspaceEvent
+	if sinceID > 0 {
+		if sinceID > h.allSeq {
+			// A cursor from before a restart
+			sinceID = 0
+		}
+		for _, ws := range h.ws {
+			for _, e := range h.collectSinceLocked(ws, 0, filter) {
+				if e.GlobalID > sinceID {
+					replay = append(replay, e)
+				}
+			}
+		}
+		sort.Slice(replay, func(i, j int) bool { return replay[i].GlobalID < replay[j].GlobalID })
+	}
+	h.mu.Unlock()
+
+	go func() {
+		for _, e := range replay {
+			h.mu.RLock()
+			if _, live := h.all[id]; !live {
+				h.mu.RUnlock()
+				return
+			}
+			select {
+			case ch <- e:
+			default:
+			}
+			h.mu.RUnlock()
+		}
+	}()
+
+	unsub := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if s, exists := h.all[id]; exists {
+			delete(h.all, id)
+			close(s.ch)
+		}
+	}
+	return ch, unsub
+}
+
+func (h *Hub) collectSinceLocked(ws *workspaceState, sinceID int64, filter Filter) []WorkspaceEvent {
 	if len(ws.ring) == 0 {
 		return nil
 	}
@@ -208,13 +448,107 @@ func (h *Hub) collectSinceLocked(ws *workspaceState, sinceID int64) []WorkspaceE
 		if e.WorkspaceID == "" && e.TS == "" && e.Type == "" {
 			continue
 		}
-		if e.ID > sinceID {
+		if e.ID > sinceID && (filter == nil || filter(e)) {
 			out = append(out, e)
 		}
 	}
 	return out
 }
 
+// LastID returns the id of the newest event published for a workspace (0 if
+// none), for subscribing to live events only.
+func (h *Hub) LastID(workspaceID string) int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if ws, ok := h.ws[workspaceID]; ok {
+		return ws.seq
+	}
+	return 0
+}
+
+// SinceIDForTime translates a wall-clock resume position into an event id usable
+// as sinceID: the id of the newest buffered event with TS at or before ts.
+// If ts predates the oldest buffered event, 0 is returned so that the whole
+// buffer is replayed (a full resync from the client's point of view).
+func (h *Hub) SinceIDForTime(workspaceID string, ts time.Time) int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ws, ok := h.ws[workspaceID]
+	if !ok {
+		return 0
+	}
+	var since int64
+	for _, e := range h.collectSinceLocked(ws, 0, nil) {
+		et, err := time.Parse(time.RFC3339Nano, e.TS)
+		if err != nil || et.After(ts) {
+			break
+		}
+		since = e.ID
+	}
+	return since
+}
+
+// Join records a client viewing a workspace and announces presence.join with the
+// new viewer count to the workspace's live subscribers. The returned function
+// announces presence.leave; it is safe to call more than once. Presence events
+// have no id and are never replayed, so they do not use up the replay buffer.
+func (h *Hub) Join(workspaceID string, actor Actor) (leave func()) {
+	h.mu.Lock()
+	h.viewers[workspaceID]++
+	n := h.viewers[workspaceID]
+	h.mu.Unlock()
+	h.broadcast(workspaceID, WorkspaceEvent{Type: "presence.join", Actor: &actor, Viewers: &n})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.mu.Lock()
+			h.viewers[workspaceID]--
+			n := h.viewers[workspaceID]
+			if n <= 0 {
+				delete(h.viewers, workspaceID)
+				n = 0
+			}
+			h.mu.Unlock()
+			h.broadcast(workspaceID, WorkspaceEvent{Type: "presence.leave", Actor: &actor, Viewers: &n})
+		})
+	}
+}
+
+// Viewers returns the number of active event streams on a workspace.
+func (h *Hub) Viewers(workspaceID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.viewers[workspaceID]
+}
+
+// Stats is a snapshot of hub activity for metrics.
+type Stats struct {
+	Published      map[string]int64 // events published since start, by type
+	Subscribers    map[string]int   // active subscribers per workspace (workspaces with none are omitted)
+	AllSubscribers int              // active SubscribeAll subscribers
+}
+
+// Stats returns a snapshot of the hub's counters.
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	st := Stats{
+		Published:      make(map[string]int64, len(h.published)),
+		Subscribers:    make(map[string]int),
+		AllSubscribers: len(h.all),
+	}
+	for t, n := range h.published {
+		st.Published[t] = n
+	}
+	for id, ws := range h.ws {
+		if len(ws.subs) > 0 {
+			st.Subscribers[id] = len(ws.subs)
+		}
+	}
+	return st
+}
+
 func makeRecentKey(workspaceID, evtType, path string) string {
 	return workspaceID + "|" + evtType + "|" + path
 }
@@ -257,18 +591,71 @@ func (h *Hub) RecentlyPublishedForPath(workspaceID, path string, within time.Dur
 	return false
 }
 
-// Close shuts down the hub and all subscriptions.
-func (h *Hub) Close() {
+// ExpectWindow is how long after Expect the fswatcher ignores matching disk events.
+const ExpectWindow = 500 * time.Millisecond
+
+// Expect registers a change the API is about to make to path, before touching
+// disk, so that the fswatcher does not report it a second time: disk events for
+// path of any of evtTypes observed within ExpectWindow are skipped (see
+// Expected). Several types can be given because the watcher cannot always tell
+// them apart (e.g. an atomic overwrite is seen as a create).
+func (h *Hub) Expect(workspaceID, path string, evtTypes ...string) {
+	path = filepath.Clean(filepath.FromSlash(path))
+	now := time.Now()
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	for k, t := range h.expected {
+		if now.Sub(t) > ExpectWindow {
+			delete(h.expected, k)
+		}
+	}
+	for _, typ := range evtTypes {
+		h.expected[makeRecentKey(workspaceID, typ, path)] = now
+	}
+}
+
+// Expected reports whether a disk event of evtType for path, observed at the
+// given time, was registered by Expect within ExpectWindow before.
+func (h *Hub) Expected(workspaceID, path, evtType string, observed time.Time) bool {
+	path = filepath.Clean(filepath.FromSlash(path))
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	t, ok := h.expected[makeRecentKey(workspaceID, evtType, path)]
+	return ok && !observed.Before(t) && observed.Sub(t) <= ExpectWindow
+}
+
+// Closed reports whether Close has been called.
+func (h *Hub) Closed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closed
+}
+
+// Close shuts down the hub and all subscriptions, writing out pending log entries.
+func (h *Hub) Close() {
+	h.mu.Lock()
 	if h.closed {
+		h.mu.Unlock()
 		return
 	}
 	h.closed = true
+	var logged []*workspaceState
 	for _, ws := range h.ws {
 		for _, s := range ws.subs {
 			close(s.ch)
 		}
 		ws.subs = map[int]subscriber{}
+		if ws.logPath != "" {
+			logged = append(logged, ws)
+		}
+	}
+	for _, s := range h.all {
+		close(s.ch)
+	}
+	h.all = map[int]subscriber{}
+	h.mu.Unlock()
+
+	for _, ws := range logged {
+		ws.closeLog()
 	}
 }
@@ -0,0 +1,261 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func collect(t *testing.T, ch <-chan WorkspaceEvent, n int) []WorkspaceEvent {
+	t.Helper()
+	var out []WorkspaceEvent
+	timeout := time.After(2 * time.Second)
+	for len(out) < n {
+		select {
+		case e := <-ch:
+			out = append(out, e)
+		case <-timeout:
+			t.Fatalf("timed out after %d of %d events", len(out), n)
+		}
+	}
+	return out
+}
+
+func ids(evts []WorkspaceEvent) []int64 {
+	var out []int64
+	for _, e := range evts {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func TestHub_EventLog_ReplayAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	h1 := NewHub(4, WithLogDir(dir))
+	for _, p := range []string{"a", "b", "c", "d", "e"} {
+		h1.Publish("ws", WorkspaceEvent{Type: "file.created", Path: p})
+	}
+	h1.Close()
+
+	// After a restart ids continue from the log instead of resetting
+	h2 := NewHub(4, WithLogDir(dir))
+	defer h2.Close()
+	h2.Publish("ws", WorkspaceEvent{Type: "file.updated", Path: "f"})
+
+	ch, unsub := h2.Subscribe("ws", 1, 64)
+	defer unsub()
+	replayed := collect(t, ch, 5)
+	require.Equal(t, []int64{2, 3, 4, 5, 6}, ids(replayed))
+	require.Equal(t, "b", replayed[0].Path)
+	require.Equal(t, "f", replayed[4].Path)
+
+	// Live events follow the replay
+	h2.Publish("ws", WorkspaceEvent{Type: "file.deleted", Path: "a"})
+	require.Equal(t, []int64{7}, ids(collect(t, ch, 1)))
+}
+
+func TestHub_EventLog_ReplayOlderThanRing(t *testing.T) {
+	h := NewHub(2, WithLogDir(t.TempDir()))
+	defer h.Close()
+	for _, p := range []string{"docs/a", "src/b", "docs/c", "docs/d", "src/e"} {
+		h.Publish("ws", WorkspaceEvent{Type: "file.created", Path: p})
+	}
+
+	// The ring only holds 4 and 5; 2 and 3 come from disk, filtered like live events
+	ch, unsub := h.SubscribeFiltered("ws", 1, 64, PathFilter("docs"))
+	defer unsub()
+	require.Equal(t, []int64{3, 4}, ids(collect(t, ch, 2)))
+}
+
+func TestHub_EventLog_RotatesAtRingSize(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHub(2, WithLogDir(dir))
+
+	// Subscribers never create logs, e.g. for ids that are not workspaces
+	_, unsub := h.Subscribe("nope", 0, 8)
+	unsub()
+	_, err := os.Stat(filepath.Join(dir, "nope.jsonl"))
+	require.True(t, os.IsNotExist(err))
+
+	for _, p := range []string{"a", "b", "c", "d", "e"} {
+		h.Publish("ws", WorkspaceEvent{Type: "file.created", Path: p})
+	}
+	h.Close()
+
+	// Only the last full log and the current one are kept
+	lines := func(name string) []int64 {
+		var out []int64
+		require.NoError(t, scanLog(filepath.Join(dir, name), func(e WorkspaceEvent) bool {
+			out = append(out, e.ID)
+			return true
+		}))
+		return out
+	}
+	require.Equal(t, []int64{3, 4}, lines("ws.jsonl.1"))
+	require.Equal(t, []int64{5}, lines("ws.jsonl"))
+
+	// Ids still continue after a restart
+	h2 := NewHub(2, WithLogDir(dir))
+	defer h2.Close()
+	h2.Publish("ws", WorkspaceEvent{Type: "file.updated", Path: "e"})
+	require.Equal(t, int64(6), h2.LastID("ws"))
+}
+
+func TestHub_EventLog_RejectsUnsafeWorkspaceIDs(t *testing.T) {
+	h := NewHub(2, WithLogDir(t.TempDir()))
+	defer h.Close()
+	require.Empty(t, h.logPath("../escape"))
+	require.Empty(t, h.logPath(".."))
+	require.NotEmpty(t, h.logPath("my-workspace"))
+}
+
+func TestHub_SubscribeAll(t *testing.T) {
+	h := NewHub(8)
+	defer h.Close()
+	h.Publish("ws1", WorkspaceEvent{Type: "file.created", Path: "old"})
+	h.Publish("ws1", WorkspaceEvent{Type: "file.created", Path: "a"})
+
+	ch, unsub := h.SubscribeAll(1, 64)
+	defer unsub()
+	require.Equal(t, "a", collect(t, ch, 1)[0].Path)
+
+	// Workspaces first seen after subscribing are included
+	h.Publish("ws2", WorkspaceEvent{Type: "file.created", Path: "b"})
+	evt := collect(t, ch, 1)[0]
+	require.Equal(t, "ws2", evt.WorkspaceID)
+	require.Equal(t, "b", evt.Path)
+}
+
+func TestHub_SubscribeAll_ResumesByGlobalID(t *testing.T) {
+	h := NewHub(8)
+	defer h.Close()
+	for _, p := range []string{"a", "b", "c"} {
+		h.Publish("ws1", WorkspaceEvent{Type: "file.created", Path: p})
+	}
+	h.Publish("ws2", WorkspaceEvent{Type: "file.created", Path: "x"})
+	h.Publish("ws1", WorkspaceEvent{Type: "file.created", Path: "d"})
+
+	// ws1's ids run ahead of ws2's; resuming after ws1's "c" (global id 3)
+	// still replays ws2's event with id 1
+	ch, unsub := h.SubscribeAll(3, 64)
+	defer unsub()
+	replayed := collect(t, ch, 2)
+	require.Equal(t, "x", replayed[0].Path)
+	require.Equal(t, []int64{1, 4}, ids(replayed))
+	require.Equal(t, []int64{4, 5}, []int64{replayed[0].GlobalID, replayed[1].GlobalID})
+
+	// A cursor from before a restart replays everything buffered
+	ch2, unsub2 := h.SubscribeAll(100, 64)
+	defer unsub2()
+	require.Len(t, collect(t, ch2, 5), 5)
+}
+
+func TestHub_PresenceIsLiveOnly(t *testing.T) {
+	h := NewHub(2, WithLogDir(t.TempDir()))
+	defer h.Close()
+	h.Publish("ws", WorkspaceEvent{Type: "file.created", Path: "a"})
+
+	live, unsub := h.Subscribe("ws", h.LastID("ws"), 8)
+	defer unsub()
+	leave := h.Join("ws", Actor{Kind: "user"})
+	leave()
+	got := collect(t, live, 2)
+	require.Equal(t, "presence.join", got[0].Type)
+	require.Equal(t, "presence.leave", got[1].Type)
+	require.Equal(t, []int64{0, 0}, ids(got))
+
+	// Neither took an id or a ring slot, and neither is replayed
+	require.Equal(t, int64(1), h.LastID("ws"))
+	replay, unsubReplay := h.Subscribe("ws", 0, 8)
+	defer unsubReplay()
+	require.Equal(t, "a", collect(t, replay, 1)[0].Path)
+	select {
+	case e := <-replay:
+		t.Fatalf("unexpected replayed %s", e.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHub_RingDropsOldest(t *testing.T) {
+	h := NewHub(3)
+	defer h.Close()
+	for _, p := range []string{"a", "b", "c", "d", "e"} {
+		h.Publish("ws", WorkspaceEvent{Type: "file.created", Path: p})
+	}
+
+	// Only the newest three events remain for replay, whatever the cursor
+	ch, unsub := h.Subscribe("ws", 0, 64)
+	defer unsub()
+	replayed := collect(t, ch, 3)
+	require.Equal(t, []int64{3, 4, 5}, ids(replayed))
+	require.Equal(t, "c", replayed[0].Path)
+
+	ch2, unsub2 := h.Subscribe("ws", 1, 64)
+	defer unsub2()
+	require.Equal(t, []int64{3, 4, 5}, ids(collect(t, ch2, 3)))
+	select {
+	case e := <-ch2:
+		t.Fatalf("unexpected event %d after replay", e.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHub_Stats(t *testing.T) {
+	h := NewHub(8)
+	defer h.Close()
+	h.Publish("ws1", WorkspaceEvent{Type: "file.created", Path: "a"})
+	h.Publish("ws1", WorkspaceEvent{Type: "file.updated", Path: "a"})
+	h.Publish("ws2", WorkspaceEvent{Type: "file.created", Path: "b"})
+
+	_, unsub1 := h.Subscribe("ws1", 0, 8)
+	_, unsub2 := h.Subscribe("ws1", 0, 8)
+	_, unsubAll := h.SubscribeAll(0, 8)
+	defer unsubAll()
+
+	st := h.Stats()
+	require.Equal(t, map[string]int64{"file.created": 2, "file.updated": 1}, st.Published)
+	require.Equal(t, map[string]int{"ws1": 2}, st.Subscribers)
+	require.Equal(t, 1, st.AllSubscribers)
+
+	unsub1()
+	unsub2()
+	require.Empty(t, h.Stats().Subscribers)
+}
+
+func TestHub_UnsubscribeWhilePublishing(t *testing.T) {
+	h := NewHub(16)
+	defer h.Close()
+	var publishers sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		publishers.Add(1)
+		go func() {
+			defer publishers.Done()
+			for i := 0; i < 5000; i++ {
+				h.Publish("ws", WorkspaceEvent{Type: "file.updated", Path: "a.txt"})
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		publishers.Wait()
+		close(done)
+	}()
+	// Subscriptions come and go while events are fanned out; closing a
+	// channel must never race a send to it
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		_, unsub := h.Subscribe("ws", 0, 1)
+		_, unsubAll := h.SubscribeAll(0, 1)
+		unsub()
+		unsubAll()
+	}
+}
@@ -10,52 +10,70 @@ import (
 	"time"
 )
 
+// AllWorkspaces is the workspaceId that selects the global stream of every workspace's events.
+const AllWorkspaces = "*"
+
+// DefaultHeartbeat is the keep-alive interval of event streams: SSE heartbeat
+// comments and WebSocket pings.
+const DefaultHeartbeat = 25 * time.Second
+
+// StreamOption configures SSEHandler and WSHandler.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	heartbeat time.Duration
+}
+
+// WithHeartbeat sets the keep-alive interval (DefaultHeartbeat when d <= 0).
+// Proxies that drop idle connections sooner need a shorter interval.
+func WithHeartbeat(d time.Duration) StreamOption {
+	return func(c *streamConfig) {
+		if d > 0 {
+			c.heartbeat = d
+		}
+	}
+}
+
+func newStreamConfig(opts []StreamOption) streamConfig {
+	c := streamConfig{heartbeat: DefaultHeartbeat}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
 // SSEHandler serves Server-Sent Events for a single workspace stream.
 // Auth: if tokens is non-empty, accepts either ?token=... (preferred for EventSource)
-// or Authorization: Bearer ... (fallback for non-browser clients).
+// or Authorization: Bearer ... (fallback for non-browser clients). A token that is
+// not in tokens is also accepted if allow (when non-nil) permits it for the
+// requested workspaceId, e.g. a share link scoped to that workspace.
 // Query:
 //
-//	workspaceId: required
-//	since: optional last seen event id (also respects Last-Event-ID header)
+//	workspaceId: required; "*" subscribes to every workspace (see SubscribeAll)
+//	since: optional last seen event id (also respects Last-Event-ID header); with
+//	       workspaceId=* it is the last seen globalId, which the stream sends as
+//	       the SSE id
+//	sinceTs: optional RFC3339 timestamp; replays buffered events newer than it
+//	         (not supported with workspaceId=*)
+//	path: optional workspace-relative path; only events for that path (or nested
+//	      under it, including moves from/to it) are delivered
+//	clientId: optional client identifier; when set the stream takes part in
+//	          presence, with clientId as the actor id of its presence events
 //
 // Behavior:
 //   - Replays buffered events with id > since (ring buffer) then streams live
-//   - Sends heartbeat comments every 25s
-func SSEHandler(hub *Hub, tokens []string) http.Handler {
+//   - With clientId, announces presence.join on connect and presence.leave on
+//     disconnect to live subscribers (not for workspaceId=*); these have no id
+//     and are not replayed
+//   - Sends heartbeat comments every 25s (see WithHeartbeat)
+func SSEHandler(hub *Hub, tokens []string, allow func(token, workspaceID string) bool, opts ...StreamOption) http.Handler {
+	cfg := newStreamConfig(opts)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if hub == nil {
-			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
-			return
-		}
-
-		// Auth (query token or Bearer header)
-		if len(tokens) > 0 {
-			if !isAuthorized(r, tokens) {
-				w.Header().Set("WWW-Authenticate", `Bearer realm="events", error="invalid_token"`)
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-				return
-			}
-		}
-
-		wsID := r.URL.Query().Get("workspaceId")
-		if strings.TrimSpace(wsID) == "" {
-			http.Error(w, "workspaceId is required", http.StatusBadRequest)
+		req, ok := parseStreamRequest(w, r, hub, tokens, allow)
+		if !ok {
 			return
 		}
 
-		// Determine since id from query or Last-Event-ID
-		var since int64
-		if s := r.URL.Query().Get("since"); s != "" {
-			if v, err := strconv.ParseInt(s, 10, 64); err == nil {
-				since = v
-			}
-		}
-		if s := r.Header.Get("Last-Event-ID"); s != "" {
-			if v, err := strconv.ParseInt(s, 10, 64); err == nil && v > since {
-				since = v
-			}
-		}
-
 		// Prepare streaming response
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
@@ -70,11 +88,12 @@ func SSEHandler(hub *Hub, tokens []string) http.Handler {
 		}
 
 		// Subscribe (includes replay)
-		eventsCh, unsubscribe := hub.Subscribe(wsID, since, 128)
+		eventsCh, unsubscribe := req.subscribe(hub)
 		defer unsubscribe()
+		defer req.join(hub)()
 
 		// Heartbeats
-		heartbeat := time.NewTicker(25 * time.Second)
+		heartbeat := time.NewTicker(cfg.heartbeat)
 		defer heartbeat.Stop()
 
 		notify := r.Context().Done()
@@ -95,9 +114,12 @@ func SSEHandler(hub *Hub, tokens []string) http.Handler {
 					slog.Warn("failed to marshal event", "error", err)
 					continue
 				}
-				// id + named event for filtering on client
-				if _, err = w.Write([]byte("id: " + strconv.FormatInt(evt.ID, 10) + "\n")); err != nil {
-					return
+				// id + named event for filtering on client; presence events have no
+				// id, and omitting it keeps the client's Last-Event-ID
+				if id := req.cursor(evt); id > 0 {
+					if _, err = w.Write([]byte("id: " + strconv.FormatInt(id, 10) + "\n")); err != nil {
+						return
+					}
 				}
 				if _, err = w.Write([]byte("event: workspace.event\n")); err != nil {
 					return
@@ -127,21 +149,123 @@ func SSEHandler(hub *Hub, tokens []string) http.Handler {
 	})
 }
 
-func isAuthorized(r *http.Request, tokens []string) bool {
+// streamRequest holds the parameters shared by the SSE and WebSocket event streams.
+type streamRequest struct {
+	workspaceID string // AllWorkspaces for the global stream
+	since       int64
+	filter      Filter
+	clientID    string
+}
+
+// parseStreamRequest authenticates an event stream request and parses its query
+// (workspaceId, since/Last-Event-ID, sinceTs, path). On failure it writes the
+// error response and returns false.
+func parseStreamRequest(w http.ResponseWriter, r *http.Request, hub *Hub, tokens []string, allow func(token, workspaceID string) bool) (streamRequest, bool) {
+	if hub == nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return streamRequest{}, false
+	}
+
+	// Auth (query token or Bearer header)
+	if len(tokens) > 0 {
+		if !isAuthorized(r, tokens, allow) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="events", error="invalid_token"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return streamRequest{}, false
+		}
+	}
+
+	wsID := r.URL.Query().Get("workspaceId")
+	if strings.TrimSpace(wsID) == "" {
+		http.Error(w, "workspaceId is required", http.StatusBadRequest)
+		return streamRequest{}, false
+	}
+
+	// Determine since id from query or Last-Event-ID
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			since = v
+		}
+	}
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil && v > since {
+			since = v
+		}
+	}
+	if s := r.URL.Query().Get("sinceTs"); s != "" {
+		if wsID == AllWorkspaces {
+			http.Error(w, "sinceTs is not supported with workspaceId=*", http.StatusBadRequest)
+			return streamRequest{}, false
+		}
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			http.Error(w, "sinceTs must be an RFC3339 timestamp", http.StatusBadRequest)
+			return streamRequest{}, false
+		}
+		if v := hub.SinceIDForTime(wsID, ts); v > since {
+			since = v
+		}
+	}
+	return streamRequest{
+		workspaceID: wsID,
+		since:       since,
+		filter:      PathFilter(r.URL.Query().Get("path")),
+		clientID:    strings.TrimSpace(r.URL.Query().Get("clientId")),
+	}, true
+}
+
+// subscribe registers the stream with hub (replay, then live events).
+func (s streamRequest) subscribe(hub *Hub) (<-chan WorkspaceEvent, func()) {
+	if s.workspaceID == AllWorkspaces {
+		return hub.SubscribeAllFiltered(s.since, 128, s.filter)
+	}
+	return hub.SubscribeFiltered(s.workspaceID, s.since, 128, s.filter)
+}
+
+// cursor returns the position of evt in the stream, which clients resume from
+// with since or Last-Event-ID: the global id on the global stream, whose events
+// come from workspaces with unrelated ids.
+func (s streamRequest) cursor(evt WorkspaceEvent) int64 {
+	if s.workspaceID == AllWorkspaces {
+		return evt.GlobalID
+	}
+	return evt.ID
+}
+
+// join announces the stream's presence on its workspace and returns the function
+// that announces its departure. Only streams that identify themselves with a
+// clientId take part; the global stream has no presence.
+func (s streamRequest) join(hub *Hub) func() {
+	if s.clientID == "" || s.workspaceID == AllWorkspaces {
+		return func() {}
+	}
+	id := s.clientID
+	return hub.Join(s.workspaceID, Actor{Kind: "user", ID: &id})
+}
+
+func isAuthorized(r *http.Request, tokens []string, allow func(token, workspaceID string) bool) bool {
+	wsID := r.URL.Query().Get("workspaceId")
+	permitted := func(tok string) bool {
+		if tokenAllowed(tok, tokens) {
+			return true
+		}
+		return allow != nil && tok != "" && allow(tok, wsID)
+	}
 	// Prefer query token for EventSource
 	q := strings.TrimSpace(r.URL.Query().Get("token"))
-	if q != "" && tokenAllowed(q, tokens) {
+	if q != "" && permitted(q) {
 		return true
 	}
 	// Fallback: Bearer header
 	authz := r.Header.Get("Authorization")
 	if strings.HasPrefix(strings.ToLower(authz), "bearer ") {
 		bearer := strings.TrimSpace(authz[len("Bearer "):])
-		return tokenAllowed(bearer, tokens)
+		return permitted(bearer)
 	}
 	parts := strings.SplitN(authz, " ", 2)
 	if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
-		return tokenAllowed(strings.TrimSpace(parts[1]), tokens)
+		return permitted(strings.TrimSpace(parts[1]))
 	}
 	return false
 }
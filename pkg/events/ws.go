@@ -0,0 +1,98 @@
+package events
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const wsWriteTimeout = 10 * time.Second
+
+// WSHandler serves the same event streams as SSEHandler over a WebSocket.
+// Auth and query parameters are identical (workspaceId, since, sinceTs, path;
+// ?token= or Authorization: Bearer), including since being a globalId with
+// workspaceId=*. Each event is sent as a text frame holding the JSON that SSE
+// sends as `data`. Ping frames replace SSE heartbeat comments
+// (same interval, see WithHeartbeat), and a client that does not answer them for
+// two intervals is disconnected; other messages from the client are ignored.
+//
+// Cross-origin upgrades are accepted only when the CORS middleware allowed the
+// request's Origin; otherwise the Origin must match the Host.
+func WSHandler(hub *Hub, tokens []string, allow func(token, workspaceID string) bool, opts ...StreamOption) http.Handler {
+	cfg := newStreamConfig(opts)
+	// pongWait is how long a client may go without answering pings
+	pongWait := 2 * cfg.heartbeat
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, ok := parseStreamRequest(w, r, hub, tokens, allow)
+		if !ok {
+			return
+		}
+
+		upgrader := websocket.Upgrader{}
+		if w.Header().Get("Access-Control-Allow-Origin") != "" {
+			upgrader.CheckOrigin = func(*http.Request) bool { return true }
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already written an error response
+			return
+		}
+		defer conn.Close()
+
+		// Subscribe (includes replay)
+		eventsCh, unsubscribe := req.subscribe(hub)
+		defer unsubscribe()
+		defer req.join(hub)()
+
+		// Read loop: answers pings/close frames and notices disconnects
+		done := make(chan struct{})
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ping := time.NewTicker(cfg.heartbeat)
+		defer ping.Stop()
+
+		for {
+			select {
+			case evt, ok := <-eventsCh:
+				if !ok {
+					// Hub closed (server shutting down)
+					_ = conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+						time.Now().Add(wsWriteTimeout))
+					return
+				}
+				data, err := json.Marshal(evt)
+				if err != nil {
+					slog.Warn("failed to marshal event", "error", err)
+					continue
+				}
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+					return
+				}
+
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+					return
+				}
+
+			case <-done:
+				return
+			}
+		}
+	})
+}
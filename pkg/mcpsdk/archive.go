@@ -0,0 +1,262 @@
+package mcpsdk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// Limits applied while extracting an imported archive, so a small upload cannot
+// expand into an unbounded amount of data (zip bombs) or entries.
+const (
+	maxImportEntries      = 100000
+	maxImportExtractBytes = 1 << 30 // 1 GiB
+)
+
+// Archive formats recognised by detectArchive.
+const (
+	archiveZip  = "zip"
+	archiveGzip = "gzip"
+)
+
+// detectArchive identifies an archive by its magic bytes, or returns "".
+func detectArchive(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return archiveZip
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return archiveGzip
+	}
+	return ""
+}
+
+// archiveEntry is one member of an archive, independent of its format.
+type archiveEntry struct {
+	name string      // as stored in the archive
+	mode fs.FileMode // type bits select directory/regular/other
+	open func() (io.ReadCloser, error)
+}
+
+// archiveReader iterates over archive members; Next returns io.EOF at the end.
+// Formats read sequentially (tar) and by index (zip) both fit this shape.
+type archiveReader interface {
+	Next() (*archiveEntry, error)
+}
+
+type zipArchive struct {
+	files []*zip.File
+	i     int
+}
+
+func newZipArchive(r io.ReaderAt, size int64) (*zipArchive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("INVALID_INPUT: invalid zip archive: %v", err)
+	}
+	return &zipArchive{files: zr.File}, nil
+}
+
+func (z *zipArchive) Next() (*archiveEntry, error) {
+	if z.i >= len(z.files) {
+		return nil, io.EOF
+	}
+	f := z.files[z.i]
+	z.i++
+	return &archiveEntry{name: f.Name, mode: f.Mode(), open: f.Open}, nil
+}
+
+type tarArchive struct {
+	tr *tar.Reader
+}
+
+func newTarGzArchive(r io.Reader) (*tarArchive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("INVALID_INPUT: invalid gzip archive: %v", err)
+	}
+	return &tarArchive{tr: tar.NewReader(gz)}, nil
+}
+
+func (t *tarArchive) Next() (*archiveEntry, error) {
+	for {
+		hdr, err := t.tr.Next()
+		if err != nil {
+			return nil, err
+		}
+		// Map the type flag rather than trusting hdr.FileInfo: hard links would
+		// otherwise look like empty regular files
+		var mode fs.FileMode
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			mode = fs.FileMode(hdr.Mode).Perm()
+		case tar.TypeDir:
+			mode = fs.ModeDir | fs.FileMode(hdr.Mode).Perm()
+		case tar.TypeXGlobalHeader:
+			continue // pax metadata, not a member
+		case tar.TypeSymlink:
+			mode = fs.ModeSymlink
+		case tar.TypeChar:
+			mode = fs.ModeDevice | fs.ModeCharDevice
+		case tar.TypeBlock:
+			mode = fs.ModeDevice
+		case tar.TypeFifo:
+			mode = fs.ModeNamedPipe
+		default:
+			mode = fs.ModeIrregular
+		}
+		return &archiveEntry{name: hdr.Name, mode: mode, open: func() (io.ReadCloser, error) {
+			return io.NopCloser(t.tr), nil
+		}}, nil
+	}
+}
+
+// archiveEntryPath validates an archive member name and returns it as a clean,
+// slash-separated path relative to the workspace root. Names that are absolute or
+// climb out of the root (`../`) are rejected. skip is set for the root itself
+// (rel is "") and for protected paths (.git, .gitkeep, see ProtectedPaths),
+// which are left out.
+func archiveEntryPath(name string, protected *workspace.ProtectedPaths) (rel string, skip bool, err error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", false, fmt.Errorf("INVALID_INPUT: archive entry %q has an absolute path", name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", false, fmt.Errorf("INVALID_INPUT: archive entry %q escapes the workspace", name)
+		}
+	}
+	rel = path.Clean(name)
+	if rel == "." {
+		return "", true, nil
+	}
+	if protected.IsProtectedPath(rel) {
+		return rel, true, nil
+	}
+	return rel, false, nil
+}
+
+// importStats summarises an extraction.
+type importStats struct {
+	files       int
+	directories int
+	skipped     int // protected entries left out
+}
+
+// errExtractBudget is returned by extractFile when a member would take the
+// extracted size over its budget.
+var errExtractBudget = errors.New("extract budget exceeded")
+
+// extractArchive writes every member of ar below root. Symlinks, devices and
+// other special files are rejected, as are paths escaping root; empty directories
+// get a .gitkeep so they are tracked like those made by fs_create_directory.
+// Archives expanding to more than maxImportExtractBytes are INVALID_INPUT, or
+// QUOTA_EXCEEDED when quota, if positive, is the lower limit.
+func extractArchive(root string, ar archiveReader, protected *workspace.ProtectedPaths, quota int64) (importStats, error) {
+	var st importStats
+	var total int64
+	budget := int64(maxImportExtractBytes)
+	if quota > 0 && quota < budget {
+		budget = quota
+	}
+	dirs := map[string]bool{}
+	for n := 0; ; n++ {
+		e, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return st, fmt.Errorf("INVALID_INPUT: failed to read archive: %v", err)
+		}
+		if n >= maxImportEntries {
+			return st, fmt.Errorf("INVALID_INPUT: archive has more than %d entries", maxImportEntries)
+		}
+		rel, skip, err := archiveEntryPath(e.name, protected)
+		if err != nil {
+			return st, err
+		}
+		if skip {
+			if rel != "" {
+				st.skipped++
+			}
+			continue
+		}
+		dst := filepath.Join(root, filepath.FromSlash(rel))
+
+		switch {
+		case e.mode.IsDir():
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return st, fmt.Errorf("INTERNAL: failed to create directory %s: %v", rel, err)
+			}
+			if !dirs[rel] {
+				dirs[rel] = true
+				st.directories++
+			}
+		case e.mode.IsRegular():
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return st, fmt.Errorf("INTERNAL: failed to create directory for %s: %v", rel, err)
+			}
+			written, err := extractFile(e, dst, budget-total)
+			total += written
+			switch {
+			case errors.Is(err, errExtractBudget) && budget == quota:
+				return st, fmt.Errorf("QUOTA_EXCEEDED: archive expands to more than the %d-byte workspace quota", quota)
+			case errors.Is(err, errExtractBudget):
+				return st, fmt.Errorf("INVALID_INPUT: archive expands to more than %d bytes", int64(maxImportExtractBytes))
+			case err != nil:
+				return st, err
+			}
+			st.files++
+		default:
+			return st, fmt.Errorf("INVALID_INPUT: archive entry %q is not a regular file or directory (%s)", e.name, e.mode.Type())
+		}
+	}
+
+	// Track directories that ended up empty
+	for rel := range dirs {
+		dir := filepath.Join(root, filepath.FromSlash(rel))
+		entries, err := os.ReadDir(dir)
+		if err == nil && len(entries) == 0 {
+			if err := os.WriteFile(filepath.Join(dir, ".gitkeep"), nil, 0644); err != nil {
+				return st, fmt.Errorf("INTERNAL: failed to track empty directory %s: %v", rel, err)
+			}
+		}
+	}
+	return st, nil
+}
+
+// extractFile copies one member to dst, failing with errExtractBudget once
+// more than budget bytes would be written.
+func extractFile(e *archiveEntry, dst string, budget int64) (int64, error) {
+	src, err := e.open()
+	if err != nil {
+		return 0, fmt.Errorf("INVALID_INPUT: failed to read archive entry %q: %v", e.name, err)
+	}
+	defer src.Close()
+	perm := e.mode.Perm() | 0600 // keep the owner able to read and write
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return 0, fmt.Errorf("INTERNAL: failed to create %s: %v", e.name, err)
+	}
+	n, err := io.Copy(out, io.LimitReader(src, budget+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("INVALID_INPUT: failed to extract %q: %v", e.name, err)
+	}
+	if n > budget {
+		return n, errExtractBudget
+	}
+	return n, nil
+}
@@ -0,0 +1,65 @@
+package mcpsdk
+
+import (
+	"context"
+	"fmt"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// managerOnlyTools lists tools that reach workspaces only through the Manager
+// and its FS, so they also work when it keeps them off the OS filesystem (see
+// workspace.WithFS). Anything not listed reads or writes workspace files with
+// the os package or needs git history, and is refused there, so new tools are
+// refused by default.
+var managerOnlyTools = map[string]bool{
+	"workspace_create":            true,
+	"workspace_list":              true,
+	"workspace_rename":            true,
+	"workspace_create_share_link": true,
+	"workspace_revoke_share_link": true,
+	"fs_write_file":               true,
+	"fs_read_text_file":           true,
+	"fs_read_lines":               true,
+	"fs_create_directory":         true,
+	"fs_list_directory":           true,
+	"fs_get_file_info":            true,
+	"fs_stat_multiple":            true,
+	"fs_move_file":                true,
+	"fs_delete_file":              true,
+}
+
+// checkToolBackend fails with UNSUPPORTED when toolName needs workspaces on
+// disk and wm keeps them elsewhere, rather than letting the tool touch the
+// real filesystem at the same paths.
+func checkToolBackend(wm *workspace.Manager, toolName string) error {
+	if wm.OnDisk() || managerOnlyTools[toolName] {
+		return nil
+	}
+	return fmt.Errorf("UNSUPPORTED: %s needs workspaces on disk, and this server keeps them on another filesystem", toolName)
+}
+
+// backendMiddleware applies checkToolBackend to MCP tool calls, and refuses
+// workspace resources, which are read from disk too, the same way.
+func backendMiddleware(wm *workspace.Manager) sdkmcp.Middleware {
+	return func(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+		return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+			if wm.OnDisk() {
+				return next(ctx, method, req)
+			}
+			switch method {
+			case "tools/call":
+				if p, ok := req.GetParams().(*sdkmcp.CallToolParamsRaw); ok && p != nil {
+					if err := checkToolBackend(wm, p.Name); err != nil {
+						return nil, err
+					}
+				}
+			case "resources/list", "resources/read":
+				return nil, fmt.Errorf("UNSUPPORTED: workspace resources need workspaces on disk")
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package mcpsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"mcp-workspace-manager/pkg/events"
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// batchToolName is the reserved path segment for POST /api/tools/batch.
+const batchToolName = "batch"
+
+// maxBatchCalls bounds the number of calls accepted in one batch request.
+const maxBatchCalls = 100
+
+// batchCall is one entry of a batch request body.
+type batchCall struct {
+	Tool   string          `json:"tool"`
+	Params json.RawMessage `json:"params"`
+}
+
+// batchResult is the outcome of one batch call. Status mirrors the HTTP status
+// the call would have produced on its own endpoint.
+type batchResult struct {
+	OK     bool   `json:"ok"`
+	Status int    `json:"status"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// restBatchHandler serves POST /api/tools/batch. The body is a JSON array of
+// {tool, params} objects; calls run sequentially and the response is an array of
+// {ok, status, result|error} in the same order. With ?stopOnError=true execution
+// stops after the first failed call and only the executed calls are returned.
+// The response size limit applies to the whole array.
+func restBatchHandler(w http.ResponseWriter, r *http.Request, wm *workspace.Manager, maxResponseBytes int) {
+	stopOnError := false
+	if v := r.URL.Query().Get("stopOnError"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeRESTError(w, &restErr{msg: "INVALID_INPUT: stopOnError must be a boolean"})
+			return
+		}
+		stopOnError = b
+	}
+
+	var calls []batchCall
+	if err := json.NewDecoder(r.Body).Decode(&calls); err != nil {
+		writeRESTError(w, errBadRequest(err))
+		return
+	}
+	if len(calls) > maxBatchCalls {
+		writeRESTError(w, &restErr{msg: fmt.Sprintf("INVALID_INPUT: batch exceeds %d calls", maxBatchCalls)})
+		return
+	}
+
+	scope := scopeFromContext(r.Context())
+	// Events published while serving REST calls are attributed to the API.
+	ctx := WithActor(r.Context(), events.Actor{Kind: "api"})
+	results := make([]batchResult, 0, len(calls))
+	for _, call := range calls {
+		err := checkToolScope(scope, call.Tool)
+		if err == nil {
+			err = checkShareAccess(r.Context(), call.Tool, call.Params)
+		}
+		var out any
+		if err == nil {
+			logDone := logToolCall(ctx, "rest", call.Tool, call.Params)
+			out, err = dispatchTool(ctx, wm, call.Tool, call.Params)
+			logDone(out, err)
+			observeToolCall(call.Tool, "rest", err)
+		}
+		if err != nil {
+			results = append(results, batchResult{Status: httpStatusFromError(err), Error: err.Error()})
+			if stopOnError {
+				break
+			}
+			continue
+		}
+		results = append(results, batchResult{OK: true, Status: http.StatusOK, Result: out})
+	}
+
+	writeRESTJSON(w, results, maxResponseBytes)
+}
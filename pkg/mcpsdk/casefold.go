@@ -0,0 +1,82 @@
+package mcpsdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// checkCaseCollision rejects creating abs when the name it adds differs only in
+// case from an existing entry (e.g. writing foo.txt next to Foo.txt, or into
+// Src/ when src/ exists). On case-insensitive filesystems (macOS, Windows) such a
+// write would silently land in the existing entry; on case-sensitive ones it
+// would create a workspace that cannot be checked out on them. The entry at
+// ignore (e.g. the source of a case-only rename) does not count as a collision.
+//
+// Existing ancestors were checked when they were created, so only the first
+// component of abs that does not exist yet is compared, against the listing of
+// its parent: one ReadDir per call rather than one per component.
+func checkCaseCollision(fsys workspace.FS, root, abs, ignore string) error {
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	dir, name := filepath.Dir(abs), filepath.Base(abs)
+	for dir != root {
+		if _, err := fsys.Stat(dir); err == nil {
+			break
+		}
+		dir, name = filepath.Dir(dir), filepath.Base(dir)
+	}
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	other := ""
+	for _, e := range entries {
+		if e.Name() == name {
+			return nil
+		}
+		if strings.EqualFold(e.Name(), name) && filepath.Join(dir, e.Name()) != ignore {
+			other = e.Name()
+		}
+	}
+	if other == "" {
+		return nil
+	}
+	existing, _ := filepath.Rel(root, filepath.Join(dir, other))
+	return fmt.Errorf("CONFLICT: %q differs only in case from existing %q", filepath.ToSlash(rel), filepath.ToSlash(existing))
+}
+
+// isCaseOnlyRename reports whether moving src to dst only changes the case of
+// the final name on a case-insensitive filesystem, where dst already resolves to
+// src and a plain existence check would report ALREADY_EXISTS.
+func isCaseOnlyRename(src, dst string) bool {
+	if src == dst || filepath.Dir(src) != filepath.Dir(dst) || !strings.EqualFold(filepath.Base(src), filepath.Base(dst)) {
+		return false
+	}
+	si, err := os.Lstat(src)
+	if err != nil {
+		return false
+	}
+	di, err := os.Lstat(dst)
+	return err == nil && os.SameFile(si, di)
+}
+
+// renameCase performs a case-only rename in two steps via a temporary name, since
+// some case-insensitive filesystems treat a direct rename as a no-op.
+func renameCase(src, dst string) error {
+	tmp := fmt.Sprintf("%s.mcp-rename-%d", src, time.Now().UnixNano())
+	if err := os.Rename(src, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.Rename(tmp, src)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package mcpsdk
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// encodingAuto asks fs_read_text_file to detect a file's encoding from its
+// byte order mark, and fs_write_file to keep the existing file's encoding.
+const encodingAuto = "auto"
+
+// textEncodings maps the accepted `encoding` values to their codecs. The
+// UTF-16 codecs strip a byte order mark when decoding and write one when
+// encoding; utf-8 strips a leading BOM but never writes one.
+var textEncodings = map[string]encoding.Encoding{
+	"utf-8":        unicode.UTF8BOM,
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"windows-1252": charmap.Windows1252,
+}
+
+// lookupEncoding validates a requested encoding name, returning its
+// canonical (lower-case) form. "" and "auto" are returned unchanged.
+func lookupEncoding(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == encodingAuto {
+		return name, nil
+	}
+	if _, ok := textEncodings[name]; !ok {
+		return "", fmt.Errorf("INVALID_INPUT: unsupported encoding %q (use auto, utf-8, utf-16le, utf-16be, latin1 or windows-1252)", name)
+	}
+	return name, nil
+}
+
+// detectEncoding names the encoding announced by the byte order mark at the
+// start of prefix, defaulting to utf-8.
+func detectEncoding(prefix []byte) string {
+	switch {
+	case bytes.HasPrefix(prefix, []byte{0xFF, 0xFE}):
+		return "utf-16le"
+	case bytes.HasPrefix(prefix, []byte{0xFE, 0xFF}):
+		return "utf-16be"
+	default:
+		return "utf-8"
+	}
+}
+
+// detectFileEncoding runs detectEncoding on the first bytes of the file at abs.
+func detectFileEncoding(fsys workspace.FS, abs string) (string, error) {
+	f, err := fsys.Open(abs)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	prefix := make([]byte, 3)
+	n, _ := f.Read(prefix)
+	return detectEncoding(prefix[:n]), nil
+}
+
+// codeUnit is the size in bytes of one code unit of the named encoding, so
+// truncated reads never split one.
+func codeUnit(name string) int64 {
+	if strings.HasPrefix(name, "utf-16") {
+		return 2
+	}
+	return 1
+}
+
+// decodeText converts data in the named encoding to UTF-8.
+func decodeText(data []byte, name string) ([]byte, error) {
+	out, err := textEncodings[name].NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("INVALID_INPUT: content is not valid %s: %v", name, err)
+	}
+	return out, nil
+}
+
+// encodeText converts UTF-8 content to the named encoding, failing for
+// characters the encoding cannot represent.
+func encodeText(content []byte, name string) ([]byte, error) {
+	if name == "utf-8" {
+		return content, nil
+	}
+	out, err := textEncodings[name].NewEncoder().Bytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("INVALID_INPUT: content cannot be encoded as %s: %v", name, err)
+	}
+	return out, nil
+}
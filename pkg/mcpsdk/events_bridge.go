@@ -1,14 +1,47 @@
 package mcpsdk
 
-import "mcp-workspace-manager/pkg/events"
+import (
+	"context"
+
+	"mcp-workspace-manager/pkg/events"
+)
 
 // eventHub is initialized by RunHTTP (and can be reused by other transports if needed).
 var eventHub *events.Hub
 
+type actorKey struct{}
+
+// WithActor returns a context that attributes events published by tool calls to actor.
+func WithActor(ctx context.Context, actor events.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFromContext returns the actor stored by WithActor, if any.
+func actorFromContext(ctx context.Context) *events.Actor {
+	if actor, ok := ctx.Value(actorKey{}).(events.Actor); ok {
+		return &actor
+	}
+	return nil
+}
+
 // publishWorkspaceEvent safely publishes an event if the hub is initialized.
-func publishWorkspaceEvent(workspaceID string, evt events.WorkspaceEvent) {
+// The event's actor defaults to the one carried by ctx.
+func publishWorkspaceEvent(ctx context.Context, workspaceID string, evt events.WorkspaceEvent) {
 	if eventHub == nil {
 		return
 	}
+	if evt.Actor == nil {
+		evt.Actor = actorFromContext(ctx)
+	}
 	eventHub.Publish(workspaceID, evt)
 }
+
+// expectWorkspaceChange registers a change the API is about to make on disk, so
+// that the fswatcher does not report it a second time (see events.Hub.Expect).
+// Call it before touching disk.
+func expectWorkspaceChange(workspaceID, path string, evtTypes ...string) {
+	if eventHub == nil {
+		return
+	}
+	eventHub.Expect(workspaceID, path, evtTypes...)
+}
@@ -0,0 +1,47 @@
+package mcpsdk
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// ignoreFunc reports whether an absolute path inside a workspace is ignored.
+// A nil ignoreFunc ignores nothing.
+type ignoreFunc func(absPath string, isDir bool) bool
+
+// gitignoreFor parses the workspace's .gitignore files (including nested ones and
+// .git/info/exclude) once and returns a matcher for absolute paths. It returns nil
+// when enabled is false, so callers can pass the result through unconditionally.
+func gitignoreFor(wm *workspace.Manager, workspaceID string, enabled bool) (ignoreFunc, error) {
+	if !enabled {
+		return nil, nil
+	}
+	root, err := wm.SafePath(workspaceID, ".")
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := gitignore.ReadPatterns(osfs.New(root), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	matcher := gitignore.NewMatcher(patterns)
+	return func(absPath string, isDir bool) bool {
+		rel, err := filepath.Rel(root, absPath)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return false
+		}
+		return matcher.Match(strings.Split(filepath.ToSlash(rel), "/"), isDir)
+	}, nil
+}
+
+func (f ignoreFunc) ignored(absPath string, isDir bool) bool {
+	return f != nil && f(absPath, isDir)
+}
@@ -1,13 +1,18 @@
 package mcpsdk
 
 import (
-	"crypto/subtle"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 
@@ -15,11 +20,74 @@ import (
 	"mcp-workspace-manager/pkg/workspace"
 )
 
+// HTTPOptions configures the HTTP transport started by RunHTTP.
+type HTTPOptions struct {
+	Host string
+	Port int
+	// AuthTokens enables Bearer auth for /mcp* and /api/* when non-empty.
+	// Entries may carry a scope suffix: "token:ro" (read-only tools) or "token:rw".
+	AuthTokens []string
+	// CORSOrigins lists origins allowed to make cross-origin requests ("*" allows any).
+	CORSOrigins []string
+	// TLSCertFile and TLSKeyFile switch the listener to HTTPS when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// RateLimit is the sustained requests/second allowed per token (or client IP
+	// without auth) on /mcp* and /api/tools/; 0 disables limiting.
+	RateLimit float64
+	// RateBurst is the bucket size for RateLimit (defaults to ceil(RateLimit)).
+	RateBurst int
+	// EventsLogDir enables the persistent per-workspace event log (see events.WithLogDir).
+	EventsLogDir string
+	// MaxResponseBytes caps the encoded size of tool results (REST and MCP); larger
+	// results fail with TOO_LARGE (413). 0 disables the cap.
+	MaxResponseBytes int
+	// EventsBuffer is the per-workspace event ring capacity used for replay
+	// (events.DefaultRingCapacity when 0).
+	EventsBuffer int
+	// EventsHeartbeat is the SSE heartbeat / WebSocket ping interval
+	// (events.DefaultHeartbeat when 0).
+	EventsHeartbeat time.Duration
+	// MaxRequestBytes caps request bodies on /mcp* and /api/tools/ (413 when
+	// exceeded). 0 uses DefaultMaxRequestBytes; negative disables the cap.
+	// Archive uploads to /api/workspaces/import have their own, larger limit.
+	MaxRequestBytes int64
+	// Metrics exposes Prometheus metrics at /metrics (unauthenticated).
+	Metrics bool
+	// MaxMediaBytes caps the files fs_read_media_file returns inline
+	// (DefaultMaxMediaBytes when 0); GET /api/workspaces/{id}/raw is not capped.
+	MaxMediaBytes int64
+	// MaxReadBytes is the default and largest maxBytes of fs_read_text_file
+	// and fs_read_multiple_files (DefaultMaxReadBytes when 0).
+	MaxReadBytes int64
+}
+
+// shutdownTimeout bounds how long RunHTTP waits for in-flight requests on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 // RunHTTP serves the MCP SDK server over HTTP using the Streamable HTTP transport,
 // and exposes a REST mirror of the tools under /api/tools/{toolName}.
-// If authTokens is non-empty, Bearer auth is required for /mcp*, /api/* endpoints.
-func RunHTTP(host string, port int, wm *workspace.Manager, authTokens []string, rootHandler http.Handler) {
+// If opts.AuthTokens is non-empty, Bearer auth is required for /mcp*, /api/* endpoints.
+// It blocks until ctx is cancelled (then shuts down gracefully) or the listener fails.
+func RunHTTP(ctx context.Context, wm *workspace.Manager, opts HTTPOptions, rootHandler http.Handler) error {
+	setMaxMediaBytes(opts.MaxMediaBytes)
+	setMaxReadBytes(opts.MaxReadBytes)
+	shareLinks.setManager(wm)
 	server := buildServer(wm)
+	tokens := parseAuthTokens(opts.AuthTokens)
+	server.AddReceivingMiddleware(scopeMiddleware(tokens))
+	server.AddReceivingMiddleware(responseLimitMiddleware(opts.MaxResponseBytes))
+	if opts.Metrics {
+		known := map[string]bool{}
+		if tools, err := listTools(ctx, server); err != nil {
+			slog.Warn("Failed to list tools for metrics", "error", err)
+		} else {
+			for _, t := range tools {
+				known[t.Name] = true
+			}
+		}
+		server.AddReceivingMiddleware(metricsMiddleware(known))
+	}
 
 	// Create a streamable HTTP handler (supports resumption and reliable streaming).
 	streamable := sdkmcp.NewStreamableHTTPHandler(func(r *http.Request) *sdkmcp.Server {
@@ -29,15 +97,28 @@ func RunHTTP(host string, port int, wm *workspace.Manager, authTokens []string,
 	mux := http.NewServeMux()
 
 	// Initialize global event hub and mount SSE endpoint for browsers
-	// Note: Authorization for /events is handled by the SSE handler (query token or Bearer).
-	eventHub = events.NewHub(200)
-	mux.Handle("/events", events.SSEHandler(eventHub, authTokens))
-
-	// Start filesystem watcher to capture external changes (not via API/MCP)
-	if stopFn, err := events.StartFSWatcher(wm.RootPath(), eventHub); err != nil {
-		slog.Warn("Failed to start fs watcher", "error", err)
-	} else {
-		_ = stopFn // kept for future graceful shutdown
+	// Note: Authorization for /events and /ws/events is handled by the stream handlers (query token or Bearer).
+	var hubOpts []events.HubOption
+	if opts.EventsLogDir != "" {
+		if err := os.MkdirAll(opts.EventsLogDir, 0755); err != nil {
+			return fmt.Errorf("failed to create events log dir: %w", err)
+		}
+		hubOpts = append(hubOpts, events.WithLogDir(opts.EventsLogDir))
+	}
+	eventHub = events.NewHub(opts.EventsBuffer, hubOpts...)
+	heartbeat := events.WithHeartbeat(opts.EventsHeartbeat)
+	mux.Handle("/events", events.SSEHandler(eventHub, tokenValues(tokens), shareLinks.allowsEvents, heartbeat))
+	mux.Handle("/ws/events", events.WSHandler(eventHub, tokenValues(tokens), shareLinks.allowsEvents, heartbeat))
+
+	// Start filesystem watcher to capture external changes (not via API/MCP);
+	// workspaces kept off disk can only change through the tools
+	stopWatcher := func() {}
+	if wm.OnDisk() {
+		if stopFn, err := events.StartFSWatcher(wm.RootPath(), eventHub, wm.Protected()); err != nil {
+			slog.Warn("Failed to start fs watcher", "error", err)
+		} else {
+			stopWatcher = stopFn
+		}
 	}
 
 	// Protected mounts (streamable and SSE alias)
@@ -50,11 +131,36 @@ func RunHTTP(host string, port int, wm *workspace.Manager, authTokens []string,
 		{"/mcp/command", streamable},
 		// SSE compatibility mount to streamable (SDK v0.4.0 may not expose SSE handler)
 		{"/mcp/sse", streamable},
+		// Catalog of the tools with their input schemas
+		{"GET /api/tools", toolCatalogHandler(server)},
 		// REST tools mirror
-		{"/api/tools/", restToolsHandler(wm)},
+		{"/api/tools/", restToolsHandler(wm, opts.MaxResponseBytes)},
+		// Multipart archive upload creating a workspace (workspace_import)
+		{"/api/workspaces/import", workspaceImportHandler(wm)},
+		// Streaming tar.gz download of a workspace (workspace_export)
+		{"GET /api/workspaces/{id}/archive", workspaceExportHandler(wm)},
+		// Streaming download of a single file, without the media size cap
+		{"GET /api/workspaces/{id}/raw", workspaceRawHandler(wm)},
+		// OpenAPI description of the REST mirror, generated from the registered tools
+		{"/api/openapi.json", openAPIHandler(server)},
+	}
+	var limiter *rateLimiter
+	if opts.RateLimit > 0 {
+		limiter = newRateLimiter(opts.RateLimit, opts.RateBurst)
+	}
+	maxRequestBytes := opts.MaxRequestBytes
+	if maxRequestBytes == 0 {
+		maxRequestBytes = DefaultMaxRequestBytes
 	}
 	for _, p := range protected {
-		mux.Handle(p.pattern, wrapAuth(p.h, authTokens))
+		h := p.h
+		if p.pattern != "/api/workspaces/import" {
+			h = withBodyLimit(h, maxRequestBytes)
+		}
+		if p.pattern != "/api/openapi.json" && p.pattern != "GET /api/tools" {
+			h = withRateLimit(h, limiter)
+		}
+		mux.Handle(p.pattern, wrapAuth(h, tokens))
 	}
 
 	// Health probe (unauthenticated)
@@ -62,362 +168,534 @@ func RunHTTP(host string, port int, wm *workspace.Manager, authTokens []string,
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("OK"))
 	})
+	// Readiness probe (unauthenticated): workspaces root and event hub
+	mux.Handle("/readyz", readinessHandler(wm))
+
+	// Prometheus metrics (unauthenticated, opt-in)
+	if opts.Metrics {
+		mux.Handle("/metrics", metricsHandler())
+	}
 
 	// Serve the embedded frontend
 	if rootHandler != nil {
 		mux.Handle("/", rootHandler)
 	}
 
-	addr := net.JoinHostPort(host, strconv.Itoa(port))
-	slog.Info("Starting MCP SDK HTTP server", "host", host, "port", port, "addr", addr, "auth_enabled", len(authTokens) > 0)
+	addr := net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port))
+	useTLS := opts.TLSCertFile != "" && opts.TLSKeyFile != ""
+	slog.Info("Starting MCP SDK HTTP server", "host", opts.Host, "port", opts.Port, "addr", addr, "auth_enabled", len(opts.AuthTokens) > 0, "cors_origins", opts.CORSOrigins, "tls", useTLS)
 
-	if err := http.ListenAndServe(addr, mux); err != nil {
-		slog.Error("MCP SDK HTTP server failed", "error", err)
+	srv := &http.Server{Addr: addr, Handler: withCORS(mux, opts.CORSOrigins)}
+	serveErr := make(chan error, 1)
+	go func() {
+		if useTLS {
+			serveErr <- srv.ListenAndServeTLS(opts.TLSCertFile, opts.TLSKeyFile)
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		stopWatcher()
+		eventHub.Close()
+		return fmt.Errorf("MCP SDK HTTP server failed: %w", err)
+	case <-ctx.Done():
 	}
+
+	slog.Info("Shutting down MCP SDK HTTP server")
+	stopWatcher()
+	// Closing the hub ends SSE streams so their connections can drain.
+	eventHub.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 // wrapAuth applies simple Bearer token auth when tokens is non-empty.
 // Authorization: Bearer <token> (case-insensitive "Bearer").
-// On failure: 401 with WWW-Authenticate header.
-func wrapAuth(next http.Handler, tokens []string) http.Handler {
+// On failure: 401 with WWW-Authenticate header. On success the token's scope
+// is attached to the request context (see scopeFromContext); share link tokens
+// are read-only and restricted to their workspace (see checkShareAccess).
+func wrapAuth(next http.Handler, tokens []authToken) http.Handler {
 	// Disabled if no tokens
 	if len(tokens) == 0 {
 		return next
 	}
-	// Normalize and deduplicate tokens
-	norm := make([][]byte, 0, len(tokens))
-	seen := map[string]struct{}{}
-	for _, t := range tokens {
-		tt := strings.TrimSpace(t)
-		if tt == "" {
-			continue
-		}
-		if _, ok := seen[tt]; ok {
-			continue
-		}
-		seen[tt] = struct{}{}
-		norm = append(norm, []byte(tt))
-	}
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authz := r.Header.Get("Authorization")
-		if authz == "" {
-			unauthorized(w)
-			return
-		}
-		// Expect "Bearer token" with case-insensitive scheme.
-		var token string
-		if strings.HasPrefix(strings.ToLower(authz), "bearer ") {
-			token = strings.TrimSpace(authz[len("Bearer "):])
-		} else {
-			parts := strings.SplitN(authz, " ", 2)
-			if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
-				token = strings.TrimSpace(parts[1])
-			}
-		}
+		token := bearerToken(r.Header)
 		if token == "" {
 			unauthorized(w)
 			return
 		}
-
-		got := []byte(token)
-		ok := false
-		for _, allowed := range norm {
-			if subtle.ConstantTimeCompare(got, allowed) == 1 {
-				ok = true
-				break
-			}
-		}
+		scope, ok := lookupScope(tokens, token)
 		if !ok {
-			unauthorized(w)
+			// Share link tokens grant read-only access to a single workspace.
+			claims, valid := shareLinks.verify(token)
+			if !valid {
+				unauthorized(w)
+				return
+			}
+			ctx := withShareWorkspace(withScope(r.Context(), scopeReadOnly), claims.WorkspaceID)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(withScope(r.Context(), scope)))
 	})
 }
 
 func unauthorized(w http.ResponseWriter) {
 	w.Header().Set("WWW-Authenticate", `Bearer realm="mcp", error="invalid_token"`)
-	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	writeRESTError(w, fmt.Errorf("UNAUTHORIZED: missing or invalid bearer token"))
 }
 
-// REST mirror: POST /api/tools/{toolName}
-func restToolsHandler(wm *workspace.Manager) http.Handler {
+// REST mirror: POST /api/tools/{toolName}, plus GET with query parameters for
+// the read-only tools in getTools.
+func restToolsHandler(wm *workspace.Manager, maxResponseBytes int) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-			return
-		}
 		toolName := strings.TrimPrefix(r.URL.Path, "/api/tools/")
 		if toolName == "" || strings.Contains(toolName, "/") {
 			http.NotFound(w, r)
 			return
 		}
-
-		enc := json.NewEncoder(w)
-		enc.SetEscapeHTML(false)
-		w.Header().Set("Content-Type", "application/json")
-
-		var err error
-		switch toolName {
-
-		case "workspace_create":
-			var in CreateWorkspaceRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := WorkspaceCreate(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_delete_file":
-			var in DeleteFileRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSDeleteFile(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "workspace_list":
-			var in ListWorkspacesRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := WorkspaceList(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_write_file":
-			var in WriteFileRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSWriteFile(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_read_text_file":
-			var in ReadFileRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSReadTextFile(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_create_directory":
-			var in CreateDirectoryRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSCreateDirectory(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_list_directory":
-			var in ListDirectoryRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSListDirectory(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_get_file_info":
-			var in GetFileInfoRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSGetFileInfo(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_get_commit_history":
-			var in GetCommitHistoryRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSGetCommitHistory(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_move_file":
-			var in MoveFileRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSMoveFile(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_edit_file":
-			var in EditFileRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSEditFile(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
-
-		case "fs_read_multiple_files":
-			var in ReadMultipleFilesRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSReadMultipleFiles(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
+		reqType, getAllowed := getTools[toolName]
+		switch {
+		case r.Method == http.MethodPost:
+		case r.Method == http.MethodGet && getAllowed:
+		default:
+			if getAllowed {
+				w.Header().Set("Allow", "GET, POST")
+			} else {
+				w.Header().Set("Allow", "POST")
 			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
+			writeRESTError(w, fmt.Errorf("METHOD_NOT_ALLOWED: %s is not allowed for %s", r.Method, toolName))
+			return
+		}
+		if toolName == batchToolName {
+			restBatchHandler(w, r, wm, maxResponseBytes)
+			return
+		}
+		if err := checkToolScope(scopeFromContext(r.Context()), toolName); err != nil {
+			writeRESTError(w, err)
+			return
+		}
 
-		case "fs_list_directory_with_sizes":
-			var in ListDirectoryWithSizesRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
+		var params json.RawMessage
+		var err error
+		if r.Method == http.MethodGet {
+			params, err = queryParams(r.URL.Query(), reqType)
+			if err != nil {
+				writeRESTError(w, err)
 				return
 			}
-			out, e := FSListDirectoryWithSizes(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
+		} else if params, err = io.ReadAll(r.Body); err != nil {
+			writeRESTError(w, errBadRequest(err))
+			return
+		}
+		if err := checkShareAccess(r.Context(), toolName, params); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		// Events published while serving REST calls are attributed to the API.
+		ctx := WithActor(r.Context(), events.Actor{Kind: "api"})
+		logDone := logToolCall(ctx, "rest", toolName, params)
+		out, err := dispatchTool(ctx, wm, toolName, params)
+		logDone(out, err)
+		observeToolCall(toolName, "rest", err)
+		if err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if etag := responseETag(out); etag != "" && r.Method == http.MethodGet {
+			w.Header().Set("ETag", etag)
+			if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+				w.WriteHeader(http.StatusNotModified)
 				return
 			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
+		}
+		writeRESTJSON(w, out, maxResponseBytes)
+	})
+}
 
-		case "fs_search_files":
-			var in SearchFilesRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSSearchFiles(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
+// dispatchTool decodes params into the request type of toolName and invokes it.
+// It is shared by the single-tool REST endpoint and the batch endpoint.
+func dispatchTool(ctx context.Context, wm *workspace.Manager, toolName string, params json.RawMessage) (any, error) {
+	if err := checkToolBackend(wm, toolName); err != nil {
+		return nil, err
+	}
+	switch toolName {
+	case "workspace_create":
+		var in CreateWorkspaceRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceCreate(ctx, wm, in)
+	case "fs_delete_file":
+		var in DeleteFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSDeleteFile(ctx, wm, in)
+	case "fs_restore_from_trash":
+		var in RestoreFromTrashRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSRestoreFromTrash(ctx, wm, in)
+	case "workspace_list":
+		var in ListWorkspacesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceList(ctx, wm, in)
+	case "workspace_diff":
+		var in WorkspaceDiffRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceDiff(ctx, wm, in)
+	case "workspace_revert":
+		var in WorkspaceRevertRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceRevert(ctx, wm, in)
+	case "workspace_undo_last_commit":
+		var in UndoLastCommitRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceUndoLastCommit(ctx, wm, in)
+	case "workspace_create_tag":
+		var in CreateTagRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceCreateTag(ctx, wm, in)
+	case "workspace_list_tags":
+		var in ListTagsRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceListTags(ctx, wm, in)
+	case "workspace_rename":
+		var in RenameWorkspaceRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceRename(ctx, wm, in)
+	case "workspace_info":
+		var in WorkspaceInfoRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceGetInfo(ctx, wm, in)
+	case "workspace_status":
+		var in WorkspaceStatusRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceStatus(ctx, wm, in)
+	case "workspace_create_from_template":
+		var in CreateFromTemplateRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceCreateFromTemplate(ctx, wm, in)
+	case "workspace_clone":
+		var in CloneWorkspaceRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceClone(ctx, wm, in)
+	case "fs_write_file":
+		var in WriteFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSWriteFile(ctx, wm, in)
+	case "fs_write_files":
+		var in WriteFilesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSWriteFiles(ctx, wm, in)
+	case "fs_read_text_file":
+		var in ReadFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSReadTextFile(ctx, wm, in)
+	case "fs_read_lines":
+		var in ReadLinesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSReadLines(ctx, wm, in)
+	case "fs_create_directory":
+		var in CreateDirectoryRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSCreateDirectory(ctx, wm, in)
+	case "fs_list_directory":
+		var in ListDirectoryRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSListDirectory(ctx, wm, in)
+	case "fs_get_file_info":
+		var in GetFileInfoRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSGetFileInfo(ctx, wm, in)
+	case "fs_stat_multiple":
+		var in StatMultipleRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSStatMultiple(ctx, wm, in)
+	case "fs_get_commit_history":
+		var in GetCommitHistoryRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSGetCommitHistory(ctx, wm, in)
+	case "fs_move_file":
+		var in MoveFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSMoveFile(ctx, wm, in)
+	case "fs_edit_file":
+		var in EditFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSEditFile(ctx, wm, in)
+	case "fs_read_multiple_files":
+		var in ReadMultipleFilesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSReadMultipleFiles(ctx, wm, in)
+	case "fs_list_directory_with_sizes":
+		var in ListDirectoryWithSizesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSListDirectoryWithSizes(ctx, wm, in)
+	case "fs_search_files":
+		var in SearchFilesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSSearchFiles(ctx, wm, in)
+	case "fs_directory_tree":
+		var in DirectoryTreeRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSDirectoryTree(ctx, wm, in)
+	case "fs_read_media_file":
+		var in ReadMediaFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSReadMediaFile(ctx, wm, in)
+	case "fs_set_mtime":
+		var in SetMtimeRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSSetMtime(ctx, wm, in)
+	case "fs_chmod":
+		var in ChmodRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSChmod(ctx, wm, in)
+	case "fs_read_file_at_commit":
+		var in ReadFileAtCommitRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSReadFileAtCommit(ctx, wm, in)
+	case "fs_diff_files":
+		var in DiffFilesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSDiffFiles(ctx, wm, in)
+	case "fs_json_set":
+		var in JSONSetRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSJSONSet(ctx, wm, in)
+	case "fs_estimate_read":
+		var in EstimateReadRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSEstimateRead(ctx, wm, in)
+	case "fs_search_and_read":
+		var in SearchAndReadRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSSearchAndRead(ctx, wm, in)
+	case "fs_restore_from_snapshot":
+		var in RestoreFromSnapshotRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSRestoreFromSnapshot(ctx, wm, in)
+	case "fs_restore_file":
+		var in RestoreFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSRestoreFile(ctx, wm, in)
+	case "fs_manifest":
+		var in ManifestRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSManifest(ctx, wm, in)
+	case "fs_copy_between_workspaces":
+		var in CopyBetweenWorkspacesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSCopyBetweenWorkspaces(ctx, wm, in)
+	case "fs_get_directory_size":
+		var in GetDirectorySizeRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSGetDirectorySize(ctx, wm, in)
+	case "workspace_find_files":
+		var in FindFilesRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceFindFiles(ctx, wm, in)
+	case "fs_merge_content":
+		var in MergeContentRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSMergeContent(ctx, wm, in)
+	case "fs_wait_for_change":
+		var in WaitForChangeRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSWaitForChange(ctx, wm, in)
+	case "fs_patch":
+		var in PatchFileRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return FSPatch(ctx, wm, in)
+	case "workspace_import":
+		var in ImportWorkspaceRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceImport(ctx, wm, in)
+	case "workspace_export":
+		var in ExportWorkspaceRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceExport(ctx, wm, in)
+	case "workspace_create_share_link":
+		var in CreateShareLinkRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceCreateShareLink(ctx, wm, in)
+	case "workspace_revoke_share_link":
+		var in RevokeShareLinkRequest
+		if err := json.Unmarshal(params, &in); err != nil {
+			return nil, errBadRequest(err)
+		}
+		return WorkspaceRevokeShareLink(ctx, wm, in)
+	default:
+		return nil, fmt.Errorf("NOT_FOUND: %w %q", errUnknownTool, toolName)
+	}
+}
 
-		case "fs_directory_tree":
-			var in DirectoryTreeRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSDirectoryTree(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
+// restError is the JSON body of a failed REST call. Code is the error's
+// prefix (e.g. "CONFLICT", or "INTERNAL" when it has none) and Message the
+// rest of it; Details carries structured context some errors attach with
+// withDetails.
+type restError struct {
+	Error restErrorInfo `json:"error"`
+}
 
-		case "fs_read_media_file":
-			var in ReadMediaFileRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSReadMediaFile(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
+type restErrorInfo struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
 
-		case "fs_read_file_at_commit":
-			var in ReadFileAtCommitRequest
-			if err = json.NewDecoder(r.Body).Decode(&in); err != nil {
-				writeRESTError(w, errBadRequest(err))
-				return
-			}
-			out, e := FSReadFileAtCommit(r.Context(), wm, in)
-			if e != nil {
-				writeRESTError(w, e)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			_ = enc.Encode(out)
+func writeRESTError(w http.ResponseWriter, err error) {
+	if tooLarge := asRequestTooLarge(err); tooLarge != nil {
+		err = tooLarge
+	}
+	msg := err.Error()
+	info := restErrorInfo{Code: errorCode(msg), Message: msg}
+	if rest, ok := strings.CutPrefix(msg, info.Code+":"); ok {
+		info.Message = strings.TrimSpace(rest)
+	}
+	var de *detailedError
+	if errors.As(err, &de) {
+		info.Details = de.details
+	}
+	body, _ := json.Marshal(restError{Error: info})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpStatusFromError(err))
+	_, _ = w.Write(append(body, '\n'))
+}
 
-		default:
-			http.NotFound(w, r)
-			return
-		}
-	})
+// detailedError is a tool error with details for the REST error body; its
+// message, and so what MCP clients see, is that of the wrapped error.
+type detailedError struct {
+	err     error
+	details map[string]any
 }
 
-func writeRESTError(w http.ResponseWriter, err error) {
-	code := httpStatusFromError(err)
-	http.Error(w, err.Error(), code)
+func (e *detailedError) Error() string { return e.err.Error() }
+func (e *detailedError) Unwrap() error { return e.err }
+
+// withDetails attaches details to err for REST clients.
+func withDetails(err error, details map[string]any) error {
+	return &detailedError{err: err, details: details}
 }
 
 func errBadRequest(err error) error {
-	return &restErr{msg: "INVALID_INPUT: " + err.Error()}
+	return &restErr{msg: "INVALID_INPUT: " + err.Error(), err: err}
 }
 
-type restErr struct{ msg string }
+type restErr struct {
+	msg string
+	err error // cause, if any
+}
 
 func (e *restErr) Error() string { return e.msg }
+func (e *restErr) Unwrap() error { return e.err }
 
 func httpStatusFromError(err error) int {
 	if err == nil {
@@ -437,6 +715,20 @@ func httpStatusFromError(err error) int {
 		return http.StatusBadRequest
 	case strings.HasPrefix(msg, "UNSUPPORTED:"):
 		return http.StatusUnprocessableEntity
+	case strings.HasPrefix(msg, "FORBIDDEN:"):
+		return http.StatusForbidden
+	case strings.HasPrefix(msg, "TOO_LARGE:"):
+		return http.StatusRequestEntityTooLarge
+	case strings.HasPrefix(msg, "QUOTA_EXCEEDED:"):
+		return http.StatusRequestEntityTooLarge
+	case strings.HasPrefix(msg, "TIMEOUT:"):
+		return http.StatusRequestTimeout
+	case strings.HasPrefix(msg, "UNAUTHORIZED:"):
+		return http.StatusUnauthorized
+	case strings.HasPrefix(msg, "METHOD_NOT_ALLOWED:"):
+		return http.StatusMethodNotAllowed
+	case strings.HasPrefix(msg, "RATE_LIMITED:"):
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -0,0 +1,220 @@
+package mcpsdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// orderedObject is a JSON object that remembers key order so that rewriting a
+// document does not shuffle keys the way map[string]any would.
+type orderedObject struct {
+	keys []string
+	vals map[string]any
+}
+
+func newOrderedObject() *orderedObject {
+	return &orderedObject{vals: map[string]any{}}
+}
+
+func (o *orderedObject) set(key string, val any) {
+	if _, exists := o.vals[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.vals[key] = val
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, err := marshalNoEscape(k)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		vb, err := marshalNoEscape(o.vals[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func marshalNoEscape(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+// parseOrderedJSON decodes a single JSON document, preserving object key order and number literals.
+func parseOrderedJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := newOrderedObject()
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := kt.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid object key %v", kt)
+			}
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(key, val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unexpected delimiter %v", delim)
+}
+
+// parseJSONPointer splits an RFC 6901 pointer into unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer must be empty or start with '/'")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// jsonPointerSet sets value at the pointer within doc, creating intermediate objects
+// as needed, and returns the (possibly replaced) root.
+func jsonPointerSet(doc any, pointer string, value any) (any, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return setAtPointer(doc, tokens, value, "")
+}
+
+func setAtPointer(node any, tokens []string, value any, at string) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	tok := tokens[0]
+	here := at + "/" + tok
+	switch n := node.(type) {
+	case nil:
+		return setAtPointer(newOrderedObject(), tokens, value, at)
+	case *orderedObject:
+		child, err := setAtPointer(n.vals[tok], tokens[1:], value, here)
+		if err != nil {
+			return nil, err
+		}
+		n.set(tok, child)
+		return n, nil
+	case []any:
+		if tok != "-" {
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx > len(n) {
+				return nil, fmt.Errorf("array index %q out of range at %s", tok, here)
+			}
+			if idx < len(n) {
+				child, err := setAtPointer(n[idx], tokens[1:], value, here)
+				if err != nil {
+					return nil, err
+				}
+				n[idx] = child
+				return n, nil
+			}
+		}
+		// "-" (or an index equal to the length) appends a new element.
+		child, err := setAtPointer(nil, tokens[1:], value, here)
+		if err != nil {
+			return nil, err
+		}
+		return append(n, child), nil
+	default:
+		return nil, fmt.Errorf("cannot traverse into a scalar value at %s", here)
+	}
+}
+
+// detectJSONIndent returns the indentation unit used by a JSON document,
+// or "" when the document appears to be compact.
+func detectJSONIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return ""
+}
+
+// encodeJSONLike serializes v using the indentation and trailing-newline style of original.
+func encodeJSONLike(v any, original []byte) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if indent := detectJSONIndent(original); indent != "" {
+		enc.SetIndent("", indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	out := b.Bytes()
+	if !bytes.HasSuffix(bytes.TrimRight(original, " \t\r"), []byte("\n")) {
+		out = bytes.TrimRight(out, "\n")
+	}
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package mcpsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultMaxRequestBytes is the request body limit used when HTTPOptions
+// leaves MaxRequestBytes at 0.
+const DefaultMaxRequestBytes = 32 << 20 // 32 MiB
+
+// DefaultMaxMediaBytes is the fs_read_media_file size limit used when the
+// transport options leave MaxMediaBytes at 0.
+const DefaultMaxMediaBytes = 10 << 20 // 10 MiB
+
+// maxMediaBytes is the largest file fs_read_media_file returns inline as
+// base64; larger files are served by GET /api/workspaces/{id}/raw.
+var maxMediaBytes int64 = DefaultMaxMediaBytes
+
+// setMaxMediaBytes applies a configured media limit, keeping the default for 0.
+func setMaxMediaBytes(n int64) {
+	if n > 0 {
+		maxMediaBytes = n
+	}
+}
+
+// DefaultMaxReadBytes is the fs_read_text_file and fs_read_multiple_files
+// limit used when the transport options leave MaxReadBytes at 0.
+const DefaultMaxReadBytes = 10 << 20 // 10 MiB
+
+// maxReadBytes is the default and the largest maxBytes of the text read
+// tools; longer files are returned truncated.
+var maxReadBytes int64 = DefaultMaxReadBytes
+
+// setMaxReadBytes applies a configured read limit, keeping the default for 0.
+func setMaxReadBytes(n int64) {
+	if n > 0 {
+		maxReadBytes = n
+	}
+}
+
+// withBodyLimit caps request bodies at max bytes; reading past the cap fails
+// with *http.MaxBytesError, which writeRESTError reports as 413. A negative max
+// disables the cap.
+func withBodyLimit(next http.Handler, max int64) http.Handler {
+	if max < 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > max {
+			writeRESTError(w, requestTooLarge(max))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestTooLarge is the error for a request body over max bytes.
+func requestTooLarge(max int64) error {
+	return fmt.Errorf("TOO_LARGE: request body exceeds the %d-byte limit", max)
+}
+
+// asRequestTooLarge returns the TOO_LARGE error when err was caused by a body
+// exceeding its http.MaxBytesReader limit, or nil otherwise.
+func asRequestTooLarge(err error) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return requestTooLarge(mbe.Limit)
+	}
+	return nil
+}
+
+// responseTooLarge is returned instead of a tool result whose JSON encoding
+// exceeds the configured maximum response size.
+func responseTooLarge(size, max int) error {
+	return fmt.Errorf("TOO_LARGE: response of %d bytes exceeds the %d-byte limit; narrow the request "+
+		"(e.g. head/tail for reads, limit/before for history, maxDepth/dirsOnly/excludePatterns for trees, a narrower path or pattern for searches)",
+		size, max)
+}
+
+// encodeJSON encodes v as the REST mirror does (no HTML escaping, trailing
+// newline) and enforces max when it is positive.
+func encodeJSON(v any, max int) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("INTERNAL: failed to encode response: %v", err)
+	}
+	if max > 0 && buf.Len() > max {
+		return nil, responseTooLarge(buf.Len(), max)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeRESTJSON writes v as a 200 JSON response, or the TOO_LARGE error (413)
+// if its encoding exceeds max.
+func writeRESTJSON(w http.ResponseWriter, v any, max int) {
+	body, err := encodeJSON(v, max)
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// responseLimitMiddleware rejects MCP tool results larger than max bytes
+// (as encoded JSON). A non-positive max disables the check.
+func responseLimitMiddleware(max int) sdkmcp.Middleware {
+	return func(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+		if max <= 0 {
+			return next
+		}
+		return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+			res, err := next(ctx, method, req)
+			if err != nil || method != "tools/call" {
+				return res, err
+			}
+			if _, err := encodeJSON(res, max); err != nil {
+				return nil, err
+			}
+			return res, nil
+		}
+	}
+}
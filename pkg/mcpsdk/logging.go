@@ -0,0 +1,97 @@
+package mcpsdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// toolLogRequest and toolLogResponse pick the fields logged for a tool call
+// out of its arguments and result. Decoding into them skips every other
+// field, so file contents never reach the log.
+type toolLogRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+}
+
+type toolLogResponse struct {
+	BytesWritten *int64 `json:"bytesWritten"`
+	Bytes        *int64 `json:"bytes"`
+	Size         *int64 `json:"size"`
+	Commit       string `json:"commit"`
+}
+
+// logToolCall logs the start of a call to tool over transport at debug level
+// and returns a function logging its outcome: at debug on success, at warn
+// for client errors and at error for INTERNAL ones.
+func logToolCall(ctx context.Context, transport, tool string, params json.RawMessage) func(out any, err error) {
+	var req toolLogRequest
+	_ = json.Unmarshal(params, &req)
+	attrs := []any{"tool", tool, "transport", transport, "workspaceId", req.WorkspaceID}
+	if req.Path != "" {
+		attrs = append(attrs, "path", req.Path)
+	}
+	slog.DebugContext(ctx, "Tool call started", attrs...)
+	start := time.Now()
+
+	return func(out any, err error) {
+		attrs := append(attrs, "duration", time.Since(start))
+		if err != nil {
+			code := errorCode(err.Error())
+			level := slog.LevelWarn
+			if code == "INTERNAL" {
+				level = slog.LevelError
+			}
+			slog.Log(ctx, level, "Tool call failed", append(attrs, "code", code, "error", err.Error())...)
+			return
+		}
+		// Results can be large (file reads); only encode them when the
+		// line is actually written.
+		if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+		var res toolLogResponse
+		if b, err := json.Marshal(out); err == nil {
+			_ = json.Unmarshal(b, &res)
+		}
+		switch {
+		case res.BytesWritten != nil:
+			attrs = append(attrs, "bytes", *res.BytesWritten)
+		case res.Bytes != nil:
+			attrs = append(attrs, "bytes", *res.Bytes)
+		case res.Size != nil:
+			attrs = append(attrs, "bytes", *res.Size)
+		}
+		if res.Commit != "" {
+			attrs = append(attrs, "commit", res.Commit)
+		}
+		slog.DebugContext(ctx, "Tool call finished", attrs...)
+	}
+}
+
+// loggingMiddleware logs MCP tools/call requests through logToolCall. Tool
+// failures are reported either as a Go error or as a result with IsError set.
+func loggingMiddleware(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+	return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+		p, ok := req.GetParams().(*sdkmcp.CallToolParamsRaw)
+		if method != "tools/call" || !ok || p == nil {
+			return next(ctx, method, req)
+		}
+		done := logToolCall(ctx, "mcp", p.Name, p.Arguments)
+		res, err := next(ctx, method, req)
+		var out any
+		failure := err
+		if r, ok := res.(*sdkmcp.CallToolResult); ok && r != nil {
+			out = r.StructuredContent
+			if r.IsError && failure == nil {
+				failure = errors.New(toolResultText(r))
+			}
+		}
+		done(out, failure)
+		return res, err
+	}
+}
@@ -0,0 +1,96 @@
+package mcpsdk
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// mergeThreeWay applies the changes from base to theirs onto ours (the current
+// content). Patches must match ours exactly, so any hunk whose context or
+// deleted text was also changed in ours is reported as a conflict instead of
+// being fuzzily applied. The merged text is only meaningful when no conflicts
+// are returned.
+func mergeThreeWay(base, theirs, ours string) (string, []MergeConflict) {
+	switch {
+	case theirs == base || theirs == ours:
+		return ours, nil
+	case ours == base:
+		return theirs, nil
+	}
+	dmp := diffmatchpatch.New()
+	// Accept a hunk's context anywhere in ours (edits elsewhere shift offsets) but
+	// only verbatim: one differing character in a 32-char pattern scores 1/32.
+	dmp.MatchThreshold = 0.01
+	dmp.MatchDistance = 1 << 30
+	dmp.PatchDeleteThreshold = 0
+
+	diffs := dmp.DiffMain(base, theirs, true)
+	diffs = dmp.DiffCleanupSemantic(diffs)
+	// PatchApply splits hunks longer than MatchMaxBits and reports each piece, so
+	// its results need not line up with the patches given; apply one patch at a
+	// time so a failed piece is traced back to the hunk it came from, and ours is
+	// kept whole when only some pieces of a hunk apply.
+	patches := dmp.PatchMake(base, diffs)
+	merged := ours
+
+	var conflicts []MergeConflict
+	for _, p := range patches {
+		next, applied := dmp.PatchApply([]diffmatchpatch.Patch{p}, merged)
+		if allTrue(applied) {
+			merged = next
+			continue
+		}
+		// Drop the patch's surrounding context so only the changed lines are reported
+		b, t := base[p.Start1:p.Start1+p.Length1], theirs[p.Start2:p.Start2+p.Length2]
+		pre := commonPrefixLen(b, t)
+		suf := commonSuffixLen(b[pre:], t[pre:])
+		bStart, bEnd := lineSpan(base, p.Start1+pre, p.Start1+p.Length1-suf)
+		tStart, tEnd := lineSpan(theirs, p.Start2+pre, p.Start2+p.Length2-suf)
+		conflicts = append(conflicts, MergeConflict{
+			Line:   strings.Count(base[:bStart], "\n") + 1,
+			Base:   base[bStart:bEnd],
+			Theirs: theirs[tStart:tEnd],
+		})
+	}
+	return merged, conflicts
+}
+
+func allTrue(bs []bool) bool {
+	for _, b := range bs {
+		if !b {
+			return false
+		}
+	}
+	return true
+}
+
+// lineSpan widens [start, end) in s to whole lines (including the final newline).
+func lineSpan(s string, start, end int) (int, int) {
+	start = min(max(start, 0), len(s))
+	end = min(max(end, start), len(s))
+	start = strings.LastIndexByte(s[:start], '\n') + 1
+	if end > start && s[end-1] == '\n' {
+		return start, end
+	}
+	if i := strings.IndexByte(s[end:], '\n'); i >= 0 {
+		return start, end + i + 1
+	}
+	return start, len(s)
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func commonSuffixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
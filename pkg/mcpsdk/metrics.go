@@ -0,0 +1,129 @@
+package mcpsdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// errUnknownTool is wrapped by dispatchTool for names it does not know; such
+// calls are counted under the tool label "unknown" to bound label cardinality.
+var errUnknownTool = errors.New("unknown tool")
+
+var (
+	toolCallsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_workspace_tool_calls_total",
+		Help: "Tool invocations by tool name and transport (rest or mcp).",
+	}, []string{"tool", "transport"})
+	toolErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_workspace_tool_errors_total",
+		Help: "Failed tool invocations by tool name, transport and error code (e.g. NOT_FOUND).",
+	}, []string{"tool", "transport", "code"})
+
+	eventsPublishedDesc = prometheus.NewDesc("mcp_workspace_events_published_total",
+		"Workspace events published to the event hub, by event type.", []string{"type"}, nil)
+	eventSubscribersDesc = prometheus.NewDesc("mcp_workspace_event_subscribers",
+		"Active event stream subscribers per workspace; workspace=\"*\" counts subscribers to all workspaces.", []string{"workspace"}, nil)
+)
+
+// metricsRegistry holds the server's metrics, served by metricsHandler.
+var metricsRegistry = func() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		toolCallsTotal,
+		toolErrorsTotal,
+		hubCollector{},
+	)
+	return r
+}()
+
+// metricsHandler serves the Prometheus exposition of metricsRegistry.
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// observeToolCall records one invocation of tool over transport and, when err
+// is non-nil, its error code.
+func observeToolCall(tool, transport string, err error) {
+	if errors.Is(err, errUnknownTool) {
+		tool = "unknown"
+	}
+	toolCallsTotal.WithLabelValues(tool, transport).Inc()
+	if err != nil {
+		toolErrorsTotal.WithLabelValues(tool, transport, errorCode(err.Error())).Inc()
+	}
+}
+
+// errorCode extracts the status prefix (e.g. "NOT_FOUND") from a tool error
+// message, falling back to INTERNAL for messages without one.
+func errorCode(msg string) string {
+	code, _, ok := strings.Cut(msg, ":")
+	if !ok || code == "" || strings.TrimLeft(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ_") != "" {
+		return "INTERNAL"
+	}
+	return code
+}
+
+// metricsMiddleware counts MCP tools/call requests; names not in known are
+// counted as "unknown". Tool failures are reported either as a Go error or as a
+// result with IsError set.
+func metricsMiddleware(known map[string]bool) sdkmcp.Middleware {
+	return func(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+		return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+			res, err := next(ctx, method, req)
+			if method != "tools/call" {
+				return res, err
+			}
+			tool := "unknown"
+			if p, ok := req.GetParams().(*sdkmcp.CallToolParamsRaw); ok && p != nil && known[p.Name] {
+				tool = p.Name
+			}
+			failure := err
+			if r, ok := res.(*sdkmcp.CallToolResult); ok && r != nil && r.IsError && failure == nil {
+				failure = errors.New(toolResultText(r))
+			}
+			observeToolCall(tool, "mcp", failure)
+			return res, err
+		}
+	}
+}
+
+// toolResultText returns the text of a tool result's first text content.
+func toolResultText(r *sdkmcp.CallToolResult) string {
+	for _, c := range r.Content {
+		if t, ok := c.(*sdkmcp.TextContent); ok {
+			return t.Text
+		}
+	}
+	return ""
+}
+
+// hubCollector exports event hub counters, read from the hub at scrape time.
+type hubCollector struct{}
+
+func (hubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- eventsPublishedDesc
+	ch <- eventSubscribersDesc
+}
+
+func (hubCollector) Collect(ch chan<- prometheus.Metric) {
+	if eventHub == nil {
+		return
+	}
+	st := eventHub.Stats()
+	for typ, n := range st.Published {
+		ch <- prometheus.MustNewConstMetric(eventsPublishedDesc, prometheus.CounterValue, float64(n), typ)
+	}
+	for ws, n := range st.Subscribers {
+		ch <- prometheus.MustNewConstMetric(eventSubscribersDesc, prometheus.GaugeValue, float64(n), ws)
+	}
+	ch <- prometheus.MustNewConstMetric(eventSubscribersDesc, prometheus.GaugeValue, float64(st.AllSubscribers), "*")
+}
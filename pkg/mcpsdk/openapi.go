@@ -0,0 +1,220 @@
+package mcpsdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// restErrorStatuses documents the error prefixes mapped by httpStatusFromError.
+var restErrorStatuses = []struct {
+	status      string
+	description string
+}{
+	{"400", "INVALID_INPUT or OUT_OF_BOUNDS: the request was malformed or escaped the workspace"},
+	{"401", "UNAUTHORIZED: the bearer token is missing or invalid"},
+	{"403", "FORBIDDEN: the token's scope does not allow this tool"},
+	{"404", "NOT_FOUND: the workspace, path, or commit does not exist"},
+	{"409", "ALREADY_EXISTS or CONFLICT: the target exists or a precondition (e.g. etag) failed"},
+	{"408", "TIMEOUT: a waiting tool (fs_wait_for_change) saw no matching change in time"},
+	{"413", "TOO_LARGE or QUOTA_EXCEEDED: the result exceeds the server's maximum response size, or a write would take the workspace over its quota"},
+	{"422", "UNSUPPORTED: the operation is not supported for this input"},
+	{"429", "RATE_LIMITED: the client exceeded its request budget; retry after the Retry-After header's seconds"},
+	{"500", "Internal error"},
+}
+
+// restErrorSchema describes the body written by writeRESTError.
+var restErrorSchema = map[string]any{
+	"type":     "object",
+	"required": []string{"error"},
+	"properties": map[string]any{
+		"error": map[string]any{
+			"type":     "object",
+			"required": []string{"code", "message"},
+			"properties": map[string]any{
+				"code":    map[string]any{"type": "string"},
+				"message": map[string]any{"type": "string"},
+				"details": map[string]any{"type": "object"},
+			},
+		},
+	},
+}
+
+// listTools returns the tools registered on server, with the input/output schemas
+// the SDK inferred from their Go request/response types. It lists them over an
+// in-memory client session so the result always matches what MCP clients see.
+func listTools(ctx context.Context, server *sdkmcp.Server) ([]*sdkmcp.Tool, error) {
+	ct, st := sdkmcp.NewInMemoryTransports()
+	ss, err := server.Connect(ctx, st, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ss.Close()
+
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "tool-catalog", Version: "0.1.0"}, nil)
+	cs, err := client.Connect(ctx, ct, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cs.Close()
+
+	var tools []*sdkmcp.Tool
+	for tool, err := range cs.Tools(ctx, nil) {
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools, nil
+}
+
+// buildOpenAPI renders an OpenAPI 3.1 document describing POST /api/tools/{name}
+// for every tool registered on server, and GET for the tools in getTools.
+func buildOpenAPI(ctx context.Context, server *sdkmcp.Server) (map[string]any, error) {
+	tools, err := listTools(ctx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	errResponses := map[string]any{}
+	for _, e := range restErrorStatuses {
+		errResponses[e.status] = map[string]any{
+			"description": e.description,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": restErrorSchema},
+			},
+		}
+	}
+
+	paths := map[string]any{}
+	for _, tool := range tools {
+		okContent := map[string]any{"schema": map[string]any{}}
+		if tool.OutputSchema != nil {
+			okContent["schema"] = tool.OutputSchema
+		}
+		responses := map[string]any{
+			"200": map[string]any{
+				"description": "Success",
+				"content":     map[string]any{"application/json": okContent},
+			},
+		}
+		for status, resp := range errResponses {
+			responses[status] = resp
+		}
+		item := map[string]any{
+			"post": map[string]any{
+				"operationId": tool.Name,
+				"summary":     tool.Description,
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": tool.InputSchema},
+					},
+				},
+				"responses": responses,
+			},
+		}
+		if _, ok := getTools[tool.Name]; ok {
+			item["get"] = map[string]any{
+				"operationId": tool.Name + "_get",
+				"summary":     tool.Description,
+				"parameters":  queryParameters(tool),
+				"responses":   responses,
+			}
+		}
+		paths["/api/tools/"+tool.Name] = item
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "MCP Workspace Manager REST API",
+			"version":     "0.1.0",
+			"description": "REST mirror of the MCP tools. Each tool is invoked with POST /api/tools/{name} and a JSON body matching its input schema; some read-only tools also accept GET with query parameters.",
+		},
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []any{}}},
+		"paths":    paths,
+	}, nil
+}
+
+// queryParameters describes a tool's input properties as OpenAPI query parameters.
+func queryParameters(tool *sdkmcp.Tool) []any {
+	params := []any{}
+	if tool.InputSchema == nil {
+		return params
+	}
+	required := map[string]bool{}
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(tool.InputSchema.Properties))
+	for name := range tool.InputSchema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": required[name],
+			"schema":   tool.InputSchema.Properties[name],
+		})
+	}
+	return params
+}
+
+// toolCatalogEntry describes one tool in the GET /api/tools catalog.
+type toolCatalogEntry struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema any    `json:"inputSchema"`
+}
+
+// toolCatalogHandler serves GET /api/tools: the name, description and input
+// schema of every registered tool, as listed to MCP clients. Like the OpenAPI
+// document it is generated once and cached.
+func toolCatalogHandler(server *sdkmcp.Server) http.Handler {
+	tools, err := listTools(context.Background(), server)
+	catalog := make([]toolCatalogEntry, 0, len(tools))
+	for _, tool := range tools {
+		catalog = append(catalog, toolCatalogEntry{Name: tool.Name, Description: tool.Description, InputSchema: tool.InputSchema})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("INTERNAL: failed to list tools: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(catalog)
+	})
+}
+
+// openAPIHandler serves the OpenAPI document for the REST mirror.
+// The document is generated once from the registered tools and cached.
+func openAPIHandler(server *sdkmcp.Server) http.Handler {
+	doc, err := buildOpenAPI(context.Background(), server)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeRESTError(w, fmt.Errorf("METHOD_NOT_ALLOWED: %s is not allowed for the OpenAPI document", r.Method))
+			return
+		}
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("INTERNAL: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(doc)
+	})
+}
@@ -0,0 +1,71 @@
+package mcpsdk
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressInterval is the minimum time between progress notifications for one
+// tool call; the final notification (done == total) is always sent.
+const progressInterval = 100 * time.Millisecond
+
+type progressKey struct{}
+
+// progressReporter sends notifications/progress for the tool call whose
+// request carried token. A nil reporter (no token, or REST) reports nothing.
+type progressReporter struct {
+	session *sdkmcp.ServerSession
+	token   any
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// progressFromContext returns the reporter attached by mcpProgressMiddleware, or nil.
+func progressFromContext(ctx context.Context) *progressReporter {
+	p, _ := ctx.Value(progressKey{}).(*progressReporter)
+	return p
+}
+
+// report notifies the client that done of total items (0 when unknown) have
+// been processed. Notifications are throttled to one per progressInterval and
+// delivery errors are ignored: progress is advisory.
+func (p *progressReporter) report(ctx context.Context, done, total int, message string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	now := time.Now()
+	if now.Sub(p.last) < progressInterval && (total == 0 || done < total) {
+		p.mu.Unlock()
+		return
+	}
+	p.last = now
+	p.mu.Unlock()
+	_ = p.session.NotifyProgress(ctx, &sdkmcp.ProgressNotificationParams{
+		ProgressToken: p.token,
+		Progress:      float64(done),
+		Total:         float64(total),
+		Message:       message,
+	})
+}
+
+// mcpProgressMiddleware threads the progress token of a tools/call request
+// into the tool's context, so long-running tools (fs_directory_tree,
+// workspace_export, fs_copy_between_workspaces) can report progress.
+func mcpProgressMiddleware(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+	return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+		params, ok := req.GetParams().(*sdkmcp.CallToolParamsRaw)
+		session, isServer := req.GetSession().(*sdkmcp.ServerSession)
+		if ok && isServer && params != nil && params.GetProgressToken() != nil {
+			ctx = context.WithValue(ctx, progressKey{}, &progressReporter{session: session, token: params.GetProgressToken()})
+		}
+		return next(ctx, method, req)
+	}
+}
@@ -0,0 +1,84 @@
+package mcpsdk
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// maxIdleLimiters bounds the limiter map; beyond it, limiters that have refilled
+// completely (i.e. idle clients) are dropped.
+const maxIdleLimiters = 1024
+
+// rateLimiter keeps one token bucket per client key.
+type rateLimiter struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*rate.Limiter
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(perSecond)))
+	}
+	return &rateLimiter{
+		limit:    rate.Limit(perSecond),
+		burst:    burst,
+		limiters: map[string]*rate.Limiter{},
+	}
+}
+
+func (rl *rateLimiter) limiterFor(key string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if l, ok := rl.limiters[key]; ok {
+		return l
+	}
+	if len(rl.limiters) >= maxIdleLimiters {
+		for k, l := range rl.limiters {
+			if l.Tokens() >= float64(rl.burst) {
+				delete(rl.limiters, k)
+			}
+		}
+	}
+	l := rate.NewLimiter(rl.limit, rl.burst)
+	rl.limiters[key] = l
+	return l
+}
+
+// rateLimitKey identifies the client: its Bearer token, or its remote IP when
+// no token is sent (auth disabled).
+func rateLimitKey(r *http.Request) string {
+	if tok := bearerToken(r.Header); tok != "" {
+		return "token:" + tok
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// withRateLimit rejects requests over the client's budget with 429 and a
+// Retry-After header. A nil limiter disables rate limiting.
+func withRateLimit(next http.Handler, rl *rateLimiter) http.Handler {
+	if rl == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := rl.limiterFor(rateLimitKey(r)).Reserve()
+		if delay := res.Delay(); delay > 0 {
+			res.Cancel()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			writeRESTError(w, fmt.Errorf("RATE_LIMITED: too many requests"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,107 @@
+package mcpsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// workspaceRawHandler serves GET /api/workspaces/{id}/raw?path=..., streaming a
+// file's bytes with its sniffed Content-Type and its Content-Length (Range
+// requests are honoured). It has no size limit, unlike fs_read_media_file, so
+// browsers can fetch large media directly. The ETag is the file's sha256 (the
+// etag fs_read_text_file reports), so clients can revalidate with
+// If-None-Match. Files are never rendered as active content on the server's
+// origin: responses carry nosniff and a sandbox CSP, and HTML, SVG and XML are
+// sent as attachments.
+func workspaceRawHandler(wm *workspace.Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsID := r.PathValue("id")
+		rel := r.URL.Query().Get("path")
+		if rel == "" {
+			writeRESTError(w, fmt.Errorf("INVALID_INPUT: 'path' is required"))
+			return
+		}
+		params, _ := json.Marshal(map[string]string{"workspaceId": wsID, "path": rel})
+		if err := checkToolScope(scopeFromContext(r.Context()), "fs_read_media_file"); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if err := checkShareAccess(r.Context(), "fs_read_media_file", params); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if err := checkToolBackend(wm, "fs_read_media_file"); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if wm.Protected().IsProtectedPath(rel) {
+			writeRESTError(w, fmt.Errorf("NOT_FOUND: file not found"))
+			return
+		}
+		abs, err := wm.SafePath(wsID, rel)
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("OUT_OF_BOUNDS: %v", err))
+			return
+		}
+		f, err := os.Open(abs)
+		if err != nil {
+			if os.IsNotExist(err) {
+				writeRESTError(w, fmt.Errorf("NOT_FOUND: file not found"))
+				return
+			}
+			writeRESTError(w, fmt.Errorf("INTERNAL: failed to open file: %v", err))
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("INTERNAL: failed to stat file: %v", err))
+			return
+		}
+		if info.IsDir() {
+			writeRESTError(w, fmt.Errorf("INVALID_INPUT: path is a directory"))
+			return
+		}
+		mimeType, _, err := sniffFile(wm.FS(), abs)
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("INTERNAL: failed to read file: %v", err))
+			return
+		}
+		_, sum, err := hashFile(wm.FS(), abs)
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("INTERNAL: failed to read file: %v", err))
+			return
+		}
+		// A preset Content-Type keeps ServeContent from guessing by extension;
+		// with an ETag set it answers If-None-Match with 304 and checks If-Range.
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("ETag", `"`+sum+`"`)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		if isActiveContent(mimeType, info.Name()) {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
+
+// isActiveContent reports whether a browser could run scripts in a file, going
+// by its sniffed type or, since SVG often sniffs as text/plain, its extension.
+func isActiveContent(mimeType, name string) bool {
+	for _, t := range []string{mimeType, mime.TypeByExtension(filepath.Ext(name))} {
+		mt, _, _ := mime.ParseMediaType(t)
+		switch {
+		case mt == "text/html", mt == "text/xml", mt == "application/xml",
+			mt == "application/xhtml+xml", strings.HasSuffix(mt, "+xml"):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package mcpsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// readinessProbePrefix names the temp file /readyz creates in the workspaces
+// root. Files directly in the root are not workspaces, so neither listings
+// nor the fswatcher see it.
+const readinessProbePrefix = ".readyz-"
+
+type readinessCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string           `json:"status"` // "ok" or "unavailable"
+	Checks []readinessCheck `json:"checks"`
+}
+
+// readinessHandler serves /readyz: 200 when the workspaces root is a
+// writable directory and the event hub is open, 503 otherwise. Unlike
+// /healthz, which only shows the process is up, it tells orchestrators
+// whether requests can be served.
+func readinessHandler(wm *workspace.Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := readinessResponse{Status: "ok", Checks: []readinessCheck{
+			checkReady("workspaces_root", func() error { return checkWorkspacesRoot(wm.FS(), wm.RootPath()) }),
+			checkReady("event_hub", func() error {
+				if eventHub == nil || eventHub.Closed() {
+					return fmt.Errorf("event hub is closed")
+				}
+				return nil
+			}),
+		}}
+		status := http.StatusOK
+		for _, c := range out.Checks {
+			if !c.OK {
+				out.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(out)
+	})
+}
+
+// checkReady runs one readiness check under name.
+func checkReady(name string, check func() error) readinessCheck {
+	if err := check(); err != nil {
+		return readinessCheck{Name: name, Error: err.Error()}
+	}
+	return readinessCheck{Name: name, OK: true}
+}
+
+// checkWorkspacesRoot verifies that root is a directory of fsys new files can
+// be created in, by creating and removing a probe file.
+func checkWorkspacesRoot(fsys workspace.FS, root string) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	name := filepath.Join(root, fmt.Sprintf("%s%d", readinessProbePrefix, time.Now().UnixNano()))
+	if err := fsys.WriteFile(name, nil, 0600); err != nil {
+		return fmt.Errorf("workspaces root is not writable: %v", err)
+	}
+	if err := fsys.RemoveAll(name); err != nil {
+		return fmt.Errorf("failed to remove probe file: %v", err)
+	}
+	return nil
+}
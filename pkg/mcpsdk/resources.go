@@ -0,0 +1,197 @@
+package mcpsdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// Workspace files are exposed as MCP resources with URIs of the form
+// workspace://{workspaceId}/{path}, path segments being percent-escaped.
+const (
+	resourceScheme      = "workspace"
+	resourceURITemplate = resourceScheme + "://{workspaceId}/{+path}"
+)
+
+// resourcesPageSize is the number of resources returned per resources/list
+// page; nextCursor fetches the rest.
+const resourcesPageSize = 500
+
+// resourceURI returns the resource URI of the file at rel in workspaceID.
+func resourceURI(workspaceID, rel string) string {
+	segs := strings.Split(filepath.ToSlash(rel), "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	return resourceScheme + "://" + workspaceID + "/" + strings.Join(segs, "/")
+}
+
+// parseResourceURI splits a workspace:// URI into its workspace id and the
+// file path relative to the workspace root.
+func parseResourceURI(uri string) (workspaceID, rel string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != resourceScheme || u.Host == "" {
+		return "", "", fmt.Errorf("not a %s:// URI: %s", resourceScheme, uri)
+	}
+	rel = strings.TrimPrefix(u.Path, "/")
+	if rel == "" {
+		return "", "", fmt.Errorf("resource URI %s names no file", uri)
+	}
+	return u.Host, filepath.FromSlash(rel), nil
+}
+
+// addWorkspaceResources registers the workspace file resource template and
+// the resources/list handler that enumerates files, which the SDK cannot do
+// for a template on its own.
+func addWorkspaceResources(server *sdkmcp.Server, wm *workspace.Manager) {
+	server.AddResourceTemplate(&sdkmcp.ResourceTemplate{
+		Name:        "workspace-file",
+		Title:       "Workspace file",
+		Description: "A file in a workspace; .git and .gitkeep are not exposed",
+		URITemplate: resourceURITemplate,
+	}, readWorkspaceResource(wm))
+	server.AddReceivingMiddleware(resourceListMiddleware(wm))
+}
+
+// readWorkspaceResource serves resources/read for workspace files, returning
+// text for UTF-8 content and a blob otherwise, with the sniffed MIME type.
+// Files larger than the fs_read_media_file cap are refused.
+func readWorkspaceResource(wm *workspace.Manager) sdkmcp.ResourceHandler {
+	return func(ctx context.Context, req *sdkmcp.ReadResourceRequest) (*sdkmcp.ReadResourceResult, error) {
+		uri := req.Params.URI
+		wsID, rel, err := parseResourceURI(uri)
+		if err != nil || wm.Protected().IsProtectedPath(rel) {
+			return nil, sdkmcp.ResourceNotFoundError(uri)
+		}
+		abs, err := wm.SafePath(wsID, rel)
+		if err != nil {
+			return nil, sdkmcp.ResourceNotFoundError(uri)
+		}
+		info, err := os.Stat(abs)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, sdkmcp.ResourceNotFoundError(uri)
+		}
+		if info.Size() > maxMediaBytes {
+			return nil, fmt.Errorf("TOO_LARGE: %s is %d bytes, over the %d-byte limit", uri, info.Size(), maxMediaBytes)
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("INTERNAL: failed to read %s: %v", uri, err)
+		}
+		contents := &sdkmcp.ResourceContents{URI: uri, MIMEType: http.DetectContentType(data)}
+		if bytes.IndexByte(data[:min(len(data), sniffLen)], 0) < 0 && utf8.Valid(data) {
+			contents.Text = string(data)
+		} else {
+			contents.Blob = data
+		}
+		return &sdkmcp.ReadResourceResult{Contents: []*sdkmcp.ResourceContents{contents}}, nil
+	}
+}
+
+// resourceListMiddleware answers resources/list with every file of every
+// workspace (or only the shared one for share link tokens), sorted by
+// workspace id and path and paged by resourcesPageSize. The cursor is the
+// offset of the next page.
+func resourceListMiddleware(wm *workspace.Manager) sdkmcp.Middleware {
+	return func(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+		return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+			if method != "resources/list" {
+				return next(ctx, method, req)
+			}
+			offset := 0
+			if params, ok := req.GetParams().(*sdkmcp.ListResourcesParams); ok && params != nil && params.Cursor != "" {
+				n, err := strconv.Atoi(params.Cursor)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("INVALID_INPUT: invalid cursor %q", params.Cursor)
+				}
+				offset = n
+			}
+			resources, err := listWorkspaceResources(ctx, wm)
+			if err != nil {
+				return nil, err
+			}
+			out := &sdkmcp.ListResourcesResult{Resources: resources[min(offset, len(resources)):]}
+			if len(out.Resources) > resourcesPageSize {
+				out.Resources = out.Resources[:resourcesPageSize]
+				out.NextCursor = strconv.Itoa(offset + resourcesPageSize)
+			}
+			return out, nil
+		}
+	}
+}
+
+// listWorkspaceResources enumerates the regular files of the visible
+// workspaces, skipping .git, .gitkeep and symlinks.
+func listWorkspaceResources(ctx context.Context, wm *workspace.Manager) ([]*sdkmcp.Resource, error) {
+	workspaces, err := wm.List()
+	if err != nil {
+		return nil, fmt.Errorf("INTERNAL: failed to list workspaces: %v", err)
+	}
+	shared, isShare := ctx.Value(shareKey{}).(string)
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	out := []*sdkmcp.Resource{}
+	for _, w := range workspaces {
+		if isShare && w.Name != shared {
+			continue
+		}
+		root, err := wm.SafePath(w.Name, ".")
+		if err != nil {
+			continue
+		}
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if p != root && wm.Protected().IsProtectedIn(root, p) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			out = append(out, &sdkmcp.Resource{
+				URI:   resourceURI(w.Name, rel),
+				Name:  w.Name + "/" + filepath.ToSlash(rel),
+				Title: filepath.Base(rel),
+				Size:  info.Size(),
+			})
+			return nil
+		})
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("INTERNAL: failed to list files of %s: %v", w.Name, err)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,98 @@
+package mcpsdk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// getTools lists the read-only tools that may also be invoked with
+// GET /api/tools/{toolName}?param=value, mapped to their request types.
+// All other tools are POST-only.
+var getTools = map[string]reflect.Type{
+	"workspace_list":    reflect.TypeOf(ListWorkspacesRequest{}),
+	"fs_read_text_file": reflect.TypeOf(ReadFileRequest{}),
+	"fs_read_lines":     reflect.TypeOf(ReadLinesRequest{}),
+	"fs_list_directory": reflect.TypeOf(ListDirectoryRequest{}),
+	"fs_get_file_info":  reflect.TypeOf(GetFileInfoRequest{}),
+	"fs_stat_multiple":  reflect.TypeOf(StatMultipleRequest{}),
+	"fs_directory_tree": reflect.TypeOf(DirectoryTreeRequest{}),
+	"workspace_status":  reflect.TypeOf(WorkspaceStatusRequest{}),
+}
+
+// queryParams converts query string values into a JSON object for the request
+// type typ, so GET requests go through the same dispatch as POST bodies. Values
+// are converted according to the field's type; slice fields take repeated
+// parameters (?excludePatterns=a&excludePatterns=b). Unknown parameters are ignored.
+func queryParams(q url.Values, typ reflect.Type) (json.RawMessage, error) {
+	obj := map[string]any{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		values, ok := q[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.String:
+			obj[name] = values[0]
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.Atoi(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("INVALID_INPUT: query parameter %q must be an integer", name)
+			}
+			obj[name] = n
+		case reflect.Bool:
+			b, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("INVALID_INPUT: query parameter %q must be a boolean", name)
+			}
+			obj[name] = b
+		case reflect.Slice:
+			if ft.Elem().Kind() != reflect.String {
+				return nil, fmt.Errorf("INVALID_INPUT: query parameter %q is not supported with GET", name)
+			}
+			obj[name] = values
+		default:
+			return nil, fmt.Errorf("INVALID_INPUT: query parameter %q is not supported with GET", name)
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// responseETag returns the HTTP entity tag for the result of a GET tool call,
+// or "" when the result has none. fs_read_text_file results are tagged with a
+// hash of the whole file's hash, its mtime and the workspace HEAD, the fields
+// of the body that can change between two reads of the same URL, so a matching
+// If-None-Match means the body would be unchanged.
+func responseETag(out any) string {
+	if r, ok := out.(ReadFileResponse); ok && r.Etag != "" {
+		sum := sha256.Sum256([]byte(r.Etag + "\x00" + r.Mtime + "\x00" + r.WorkspaceHead))
+		return `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	return ""
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package mcpsdk
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Token scopes. A token configured as "secret:ro" may only call read-only tools;
+// "secret:rw" (or a bare "secret") may call everything.
+const (
+	scopeReadOnly  = "ro"
+	scopeReadWrite = "rw"
+)
+
+// readOnlyTools lists tools that never modify a workspace. Anything not listed is
+// treated as mutating, so new tools are denied to read-only tokens by default.
+var readOnlyTools = map[string]bool{
+	"workspace_list":               true,
+	"workspace_diff":               true,
+	"fs_read_text_file":            true,
+	"fs_read_lines":                true,
+	"fs_list_directory":            true,
+	"fs_get_file_info":             true,
+	"fs_stat_multiple":             true,
+	"fs_get_commit_history":        true,
+	"fs_read_multiple_files":       true,
+	"fs_list_directory_with_sizes": true,
+	"fs_search_files":              true,
+	"fs_directory_tree":            true,
+	"fs_read_media_file":           true,
+	"fs_read_file_at_commit":       true,
+	"fs_diff_files":                true,
+	"fs_estimate_read":             true,
+	"fs_search_and_read":           true,
+	"fs_manifest":                  true,
+	"fs_get_directory_size":        true,
+	"workspace_find_files":         true,
+	"fs_merge_content":             true,
+	"fs_wait_for_change":           true,
+	"workspace_export":             true,
+	"workspace_info":               true,
+	"workspace_status":             true,
+	"workspace_list_tags":          true,
+}
+
+type authToken struct {
+	value []byte
+	scope string
+}
+
+// parseAuthTokens splits "token[:ro|:rw]" entries into tokens and scopes, skipping
+// blanks. If the same token is listed with different scopes, read-only wins.
+func parseAuthTokens(raw []string) []authToken {
+	var out []authToken
+	index := map[string]int{}
+	for _, t := range raw {
+		t = strings.TrimSpace(t)
+		scope := scopeReadWrite
+		if i := strings.LastIndex(t, ":"); i >= 0 {
+			switch suffix := t[i+1:]; suffix {
+			case scopeReadOnly, scopeReadWrite:
+				scope = suffix
+				t = t[:i]
+			}
+		}
+		if t == "" {
+			continue
+		}
+		if i, ok := index[t]; ok {
+			if scope == scopeReadOnly {
+				out[i].scope = scopeReadOnly
+			}
+			continue
+		}
+		index[t] = len(out)
+		out = append(out, authToken{value: []byte(t), scope: scope})
+	}
+	return out
+}
+
+// tokenValues returns the bare token strings (without scopes).
+func tokenValues(tokens []authToken) []string {
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		out[i] = string(t.value)
+	}
+	return out
+}
+
+// lookupScope returns the scope granted to token, comparing in constant time.
+func lookupScope(tokens []authToken, token string) (string, bool) {
+	got := []byte(token)
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare(got, t.value) == 1 {
+			return t.scope, true
+		}
+	}
+	return "", false
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+// (case-insensitive scheme), or "" if absent.
+func bearerToken(h http.Header) string {
+	parts := strings.SplitN(strings.TrimSpace(h.Get("Authorization")), " ", 2)
+	if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+		return strings.TrimSpace(parts[1])
+	}
+	return ""
+}
+
+type scopeKey struct{}
+
+func withScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// scopeFromContext returns the scope granted by wrapAuth; without auth every
+// request is read-write.
+func scopeFromContext(ctx context.Context) string {
+	if s, ok := ctx.Value(scopeKey{}).(string); ok {
+		return s
+	}
+	return scopeReadWrite
+}
+
+// checkToolScope rejects mutating tools for read-only scopes.
+func checkToolScope(scope, toolName string) error {
+	if scope == scopeReadOnly && !readOnlyTools[toolName] {
+		return fmt.Errorf("FORBIDDEN: read-only token cannot call %s", toolName)
+	}
+	return nil
+}
+
+// scopeMiddleware enforces token scopes for MCP tool calls, and restricts share
+// link tokens to their workspace's resources. The SDK does not carry the HTTP
+// request context into handlers, so the token is re-read from the request
+// headers it forwards.
+func scopeMiddleware(tokens []authToken) sdkmcp.Middleware {
+	return func(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+		return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+			if len(tokens) == 0 {
+				return next(ctx, method, req)
+			}
+			if method == "resources/list" || method == "resources/read" {
+				return shareResourceAccess(ctx, tokens, method, req, next)
+			}
+			if method != "tools/call" {
+				return next(ctx, method, req)
+			}
+			params, ok := req.GetParams().(*sdkmcp.CallToolParamsRaw)
+			extra := req.GetExtra()
+			if !ok || extra == nil {
+				return next(ctx, method, req)
+			}
+			token := bearerToken(extra.Header)
+			scope, found := lookupScope(tokens, token)
+			if !found {
+				scope = scopeReadOnly
+			}
+			if err := checkToolScope(scope, params.Name); err != nil {
+				return nil, err
+			}
+			if !found {
+				if claims, ok := shareLinks.verify(token); ok {
+					if err := checkShareWorkspace(claims.WorkspaceID, params.Name, params.Arguments); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// shareResourceAccess limits resources/list to the shared workspace and
+// rejects resources/read outside it when the request carries a share link
+// token; other tokens see every workspace.
+func shareResourceAccess(ctx context.Context, tokens []authToken, method string, req sdkmcp.Request, next sdkmcp.MethodHandler) (sdkmcp.Result, error) {
+	extra := req.GetExtra()
+	if extra == nil {
+		return next(ctx, method, req)
+	}
+	token := bearerToken(extra.Header)
+	if _, found := lookupScope(tokens, token); found {
+		return next(ctx, method, req)
+	}
+	claims, ok := shareLinks.verify(token)
+	if !ok {
+		return next(ctx, method, req)
+	}
+	if params, ok := req.GetParams().(*sdkmcp.ReadResourceParams); ok && params != nil {
+		if wsID, _, err := parseResourceURI(params.URI); err == nil && wsID != claims.WorkspaceID {
+			return nil, fmt.Errorf("FORBIDDEN: share link only grants access to workspace %q (%s)", claims.WorkspaceID, method)
+		}
+	}
+	return next(withShareWorkspace(ctx, claims.WorkspaceID), method, req)
+}
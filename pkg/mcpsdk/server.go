@@ -2,15 +2,19 @@ package mcpsdk
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"time"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"mcp-workspace-manager/pkg/events"
 	"mcp-workspace-manager/pkg/workspace"
 )
 
@@ -20,7 +24,23 @@ func newTool(name, description string) *sdkmcp.Tool {
 	if !toolNameRegex.MatchString(name) {
 		panic(fmt.Errorf("invalid tool name: %s (must match ^[a-zA-Z0-9_-]+$)", name))
 	}
-	return &sdkmcp.Tool{Name: name, Description: description}
+	tool := &sdkmcp.Tool{Name: name, Description: description}
+	if readOnlyTools[name] {
+		tool.Annotations = &sdkmcp.ToolAnnotations{ReadOnlyHint: true}
+	}
+	return tool
+}
+
+// jsonSetInputSchema is the schema inferred for JSONSetRequest, except that value
+// accepts any JSON value: inferred from json.RawMessage it would be an array of
+// bytes.
+func jsonSetInputSchema() *jsonschema.Schema {
+	s, err := jsonschema.For[JSONSetRequest](nil)
+	if err != nil {
+		panic(err)
+	}
+	s.Properties["value"] = &jsonschema.Schema{}
+	return s
 }
 
 // ===== Workspace tool types =====
@@ -34,15 +54,83 @@ type CreateWorkspaceResponse struct {
 	Path        string `json:"path"`
 }
 
-type ListWorkspacesRequest struct{}
+type ListWorkspacesRequest struct {
+	Limit        int    `json:"limit,omitempty"`        // max workspaces returned; 0 = all
+	Offset       int    `json:"offset,omitempty"`       // workspaces skipped (after filtering and sorting)
+	NameContains string `json:"nameContains,omitempty"` // case-insensitive match on id or display name
+}
 
 type WorkspaceInfo struct {
-	Name string `json:"name"`
-	Path string `json:"path"`
+	Name              string `json:"name"` // workspace id
+	Path              string `json:"path"`
+	DisplayName       string `json:"displayName,omitempty"`
+	HeadCommit        string `json:"headCommit"`        // empty when the workspace has no commits
+	LastModified      string `json:"lastModified"`      // RFC3339 committer time of the newest commit; empty when none
+	LastCommitMessage string `json:"lastCommitMessage"` // first line of the newest commit's message
 }
 
 type ListWorkspacesResponse struct {
 	Workspaces []WorkspaceInfo `json:"workspaces"`
+	Total      int             `json:"total"` // matching workspaces before limit/offset
+}
+
+type RenameWorkspaceRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Name        string `json:"name"` // new display name; the new id is its slug
+}
+type RenameWorkspaceResponse struct {
+	WorkspaceID     string `json:"workspaceId"` // new id
+	PrevWorkspaceID string `json:"prevWorkspaceId"`
+	Path            string `json:"path"`
+}
+
+type WorkspaceInfoRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+}
+type WorkspaceInfoResponse struct {
+	WorkspaceID  string `json:"workspaceId"`
+	DisplayName  string `json:"displayName,omitempty"`
+	Files        int    `json:"files"`       // excluding .git and .gitkeep
+	Directories  int    `json:"directories"` // excluding .git
+	CombinedSize int64  `json:"combinedSize"`
+	HeadCommit   string `json:"headCommit"` // empty when the workspace has no commits
+	Branch       string `json:"branch"`     // empty when HEAD is detached
+	CommitCount  int    `json:"commitCount"`
+	CreatedAt    string `json:"createdAt,omitempty"` // RFC3339, from workspace metadata
+}
+
+type WorkspaceStatusRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+}
+type PendingFile struct {
+	Path   string `json:"path"`
+	Status string `json:"status"` // untracked, added, modified, deleted, renamed, copied or unmerged
+	Staged bool   `json:"staged,omitempty"`
+}
+type WorkspaceStatusResponse struct {
+	Clean bool          `json:"clean"`
+	Files []PendingFile `json:"files"`
+}
+
+type CreateFromTemplateRequest struct {
+	Name     string `json:"name"`
+	Template string `json:"template"` // subdirectory of --templates-dir
+}
+type CreateFromTemplateResponse struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Commit      string `json:"commit,omitempty"` // empty when the template has no files
+}
+
+type CloneWorkspaceRequest struct {
+	WorkspaceID string `json:"workspaceId"` // workspace to clone
+	Name        string `json:"name"`        // display name of the clone; its id is derived as in workspace_create
+}
+type CloneWorkspaceResponse struct {
+	WorkspaceID       string `json:"workspaceId"`
+	SourceWorkspaceID string `json:"sourceWorkspaceId"`
+	Path              string `json:"path"`
+	HeadCommit        string `json:"headCommit"` // shared with the source
 }
 
 // ===== FS tool types =====
@@ -51,14 +139,50 @@ type WriteFileRequest struct {
 	WorkspaceID          string  `json:"workspaceId"`
 	Path                 string  `json:"path"`
 	Content              string  `json:"content"`
+	AuthorName           string  `json:"authorName,omitempty"`  // commit author; default "mcp-client"
+	AuthorEmail          string  `json:"authorEmail,omitempty"` // default "mcp-server@localhost"
 	IfMatchFileEtag      *string `json:"ifMatchFileEtag,omitempty"`
 	IfMatchWorkspaceHead *string `json:"ifMatchWorkspaceHead,omitempty"`
+	// NormalizeNewlines converts CRLF and lone CR line endings to LF.
+	NormalizeNewlines bool `json:"normalizeNewlines,omitempty"`
+	// EnsureTrailingNewline appends "\n" to non-empty content lacking one.
+	EnsureTrailingNewline bool `json:"ensureTrailingNewline,omitempty"`
+	// DryRun returns the diff against the current file and the bytes that
+	// would be written, without writing or committing.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Encoding stores the content as utf-8, utf-16le or utf-16be (with a
+	// byte order mark), latin1 or windows-1252; "auto" keeps the encoding of
+	// the file being replaced.
+	Encoding string `json:"encoding,omitempty"`
 }
 type WriteFileResponse struct {
+	DryRun       bool   `json:"dryRun,omitempty"`
 	Path         string `json:"path"`
-	BytesWritten int    `json:"bytesWritten"`
+	BytesWritten int    `json:"bytesWritten"` // with dryRun: the bytes that would be written
 	Overwritten  bool   `json:"overwritten"`
 	Commit       string `json:"commit"`
+	Diff         string `json:"diff,omitempty"` // with dryRun
+}
+
+type WriteFilesRequest struct {
+	WorkspaceID string      `json:"workspaceId"`
+	Files       []FileWrite `json:"files"`
+	AuthorName  string      `json:"authorName,omitempty"`  // commit author; default "mcp-client"
+	AuthorEmail string      `json:"authorEmail,omitempty"` // default "mcp-server@localhost"
+}
+type FileWrite struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+type WriteFilesResponse struct {
+	Files  []WrittenFile `json:"files"`  // in request order
+	Commit string        `json:"commit"` // shared by every written file; empty when nothing changed
+}
+type WrittenFile struct {
+	Path         string `json:"path"`
+	BytesWritten int    `json:"bytesWritten"`
+	Overwritten  bool   `json:"overwritten"`
+	Unchanged    bool   `json:"unchanged,omitempty"` // content already matched; not written
 }
 
 type ReadFileRequest struct {
@@ -66,20 +190,53 @@ type ReadFileRequest struct {
 	Path        string `json:"path"`
 	Head        *int   `json:"head,omitempty"`
 	Tail        *int   `json:"tail,omitempty"`
+	MaxBytes    int64  `json:"maxBytes,omitempty"` // read at most this many bytes; default and cap --max-read-bytes
+	// Encoding decodes the file from utf-8, utf-16le, utf-16be, latin1 or
+	// windows-1252, or "auto" to detect it from a byte order mark. Raw bytes
+	// are returned when empty.
+	Encoding string `json:"encoding,omitempty"`
+	// Conditional read: the content is omitted (notModified) when the file's
+	// etag matches IfNoneMatchEtag or, without one, when it has not been
+	// modified after IfModifiedSince (RFC3339).
+	IfNoneMatchEtag string `json:"ifNoneMatchEtag,omitempty"`
+	IfModifiedSince string `json:"ifModifiedSince,omitempty"`
 }
 type ReadFileResponse struct {
 	Content       string `json:"content"`
 	TotalLines    int    `json:"totalLines,omitempty"`
 	Head          *int   `json:"head,omitempty"`
 	Tail          *int   `json:"tail,omitempty"`
-	Etag          string `json:"etag,omitempty"`
+	Etag          string `json:"etag,omitempty"`      // sha256 of the whole file
+	SliceEtag     string `json:"sliceEtag,omitempty"` // sha256 of the returned content only
 	Mtime         string `json:"mtime,omitempty"`
 	WorkspaceHead string `json:"workspaceHead,omitempty"`
+	Size          int64  `json:"size"`                  // size of the whole file in bytes
+	Truncated     bool   `json:"truncated,omitempty"`   // content was cut at maxBytes
+	Encoding      string `json:"encoding,omitempty"`    // the encoding decoded from, when requested
+	NotModified   bool   `json:"notModified,omitempty"` // a conditional read matched; content is empty
+}
+
+type ReadLinesRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	StartLine   int    `json:"startLine,omitempty"` // 1-based; default 1
+	Count       int    `json:"count,omitempty"`     // default: to the end of the file
+}
+type NumberedLine struct {
+	LineNumber int    `json:"lineNumber"`
+	Text       string `json:"text"` // without the line ending
+}
+type ReadLinesResponse struct {
+	Lines      []NumberedLine `json:"lines"`
+	TotalLines int            `json:"totalLines"`
+	Truncated  bool           `json:"truncated,omitempty"` // the window was cut at --max-read-bytes
 }
 
 type CreateDirectoryRequest struct {
 	WorkspaceID string `json:"workspaceId"`
 	Path        string `json:"path"`
+	AuthorName  string `json:"authorName,omitempty"`  // commit author; default "mcp-client"
+	AuthorEmail string `json:"authorEmail,omitempty"` // default "mcp-server@localhost"
 }
 type CreateDirectoryResponse struct {
 	Path    string `json:"path"`
@@ -104,12 +261,34 @@ type GetFileInfoResponse struct {
 	Mtime       string `json:"mtime"`
 	Type        string `json:"type"`
 	Permissions string `json:"permissions"`
+	MimeType    string `json:"mimeType,omitempty"` // sniffed from the first 512 bytes; files only
+	IsBinary    bool   `json:"isBinary,omitempty"` // the sniffed prefix contains a NUL byte
+}
+
+type StatMultipleRequest struct {
+	WorkspaceID string   `json:"workspaceId"`
+	Paths       []string `json:"paths"`
+}
+
+// FileStatResult is one fs_stat_multiple entry: the fs_get_file_info fields
+// when ok, the error otherwise.
+type FileStatResult struct {
+	Path string `json:"path"`
+	OK   bool   `json:"ok"`
+	*GetFileInfoResponse
+	Error *string `json:"error,omitempty"`
+}
+type StatMultipleResponse struct {
+	Results []FileStatResult `json:"results"`
 }
 
 type GetCommitHistoryRequest struct {
 	WorkspaceID string `json:"workspaceId"`
 	Path        string `json:"path,omitempty"`
 	Limit       int    `json:"limit,omitempty"`
+	Before      string `json:"before,omitempty"` // cursor: return commits older than this commit hash
+	Since       string `json:"since,omitempty"`  // RFC3339; only commits authored at or after this time
+	Until       string `json:"until,omitempty"`  // RFC3339; only commits authored at or before this time
 }
 type CommitLog struct {
 	Commit  string `json:"commit"`
@@ -119,17 +298,22 @@ type CommitLog struct {
 	Parent  string `json:"parent,omitempty"`
 }
 type GetCommitHistoryResponse struct {
-	Log []CommitLog `json:"log"`
+	Log        []CommitLog `json:"log"`
+	NextCursor string      `json:"nextCursor,omitempty"` // pass as 'before' to fetch the next page
 }
 
 type MoveFileRequest struct {
 	WorkspaceID string `json:"workspaceId"`
 	Source      string `json:"source"`
 	Destination string `json:"destination"`
+	Overwrite   bool   `json:"overwrite,omitempty"`   // replace an existing destination file
+	AuthorName  string `json:"authorName,omitempty"`  // commit author; default "mcp-client"
+	AuthorEmail string `json:"authorEmail,omitempty"` // default "mcp-server@localhost"
 }
 type MoveFileResponse struct {
 	Source      string `json:"source"`
 	Destination string `json:"destination"`
+	Overwritten bool   `json:"overwritten"`
 	Commit      string `json:"commit"`
 }
 
@@ -142,31 +326,43 @@ type EditFileRequest struct {
 	Path                 string  `json:"path"`
 	Edits                []Edit  `json:"edits"`
 	DryRun               bool    `json:"dryRun"`
+	AllowNoMatch         bool    `json:"allowNoMatch,omitempty"`      // skip edits whose oldText does not occur instead of failing
+	ExpectSingleMatch    bool    `json:"expectSingleMatch,omitempty"` // fail unless each oldText occurs exactly once
+	IncludeDiff          bool    `json:"includeDiff,omitempty"`       // return the applied change's diff (dry runs always do)
+	AuthorName           string  `json:"authorName,omitempty"`        // commit author; default "mcp-client"
+	AuthorEmail          string  `json:"authorEmail,omitempty"`       // default "mcp-server@localhost"
 	IfMatchFileEtag      *string `json:"ifMatchFileEtag,omitempty"`
 	IfMatchWorkspaceHead *string `json:"ifMatchWorkspaceHead,omitempty"`
 }
 type EditFileDryRunResponse struct {
-	DryRun  bool   `json:"dryRun"`
-	Diff    string `json:"diff"`
-	Matches int    `json:"matches"`
+	DryRun      bool   `json:"dryRun"`
+	Diff        string `json:"diff"`
+	Matches     int    `json:"matches"`
+	EditMatches []int  `json:"editMatches"` // occurrences replaced per edit, in request order
 }
 type EditFileResponse struct {
 	DryRun       bool   `json:"dryRun"`
 	Path         string `json:"path"`
 	Changes      int    `json:"changes"`
+	EditMatches  []int  `json:"editMatches"`
 	BytesWritten int    `json:"bytesWritten"`
 	Commit       string `json:"commit"`
+	Diff         string `json:"diff,omitempty"` // with includeDiff
 }
 
 type ReadMultipleFilesRequest struct {
-	WorkspaceID string   `json:"workspaceId"`
-	Paths       []string `json:"paths"`
+	WorkspaceID    string   `json:"workspaceId"`
+	Paths          []string `json:"paths"`
+	MaxConcurrency int      `json:"maxConcurrency,omitempty"` // files read at once; default 4, capped at 8
+	MaxBytes       int64    `json:"maxBytes,omitempty"`       // per file, as for fs_read_text_file
 }
 type FileReadResult struct {
-	Path    string  `json:"path"`
-	OK      bool    `json:"ok"`
-	Content *string `json:"content,omitempty"`
-	Error   *string `json:"error,omitempty"`
+	Path      string  `json:"path"`
+	OK        bool    `json:"ok"`
+	Content   *string `json:"content,omitempty"`
+	Error     *string `json:"error,omitempty"`
+	Size      int64   `json:"size,omitempty"`
+	Truncated bool    `json:"truncated,omitempty"`
 }
 type ReadMultipleFilesResponse struct {
 	Results []FileReadResult `json:"results"`
@@ -193,24 +389,36 @@ type ListDirectoryWithSizesResponse struct {
 }
 
 type SearchFilesRequest struct {
-	WorkspaceID     string   `json:"workspaceId"`
-	Path            string   `json:"path"`
-	Pattern         string   `json:"pattern"`
-	ExcludePatterns []string `json:"excludePatterns,omitempty"`
+	WorkspaceID      string   `json:"workspaceId"`
+	Path             string   `json:"path"`
+	Pattern          string   `json:"pattern"`
+	ExcludePatterns  []string `json:"excludePatterns,omitempty"`
+	RespectGitignore bool     `json:"respectGitignore,omitempty"` // skip paths ignored by the workspace's .gitignore files
+	MaxResults       int      `json:"maxResults,omitempty"`       // stop after this many matches; 0 means unlimited
+	TimeoutMs        int      `json:"timeoutMs,omitempty"`        // fail with TIMEOUT when the walk takes longer; max 300000
 }
 type SearchFilesResponse struct {
-	Matches []string `json:"matches"`
+	Matches   []string `json:"matches"`
+	Truncated bool     `json:"truncated,omitempty"` // more files matched than maxResults
 }
 
 type DirectoryTreeRequest struct {
-	WorkspaceID     string   `json:"workspaceId"`
-	Path            string   `json:"path"`
-	ExcludePatterns []string `json:"excludePatterns,omitempty"`
+	WorkspaceID      string   `json:"workspaceId"`
+	Path             string   `json:"path"`
+	ExcludePatterns  []string `json:"excludePatterns,omitempty"`
+	MaxDepth         int      `json:"maxDepth,omitempty"`         // levels to descend; 0 means unlimited
+	DirsOnly         bool     `json:"dirsOnly,omitempty"`         // omit files
+	RespectGitignore bool     `json:"respectGitignore,omitempty"` // skip paths ignored by the workspace's .gitignore files
+	IncludeStats     bool     `json:"includeStats,omitempty"`     // add size and mtime to files, childCount to directories
 }
 type TreeNode struct {
-	Name     string      `json:"name"`
-	Type     string      `json:"type"`
-	Children *[]TreeNode `json:"children,omitempty"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Children   *[]TreeNode `json:"children,omitempty"`
+	Truncated  bool        `json:"truncated,omitempty"`  // directory at maxDepth; children not listed
+	Size       *int64      `json:"size,omitempty"`       // with includeStats, files only
+	Mtime      string      `json:"mtime,omitempty"`      // with includeStats, files only
+	ChildCount *int        `json:"childCount,omitempty"` // with includeStats, directories only; counted even when truncated
 }
 type DirectoryTreeResponse struct {
 	Tree []TreeNode `json:"tree"`
@@ -226,14 +434,111 @@ type ReadMediaFileResponse struct {
 	Size     int64  `json:"size"`
 }
 
+type SetMtimeRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Mtime       string `json:"mtime"` // RFC3339
+}
+type SetMtimeResponse struct {
+	Path  string `json:"path"`
+	Mtime string `json:"mtime"`
+}
+
+type ChmodRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Mode        string `json:"mode"`                  // octal permission bits, e.g. "0755"
+	AuthorName  string `json:"authorName,omitempty"`  // commit author; default "mcp-client"
+	AuthorEmail string `json:"authorEmail,omitempty"` // default "mcp-server@localhost"
+}
+type ChmodResponse struct {
+	Path        string `json:"path"`
+	Mode        string `json:"mode"`        // applied mode as 4-digit octal
+	Permissions string `json:"permissions"` // as reported by fs_get_file_info
+	Commit      string `json:"commit"`      // empty when git saw no change (it only tracks the executable bit)
+}
+
 type DeleteFileRequest struct {
 	WorkspaceID string `json:"workspaceId"`
 	Path        string `json:"path"`
+	SoftDelete  bool   `json:"softDelete,omitempty"`  // move to the workspace trash instead of deleting
+	AuthorName  string `json:"authorName,omitempty"`  // commit author; default "mcp-client"
+	AuthorEmail string `json:"authorEmail,omitempty"` // default "mcp-server@localhost"
 }
 
 type DeleteFileResponse struct {
+	Path    string `json:"path"`
+	Commit  string `json:"commit"`
+	TrashID string `json:"trashId,omitempty"` // with softDelete: the id fs_restore_from_trash takes
+}
+
+type RestoreFromTrashRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	TrashID     string `json:"trashId,omitempty"` // as returned by a soft fs_delete_file
+	Path        string `json:"path,omitempty"`    // without trashId: restore the latest deletion of this path
+	AuthorName  string `json:"authorName,omitempty"`
+	AuthorEmail string `json:"authorEmail,omitempty"`
+}
+type RestoreFromTrashResponse struct {
+	Path    string `json:"path"`
+	TrashID string `json:"trashId"`
+	Commit  string `json:"commit"`
+}
+
+type WorkspaceDiffRequest struct {
+	WorkspaceID  string `json:"workspaceId"`
+	From         string `json:"from"`
+	To           string `json:"to,omitempty"`
+	IncludePatch bool   `json:"includePatch,omitempty"`
+}
+type ChangedFile struct {
 	Path   string `json:"path"`
-	Commit string `json:"commit"`
+	Action string `json:"action"` // "added" | "modified" | "deleted"
+	Patch  string `json:"patch,omitempty"`
+}
+type WorkspaceDiffResponse struct {
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Changes []ChangedFile `json:"changes"`
+}
+
+type WorkspaceRevertRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Commit      string `json:"commit"`
+}
+type WorkspaceRevertResponse struct {
+	Commit     string        `json:"commit"` // new HEAD
+	RevertedTo string        `json:"revertedTo"`
+	Changes    []ChangedFile `json:"changes"`
+}
+
+type UndoLastCommitRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Mode        string `json:"mode,omitempty"` // "soft" (default) keeps the working tree; "hard" discards the changes
+}
+type UndoLastCommitResponse struct {
+	Commit  string        `json:"commit"` // new HEAD, the parent of the undone commit
+	Undone  string        `json:"undone"` // the commit HEAD pointed at before
+	Mode    string        `json:"mode"`
+	Changes []ChangedFile `json:"changes"` // files restored on disk (hard only)
+}
+
+type CreateTagRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Name        string `json:"name"`
+	Message     string `json:"message,omitempty"` // creates an annotated tag when set
+	Commit      string `json:"commit,omitempty"`  // any revision; default HEAD
+}
+type TagInfo struct {
+	Name    string `json:"name"`
+	Commit  string `json:"commit"`
+	Message string `json:"message,omitempty"`
+}
+type ListTagsRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+}
+type ListTagsResponse struct {
+	Tags []TagInfo `json:"tags"`
 }
 
 type ReadFileAtCommitRequest struct {
@@ -246,6 +551,230 @@ type ReadFileAtCommitResponse struct {
 	Commit  string `json:"commit"`
 }
 
+type DiffFilesRequest struct {
+	WorkspaceID string  `json:"workspaceId"`
+	PathA       string  `json:"pathA"`
+	PathB       string  `json:"pathB,omitempty"`
+	ContentB    *string `json:"contentB,omitempty"`
+}
+type DiffFilesResponse struct {
+	Diff    string `json:"diff"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+type JSONSetRequest struct {
+	WorkspaceID string          `json:"workspaceId"`
+	Path        string          `json:"path"`
+	Pointer     string          `json:"pointer"`
+	Value       json.RawMessage `json:"value"` // any JSON value, including null
+}
+type JSONSetResponse struct {
+	Path         string `json:"path"`
+	Pointer      string `json:"pointer"`
+	BytesWritten int    `json:"bytesWritten"`
+	Commit       string `json:"commit"`
+}
+
+type EstimateReadRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Recursive   bool   `json:"recursive,omitempty"` // for directories: include nested files
+}
+type EstimateReadResponse struct {
+	Path            string `json:"path"`
+	Files           int    `json:"files"`
+	Bytes           int64  `json:"bytes"`
+	Lines           int    `json:"lines"`
+	EstimatedTokens int64  `json:"estimatedTokens"` // approximately bytes/4
+}
+
+type SearchAndReadRequest struct {
+	WorkspaceID     string   `json:"workspaceId"`
+	Path            string   `json:"path,omitempty"`
+	Pattern         string   `json:"pattern"`           // file-name glob, as in fs_search_files
+	Content         string   `json:"content,omitempty"` // optional substring the file must contain
+	ExcludePatterns []string `json:"excludePatterns,omitempty"`
+	MaxFiles        int      `json:"maxFiles,omitempty"`        // default 20, max 100
+	MaxBytesPerFile int      `json:"maxBytesPerFile,omitempty"` // default 64 KiB
+}
+type SearchAndReadFile struct {
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"` // content cut at maxBytesPerFile
+}
+type SearchAndReadResponse struct {
+	Files     []SearchAndReadFile `json:"files"`
+	Truncated bool                `json:"truncated"` // more files matched than were returned
+}
+
+type RestoreFromSnapshotRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Snapshot    string `json:"snapshot"` // tag, branch, or commit hash
+	Path        string `json:"path"`
+}
+type RestoreFromSnapshotResponse struct {
+	Path           string `json:"path"`
+	SnapshotCommit string `json:"snapshotCommit"` // commit the snapshot resolved to
+	Commit         string `json:"commit"`         // empty when the file already matched the snapshot
+}
+
+type RestoreFileRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Commit      string `json:"commit"` // full or abbreviated hash
+}
+type RestoreFileResponse struct {
+	Path         string `json:"path"`
+	RestoredFrom string `json:"restoredFrom"` // full hash of the commit restored from
+	Commit       string `json:"commit"`       // empty when the file already matched
+}
+
+type ManifestRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path,omitempty"` // subtree root; defaults to the workspace root
+}
+type ManifestEntry struct {
+	Path   string `json:"path"` // relative to the workspace root, slash-separated
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+type ManifestResponse struct {
+	Path  string          `json:"path"`
+	Files []ManifestEntry `json:"files"` // sorted by path
+}
+
+type CopyBetweenWorkspacesRequest struct {
+	SourceWorkspaceID string `json:"sourceWorkspaceId"`
+	SourcePath        string `json:"sourcePath"`
+	DestWorkspaceID   string `json:"destWorkspaceId"`
+	DestPath          string `json:"destPath"`
+}
+type CopyBetweenWorkspacesResponse struct {
+	DestWorkspaceID string `json:"destWorkspaceId"`
+	DestPath        string `json:"destPath"`
+	IsDir           bool   `json:"isDir"`
+	Commit          string `json:"commit"` // commit in the destination workspace
+}
+
+type GetDirectorySizeRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	MaxDepth    int    `json:"maxDepth,omitempty"` // levels below path to walk; 0 means unlimited
+}
+type GetDirectorySizeResponse struct {
+	Path         string `json:"path"`
+	CombinedSize int64  `json:"combinedSize"`
+	Files        int    `json:"files"`
+	Directories  int    `json:"directories"`
+	Truncated    bool   `json:"truncated,omitempty"` // maxDepth stopped the walk before all entries were counted
+}
+
+type FindFilesRequest struct {
+	Pattern         string   `json:"pattern"`                // file-name glob, as in fs_search_files
+	Content         string   `json:"content,omitempty"`      // only files containing this substring
+	WorkspaceIDs    []string `json:"workspaceIds,omitempty"` // limit the search; default all workspaces
+	ExcludePatterns []string `json:"excludePatterns,omitempty"`
+}
+type WorkspaceMatches struct {
+	WorkspaceID string   `json:"workspaceId"`
+	Matches     []string `json:"matches"`
+}
+type FindFilesResponse struct {
+	Workspaces []WorkspaceMatches `json:"workspaces"` // only workspaces with matches, sorted by id
+}
+
+type CreateShareLinkRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	TTLSeconds  int    `json:"ttlSeconds,omitempty"` // default 86400 (24h), at most 30 days
+}
+type CreateShareLinkResponse struct {
+	Token       string `json:"token"` // Bearer token (or ?token= for /events) with read-only access to the workspace
+	WorkspaceID string `json:"workspaceId"`
+	ExpiresAt   string `json:"expiresAt"` // RFC3339
+	EventsURL   string `json:"eventsUrl"` // relative /events URL carrying the token
+}
+
+type RevokeShareLinkRequest struct {
+	Token string `json:"token"`
+}
+type RevokeShareLinkResponse struct {
+	WorkspaceID string `json:"workspaceId"`
+	Revoked     bool   `json:"revoked"` // false if the token had already expired or been revoked
+}
+
+type MergeContentRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Base        string `json:"base"`   // content the client originally read
+	Theirs      string `json:"theirs"` // the client's edited version of base
+}
+type MergeConflict struct {
+	Line   int    `json:"line"`   // 1-based line in base where the conflicting hunk starts
+	Base   string `json:"base"`   // the hunk's lines in base; no longer present verbatim in the file
+	Theirs string `json:"theirs"` // the client's replacement for those lines
+}
+type MergeContentResponse struct {
+	Path      string          `json:"path"`
+	Clean     bool            `json:"clean"`
+	Merged    string          `json:"merged,omitempty"` // set when clean
+	Conflicts []MergeConflict `json:"conflicts,omitempty"`
+	Etag      string          `json:"etag"` // of the current file, for a conditional fs_write_file of merged
+}
+
+type WaitForChangeRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`                // file or directory; "." waits for any change in the workspace
+	TimeoutMs   int    `json:"timeoutMs,omitempty"` // default 30000, max 300000
+}
+type WaitForChangeResponse struct {
+	Event events.WorkspaceEvent `json:"event"` // the first matching event published after the call started
+}
+
+type ImportWorkspaceRequest struct {
+	Name          string `json:"name"`
+	ArchiveBase64 string `json:"archiveBase64"` // zip or tar.gz archive, detected by its magic bytes
+}
+type ImportWorkspaceResponse struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Files       int    `json:"files"`
+	Directories int    `json:"directories"`
+	Skipped     int    `json:"skipped"`          // protected entries (.git, .gitkeep) left out
+	Commit      string `json:"commit,omitempty"` // commit recording the imported tree; empty for an empty archive
+}
+
+type ExportWorkspaceRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	IncludeGit  bool   `json:"includeGit,omitempty"` // also archive .git (and .gitkeep files)
+}
+type ExportWorkspaceResponse struct {
+	WorkspaceID   string `json:"workspaceId"`
+	ArchiveBase64 string `json:"archiveBase64"` // gzip-compressed tar
+	Size          int64  `json:"size"`          // archive bytes before base64
+	Files         int    `json:"files"`
+	Directories   int    `json:"directories"`
+}
+
+type PatchFileRequest struct {
+	WorkspaceID string `json:"workspaceId"`
+	Path        string `json:"path"`
+	Patch       string `json:"patch"`                 // unified diff for this one file
+	DryRun      bool   `json:"dryRun,omitempty"`      // only check that the patch applies
+	AuthorName  string `json:"authorName,omitempty"`  // commit author; default "mcp-client"
+	AuthorEmail string `json:"authorEmail,omitempty"` // default "mcp-server@localhost"
+}
+type PatchFileResponse struct {
+	Path         string `json:"path"`
+	DryRun       bool   `json:"dryRun"`
+	Applies      bool   `json:"applies"`
+	Conflict     string `json:"conflict,omitempty"` // dry run only: why the patch does not apply
+	Hunks        int    `json:"hunks"`
+	BytesWritten int    `json:"bytesWritten"`
+	Commit       string `json:"commit"`
+}
+
 // buildServer constructs an MCP SDK server and registers tools using typed handlers.
 // Each tool delegates to a shared implementation in tools.go so both MCP and REST share logic.
 func buildServer(wm *workspace.Manager) *sdkmcp.Server {
@@ -254,6 +783,8 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		Version: "0.1.0",
 	}
 	server := sdkmcp.NewServer(impl, nil)
+	server.AddReceivingMiddleware(loggingMiddleware, mcpActorMiddleware, mcpProgressMiddleware, backendMiddleware(wm))
+	addWorkspaceResources(server, wm)
 
 	// workspace/create
 	sdkmcp.AddTool[CreateWorkspaceRequest, CreateWorkspaceResponse](
@@ -281,6 +812,136 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		},
 	)
 
+	// workspace/diff
+	sdkmcp.AddTool[WorkspaceDiffRequest, WorkspaceDiffResponse](
+		server,
+		newTool("workspace_diff", "List files changed between two commits (to defaults to HEAD)"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input WorkspaceDiffRequest) (*sdkmcp.CallToolResult, WorkspaceDiffResponse, error) {
+			out, err := WorkspaceDiff(ctx, wm, input)
+			if err != nil {
+				return nil, WorkspaceDiffResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/revert
+	sdkmcp.AddTool[WorkspaceRevertRequest, WorkspaceRevertResponse](
+		server,
+		newTool("workspace_revert", "Restore the workspace to a prior commit, recording a new commit"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input WorkspaceRevertRequest) (*sdkmcp.CallToolResult, WorkspaceRevertResponse, error) {
+			out, err := WorkspaceRevert(ctx, wm, input)
+			if err != nil {
+				return nil, WorkspaceRevertResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/undo_last_commit
+	sdkmcp.AddTool[UndoLastCommitRequest, UndoLastCommitResponse](
+		server,
+		newTool("workspace_undo_last_commit", "Move HEAD back to its parent commit, keeping (soft) or discarding (hard) its changes"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input UndoLastCommitRequest) (*sdkmcp.CallToolResult, UndoLastCommitResponse, error) {
+			out, err := WorkspaceUndoLastCommit(ctx, wm, input)
+			if err != nil {
+				return nil, UndoLastCommitResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/create_tag
+	sdkmcp.AddTool[CreateTagRequest, TagInfo](
+		server,
+		newTool("workspace_create_tag", "Tag a commit (default HEAD) as a named checkpoint"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input CreateTagRequest) (*sdkmcp.CallToolResult, TagInfo, error) {
+			out, err := WorkspaceCreateTag(ctx, wm, input)
+			if err != nil {
+				return nil, TagInfo{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/list_tags
+	sdkmcp.AddTool[ListTagsRequest, ListTagsResponse](
+		server,
+		newTool("workspace_list_tags", "List the tags of a workspace with their commits and messages"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input ListTagsRequest) (*sdkmcp.CallToolResult, ListTagsResponse, error) {
+			out, err := WorkspaceListTags(ctx, wm, input)
+			if err != nil {
+				return nil, ListTagsResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/rename
+	sdkmcp.AddTool[RenameWorkspaceRequest, RenameWorkspaceResponse](
+		server,
+		newTool("workspace_rename", "Rename a workspace, moving it to the id derived from the new name while keeping its files and history"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input RenameWorkspaceRequest) (*sdkmcp.CallToolResult, RenameWorkspaceResponse, error) {
+			out, err := WorkspaceRename(ctx, wm, input)
+			if err != nil {
+				return nil, RenameWorkspaceResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/info
+	sdkmcp.AddTool[WorkspaceInfoRequest, WorkspaceInfoResponse](
+		server,
+		newTool("workspace_info", "Summarise a workspace: file and directory counts, combined size, HEAD, branch, commit count and creation time"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input WorkspaceInfoRequest) (*sdkmcp.CallToolResult, WorkspaceInfoResponse, error) {
+			out, err := WorkspaceGetInfo(ctx, wm, input)
+			if err != nil {
+				return nil, WorkspaceInfoResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/status
+	sdkmcp.AddTool[WorkspaceStatusRequest, WorkspaceStatusResponse](
+		server,
+		newTool("workspace_status", "List uncommitted changes in a workspace: untracked, modified and deleted files not yet recorded in a commit"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input WorkspaceStatusRequest) (*sdkmcp.CallToolResult, WorkspaceStatusResponse, error) {
+			out, err := WorkspaceStatus(ctx, wm, input)
+			if err != nil {
+				return nil, WorkspaceStatusResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/create_from_template
+	sdkmcp.AddTool[CreateFromTemplateRequest, CreateFromTemplateResponse](
+		server,
+		newTool("workspace_create_from_template", "Create a workspace pre-populated with the contents of a template from the server's templates directory"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input CreateFromTemplateRequest) (*sdkmcp.CallToolResult, CreateFromTemplateResponse, error) {
+			out, err := WorkspaceCreateFromTemplate(ctx, wm, input)
+			if err != nil {
+				return nil, CreateFromTemplateResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// workspace/clone
+	sdkmcp.AddTool[CloneWorkspaceRequest, CloneWorkspaceResponse](
+		server,
+		newTool("workspace_clone", "Fork a workspace into a new one with the same files, uncommitted changes and full git history"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input CloneWorkspaceRequest) (*sdkmcp.CallToolResult, CloneWorkspaceResponse, error) {
+			out, err := WorkspaceClone(ctx, wm, input)
+			if err != nil {
+				return nil, CloneWorkspaceResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
 	// fs/write_file
 	sdkmcp.AddTool[WriteFileRequest, WriteFileResponse](server, newTool("fs_write_file", "Write a text file"),
 		func(ctx context.Context, req *sdkmcp.CallToolRequest, a WriteFileRequest) (*sdkmcp.CallToolResult, WriteFileResponse, error) {
@@ -292,6 +953,17 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		},
 	)
 
+	// fs/write_files
+	sdkmcp.AddTool[WriteFilesRequest, WriteFilesResponse](server, newTool("fs_write_files", "Write several files and commit them together in one commit"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, a WriteFilesRequest) (*sdkmcp.CallToolResult, WriteFilesResponse, error) {
+			out, err := FSWriteFiles(ctx, wm, a)
+			if err != nil {
+				return nil, WriteFilesResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
 	// fs/read_text_file
 	sdkmcp.AddTool[ReadFileRequest, ReadFileResponse](server, newTool("fs_read_text_file", "Read a UTF-8 text file"),
 		func(ctx context.Context, req *sdkmcp.CallToolRequest, a ReadFileRequest) (*sdkmcp.CallToolResult, ReadFileResponse, error) {
@@ -303,6 +975,17 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		},
 	)
 
+	// fs/read_lines
+	sdkmcp.AddTool[ReadLinesRequest, ReadLinesResponse](server, newTool("fs_read_lines", "Read a window of a text file as numbered lines"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, a ReadLinesRequest) (*sdkmcp.CallToolResult, ReadLinesResponse, error) {
+			out, err := FSReadLines(ctx, wm, a)
+			if err != nil {
+				return nil, ReadLinesResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
 	// fs/create_directory
 	sdkmcp.AddTool[CreateDirectoryRequest, CreateDirectoryResponse](server, newTool("fs_create_directory", "Create a directory (idempotent)"),
 		func(ctx context.Context, req *sdkmcp.CallToolRequest, a CreateDirectoryRequest) (*sdkmcp.CallToolResult, CreateDirectoryResponse, error) {
@@ -336,6 +1019,17 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		},
 	)
 
+	// fs/stat_multiple
+	sdkmcp.AddTool[StatMultipleRequest, StatMultipleResponse](server, newTool("fs_stat_multiple", "Get metadata of several files or directories"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, a StatMultipleRequest) (*sdkmcp.CallToolResult, StatMultipleResponse, error) {
+			out, err := FSStatMultiple(ctx, wm, a)
+			if err != nil {
+				return nil, StatMultipleResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
 	// fs/get_commit_history (workspace-scoped)
 	sdkmcp.AddTool[GetCommitHistoryRequest, GetCommitHistoryResponse](server, newTool("fs_get_commit_history", "Get git commit history"),
 		func(ctx context.Context, req *sdkmcp.CallToolRequest, a GetCommitHistoryRequest) (*sdkmcp.CallToolResult, GetCommitHistoryResponse, error) {
@@ -425,10 +1119,34 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		},
 	)
 
+	// fs/set_mtime
+	sdkmcp.AddTool[SetMtimeRequest, SetMtimeResponse](server, newTool("fs_set_mtime", "Set a file's modification time (not committed; git does not track mtimes)"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, a SetMtimeRequest) (*sdkmcp.CallToolResult, SetMtimeResponse, error) {
+			out, err := FSSetMtime(ctx, wm, a)
+			if err != nil {
+				return nil, SetMtimeResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// fs/chmod
+	sdkmcp.AddTool[ChmodRequest, ChmodResponse](
+		server,
+		newTool("fs_chmod", "Change a file's permission bits (octal mode such as \"0755\") and commit the change"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input ChmodRequest) (*sdkmcp.CallToolResult, ChmodResponse, error) {
+			out, err := FSChmod(ctx, wm, input)
+			if err != nil {
+				return nil, ChmodResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
 	// fs/delete_file
 	sdkmcp.AddTool[DeleteFileRequest, DeleteFileResponse](
 		server,
-		newTool("fs_delete_file", "Delete a file or directory"),
+		newTool("fs_delete_file", "Delete a file or directory; with softDelete it is moved to the workspace trash and can be restored with fs_restore_from_trash"),
 		func(ctx context.Context, req *sdkmcp.CallToolRequest, input DeleteFileRequest) (*sdkmcp.CallToolResult, DeleteFileResponse, error) {
 			out, err := FSDeleteFile(ctx, wm, input)
 			if err != nil {
@@ -438,6 +1156,19 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		},
 	)
 
+	// fs/restore_from_trash
+	sdkmcp.AddTool[RestoreFromTrashRequest, RestoreFromTrashResponse](
+		server,
+		newTool("fs_restore_from_trash", "Restore a file or directory deleted with softDelete to its original path"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input RestoreFromTrashRequest) (*sdkmcp.CallToolResult, RestoreFromTrashResponse, error) {
+			out, err := FSRestoreFromTrash(ctx, wm, input)
+			if err != nil {
+				return nil, RestoreFromTrashResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
 	// fs/read_file_at_commit
 	sdkmcp.AddTool[ReadFileAtCommitRequest, ReadFileAtCommitResponse](
 		server,
@@ -451,56 +1182,372 @@ func buildServer(wm *workspace.Manager) *sdkmcp.Server {
 		},
 	)
 
+	// fs/diff_files
+	sdkmcp.AddTool[DiffFilesRequest, DiffFilesResponse](
+		server,
+		newTool("fs_diff_files", "Unified diff between two files, or a file and inline content"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input DiffFilesRequest) (*sdkmcp.CallToolResult, DiffFilesResponse, error) {
+			out, err := FSDiffFiles(ctx, wm, input)
+			if err != nil {
+				return nil, DiffFilesResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	// fs/json_set
+	jsonSetTool := newTool("fs_json_set", "Set a value at a JSON pointer within a JSON file")
+	jsonSetTool.InputSchema = jsonSetInputSchema()
+	sdkmcp.AddTool[JSONSetRequest, JSONSetResponse](
+		server,
+		jsonSetTool,
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input JSONSetRequest) (*sdkmcp.CallToolResult, JSONSetResponse, error) {
+			out, err := FSJSONSet(ctx, wm, input)
+			if err != nil {
+				return nil, JSONSetResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[EstimateReadRequest, EstimateReadResponse](
+		server,
+		newTool("fs_estimate_read", "Estimate bytes, lines and tokens needed to read a file or directory before reading it"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input EstimateReadRequest) (*sdkmcp.CallToolResult, EstimateReadResponse, error) {
+			out, err := FSEstimateRead(ctx, wm, input)
+			if err != nil {
+				return nil, EstimateReadResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[SearchAndReadRequest, SearchAndReadResponse](
+		server,
+		newTool("fs_search_and_read", "Find files by name pattern (optionally containing text) and return their contents in one call, with size and count caps"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input SearchAndReadRequest) (*sdkmcp.CallToolResult, SearchAndReadResponse, error) {
+			out, err := FSSearchAndRead(ctx, wm, input)
+			if err != nil {
+				return nil, SearchAndReadResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[RestoreFromSnapshotRequest, RestoreFromSnapshotResponse](
+		server,
+		newTool("fs_restore_from_snapshot", "Restore a single file to its content at a snapshot (tag, branch, or commit) and commit the result"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input RestoreFromSnapshotRequest) (*sdkmcp.CallToolResult, RestoreFromSnapshotResponse, error) {
+			out, err := FSRestoreFromSnapshot(ctx, wm, input)
+			if err != nil {
+				return nil, RestoreFromSnapshotResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[RestoreFileRequest, RestoreFileResponse](
+		server,
+		newTool("fs_restore_file", "Restore a single file to its content at a commit and commit the result"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input RestoreFileRequest) (*sdkmcp.CallToolResult, RestoreFileResponse, error) {
+			out, err := FSRestoreFile(ctx, wm, input)
+			if err != nil {
+				return nil, RestoreFileResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[ManifestRequest, ManifestResponse](
+		server,
+		newTool("fs_manifest", "List every file under a path with its size and SHA-256 checksum, sorted by path"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input ManifestRequest) (*sdkmcp.CallToolResult, ManifestResponse, error) {
+			out, err := FSManifest(ctx, wm, input)
+			if err != nil {
+				return nil, ManifestResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[CopyBetweenWorkspacesRequest, CopyBetweenWorkspacesResponse](
+		server,
+		newTool("fs_copy_between_workspaces", "Copy a file or directory from one workspace into another (never overwrites) and commit in the destination"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input CopyBetweenWorkspacesRequest) (*sdkmcp.CallToolResult, CopyBetweenWorkspacesResponse, error) {
+			out, err := FSCopyBetweenWorkspaces(ctx, wm, input)
+			if err != nil {
+				return nil, CopyBetweenWorkspacesResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[GetDirectorySizeRequest, GetDirectorySizeResponse](
+		server,
+		newTool("fs_get_directory_size", "Get the recursive size of a directory with file and directory counts, optionally bounded by maxDepth"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input GetDirectorySizeRequest) (*sdkmcp.CallToolResult, GetDirectorySizeResponse, error) {
+			out, err := FSGetDirectorySize(ctx, wm, input)
+			if err != nil {
+				return nil, GetDirectorySizeResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[FindFilesRequest, FindFilesResponse](
+		server,
+		newTool("workspace_find_files", "Find files by name pattern (optionally containing text) across all or selected workspaces, grouped by workspace"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input FindFilesRequest) (*sdkmcp.CallToolResult, FindFilesResponse, error) {
+			out, err := WorkspaceFindFiles(ctx, wm, input)
+			if err != nil {
+				return nil, FindFilesResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[CreateShareLinkRequest, CreateShareLinkResponse](
+		server,
+		newTool("workspace_create_share_link", "Create an expiring, signed token granting read-only access to one workspace (REST reads and /events)"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input CreateShareLinkRequest) (*sdkmcp.CallToolResult, CreateShareLinkResponse, error) {
+			out, err := WorkspaceCreateShareLink(ctx, wm, input)
+			if err != nil {
+				return nil, CreateShareLinkResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[RevokeShareLinkRequest, RevokeShareLinkResponse](
+		server,
+		newTool("workspace_revoke_share_link", "Revoke a share link token before it expires"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input RevokeShareLinkRequest) (*sdkmcp.CallToolResult, RevokeShareLinkResponse, error) {
+			out, err := WorkspaceRevokeShareLink(ctx, wm, input)
+			if err != nil {
+				return nil, RevokeShareLinkResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[MergeContentRequest, MergeContentResponse](
+		server,
+		newTool("fs_merge_content", "Three-way merge: apply the changes from base to theirs onto the current file content, returning merged content or the conflicting hunks (does not write)"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input MergeContentRequest) (*sdkmcp.CallToolResult, MergeContentResponse, error) {
+			out, err := FSMergeContent(ctx, wm, input)
+			if err != nil {
+				return nil, MergeContentResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[WaitForChangeRequest, WaitForChangeResponse](
+		server,
+		newTool("fs_wait_for_change", "Block until a file or directory (or anything under it) changes, returning the change event, or fail with TIMEOUT after timeoutMs"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input WaitForChangeRequest) (*sdkmcp.CallToolResult, WaitForChangeResponse, error) {
+			out, err := FSWaitForChange(ctx, wm, input)
+			if err != nil {
+				return nil, WaitForChangeResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[ImportWorkspaceRequest, ImportWorkspaceResponse](
+		server,
+		newTool("workspace_import", "Create a workspace from a base64-encoded zip or tar.gz archive and commit its contents"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input ImportWorkspaceRequest) (*sdkmcp.CallToolResult, ImportWorkspaceResponse, error) {
+			out, err := WorkspaceImport(ctx, wm, input)
+			if err != nil {
+				return nil, ImportWorkspaceResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[ExportWorkspaceRequest, ExportWorkspaceResponse](
+		server,
+		newTool("workspace_export", "Export a workspace as a base64-encoded tar.gz (up to 8 MiB; use GET /api/workspaces/{id}/archive for larger ones)"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input ExportWorkspaceRequest) (*sdkmcp.CallToolResult, ExportWorkspaceResponse, error) {
+			out, err := WorkspaceExport(ctx, wm, input)
+			if err != nil {
+				return nil, ExportWorkspaceResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
+	sdkmcp.AddTool[PatchFileRequest, PatchFileResponse](
+		server,
+		newTool("fs_patch", "Apply a unified diff to a file, failing with CONFLICT if a hunk does not match; dryRun only checks that it applies"),
+		func(ctx context.Context, req *sdkmcp.CallToolRequest, input PatchFileRequest) (*sdkmcp.CallToolResult, PatchFileResponse, error) {
+			out, err := FSPatch(ctx, wm, input)
+			if err != nil {
+				return nil, PatchFileResponse{}, err
+			}
+			return nil, out, nil
+		},
+	)
+
 	return server
 }
 
+// treeOptions controls which entries buildTree includes and how deep it descends.
+type treeOptions struct {
+	protected       *workspace.ProtectedPaths
+	wsRoot          string // workspace root, for matching protected paths
+	excludePatterns []string
+	maxDepth        int // 0 means unlimited
+	dirsOnly        bool
+	ignore          ignoreFunc // optional .gitignore matcher
+	progress        func()     // optional; called for every entry added to the tree
+	stats           bool       // fill in Size, Mtime and ChildCount
+}
+
 // buildTree builds the directory tree respecting simple exclude patterns (name-match).
-func buildTree(root string, excludePatterns []string) ([]TreeNode, error) {
+// depth is the level of root's entries (1 for the requested directory); directories
+// at maxDepth are returned with Truncated set instead of children.
+func buildTree(root string, opts treeOptions, depth int) ([]TreeNode, error) {
 	var tree []TreeNode
 	files, err := os.ReadDir(root)
 	if err != nil {
 		return nil, err
 	}
 	for _, f := range files {
-		// Always hide protected names
-		if isProtectedName(f.Name()) {
+		if ok, err := treeEntryVisible(root, f, opts); err != nil {
+			return nil, err
+		} else if !ok {
 			continue
 		}
-		// Exclude by name
-		isExcluded := false
-		for _, pattern := range excludePatterns {
-			match, err := filepath.Match(pattern, f.Name())
-			if err != nil {
-				return nil, err
-			}
-			if match {
-				isExcluded = true
-				break
-			}
-		}
-		if isExcluded {
-			continue
+		if opts.progress != nil {
+			opts.progress()
 		}
 		node := TreeNode{Name: f.Name()}
 		if f.IsDir() {
 			node.Type = "directory"
-			children, err := buildTree(filepath.Join(root, f.Name()), excludePatterns)
-			if err != nil {
-				return nil, err
+			dir := filepath.Join(root, f.Name())
+			if opts.maxDepth > 0 && depth >= opts.maxDepth {
+				node.Truncated = true
+				if opts.stats {
+					n, err := countTreeEntries(dir, opts)
+					if err != nil {
+						return nil, err
+					}
+					node.ChildCount = &n
+				}
+			} else {
+				children, err := buildTree(dir, opts, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				node.Children = &children
+				if opts.stats {
+					n := len(children)
+					node.ChildCount = &n
+				}
 			}
-			node.Children = &children
 		} else {
 			node.Type = "file"
+			if opts.stats {
+				// The entry's info comes from the directory read; the file
+				// itself is not opened.
+				info, err := f.Info()
+				if err != nil {
+					return nil, err
+				}
+				size := info.Size()
+				node.Size = &size
+				node.Mtime = info.ModTime().UTC().Format(time.RFC3339)
+			}
 		}
 		tree = append(tree, node)
 	}
 	return tree, nil
 }
 
+// treeEntryVisible reports whether buildTree lists the entry f of root.
+func treeEntryVisible(root string, f os.DirEntry, opts treeOptions) (bool, error) {
+	// Always hide protected paths
+	if opts.protected.IsProtectedIn(opts.wsRoot, filepath.Join(root, f.Name())) {
+		return false, nil
+	}
+	if opts.dirsOnly && !f.IsDir() {
+		return false, nil
+	}
+	if opts.ignore.ignored(filepath.Join(root, f.Name()), f.IsDir()) {
+		return false, nil
+	}
+	// Exclude by name
+	for _, pattern := range opts.excludePatterns {
+		match, err := filepath.Match(pattern, f.Name())
+		if err != nil {
+			return false, err
+		}
+		if match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// countTreeEntries counts the entries of dir that buildTree would list, for
+// the childCount of a directory cut off at maxDepth.
+func countTreeEntries(dir string, opts treeOptions) (int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, f := range files {
+		ok, err := treeEntryVisible(dir, f, opts)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// StdioOptions configures the stdio transport started by RunStdio.
+type StdioOptions struct {
+	// MaxResponseBytes caps the encoded size of tool results; 0 disables the cap.
+	MaxResponseBytes int
+	// MaxMediaBytes caps the files fs_read_media_file returns
+	// (DefaultMaxMediaBytes when 0).
+	MaxMediaBytes int64
+	// MaxReadBytes is the default and largest maxBytes of the text read tools
+	// (DefaultMaxReadBytes when 0).
+	MaxReadBytes int64
+}
+
 // RunStdio starts the MCP SDK server over stdio until the client disconnects or context is cancelled.
-func RunStdio(wm *workspace.Manager) {
+func RunStdio(ctx context.Context, wm *workspace.Manager, opts StdioOptions) error {
+	setMaxMediaBytes(opts.MaxMediaBytes)
+	setMaxReadBytes(opts.MaxReadBytes)
 	server := buildServer(wm)
-	if err := server.Run(context.Background(), &sdkmcp.StdioTransport{}); err != nil && err != io.EOF {
-		slog.Error("MCP SDK stdio server exited with error", "error", err)
+	server.AddReceivingMiddleware(responseLimitMiddleware(opts.MaxResponseBytes))
+	err := server.Run(ctx, &sdkmcp.StdioTransport{})
+	if err == nil || err == io.EOF || errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return fmt.Errorf("MCP SDK stdio server exited with error: %w", err)
+}
+
+// mcpActorMiddleware attributes events published by MCP tool calls to the calling
+// session, so frontends can tell agent edits apart from API or filesystem changes.
+func mcpActorMiddleware(next sdkmcp.MethodHandler) sdkmcp.MethodHandler {
+	return func(ctx context.Context, method string, req sdkmcp.Request) (sdkmcp.Result, error) {
+		if method == "tools/call" {
+			actor := events.Actor{Kind: "mcp"}
+			if sess := req.GetSession(); sess != nil && sess.ID() != "" {
+				id := sess.ID()
+				actor.ID = &id
+			}
+			ctx = WithActor(ctx, actor)
+		}
+		return next(ctx, method, req)
 	}
 }
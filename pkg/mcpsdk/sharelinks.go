@@ -0,0 +1,290 @@
+package mcpsdk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// shareTokenPrefix marks share link tokens so they are never confused with
+// configured Bearer tokens.
+const shareTokenPrefix = "wss_"
+
+const (
+	defaultShareLinkTTL = 24 * time.Hour
+	maxShareLinkTTL     = 30 * 24 * time.Hour
+)
+
+var errInvalidShareToken = errors.New("invalid share token")
+
+// shareClaims is the signed payload of a share token.
+type shareClaims struct {
+	ID          string `json:"jti"`
+	WorkspaceID string `json:"ws"`
+	Created     int64  `json:"wsc"` // the workspace's creation time, unix nanoseconds
+	Expires     int64  `json:"exp"` // unix seconds
+}
+
+// shareLinkSigner issues and verifies share tokens: HMAC-SHA256 signed claims
+// granting read-only access to one workspace until they expire or are revoked.
+// The key is generated per process, so share tokens do not survive a restart.
+// Tokens are bound to the workspace's creation time, so a workspace later
+// created under a shared id (e.g. after a rename) is not shared with them.
+type shareLinkSigner struct {
+	key []byte
+	wm  *workspace.Manager // see setManager; nil skips the workspace check
+
+	mu      sync.Mutex
+	revoked map[string]time.Time // token id -> expiry, pruned once expired
+}
+
+var shareLinks = newShareLinkSigner()
+
+func newShareLinkSigner() *shareLinkSigner {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("share links: failed to generate signing key: %v", err))
+	}
+	return &shareLinkSigner{key: key, revoked: make(map[string]time.Time)}
+}
+
+// setManager makes verify check that a token's workspace still exists and is
+// the one it was issued for.
+func (s *shareLinkSigner) setManager(wm *workspace.Manager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wm = wm
+}
+
+// workspaceCreated returns the creation time recorded for workspaceID, in unix
+// nanoseconds, and false if the workspace does not exist.
+func workspaceCreated(wm *workspace.Manager, workspaceID string) (int64, bool) {
+	if _, err := wm.SafePath(workspaceID, "."); err != nil {
+		return 0, false
+	}
+	md, err := wm.Metadata(workspaceID)
+	if err != nil {
+		return 0, false
+	}
+	if md.CreatedAt.IsZero() {
+		return 0, true
+	}
+	return md.CreatedAt.UnixNano(), true
+}
+
+func (s *shareLinkSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issue returns a token for workspaceID, created at created (see
+// workspaceCreated), valid until now+ttl.
+func (s *shareLinkSigner) issue(workspaceID string, created int64, ttl time.Duration) (string, shareClaims, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", shareClaims{}, err
+	}
+	claims := shareClaims{
+		ID:          hex.EncodeToString(id),
+		WorkspaceID: workspaceID,
+		Created:     created,
+		Expires:     time.Now().Add(ttl).Unix(),
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", shareClaims{}, err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return shareTokenPrefix + payload + "." + s.sign(payload), claims, nil
+}
+
+// parse checks the token's signature and returns its claims, without checking
+// expiry or revocation.
+func (s *shareLinkSigner) parse(token string) (shareClaims, error) {
+	rest, ok := strings.CutPrefix(token, shareTokenPrefix)
+	if !ok {
+		return shareClaims{}, errInvalidShareToken
+	}
+	payload, sig, ok := strings.Cut(rest, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return shareClaims{}, errInvalidShareToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return shareClaims{}, errInvalidShareToken
+	}
+	var claims shareClaims
+	if err := json.Unmarshal(b, &claims); err != nil || claims.ID == "" || claims.WorkspaceID == "" {
+		return shareClaims{}, errInvalidShareToken
+	}
+	return claims, nil
+}
+
+// verify returns the claims of a valid, unexpired and unrevoked token whose
+// workspace still exists and was created when the token was issued.
+func (s *shareLinkSigner) verify(token string) (shareClaims, bool) {
+	claims, err := s.parse(token)
+	if err != nil || time.Now().Unix() >= claims.Expires {
+		return shareClaims{}, false
+	}
+	s.mu.Lock()
+	_, revoked := s.revoked[claims.ID]
+	wm := s.wm
+	s.mu.Unlock()
+	if revoked {
+		return shareClaims{}, false
+	}
+	if wm != nil {
+		if created, ok := workspaceCreated(wm, claims.WorkspaceID); !ok || created != claims.Created {
+			return shareClaims{}, false
+		}
+	}
+	return claims, true
+}
+
+// revoke invalidates token. It reports false if the token was already revoked or expired.
+func (s *shareLinkSigner) revoke(token string) (shareClaims, bool, error) {
+	claims, err := s.parse(token)
+	if err != nil {
+		return shareClaims{}, false, err
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, exp := range s.revoked {
+		if now.After(exp) {
+			delete(s.revoked, id)
+		}
+	}
+	if now.Unix() >= claims.Expires {
+		return claims, false, nil
+	}
+	if _, already := s.revoked[claims.ID]; already {
+		return claims, false, nil
+	}
+	s.revoked[claims.ID] = time.Unix(claims.Expires, 0)
+	return claims, true, nil
+}
+
+// allowsEvents reports whether token is a valid share token for workspaceID; it
+// authorizes /events streams for the shared workspace only.
+func (s *shareLinkSigner) allowsEvents(token, workspaceID string) bool {
+	claims, ok := s.verify(token)
+	return ok && claims.WorkspaceID == workspaceID
+}
+
+type shareKey struct{}
+
+func withShareWorkspace(ctx context.Context, workspaceID string) context.Context {
+	return context.WithValue(ctx, shareKey{}, workspaceID)
+}
+
+// sharedWorkspaceTools lists the read-only tools a share token may call: those
+// whose request names exactly one workspace, in workspaceId. Anything not
+// listed, such as workspace_list or workspace_find_files (which searches
+// workspaceIds, or every workspace), is denied, so new tools are too.
+var sharedWorkspaceTools = map[string]bool{
+	"workspace_diff":               true,
+	"fs_read_text_file":            true,
+	"fs_read_lines":                true,
+	"fs_list_directory":            true,
+	"fs_get_file_info":             true,
+	"fs_stat_multiple":             true,
+	"fs_get_commit_history":        true,
+	"fs_read_multiple_files":       true,
+	"fs_list_directory_with_sizes": true,
+	"fs_search_files":              true,
+	"fs_directory_tree":            true,
+	"fs_read_media_file":           true,
+	"fs_read_file_at_commit":       true,
+	"fs_diff_files":                true,
+	"fs_estimate_read":             true,
+	"fs_search_and_read":           true,
+	"fs_manifest":                  true,
+	"fs_get_directory_size":        true,
+	"fs_merge_content":             true,
+	"fs_wait_for_change":           true,
+	"workspace_export":             true,
+	"workspace_info":               true,
+	"workspace_status":             true,
+	"workspace_list_tags":          true,
+}
+
+// checkShareAccess restricts requests authenticated with a share token to the
+// sharedWorkspaceTools, called with the shared workspace as workspaceId. Other
+// requests are unaffected.
+func checkShareAccess(ctx context.Context, toolName string, params json.RawMessage) error {
+	shared, ok := ctx.Value(shareKey{}).(string)
+	if !ok {
+		return nil
+	}
+	return checkShareWorkspace(shared, toolName, params)
+}
+
+func checkShareWorkspace(shared, toolName string, params json.RawMessage) error {
+	if !sharedWorkspaceTools[toolName] {
+		return fmt.Errorf("FORBIDDEN: share link cannot call %s", toolName)
+	}
+	var in struct {
+		WorkspaceID string `json:"workspaceId"`
+	}
+	if len(params) > 0 {
+		_ = json.Unmarshal(params, &in)
+	}
+	if in.WorkspaceID != shared {
+		return fmt.Errorf("FORBIDDEN: share link only grants access to workspace %q (%s)", shared, toolName)
+	}
+	return nil
+}
+
+// WorkspaceCreateShareLink issues a read-only share token for one workspace.
+func WorkspaceCreateShareLink(_ context.Context, wm *workspace.Manager, a CreateShareLinkRequest) (CreateShareLinkResponse, error) {
+	if a.WorkspaceID == "" {
+		return CreateShareLinkResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	if a.TTLSeconds < 0 || time.Duration(a.TTLSeconds)*time.Second > maxShareLinkTTL {
+		return CreateShareLinkResponse{}, fmt.Errorf("INVALID_INPUT: 'ttlSeconds' must be between 1 and %d", int(maxShareLinkTTL.Seconds()))
+	}
+	created, ok := workspaceCreated(wm, a.WorkspaceID)
+	if !ok {
+		return CreateShareLinkResponse{}, fmt.Errorf("NOT_FOUND: workspace '%s' not found", a.WorkspaceID)
+	}
+	ttl := defaultShareLinkTTL
+	if a.TTLSeconds > 0 {
+		ttl = time.Duration(a.TTLSeconds) * time.Second
+	}
+	token, claims, err := shareLinks.issue(a.WorkspaceID, created, ttl)
+	if err != nil {
+		return CreateShareLinkResponse{}, fmt.Errorf("INTERNAL: failed to issue share link: %v", err)
+	}
+	return CreateShareLinkResponse{
+		Token:       token,
+		WorkspaceID: a.WorkspaceID,
+		ExpiresAt:   time.Unix(claims.Expires, 0).UTC().Format(time.RFC3339),
+		EventsURL:   "/events?workspaceId=" + a.WorkspaceID + "&token=" + token,
+	}, nil
+}
+
+// WorkspaceRevokeShareLink invalidates a share token before it expires.
+func WorkspaceRevokeShareLink(_ context.Context, _ *workspace.Manager, a RevokeShareLinkRequest) (RevokeShareLinkResponse, error) {
+	if a.Token == "" {
+		return RevokeShareLinkResponse{}, fmt.Errorf("INVALID_INPUT: 'token' is required")
+	}
+	claims, revoked, err := shareLinks.revoke(a.Token)
+	if err != nil {
+		return RevokeShareLinkResponse{}, fmt.Errorf("INVALID_INPUT: %v", err)
+	}
+	return RevokeShareLinkResponse{WorkspaceID: claims.WorkspaceID, Revoked: revoked}, nil
+}
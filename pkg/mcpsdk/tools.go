@@ -1,9 +1,12 @@
 package mcpsdk
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -11,9 +14,14 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/sergi/go-diff/diffmatchpatch"
 
 	"mcp-workspace-manager/pkg/events"
@@ -22,21 +30,24 @@ import (
 
 // Shared tool implementations used by both MCP server tools and REST API.
 
-func isProtectedName(name string) bool {
-	return name == ".git" || name == ".gitkeep"
+// removeRedundantGitkeeps deletes the .gitkeep markers fs_create_directory
+// left in the directories above abs: once abs exists those directories are no
+// longer empty and git tracks them without the marker. Call it after creating
+// abs and before committing, so the removal lands in the same commit. The
+// workspace root's marker, which only exists for the initial commit, is kept.
+func removeRedundantGitkeeps(fsys workspace.FS, root, abs string) {
+	for d := filepath.Dir(abs); strings.HasPrefix(d, root+string(filepath.Separator)); d = filepath.Dir(d) {
+		_ = fsys.RemoveAll(filepath.Join(d, ".gitkeep"))
+	}
 }
 
-func isProtectedPath(rel string) bool {
-	cleaned := filepath.Clean(rel)
-	for _, seg := range strings.Split(cleaned, string(os.PathSeparator)) {
-		if seg == "" {
-			continue
-		}
-		if isProtectedName(seg) {
-			return true
-		}
+// writeFailed wraps an error from writing into a workspace, reporting quota
+// overruns as QUOTA_EXCEEDED and anything else as INTERNAL.
+func writeFailed(what string, err error) error {
+	if errors.Is(err, workspace.ErrQuotaExceeded) {
+		return fmt.Errorf("QUOTA_EXCEEDED: %v", err)
 	}
-	return false
+	return fmt.Errorf("INTERNAL: %s: %v", what, err)
 }
 
 func WorkspaceCreate(ctx context.Context, wm *workspace.Manager, input CreateWorkspaceRequest) (CreateWorkspaceResponse, error) {
@@ -50,70 +61,612 @@ func WorkspaceCreate(ctx context.Context, wm *workspace.Manager, input CreateWor
 	return CreateWorkspaceResponse{WorkspaceID: id, Path: path}, nil
 }
 
+// WorkspaceList returns the workspaces sorted by display name (case-insensitively,
+// then by id), optionally filtered by
+// NameContains and paged with Limit/Offset. Total counts all matches.
 func WorkspaceList(ctx context.Context, wm *workspace.Manager, input ListWorkspacesRequest) (ListWorkspacesResponse, error) {
+	if input.Limit < 0 || input.Offset < 0 {
+		return ListWorkspacesResponse{}, fmt.Errorf("INVALID_INPUT: 'limit' and 'offset' must not be negative")
+	}
 	workspaces, err := wm.List()
 	if err != nil {
 		return ListWorkspacesResponse{}, err
 	}
-	var out []WorkspaceInfo
+	needle := strings.ToLower(input.NameContains)
+	matched := make([]workspace.Workspace, 0, len(workspaces))
 	for _, w := range workspaces {
-		out = append(out, WorkspaceInfo{
-			Name: w.Name,
-			Path: w.Path,
+		if needle != "" && !strings.Contains(strings.ToLower(w.Name), needle) && !strings.Contains(strings.ToLower(w.DisplayName), needle) {
+			continue
+		}
+		matched = append(matched, w)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := strings.ToLower(matched[i].DisplayName), strings.ToLower(matched[j].DisplayName)
+		if a != b {
+			return a < b
+		}
+		return matched[i].Name < matched[j].Name
+	})
+
+	page := matched[min(input.Offset, len(matched)):]
+	if input.Limit > 0 && len(page) > input.Limit {
+		page = page[:input.Limit]
+	}
+	out := []WorkspaceInfo{}
+	for _, w := range page {
+		info := WorkspaceInfo{
+			Name:        w.Name,
+			Path:        w.Path,
+			DisplayName: w.DisplayName,
+		}
+		if c, err := wm.HeadCommitObject(w.Name); err == nil && c != nil {
+			info.HeadCommit = c.Hash.String()
+			info.LastModified = c.Committer.When.UTC().Format(time.RFC3339)
+			info.LastCommitMessage, _, _ = strings.Cut(c.Message, "\n")
+		}
+		out = append(out, info)
+	}
+	return ListWorkspacesResponse{Workspaces: out, Total: len(matched)}, nil
+}
+
+// findFilesConcurrency bounds how many workspaces workspace_find_files scans at once.
+const findFilesConcurrency = 4
+
+// WorkspaceFindFiles runs the fs_search_files name match in every workspace (or the
+// requested subset), optionally keeping only files that contain Content.
+func WorkspaceFindFiles(ctx context.Context, wm *workspace.Manager, a FindFilesRequest) (FindFilesResponse, error) {
+	if a.Pattern == "" {
+		return FindFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'pattern' is required")
+	}
+	ids := a.WorkspaceIDs
+	if len(ids) == 0 {
+		workspaces, err := wm.List()
+		if err != nil {
+			return FindFilesResponse{}, fmt.Errorf("INTERNAL: failed to list workspaces: %v", err)
+		}
+		for _, w := range workspaces {
+			ids = append(ids, w.Name)
+		}
+	} else {
+		for _, id := range ids {
+			if _, err := wm.SafePath(id, "."); err != nil {
+				return FindFilesResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+			}
+		}
+	}
+
+	results := make([]WorkspaceMatches, len(ids))
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, findFilesConcurrency)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				errs[i] = ctx.Err()
+				return
+			}
+			found, err := FSSearchFiles(ctx, wm, SearchFilesRequest{
+				WorkspaceID:     id,
+				Path:            ".",
+				Pattern:         a.Pattern,
+				ExcludePatterns: a.ExcludePatterns,
+			})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			matches := []string{}
+			for _, rel := range found.Matches {
+				if a.Content != "" {
+					abs, err := wm.SafePath(id, rel)
+					if err != nil {
+						continue
+					}
+					if ok, err := fileContains(wm.FS(), abs, []byte(a.Content)); err != nil || !ok {
+						continue
+					}
+				}
+				matches = append(matches, filepath.ToSlash(rel))
+			}
+			sort.Strings(matches)
+			results[i] = WorkspaceMatches{WorkspaceID: id, Matches: matches}
+		}()
+	}
+	wg.Wait()
+
+	out := FindFilesResponse{Workspaces: []WorkspaceMatches{}}
+	for i, r := range results {
+		if errs[i] != nil {
+			return FindFilesResponse{}, errs[i]
+		}
+		if len(r.Matches) > 0 {
+			out.Workspaces = append(out.Workspaces, r)
+		}
+	}
+	sort.Slice(out.Workspaces, func(i, j int) bool { return out.Workspaces[i].WorkspaceID < out.Workspaces[j].WorkspaceID })
+	return out, nil
+}
+
+// WorkspaceDiff lists the files changed between two commits of a workspace.
+func WorkspaceDiff(ctx context.Context, wm *workspace.Manager, a WorkspaceDiffRequest) (WorkspaceDiffResponse, error) {
+	if a.WorkspaceID == "" || a.From == "" {
+		return WorkspaceDiffResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'from' are required")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return WorkspaceDiffResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	to := a.To
+	if to == "" {
+		to = "HEAD"
+	}
+	changes, err := wm.DiffCommits(a.WorkspaceID, a.From, to)
+	if err != nil {
+		if errors.Is(err, workspace.ErrCommitNotFound) {
+			return WorkspaceDiffResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		}
+		return WorkspaceDiffResponse{}, fmt.Errorf("INTERNAL: diff failed: %v", err)
+	}
+	out := WorkspaceDiffResponse{From: a.From, To: to, Changes: []ChangedFile{}}
+	for _, c := range changes {
+		if wm.Protected().IsProtectedPath(c.Path) {
+			continue
+		}
+		cf := ChangedFile{Path: c.Path, Action: c.Action}
+		if a.IncludePatch {
+			cf.Patch = c.Patch
+		}
+		out.Changes = append(out.Changes, cf)
+	}
+	return out, nil
+}
+
+// WorkspaceRevert restores the workspace to a prior commit and emits an event per changed file.
+func WorkspaceRevert(ctx context.Context, wm *workspace.Manager, a WorkspaceRevertRequest) (WorkspaceRevertResponse, error) {
+	if a.WorkspaceID == "" || a.Commit == "" {
+		return WorkspaceRevertResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'commit' are required")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return WorkspaceRevertResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	unlock, err := wm.LockExclusive(a.WorkspaceID)
+	if err != nil {
+		return WorkspaceRevertResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	commit, changes, err := wm.RevertToCommit(a.WorkspaceID, a.Commit)
+	if err != nil {
+		if errors.Is(err, workspace.ErrCommitNotFound) {
+			return WorkspaceRevertResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		}
+		return WorkspaceRevertResponse{}, fmt.Errorf("INTERNAL: revert failed: %v", err)
+	}
+
+	return WorkspaceRevertResponse{Commit: commit, RevertedTo: a.Commit, Changes: publishFileChanges(ctx, wm, a.WorkspaceID, commit, changes)}, nil
+}
+
+// WorkspaceCreateTag tags a commit (HEAD by default) as a named checkpoint;
+// a message makes the tag annotated.
+func WorkspaceCreateTag(ctx context.Context, wm *workspace.Manager, a CreateTagRequest) (TagInfo, error) {
+	if a.WorkspaceID == "" || a.Name == "" {
+		return TagInfo{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'name' are required")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return TagInfo{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	tag, err := wm.CreateTag(a.WorkspaceID, a.Name, a.Message, a.Commit)
+	if err != nil {
+		switch {
+		case errors.Is(err, workspace.ErrInvalidTagName):
+			return TagInfo{}, fmt.Errorf("INVALID_INPUT: %v", err)
+		case errors.Is(err, workspace.ErrTagExists):
+			return TagInfo{}, fmt.Errorf("ALREADY_EXISTS: %v", err)
+		case strings.Contains(err.Error(), "not found"):
+			return TagInfo{}, fmt.Errorf("NOT_FOUND: %v", err)
+		}
+		return TagInfo{}, fmt.Errorf("INTERNAL: failed to create tag: %v", err)
+	}
+	return TagInfo{Name: tag.Name, Commit: tag.Commit, Message: tag.Message}, nil
+}
+
+// WorkspaceListTags lists a workspace's tags sorted by name.
+func WorkspaceListTags(ctx context.Context, wm *workspace.Manager, a ListTagsRequest) (ListTagsResponse, error) {
+	if a.WorkspaceID == "" {
+		return ListTagsResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return ListTagsResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	tags, err := wm.ListTags(a.WorkspaceID)
+	if err != nil {
+		return ListTagsResponse{}, fmt.Errorf("INTERNAL: failed to list tags: %v", err)
+	}
+	out := ListTagsResponse{Tags: make([]TagInfo, 0, len(tags))}
+	for _, t := range tags {
+		out.Tags = append(out.Tags, TagInfo{Name: t.Name, Commit: t.Commit, Message: t.Message})
+	}
+	return out, nil
+}
+
+// publishFileChanges emits a file event for each change a history operation
+// made to the working tree, recorded as commit, and returns the changes
+// outside protected paths.
+func publishFileChanges(ctx context.Context, wm *workspace.Manager, workspaceID, commit string, changes []workspace.FileChange) []ChangedFile {
+	out := []ChangedFile{}
+	for _, c := range changes {
+		if wm.Protected().IsProtectedPath(c.Path) {
+			continue
+		}
+		evtType := "file.updated"
+		switch c.Action {
+		case "added":
+			evtType = "file.created"
+		case "deleted":
+			evtType = "file.deleted"
+		}
+		commitCopy := commit
+		publishWorkspaceEvent(ctx, workspaceID, events.WorkspaceEvent{
+			Type:   evtType,
+			Path:   c.Path,
+			IsDir:  false,
+			Commit: &commitCopy,
+		})
+		out = append(out, ChangedFile{Path: c.Path, Action: c.Action})
+	}
+	return out
+}
+
+// WorkspaceUndoLastCommit moves HEAD back to its parent. The soft mode (the
+// default) keeps the working tree, so nothing changes on disk; hard also
+// restores the files to the parent commit and emits their events.
+func WorkspaceUndoLastCommit(ctx context.Context, wm *workspace.Manager, a UndoLastCommitRequest) (UndoLastCommitResponse, error) {
+	if a.WorkspaceID == "" {
+		return UndoLastCommitResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	mode := a.Mode
+	if mode == "" {
+		mode = "soft"
+	}
+	if mode != "soft" && mode != "hard" {
+		return UndoLastCommitResponse{}, fmt.Errorf("INVALID_INPUT: 'mode' must be 'soft' or 'hard'")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return UndoLastCommitResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	unlock, err := wm.LockExclusive(a.WorkspaceID)
+	if err != nil {
+		return UndoLastCommitResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	undone, err := wm.HeadCommit(a.WorkspaceID)
+	if err != nil {
+		return UndoLastCommitResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+
+	out := UndoLastCommitResponse{Undone: undone, Mode: mode, Changes: []ChangedFile{}}
+	var changes []workspace.FileChange
+	if mode == "hard" {
+		out.Commit, changes, err = wm.ResetHard(a.WorkspaceID)
+	} else {
+		out.Commit, err = wm.ResetSoft(a.WorkspaceID)
+	}
+	if err != nil {
+		if errors.Is(err, workspace.ErrRootCommit) {
+			return UndoLastCommitResponse{}, fmt.Errorf("CONFLICT: %v", err)
+		}
+		return UndoLastCommitResponse{}, fmt.Errorf("INTERNAL: undo failed: %v", err)
+	}
+	out.Changes = publishFileChanges(ctx, wm, a.WorkspaceID, out.Commit, changes)
+	return out, nil
+}
+
+// WorkspaceRename moves a workspace to the id derived from the new name and
+// emits workspace.renamed on the old id, so open streams learn the new one.
+func WorkspaceRename(ctx context.Context, wm *workspace.Manager, a RenameWorkspaceRequest) (RenameWorkspaceResponse, error) {
+	if a.WorkspaceID == "" || a.Name == "" {
+		return RenameWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'name' are required")
+	}
+	unlock, err := wm.LockExclusive(a.WorkspaceID)
+	if err != nil {
+		return RenameWorkspaceResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	newID, err := wm.Rename(a.WorkspaceID, a.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, workspace.ErrWorkspaceExists):
+			return RenameWorkspaceResponse{}, fmt.Errorf("ALREADY_EXISTS: %v", err)
+		case strings.Contains(err.Error(), "not found"):
+			return RenameWorkspaceResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		case strings.Contains(err.Error(), "no characters"):
+			return RenameWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: %v", err)
+		}
+		return RenameWorkspaceResponse{}, fmt.Errorf("INTERNAL: rename failed: %v", err)
+	}
+	path, _ := wm.SafePath(newID, ".")
+	if newID != a.WorkspaceID {
+		publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+			Type:      "workspace.renamed",
+			IsDir:     true,
+			RenamedTo: &newID,
 		})
 	}
-	return ListWorkspacesResponse{Workspaces: out}, nil
+	return RenameWorkspaceResponse{WorkspaceID: newID, PrevWorkspaceID: a.WorkspaceID, Path: path}, nil
+}
+
+// WorkspaceGetInfo summarises one workspace: the fs_get_directory_size totals
+// for its root plus repository and metadata details.
+func WorkspaceGetInfo(ctx context.Context, wm *workspace.Manager, a WorkspaceInfoRequest) (WorkspaceInfoResponse, error) {
+	if a.WorkspaceID == "" {
+		return WorkspaceInfoResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return WorkspaceInfoResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	repo, err := wm.RepoInfo(a.WorkspaceID)
+	if err != nil {
+		return WorkspaceInfoResponse{}, fmt.Errorf("NOT_FOUND: workspace '%s' not found", a.WorkspaceID)
+	}
+	size, err := FSGetDirectorySize(ctx, wm, GetDirectorySizeRequest{WorkspaceID: a.WorkspaceID})
+	if err != nil {
+		return WorkspaceInfoResponse{}, err
+	}
+	out := WorkspaceInfoResponse{
+		WorkspaceID:  a.WorkspaceID,
+		Files:        size.Files,
+		Directories:  size.Directories,
+		CombinedSize: size.CombinedSize,
+		HeadCommit:   repo.Head,
+		Branch:       repo.Branch,
+		CommitCount:  repo.Commits,
+	}
+	if md, err := wm.Metadata(a.WorkspaceID); err == nil {
+		out.DisplayName = md.Name
+		if !md.CreatedAt.IsZero() {
+			out.CreatedAt = md.CreatedAt.UTC().Format(time.RFC3339)
+		}
+	}
+	return out, nil
+}
+
+// WorkspaceStatus lists the changes not yet committed in a workspace. Tools
+// commit after every mutation, so these are normally edits made outside the
+// server (picked up by the filesystem watcher but never committed).
+func WorkspaceStatus(ctx context.Context, wm *workspace.Manager, a WorkspaceStatusRequest) (WorkspaceStatusResponse, error) {
+	if a.WorkspaceID == "" {
+		return WorkspaceStatusResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return WorkspaceStatusResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	changes, err := wm.Status(a.WorkspaceID)
+	if err != nil {
+		if errors.Is(err, workspace.ErrNoHistory) {
+			return WorkspaceStatusResponse{}, fmt.Errorf("UNSUPPORTED: %v", err)
+		}
+		return WorkspaceStatusResponse{}, fmt.Errorf("INTERNAL: %v", err)
+	}
+	out := WorkspaceStatusResponse{Files: []PendingFile{}}
+	for _, c := range changes {
+		if wm.Protected().IsProtectedPath(c.Path) {
+			continue
+		}
+		out.Files = append(out.Files, PendingFile{Path: c.Path, Status: c.Status, Staged: c.Staged})
+	}
+	out.Clean = len(out.Files) == 0
+	return out, nil
+}
+
+// WorkspaceCreateFromTemplate creates a workspace from a template directory,
+// commits the copied files and emits workspace.created on the new id.
+func WorkspaceCreateFromTemplate(ctx context.Context, wm *workspace.Manager, a CreateFromTemplateRequest) (CreateFromTemplateResponse, error) {
+	if a.Name == "" || a.Template == "" {
+		return CreateFromTemplateResponse{}, fmt.Errorf("INVALID_INPUT: 'name' and 'template' are required")
+	}
+	id, path, err := wm.CreateFromTemplate(a.Name, a.Template)
+	if err != nil {
+		switch {
+		case errors.Is(err, workspace.ErrTemplatesDisabled):
+			return CreateFromTemplateResponse{}, fmt.Errorf("UNSUPPORTED: %v", err)
+		case errors.Is(err, workspace.ErrInvalidTemplate):
+			return CreateFromTemplateResponse{}, fmt.Errorf("INVALID_INPUT: %v", err)
+		case errors.Is(err, workspace.ErrTemplateNotFound):
+			return CreateFromTemplateResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		}
+		return CreateFromTemplateResponse{}, writeFailed("failed to create workspace from template", err)
+	}
+	out := CreateFromTemplateResponse{WorkspaceID: id, Path: path}
+	commit, err := wm.Commit(id, fmt.Sprintf("mcp/workspace_create_from_template: Create from template %s", a.Template), defaultCommitAuthor)
+	switch {
+	case err == nil:
+		out.Commit = commit
+	case !errors.Is(err, git.ErrEmptyCommit):
+		return CreateFromTemplateResponse{}, fmt.Errorf("INTERNAL: failed to commit template: %v", err)
+	}
+	var commitRef *string
+	if out.Commit != "" {
+		commitRef = &out.Commit
+	}
+	publishWorkspaceEvent(ctx, id, events.WorkspaceEvent{
+		Type:   "workspace.created",
+		IsDir:  true,
+		Commit: commitRef,
+	})
+	return out, nil
+}
+
+// WorkspaceClone copies a workspace, history included, into a new workspace
+// named a.Name and emits workspace.created on the new id. The source is locked
+// while it is copied.
+func WorkspaceClone(ctx context.Context, wm *workspace.Manager, a CloneWorkspaceRequest) (CloneWorkspaceResponse, error) {
+	if a.WorkspaceID == "" || a.Name == "" {
+		return CloneWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'name' are required")
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return CloneWorkspaceResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	id, path, err := wm.Clone(a.WorkspaceID, a.Name)
+	unlock()
+	if err != nil {
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+			return CloneWorkspaceResponse{}, fmt.Errorf("UNSUPPORTED: %v", err)
+		case errors.Is(err, workspace.ErrWorkspaceExists):
+			return CloneWorkspaceResponse{}, fmt.Errorf("ALREADY_EXISTS: %v", err)
+		case strings.Contains(err.Error(), "not found"):
+			return CloneWorkspaceResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		}
+		return CloneWorkspaceResponse{}, writeFailed("clone failed", err)
+	}
+	head, _ := wm.HeadCommit(id)
+	var commitRef *string
+	if head != "" {
+		commitRef = &head
+	}
+	publishWorkspaceEvent(ctx, id, events.WorkspaceEvent{
+		Type:   "workspace.created",
+		IsDir:  true,
+		Commit: commitRef,
+	})
+	return CloneWorkspaceResponse{WorkspaceID: id, SourceWorkspaceID: a.WorkspaceID, Path: path, HeadCommit: head}, nil
+}
+
+// defaultCommitAuthor is the commit author name used when a request names none.
+const defaultCommitAuthor = "mcp-client"
+
+// checkAuthor rejects author names and emails that would corrupt the commit
+// signature ("Name <email>").
+func checkAuthor(name, email string) error {
+	if strings.ContainsAny(name, "<>\n") || strings.ContainsAny(email, "<>\n") {
+		return fmt.Errorf("INVALID_INPUT: 'authorName' and 'authorEmail' must not contain '<', '>' or newlines")
+	}
+	return nil
+}
+
+// checkProtectedContents refuses, with FORBIDDEN, to move, copy or delete the
+// directory abs of the workspace at root while it holds a protected entry, so
+// that a parent cannot carry protected files along.
+func checkProtectedContents(wm *workspace.Manager, root, abs, verb string) error {
+	rel, err := wm.Protected().ProtectedWithin(wm.FS(), root, abs)
+	if err != nil {
+		return fmt.Errorf("INTERNAL: failed to scan directory: %v", err)
+	}
+	if rel != "" {
+		return fmt.Errorf("FORBIDDEN: directory contains protected path %q and cannot be %s", rel, verb)
+	}
+	return nil
+}
+
+// commitAs commits the workspace's pending changes attributed to the caller's
+// author, falling back to defaultCommitAuthor and the manager's default email.
+func commitAs(wm *workspace.Manager, workspaceID, message, name, email string) (string, error) {
+	if name == "" {
+		name = defaultCommitAuthor
+	}
+	return wm.CommitAs(workspaceID, message, name, email)
 }
 
 func FSWriteFile(ctx context.Context, wm *workspace.Manager, a WriteFileRequest) (WriteFileResponse, error) {
 	if a.WorkspaceID == "" || a.Path == "" {
 		return WriteFileResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
 	}
-	if isProtectedPath(a.Path) {
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return WriteFileResponse{}, err
+	}
+	enc, err := lookupEncoding(a.Encoding)
+	if err != nil {
+		return WriteFileResponse{}, err
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
 		return WriteFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 	}
 	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
 		return WriteFileResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	_, statErr := os.Stat(absPath)
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return WriteFileResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	fsys := wm.FS()
+	root, _ := wm.SafePath(a.WorkspaceID, ".")
+	if err := checkCaseCollision(fsys, root, absPath, ""); err != nil {
+		return WriteFileResponse{}, err
+	}
+	_, statErr := fsys.Stat(absPath)
 	overwritten := !os.IsNotExist(statErr)
 
 	// Preconditions
 	var currEtag string
+	var curr []byte
 	if overwritten {
-		if b, errR := os.ReadFile(absPath); errR == nil {
+		if b, errR := fsys.ReadFile(absPath); errR == nil {
+			curr = b
 			sum := sha256.Sum256(b)
 			currEtag = fmt.Sprintf("%x", sum[:])
 		}
 	}
 	if a.IfMatchFileEtag != nil && *a.IfMatchFileEtag != "" && overwritten && currEtag != *a.IfMatchFileEtag {
-		return WriteFileResponse{}, fmt.Errorf("CONFLICT: file etag mismatch")
+		return WriteFileResponse{}, withDetails(fmt.Errorf("CONFLICT: file etag mismatch"), map[string]any{"currentEtag": currEtag})
 	}
 	if a.IfMatchWorkspaceHead != nil && *a.IfMatchWorkspaceHead != "" {
 		if head, _ := wm.HeadCommit(a.WorkspaceID); head != *a.IfMatchWorkspaceHead {
-			return WriteFileResponse{}, fmt.Errorf("CONFLICT: workspace head mismatch")
+			return WriteFileResponse{}, withDetails(fmt.Errorf("CONFLICT: workspace head mismatch"), map[string]any{"currentHead": head})
 		}
 	}
 
-	// Prepare content and short-circuit if no-op (unchanged file)
-	contentBytes := []byte(a.Content)
+	// Prepare content and short-circuit if no-op (unchanged file). The
+	// comparison uses the normalized bytes so rewriting an already-normalized
+	// file is still a no-op.
+	content := normalizeContent(a.Content, a.NormalizeNewlines, a.EnsureTrailingNewline)
+	contentBytes := []byte(content)
+	if enc == encodingAuto {
+		enc = detectEncoding(curr)
+	}
+	if enc != "" {
+		if contentBytes, err = encodeText(contentBytes, enc); err != nil {
+			return WriteFileResponse{}, err
+		}
+	}
 	if overwritten {
 		sumNew := sha256.Sum256(contentBytes)
 		newEtag := fmt.Sprintf("%x", sumNew[:])
 		if currEtag != "" && newEtag == currEtag {
 			// No changes; do not write, do not commit, do not emit events
-			return WriteFileResponse{Path: a.Path, BytesWritten: 0, Overwritten: overwritten, Commit: ""}, nil
+			return WriteFileResponse{DryRun: a.DryRun, Path: a.Path, BytesWritten: 0, Overwritten: overwritten, Commit: ""}, nil
 		}
 	}
+	if a.DryRun {
+		// Diff the text as fs_read_text_file would return it, decoding the
+		// current file when an encoding applies.
+		currText := string(curr)
+		if enc != "" && curr != nil {
+			if b, err := decodeText(curr, enc); err == nil {
+				currText = string(b)
+			}
+		}
+		fromName := "a/" + a.Path
+		if !overwritten {
+			fromName = "/dev/null"
+		}
+		diff, _, _ := unifiedDiff(fromName, "b/"+a.Path, currText, content)
+		return WriteFileResponse{DryRun: true, Path: a.Path, BytesWritten: len(contentBytes), Overwritten: overwritten, Diff: diff}, nil
+	}
 
-	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+	expectWorkspaceChange(a.WorkspaceID, a.Path, "file.created", "file.updated")
+	if err := fsys.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
 		return WriteFileResponse{}, fmt.Errorf("INTERNAL: failed to create parent directories: %v", err)
 	}
-	if err := os.WriteFile(absPath, contentBytes, 0644); err != nil {
-		return WriteFileResponse{}, fmt.Errorf("INTERNAL: failed to write file: %v", err)
+	if err := wm.WriteFileAtomic(a.WorkspaceID, absPath, contentBytes, 0644); err != nil {
+		return WriteFileResponse{}, writeFailed("failed to write file", err)
 	}
-	commit, err := wm.Commit(a.WorkspaceID, fmt.Sprintf("mcp/fs_write_file: Write %s", a.Path), "mcp-client")
+	removeRedundantGitkeeps(fsys, root, absPath)
+	commit, err := commitAs(wm, a.WorkspaceID, fmt.Sprintf("mcp/fs_write_file: Write %s", a.Path), a.AuthorName, a.AuthorEmail)
 	if err != nil {
 		return WriteFileResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
 	}
@@ -124,7 +677,7 @@ func FSWriteFile(ctx context.Context, wm *workspace.Manager, a WriteFileRequest)
 		evtType = "file.updated"
 	}
 	commitCopy := commit
-	publishWorkspaceEvent(a.WorkspaceID, events.WorkspaceEvent{
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
 		Type:   evtType,
 		Path:   a.Path,
 		IsDir:  false,
@@ -134,6 +687,142 @@ func FSWriteFile(ctx context.Context, wm *workspace.Manager, a WriteFileRequest)
 	return WriteFileResponse{Path: a.Path, BytesWritten: len(contentBytes), Overwritten: overwritten, Commit: commit}, nil
 }
 
+// readLimit returns the number of bytes a read tool may return for a
+// requested maxBytes: the request value when set, capped at maxReadBytes.
+func readLimit(maxBytes int64) (int64, error) {
+	if maxBytes < 0 {
+		return 0, fmt.Errorf("INVALID_INPUT: 'maxBytes' must not be negative")
+	}
+	if maxBytes == 0 || maxBytes > maxReadBytes {
+		return maxReadBytes, nil
+	}
+	return maxBytes, nil
+}
+
+// readCapped reads at most limit bytes of the size-byte file at abs, from its
+// start or, with fromEnd, from its end. The window never splits a character
+// of the named encoding ("" being UTF-8): UTF-16 windows are aligned to code
+// units and surrogate pairs, and a UTF-8 character cut at the edge is dropped.
+func readCapped(fsys workspace.FS, abs string, size, limit int64, fromEnd bool, enc string) ([]byte, error) {
+	f, err := fsys.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	unit := codeUnit(enc)
+	limit -= limit % unit
+	var off int64
+	if fromEnd {
+		off = size - limit
+		if rem := off % unit; rem != 0 {
+			off += unit - rem
+		}
+	}
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, limit)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	buf = buf[:n]
+	switch enc {
+	case "", "utf-8":
+		return trimUTF8Window(buf, fromEnd), nil
+	case "utf-16le", "utf-16be":
+		return trimUTF16Window(buf, fromEnd, enc == "utf-16be"), nil
+	}
+	return buf, nil
+}
+
+// fileContains reports whether the file at abs contains needle, which must not
+// be empty, reading it in chunks rather than whole.
+func fileContains(fsys workspace.FS, abs string, needle []byte) (bool, error) {
+	f, err := fsys.Open(abs)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	// Each chunk keeps the previous one's last len(needle)-1 bytes, so matches
+	// spanning two reads are still found.
+	keep := len(needle) - 1
+	buf := make([]byte, 0, max(64<<10, 2*len(needle)))
+	for {
+		n, err := f.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if bytes.Contains(buf, needle) {
+			return true, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if len(buf) > keep {
+			buf = buf[:copy(buf, buf[len(buf)-keep:])]
+		}
+	}
+}
+
+// trimUTF8Window drops a UTF-8 character cut at the start (fromEnd) or the
+// end of a window.
+func trimUTF8Window(buf []byte, fromEnd bool) []byte {
+	if fromEnd {
+		for i := 0; i < utf8.UTFMax-1 && len(buf) > 0 && !utf8.RuneStart(buf[0]); i++ {
+			buf = buf[1:]
+		}
+		return buf
+	}
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				buf = buf[:i]
+			}
+			break
+		}
+	}
+	return buf
+}
+
+// trimUTF16Window drops half a surrogate pair cut at the start (fromEnd) or
+// the end of a code-unit aligned window.
+func trimUTF16Window(buf []byte, fromEnd, bigEndian bool) []byte {
+	unitAt := func(i int) uint16 {
+		if bigEndian {
+			return uint16(buf[i])<<8 | uint16(buf[i+1])
+		}
+		return uint16(buf[i+1])<<8 | uint16(buf[i])
+	}
+	if len(buf) < 2 {
+		return buf
+	}
+	if fromEnd {
+		if u := unitAt(0); u >= 0xDC00 && u <= 0xDFFF {
+			return buf[2:]
+		}
+		return buf
+	}
+	if u := unitAt(len(buf) - 2); u >= 0xD800 && u <= 0xDBFF {
+		return buf[:len(buf)-2]
+	}
+	return buf
+}
+
+// normalizeContent applies fs_write_file's optional newline normalization:
+// CRLF and lone CR become LF, and a final "\n" is appended to non-empty content.
+func normalizeContent(content string, normalizeNewlines, ensureTrailingNewline bool) string {
+	if normalizeNewlines {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+		content = strings.ReplaceAll(content, "\r", "\n")
+	}
+	if ensureTrailingNewline && content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content
+}
+
 func FSReadTextFile(ctx context.Context, wm *workspace.Manager, a ReadFileRequest) (ReadFileResponse, error) {
 	if a.WorkspaceID == "" || a.Path == "" {
 		return ReadFileResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
@@ -141,41 +830,107 @@ func FSReadTextFile(ctx context.Context, wm *workspace.Manager, a ReadFileReques
 	if a.Head != nil && a.Tail != nil {
 		return ReadFileResponse{}, fmt.Errorf("INVALID_INPUT: cannot specify both 'head' and 'tail'")
 	}
-	if isProtectedPath(a.Path) {
+	if wm.Protected().IsProtectedPath(a.Path) {
 		return ReadFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 	}
+	limit, err := readLimit(a.MaxBytes)
+	if err != nil {
+		return ReadFileResponse{}, err
+	}
+	enc, err := lookupEncoding(a.Encoding)
+	if err != nil {
+		return ReadFileResponse{}, err
+	}
+	var modifiedSince time.Time
+	if a.IfModifiedSince != "" {
+		if modifiedSince, err = time.Parse(time.RFC3339, a.IfModifiedSince); err != nil {
+			return ReadFileResponse{}, fmt.Errorf("INVALID_INPUT: 'ifModifiedSince' must be RFC3339: %v", err)
+		}
+	}
 	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
 		return ReadFileResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	contentBytes, err := os.ReadFile(absPath)
+	fsys := wm.FS()
+	info, err := fsys.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return ReadFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 		}
 		return ReadFileResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
 	}
+	// As with HTTP conditional requests, the etag takes precedence over the
+	// timestamp. mtime is compared at the second resolution it is reported in.
+	if info.Mode().IsRegular() && (a.IfNoneMatchEtag != "" || !modifiedSince.IsZero()) {
+		var etag string
+		notModified := false
+		if a.IfNoneMatchEtag != "" {
+			if _, etag, err = hashFile(fsys, absPath); err != nil {
+				return ReadFileResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+			}
+			notModified = etag == a.IfNoneMatchEtag
+		} else {
+			notModified = !info.ModTime().Truncate(time.Second).After(modifiedSince)
+		}
+		if notModified {
+			head, _ := wm.HeadCommit(a.WorkspaceID)
+			return ReadFileResponse{
+				Etag:          etag,
+				Mtime:         info.ModTime().UTC().Format(time.RFC3339),
+				WorkspaceHead: head,
+				Size:          info.Size(),
+				NotModified:   true,
+			}, nil
+		}
+	}
+	if enc == encodingAuto && info.Mode().IsRegular() {
+		if enc, err = detectFileEncoding(fsys, absPath); err != nil {
+			return ReadFileResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+		}
+	}
+	// Over the limit only a window of limit bytes is read: the end of the
+	// file for tail, its start otherwise. The etag still covers the whole file.
+	truncated := info.Mode().IsRegular() && info.Size() > limit
+	var contentBytes []byte
+	var etag string
+	if truncated {
+		contentBytes, err = readCapped(fsys, absPath, info.Size(), limit, a.Tail != nil, enc)
+		if err == nil {
+			_, etag, err = hashFile(fsys, absPath)
+		}
+	} else {
+		contentBytes, err = fsys.ReadFile(absPath)
+		sum := sha256.Sum256(contentBytes)
+		etag = fmt.Sprintf("%x", sum[:])
+	}
+	if err != nil {
+		return ReadFileResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+	}
+	if enc != "" {
+		if contentBytes, err = decodeText(contentBytes, enc); err != nil {
+			return ReadFileResponse{}, err
+		}
+	}
 	content := string(contentBytes)
 	lines := strings.Split(content, "\n")
 	total := len(lines)
 
-	sum := sha256.Sum256(contentBytes)
-	etag := fmt.Sprintf("%x", sum[:])
-
-	var mtimeStr string
-	if info, statErr := os.Stat(absPath); statErr == nil {
-		mtimeStr = info.ModTime().UTC().Format(time.RFC3339)
-	}
-
 	head, _ := wm.HeadCommit(a.WorkspaceID)
 
 	resp := ReadFileResponse{
-		TotalLines:    total,
 		Etag:          etag,
-		Mtime:         mtimeStr,
+		Mtime:         info.ModTime().UTC().Format(time.RFC3339),
 		WorkspaceHead: head,
+		Size:          info.Size(),
+		Encoding:      enc,
+	}
+	if !truncated {
+		resp.TotalLines = total
 	}
 
+	// A truncated read only misses content when the requested lines do not
+	// all fit in the window, which always ends (or, for tail, starts) with
+	// a partial line.
 	if a.Head != nil {
 		h := *a.Head
 		if h > total {
@@ -183,6 +938,7 @@ func FSReadTextFile(ctx context.Context, wm *workspace.Manager, a ReadFileReques
 		}
 		resp.Content = strings.Join(lines[:h], "\n")
 		resp.Head = &h
+		resp.Truncated = truncated && h == total
 	} else if a.Tail != nil {
 		t := *a.Tail
 		if t > total {
@@ -190,42 +946,124 @@ func FSReadTextFile(ctx context.Context, wm *workspace.Manager, a ReadFileReques
 		}
 		resp.Content = strings.Join(lines[total-t:], "\n")
 		resp.Tail = &t
+		resp.Truncated = truncated && t == total
 	} else {
 		resp.Content = content
+		resp.Truncated = truncated
 	}
+	sliceSum := sha256.Sum256([]byte(resp.Content))
+	resp.SliceEtag = fmt.Sprintf("%x", sliceSum[:])
 	return resp, nil
 }
 
-func FSCreateDirectory(ctx context.Context, wm *workspace.Manager, a CreateDirectoryRequest) (CreateDirectoryResponse, error) {
-	if isProtectedPath(a.Path) {
-		return CreateDirectoryResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+// FSReadLines returns count lines of a text file starting at the 1-based
+// startLine, each with its line number. The window is clamped to the file,
+// which is streamed so only the returned lines are held in memory; at most
+// --max-read-bytes of line text is returned.
+func FSReadLines(ctx context.Context, wm *workspace.Manager, a ReadLinesRequest) (ReadLinesResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" {
+		return ReadLinesResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
+	}
+	if a.StartLine < 0 || a.Count < 0 {
+		return ReadLinesResponse{}, fmt.Errorf("INVALID_INPUT: 'startLine' and 'count' must not be negative")
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return ReadLinesResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 	}
 	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
-		return CreateDirectoryResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
-	}
-	_, statErr := os.Stat(absPath)
-	created := os.IsNotExist(statErr)
-	if err := os.MkdirAll(absPath, 0755); err != nil {
-		return CreateDirectoryResponse{}, fmt.Errorf("INTERNAL: failed to create directory: %v", err)
+		return ReadLinesResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	// Ensure tracking empty folders
-	gk := filepath.Join(absPath, ".gitkeep")
-	if _, err := os.Stat(gk); os.IsNotExist(err) {
-		if f, e := os.Create(gk); e == nil {
-			f.Close()
+	f, err := wm.FS().Open(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ReadLinesResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 		}
+		return ReadLinesResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
 	}
-	commit, err := wm.Commit(a.WorkspaceID, fmt.Sprintf("mcp/fs_create_directory: Create %s", a.Path), "mcp-client")
-	if err != nil {
-		return CreateDirectoryResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		return ReadLinesResponse{}, fmt.Errorf("INVALID_INPUT: path is a directory")
 	}
 
-	// Publish event
-	commitCopy := commit
-	publishWorkspaceEvent(a.WorkspaceID, events.WorkspaceEvent{
-		Type:   "dir.created",
-		Path:   a.Path,
+	start := max(a.StartLine, 1)
+	resp := ReadLinesResponse{Lines: []NumberedLine{}}
+	budget := maxReadBytes
+	rd := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := rd.ReadString('\n')
+		if line == "" && err != nil {
+			if err != io.EOF {
+				return ReadLinesResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+			}
+			break
+		}
+		resp.TotalLines = n
+		if n < start || (a.Count > 0 && n >= start+a.Count) || resp.Truncated {
+			continue
+		}
+		text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if int64(len(text)) > budget {
+			resp.Truncated = true
+			continue
+		}
+		budget -= int64(len(text))
+		resp.Lines = append(resp.Lines, NumberedLine{LineNumber: n, Text: text})
+	}
+	return resp, nil
+}
+
+func FSCreateDirectory(ctx context.Context, wm *workspace.Manager, a CreateDirectoryRequest) (CreateDirectoryResponse, error) {
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return CreateDirectoryResponse{}, err
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return CreateDirectoryResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	}
+	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
+	if err != nil {
+		return CreateDirectoryResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return CreateDirectoryResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	fsys := wm.FS()
+	root, _ := wm.SafePath(a.WorkspaceID, ".")
+	if err := checkCaseCollision(fsys, root, absPath, ""); err != nil {
+		return CreateDirectoryResponse{}, err
+	}
+	_, statErr := fsys.Stat(absPath)
+	created := os.IsNotExist(statErr)
+	expectWorkspaceChange(a.WorkspaceID, a.Path, "dir.created")
+	if err := fsys.MkdirAll(absPath, 0755); err != nil {
+		return CreateDirectoryResponse{}, fmt.Errorf("INTERNAL: failed to create directory: %v", err)
+	}
+	// Ensure tracking empty folders; a directory with entries is tracked
+	// through them and needs no marker
+	addedKeep := false
+	if entries, err := fsys.ReadDir(absPath); err == nil && len(entries) == 0 {
+		addedKeep = fsys.WriteFile(filepath.Join(absPath, ".gitkeep"), nil, 0644) == nil
+	}
+	if !created && !addedKeep {
+		// Directory already existed and is tracked: no commit, no event
+		return CreateDirectoryResponse{Path: a.Path, Created: false, Commit: ""}, nil
+	}
+	commit, err := commitAs(wm, a.WorkspaceID, fmt.Sprintf("mcp/fs_create_directory: Create %s", a.Path), a.AuthorName, a.AuthorEmail)
+	if err != nil {
+		return CreateDirectoryResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+	if !created {
+		// Only the .gitkeep marker was added; the directory itself is not new
+		return CreateDirectoryResponse{Path: a.Path, Created: false, Commit: commit}, nil
+	}
+
+	// Publish event
+	commitCopy := commit
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+		Type:   "dir.created",
+		Path:   a.Path,
 		IsDir:  true,
 		Commit: &commitCopy,
 	})
@@ -238,13 +1076,13 @@ func FSListDirectory(ctx context.Context, wm *workspace.Manager, a ListDirectory
 	if err != nil {
 		return ListDirectoryResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	files, err := os.ReadDir(absPath)
+	files, err := wm.FS().ReadDir(absPath)
 	if err != nil {
 		return ListDirectoryResponse{}, fmt.Errorf("INTERNAL: failed to list directory: %v", err)
 	}
 	var entries []string
 	for _, f := range files {
-		if isProtectedName(f.Name()) {
+		if wm.Protected().IsProtectedPath(filepath.Join(a.Path, f.Name())) {
 			continue
 		}
 		prefix := "[FILE]"
@@ -257,14 +1095,14 @@ func FSListDirectory(ctx context.Context, wm *workspace.Manager, a ListDirectory
 }
 
 func FSGetFileInfo(ctx context.Context, wm *workspace.Manager, a GetFileInfoRequest) (GetFileInfoResponse, error) {
-	if isProtectedPath(a.Path) {
+	if wm.Protected().IsProtectedPath(a.Path) {
 		return GetFileInfoResponse{}, fmt.Errorf("NOT_FOUND: file or directory not found")
 	}
 	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
 		return GetFileInfoResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	info, err := os.Stat(absPath)
+	info, err := wm.FS().Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return GetFileInfoResponse{}, fmt.Errorf("NOT_FOUND: file or directory not found")
@@ -281,9 +1119,58 @@ func FSGetFileInfo(ctx context.Context, wm *workspace.Manager, a GetFileInfoRequ
 		Type:        ftype,
 		Permissions: info.Mode().String(),
 	}
+	if !info.IsDir() {
+		mimeType, isBinary, err := sniffFile(wm.FS(), absPath)
+		if err != nil {
+			return GetFileInfoResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+		}
+		out.MimeType = mimeType
+		out.IsBinary = isBinary
+	}
 	return out, nil
 }
 
+// FSStatMultiple runs fs_get_file_info for each of Paths, keeping their order;
+// a path that fails (missing, protected, out of bounds) gets its error in its
+// entry instead of failing the call.
+func FSStatMultiple(ctx context.Context, wm *workspace.Manager, a StatMultipleRequest) (StatMultipleResponse, error) {
+	if a.WorkspaceID == "" || len(a.Paths) == 0 {
+		return StatMultipleResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'paths' are required")
+	}
+	out := make([]FileStatResult, len(a.Paths))
+	for i, p := range a.Paths {
+		info, err := FSGetFileInfo(ctx, wm, GetFileInfoRequest{WorkspaceID: a.WorkspaceID, Path: p})
+		if err != nil {
+			errStr := err.Error()
+			out[i] = FileStatResult{Path: p, Error: &errStr}
+			continue
+		}
+		out[i] = FileStatResult{Path: p, OK: true, GetFileInfoResponse: &info}
+	}
+	return StatMultipleResponse{Results: out}, nil
+}
+
+// sniffLen is how much of a file sniffFile reads, matching what
+// http.DetectContentType considers.
+const sniffLen = 512
+
+// sniffFile detects the MIME type of the file at path from its first sniffLen
+// bytes and reports it as binary when that prefix contains a NUL byte.
+func sniffFile(fsys workspace.FS, path string) (mimeType string, isBinary bool, err error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", false, err
+	}
+	buf = buf[:n]
+	return http.DetectContentType(buf), bytes.IndexByte(buf, 0) >= 0, nil
+}
+
 func FSGetCommitHistory(ctx context.Context, wm *workspace.Manager, a GetCommitHistoryRequest) (GetCommitHistoryResponse, error) {
 	if a.WorkspaceID == "" {
 		return GetCommitHistoryResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
@@ -292,43 +1179,61 @@ func FSGetCommitHistory(ctx context.Context, wm *workspace.Manager, a GetCommitH
 	if a.Limit > 0 {
 		limit = a.Limit
 	}
+	var window workspace.TimeWindow
+	if a.Since != "" {
+		t, err := time.Parse(time.RFC3339, a.Since)
+		if err != nil {
+			return GetCommitHistoryResponse{}, fmt.Errorf("INVALID_INPUT: 'since' must be RFC3339: %v", err)
+		}
+		window.Since = t
+	}
+	if a.Until != "" {
+		t, err := time.Parse(time.RFC3339, a.Until)
+		if err != nil {
+			return GetCommitHistoryResponse{}, fmt.Errorf("INVALID_INPUT: 'until' must be RFC3339: %v", err)
+		}
+		window.Until = t
+	}
+	if !window.Since.IsZero() && !window.Until.IsZero() && window.Until.Before(window.Since) {
+		return GetCommitHistoryResponse{}, fmt.Errorf("INVALID_INPUT: 'until' is before 'since'")
+	}
+	var before string
+	if a.Before != "" {
+		h, err := wm.ResolveRef(a.WorkspaceID, a.Before)
+		if err != nil {
+			return GetCommitHistoryResponse{}, fmt.Errorf("NOT_FOUND: commit not found")
+		}
+		before = h
+	}
 
-	// Determine which history to fetch: workspace-wide or file-scoped
+	// Fetch one extra commit to learn whether another page exists.
+	var commits []object.Commit
+	var err error
 	if strings.TrimSpace(a.Path) != "" {
 		// Per-file history
-		fileCommits, err := wm.GetFileCommitHistory(a.WorkspaceID, a.Path, limit)
+		commits, err = wm.GetFileCommitHistory(a.WorkspaceID, a.Path, limit+1, before, window)
 		if err != nil {
 			return GetCommitHistoryResponse{}, fmt.Errorf("INTERNAL: failed to get file commit history: %v", err)
 		}
-		var log []CommitLog
-		for _, c := range fileCommits {
-			var parent string
-			if p, err := c.Parents().Next(); err == nil && p != nil {
-				parent = p.Hash.String()
-			}
-			log = append(log, CommitLog{
-				Commit:  c.Hash.String(),
-				Author:  c.Author.String(),
-				Date:    c.Author.When.UTC().Format(time.RFC3339),
-				Message: c.Message,
-				Parent:  parent,
-			})
+	} else {
+		// Workspace-wide history (fallback)
+		commits, err = wm.GetCommitHistory(a.WorkspaceID, limit+1, before, window)
+		if err != nil {
+			return GetCommitHistoryResponse{}, fmt.Errorf("INTERNAL: failed to get commit history: %v", err)
 		}
-		return GetCommitHistoryResponse{Log: log}, nil
 	}
 
-	// Workspace-wide history (fallback)
-	wsCommits, err := wm.GetCommitHistory(a.WorkspaceID, limit)
-	if err != nil {
-		return GetCommitHistoryResponse{}, fmt.Errorf("INTERNAL: failed to get commit history: %v", err)
+	var out GetCommitHistoryResponse
+	if len(commits) > limit {
+		commits = commits[:limit]
+		out.NextCursor = commits[limit-1].Hash.String()
 	}
-	var log []CommitLog
-	for _, c := range wsCommits {
+	for _, c := range commits {
 		var parent string
 		if p, err := c.Parents().Next(); err == nil && p != nil {
 			parent = p.Hash.String()
 		}
-		log = append(log, CommitLog{
+		out.Log = append(out.Log, CommitLog{
 			Commit:  c.Hash.String(),
 			Author:  c.Author.String(),
 			Date:    c.Author.When.UTC().Format(time.RFC3339),
@@ -336,7 +1241,7 @@ func FSGetCommitHistory(ctx context.Context, wm *workspace.Manager, a GetCommitH
 			Parent:  parent,
 		})
 	}
-	return GetCommitHistoryResponse{Log: log}, nil
+	return out, nil
 }
 
 // FSReadFileAtCommit returns the content of a file at a specific commit.
@@ -344,7 +1249,7 @@ func FSReadFileAtCommit(ctx context.Context, wm *workspace.Manager, a ReadFileAt
 	if a.WorkspaceID == "" || a.Path == "" || a.Commit == "" {
 		return ReadFileAtCommitResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'path', and 'commit' are required")
 	}
-	if isProtectedPath(a.Path) {
+	if wm.Protected().IsProtectedPath(a.Path) {
 		return ReadFileAtCommitResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 	}
 	content, err := wm.ReadFileAtCommit(a.WorkspaceID, a.Path, a.Commit)
@@ -355,9 +1260,119 @@ func FSReadFileAtCommit(ctx context.Context, wm *workspace.Manager, a ReadFileAt
 	return ReadFileAtCommitResponse{Content: content, Commit: a.Commit}, nil
 }
 
+// FSRestoreFromSnapshot writes a file's content at a snapshot (tag, branch, or commit)
+// back to the working tree and commits the restore. Restoring a file that already
+// matches the snapshot is a no-op: no write, no commit, no event.
+func FSRestoreFromSnapshot(ctx context.Context, wm *workspace.Manager, a RestoreFromSnapshotRequest) (RestoreFromSnapshotResponse, error) {
+	if a.WorkspaceID == "" || a.Snapshot == "" || a.Path == "" {
+		return RestoreFromSnapshotResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'snapshot', and 'path' are required")
+	}
+	snapshotCommit, commit, err := restoreFileAt(ctx, wm, a.WorkspaceID, a.Path, a.Snapshot, "snapshot",
+		fmt.Sprintf("mcp/fs_restore_from_snapshot: Restore %s from %s", a.Path, a.Snapshot))
+	if err != nil {
+		return RestoreFromSnapshotResponse{}, err
+	}
+	return RestoreFromSnapshotResponse{Path: a.Path, SnapshotCommit: snapshotCommit, Commit: commit}, nil
+}
+
+// FSRestoreFile writes path back to its content at commit and commits the
+// restore; fs_restore_from_snapshot does the same for tags and branches.
+func FSRestoreFile(ctx context.Context, wm *workspace.Manager, a RestoreFileRequest) (RestoreFileResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" || a.Commit == "" {
+		return RestoreFileResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'path', and 'commit' are required")
+	}
+	restoredFrom, commit, err := restoreFileAt(ctx, wm, a.WorkspaceID, a.Path, a.Commit, "commit",
+		fmt.Sprintf("mcp/fs_restore_file: Restore %s from %s", a.Path, a.Commit))
+	if err != nil {
+		return RestoreFileResponse{}, err
+	}
+	return RestoreFileResponse{Path: a.Path, RestoredFrom: restoredFrom, Commit: commit}, nil
+}
+
+// restoreFileAt resolves rev (a "snapshot" or "commit", as named in errors)
+// and writes rel back to its content there, committing with message. It
+// returns the resolved commit and the new commit, which is empty when the
+// file already matched.
+func restoreFileAt(ctx context.Context, wm *workspace.Manager, workspaceID, rel, rev, revKind, message string) (string, string, error) {
+	if wm.Protected().IsProtectedPath(rel) {
+		return "", "", fmt.Errorf("NOT_FOUND: file not found")
+	}
+	absPath, err := wm.SafePath(workspaceID, rel)
+	if err != nil {
+		return "", "", fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	unlock, err := wm.Lock(workspaceID)
+	if err != nil {
+		return "", "", fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	resolved, err := wm.ResolveRef(workspaceID, rev)
+	if err != nil {
+		return "", "", fmt.Errorf("NOT_FOUND: %s not found", revKind)
+	}
+	content, err := wm.ReadFileAtCommit(workspaceID, filepath.ToSlash(filepath.Clean(rel)), resolved)
+	if err != nil {
+		return "", "", fmt.Errorf("NOT_FOUND: file not found at %s", revKind)
+	}
+
+	curr, readErr := os.ReadFile(absPath)
+	existed := readErr == nil
+	if existed && bytes.Equal(curr, []byte(content)) {
+		return resolved, "", nil
+	}
+	if readErr != nil && !os.IsNotExist(readErr) {
+		return "", "", fmt.Errorf("INTERNAL: failed to read file: %v", readErr)
+	}
+
+	expectWorkspaceChange(workspaceID, rel, "file.created", "file.updated")
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return "", "", fmt.Errorf("INTERNAL: failed to create parent directories: %v", err)
+	}
+	if err := wm.WriteFileAtomic(workspaceID, absPath, []byte(content), 0644); err != nil {
+		return "", "", writeFailed("failed to write file", err)
+	}
+	if root, err := wm.SafePath(workspaceID, "."); err == nil {
+		removeRedundantGitkeeps(wm.FS(), root, absPath)
+	}
+	commit, err := wm.Commit(workspaceID, message, "mcp-client")
+	if err != nil {
+		return "", "", fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+
+	evtType := "file.created"
+	if existed {
+		evtType = "file.updated"
+	}
+	commitCopy := commit
+	publishWorkspaceEvent(ctx, workspaceID, events.WorkspaceEvent{
+		Type:   evtType,
+		Path:   rel,
+		Commit: &commitCopy,
+	})
+	return resolved, commit, nil
+}
+
+// FSMoveFile renames a file or directory, creating the destination's parent
+// directories. Paths that are themselves protected (.git, .gitkeep, or anything
+// inside .git) are refused with FORBIDDEN; a directory that merely contains a
+// .gitkeep moves normally, taking the marker with it. An existing destination
+// file is replaced only with Overwrite, and only by a file.
 func FSMoveFile(ctx context.Context, wm *workspace.Manager, a MoveFileRequest) (MoveFileResponse, error) {
-	if isProtectedPath(a.Source) || isProtectedPath(a.Destination) {
-		return MoveFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	if a.WorkspaceID == "" || a.Source == "" || a.Destination == "" {
+		return MoveFileResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'source', and 'destination' are required")
+	}
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return MoveFileResponse{}, err
+	}
+	if wm.Protected().IsProtectedPath(a.Source) {
+		return MoveFileResponse{}, fmt.Errorf("FORBIDDEN: source %q is a protected path and cannot be moved", a.Source)
+	}
+	if wm.Protected().IsProtectedPath(a.Destination) {
+		return MoveFileResponse{}, fmt.Errorf("FORBIDDEN: destination %q is a protected path and cannot be overwritten or created", a.Destination)
+	}
+	root, err := wm.SafePath(a.WorkspaceID, ".")
+	if err != nil {
+		return MoveFileResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
 	}
 	src, err := wm.SafePath(a.WorkspaceID, a.Source)
 	if err != nil {
@@ -367,47 +1382,102 @@ func FSMoveFile(ctx context.Context, wm *workspace.Manager, a MoveFileRequest) (
 	if err != nil {
 		return MoveFileResponse{}, fmt.Errorf("OUT_OF_BOUNDS: destination path invalid: %v", err)
 	}
-	if _, err := os.Stat(dst); !os.IsNotExist(err) {
-		return MoveFileResponse{}, fmt.Errorf("ALREADY_EXISTS: destination exists")
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return MoveFileResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	if src == root || dst == root {
+		return MoveFileResponse{}, fmt.Errorf("FORBIDDEN: the workspace root cannot be moved or replaced")
+	}
+	fsys := wm.FS()
+	srcInfo, err := fsys.Stat(src)
+	if os.IsNotExist(err) {
+		return MoveFileResponse{}, fmt.Errorf("NOT_FOUND: source not found")
+	}
+	if strings.HasPrefix(dst, src+string(os.PathSeparator)) {
+		return MoveFileResponse{}, fmt.Errorf("INVALID_INPUT: cannot move a directory into itself")
+	}
+	if srcInfo != nil && srcInfo.IsDir() {
+		if err := checkProtectedContents(wm, root, src, "moved"); err != nil {
+			return MoveFileResponse{}, err
+		}
+	}
+	// Changing only the case of a name: on case-insensitive filesystems dst
+	// already resolves to src, so skip the existence check and rename in two steps
+	rename := fsys.Rename
+	overwritten := false
+	if wm.OnDisk() && isCaseOnlyRename(src, dst) {
+		rename = renameCase
+	} else {
+		if dstInfo, err := fsys.Stat(dst); !os.IsNotExist(err) {
+			if !a.Overwrite {
+				return MoveFileResponse{}, fmt.Errorf("ALREADY_EXISTS: destination exists (set 'overwrite' to replace it)")
+			}
+			if err != nil || dstInfo.IsDir() || srcInfo == nil || srcInfo.IsDir() {
+				return MoveFileResponse{}, fmt.Errorf("CONFLICT: only a file can overwrite an existing destination, and only a file can be overwritten")
+			}
+			overwritten = true
+		} else if err := checkCaseCollision(fsys, root, dst, src); err != nil {
+			return MoveFileResponse{}, err
+		}
 	}
-	if err := os.Rename(src, dst); err != nil {
+	if err := fsys.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return MoveFileResponse{}, fmt.Errorf("INTERNAL: failed to create parent directories: %v", err)
+	}
+	expectWorkspaceChange(a.WorkspaceID, a.Source, "file.deleted", "dir.deleted")
+	expectWorkspaceChange(a.WorkspaceID, a.Destination, "file.moved", "file.created", "file.updated", "dir.created", "dir.moved")
+	if err := rename(src, dst); err != nil {
 		return MoveFileResponse{}, fmt.Errorf("INTERNAL: move failed: %v", err)
 	}
-	commit, err := wm.Commit(a.WorkspaceID, fmt.Sprintf("mcp/fs_move_file: Move %s to %s", a.Source, a.Destination), "mcp-client")
+	removeRedundantGitkeeps(fsys, root, dst)
+	commit, err := commitAs(wm, a.WorkspaceID, fmt.Sprintf("mcp/fs_move_file: Move %s to %s", a.Source, a.Destination), a.AuthorName, a.AuthorEmail)
 	if err != nil {
 		return MoveFileResponse{}, fmt.Errorf("INTERNAL: commit failed: %v", err)
 	}
 
 	// Determine dir/file
 	isDir := false
-	if info, statErr := os.Stat(dst); statErr == nil {
+	if info, statErr := fsys.Stat(dst); statErr == nil {
 		isDir = info.IsDir()
 	}
-	// Publish event
+	// Publish event; replacing a file updates the destination rather than creating it
+	evtType := "file.moved"
+	if overwritten {
+		evtType = "file.updated"
+	}
 	commitCopy := commit
 	prev := a.Source
-	publishWorkspaceEvent(a.WorkspaceID, events.WorkspaceEvent{
-		Type:     "file.moved",
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+		Type:     evtType,
 		Path:     a.Destination,
 		PrevPath: &prev,
 		IsDir:    isDir,
 		Commit:   &commitCopy,
 	})
 
-	return MoveFileResponse{Source: a.Source, Destination: a.Destination, Commit: commit}, nil
+	return MoveFileResponse{Source: a.Source, Destination: a.Destination, Overwritten: overwritten, Commit: commit}, nil
 }
 
 func FSEditFile(ctx context.Context, wm *workspace.Manager, a EditFileRequest) (any, error) {
 	if a.WorkspaceID == "" || a.Path == "" || len(a.Edits) == 0 {
 		return nil, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'path', and 'edits' are required")
 	}
-	if isProtectedPath(a.Path) {
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return nil, err
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
 		return nil, fmt.Errorf("NOT_FOUND: file not found")
 	}
 	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
 		return nil, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
 	orig, err := os.ReadFile(absPath)
 	if err != nil {
 		return nil, fmt.Errorf("NOT_FOUND: file not found")
@@ -416,85 +1486,279 @@ func FSEditFile(ctx context.Context, wm *workspace.Manager, a EditFileRequest) (
 	sum := sha256.Sum256(orig)
 	currEtag := fmt.Sprintf("%x", sum[:])
 	if a.IfMatchFileEtag != nil && *a.IfMatchFileEtag != "" && currEtag != *a.IfMatchFileEtag {
-		return nil, fmt.Errorf("CONFLICT: file etag mismatch")
+		return nil, withDetails(fmt.Errorf("CONFLICT: file etag mismatch"), map[string]any{"currentEtag": currEtag})
 	}
 	if a.IfMatchWorkspaceHead != nil && *a.IfMatchWorkspaceHead != "" {
 		if head, _ := wm.HeadCommit(a.WorkspaceID); head != *a.IfMatchWorkspaceHead {
-			return nil, fmt.Errorf("CONFLICT: workspace head mismatch")
+			return nil, withDetails(fmt.Errorf("CONFLICT: workspace head mismatch"), map[string]any{"currentHead": head})
 		}
 	}
 
-	newContent := string(orig)
+	newContent, editMatches, err := applyEdits(string(orig), a.Edits, a.AllowNoMatch, a.ExpectSingleMatch)
+	if err != nil {
+		return nil, err
+	}
 	matches := 0
-	for _, e := range a.Edits {
-		matches += strings.Count(newContent, e.OldText)
-		newContent = strings.ReplaceAll(newContent, e.OldText, e.NewText)
+	for _, n := range editMatches {
+		matches += n
 	}
 
 	// If no effective change, short-circuit (no write, no commit, no event)
 	if newContent == string(orig) {
-		out := EditFileResponse{DryRun: false, Path: a.Path, Changes: 0, BytesWritten: 0, Commit: ""}
+		out := EditFileResponse{DryRun: false, Path: a.Path, Changes: 0, EditMatches: editMatches, BytesWritten: 0, Commit: ""}
 		return out, nil
 	}
 
-	if a.DryRun {
+	var diff string
+	if a.DryRun || a.IncludeDiff {
 		dmp := diffmatchpatch.New()
-		diffs := dmp.DiffMain(string(orig), newContent, true)
-		out := EditFileDryRunResponse{DryRun: true, Diff: dmp.DiffPrettyText(diffs), Matches: matches}
+		diff = dmp.DiffPrettyText(dmp.DiffMain(string(orig), newContent, true))
+	}
+	if a.DryRun {
+		out := EditFileDryRunResponse{DryRun: true, Diff: diff, Matches: matches, EditMatches: editMatches}
 		return out, nil
 	}
 
 	contentBytes := []byte(newContent)
-	if err := os.WriteFile(absPath, contentBytes, 0644); err != nil {
-		return nil, fmt.Errorf("INTERNAL: failed to write edited file: %v", err)
+	expectWorkspaceChange(a.WorkspaceID, a.Path, "file.created", "file.updated")
+	if err := wm.WriteFileAtomic(a.WorkspaceID, absPath, contentBytes, 0644); err != nil {
+		return nil, writeFailed("failed to write edited file", err)
 	}
-	commit, err := wm.Commit(a.WorkspaceID, fmt.Sprintf("mcp/fs_edit_file: Edit %s", a.Path), "mcp-client")
+	commit, err := commitAs(wm, a.WorkspaceID, fmt.Sprintf("mcp/fs_edit_file: Edit %s", a.Path), a.AuthorName, a.AuthorEmail)
 	if err != nil {
 		return nil, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
 	}
 
 	// Publish event
 	commitCopy := commit
-	publishWorkspaceEvent(a.WorkspaceID, events.WorkspaceEvent{
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
 		Type:   "file.updated",
 		Path:   a.Path,
 		IsDir:  false,
 		Commit: &commitCopy,
 	})
 
-	out := EditFileResponse{DryRun: false, Path: a.Path, Changes: len(a.Edits), BytesWritten: len(contentBytes), Commit: commit}
+	out := EditFileResponse{DryRun: false, Path: a.Path, Changes: len(a.Edits), EditMatches: editMatches, BytesWritten: len(contentBytes), Commit: commit, Diff: diff}
 	return out, nil
 }
 
-func FSReadMultipleFiles(ctx context.Context, wm *workspace.Manager, a ReadMultipleFilesRequest) (ReadMultipleFilesResponse, error) {
-	if a.WorkspaceID == "" || len(a.Paths) == 0 {
-		return ReadMultipleFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'paths' are required")
+// applyEdits replaces every occurrence of each edit's oldText in content. All
+// occurrences are located in the original content before anything is replaced,
+// so an edit never matches text introduced by another and the result does not
+// depend on edit order; occurrences of different edits that overlap (including
+// two edits with the same oldText) are rejected. It returns the new content and
+// the number of occurrences replaced per edit.
+func applyEdits(content string, edits []Edit, allowNoMatch, expectSingle bool) (string, []int, error) {
+	type span struct{ start, end, edit int }
+	var spans []span
+	counts := make([]int, len(edits))
+	for i, e := range edits {
+		if e.OldText == "" {
+			return "", nil, fmt.Errorf("INVALID_INPUT: edit %d: 'oldText' must not be empty", i)
+		}
+		for off := 0; ; {
+			j := strings.Index(content[off:], e.OldText)
+			if j < 0 {
+				break
+			}
+			start := off + j
+			spans = append(spans, span{start, start + len(e.OldText), i})
+			counts[i]++
+			off = start + len(e.OldText)
+		}
+		switch {
+		case counts[i] == 0 && !allowNoMatch:
+			return "", nil, fmt.Errorf("CONFLICT: edit %d: 'oldText' not found", i)
+		case counts[i] > 1 && expectSingle:
+			return "", nil, fmt.Errorf("CONFLICT: edit %d: 'oldText' occurs %d times, expected exactly once", i, counts[i])
+		}
 	}
-	out := make([]FileReadResult, 0, len(a.Paths))
-	for _, p := range a.Paths {
-		if isProtectedPath(p) {
-			errStr := "NOT_FOUND: file not found"
-			out = append(out, FileReadResult{Path: p, OK: false, Error: &errStr})
-			continue
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i].start < spans[i-1].end {
+			a, b := min(spans[i-1].edit, spans[i].edit), max(spans[i-1].edit, spans[i].edit)
+			return "", nil, fmt.Errorf("INVALID_INPUT: edits %d and %d overlap", a, b)
 		}
-		abs, err := wm.SafePath(a.WorkspaceID, p)
-		if err != nil {
-			errStr := err.Error()
-			out = append(out, FileReadResult{Path: p, OK: false, Error: &errStr})
-			continue
+	}
+
+	var out strings.Builder
+	prev := 0
+	for _, sp := range spans {
+		out.WriteString(content[prev:sp.start])
+		out.WriteString(edits[sp.edit].NewText)
+		prev = sp.end
+	}
+	out.WriteString(content[prev:])
+	return out.String(), counts, nil
+}
+
+// FSPatch applies a unified diff to a file. Hunks must match the current content
+// exactly at the lines they name; otherwise nothing is written and CONFLICT is
+// returned (or, with dryRun, applies is false). A missing file is treated as empty,
+// so a diff against /dev/null creates it.
+func FSPatch(ctx context.Context, wm *workspace.Manager, a PatchFileRequest) (PatchFileResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" || a.Patch == "" {
+		return PatchFileResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'path', and 'patch' are required")
+	}
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return PatchFileResponse{}, err
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return PatchFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	}
+	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
+	if err != nil {
+		return PatchFileResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return PatchFileResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	hunks, err := parseUnifiedDiff(a.Patch)
+	if err != nil {
+		return PatchFileResponse{}, fmt.Errorf("INVALID_INPUT: %v", err)
+	}
+	orig, err := os.ReadFile(absPath)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return PatchFileResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+	}
+	if !exists {
+		root, _ := wm.SafePath(a.WorkspaceID, ".")
+		if err := checkCaseCollision(wm.FS(), root, absPath, ""); err != nil {
+			return PatchFileResponse{}, err
 		}
-		contentBytes, err := os.ReadFile(abs)
-		if err != nil {
-			errStr := err.Error()
-			out = append(out, FileReadResult{Path: p, OK: false, Error: &errStr})
-			continue
+	}
+
+	out := PatchFileResponse{Path: a.Path, DryRun: a.DryRun, Hunks: len(hunks)}
+	patched, err := applyUnifiedDiff(string(orig), hunks)
+	if err != nil {
+		if a.DryRun {
+			out.Conflict = err.Error()
+			return out, nil
 		}
-		content := string(contentBytes)
-		out = append(out, FileReadResult{Path: p, OK: true, Content: &content})
+		return PatchFileResponse{}, fmt.Errorf("CONFLICT: %v", err)
+	}
+	out.Applies = true
+	if a.DryRun || (exists && patched == string(orig)) {
+		return out, nil
+	}
+
+	contentBytes := []byte(patched)
+	expectWorkspaceChange(a.WorkspaceID, a.Path, "file.created", "file.updated")
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return PatchFileResponse{}, fmt.Errorf("INTERNAL: failed to create parent directories: %v", err)
+	}
+	if err := wm.WriteFileAtomic(a.WorkspaceID, absPath, contentBytes, 0644); err != nil {
+		return PatchFileResponse{}, writeFailed("failed to write patched file", err)
+	}
+	if root, err := wm.SafePath(a.WorkspaceID, "."); err == nil {
+		removeRedundantGitkeeps(wm.FS(), root, absPath)
+	}
+	commit, err := commitAs(wm, a.WorkspaceID, fmt.Sprintf("mcp/fs_patch: Patch %s", a.Path), a.AuthorName, a.AuthorEmail)
+	if err != nil {
+		return PatchFileResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+
+	evtType := "file.updated"
+	if !exists {
+		evtType = "file.created"
+	}
+	commitCopy := commit
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+		Type:   evtType,
+		Path:   a.Path,
+		IsDir:  false,
+		Commit: &commitCopy,
+	})
+
+	out.BytesWritten = len(contentBytes)
+	out.Commit = commit
+	return out, nil
+}
+
+// readMultipleConcurrency and maxReadMultipleConcurrency are the default and
+// the upper bound of the number of files fs_read_multiple_files reads at once.
+const (
+	readMultipleConcurrency    = 4
+	maxReadMultipleConcurrency = 8
+)
+
+func FSReadMultipleFiles(ctx context.Context, wm *workspace.Manager, a ReadMultipleFilesRequest) (ReadMultipleFilesResponse, error) {
+	if a.WorkspaceID == "" || len(a.Paths) == 0 {
+		return ReadMultipleFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'paths' are required")
+	}
+	if a.MaxConcurrency < 0 {
+		return ReadMultipleFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'maxConcurrency' must not be negative")
+	}
+	limit, err := readLimit(a.MaxBytes)
+	if err != nil {
+		return ReadMultipleFilesResponse{}, err
+	}
+	workers := a.MaxConcurrency
+	if workers == 0 {
+		workers = readMultipleConcurrency
 	}
+	workers = min(workers, maxReadMultipleConcurrency, len(a.Paths))
+
+	// Workers take indexes from jobs and fill their own slot, so results keep
+	// the order of Paths whatever order the reads finish in.
+	out := make([]FileReadResult, len(a.Paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out[i] = readOneFile(ctx, wm, a.WorkspaceID, a.Paths[i], limit)
+			}
+		}()
+	}
+	for i := range a.Paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 	return ReadMultipleFilesResponse{Results: out}, nil
 }
 
+// readOneFile reads at most limit bytes of p for fs_read_multiple_files,
+// recording a failure in the result instead of returning it so one bad path
+// does not fail the batch.
+func readOneFile(ctx context.Context, wm *workspace.Manager, workspaceID, p string, limit int64) FileReadResult {
+	fail := func(msg string) FileReadResult {
+		return FileReadResult{Path: p, OK: false, Error: &msg}
+	}
+	if err := ctx.Err(); err != nil {
+		return fail(err.Error())
+	}
+	if wm.Protected().IsProtectedPath(p) {
+		return fail("NOT_FOUND: file not found")
+	}
+	abs, err := wm.SafePath(workspaceID, p)
+	if err != nil {
+		return fail(err.Error())
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fail(err.Error())
+	}
+	var contentBytes []byte
+	truncated := info.Mode().IsRegular() && info.Size() > limit
+	if truncated {
+		contentBytes, err = readCapped(wm.FS(), abs, info.Size(), limit, false, "")
+	} else {
+		contentBytes, err = os.ReadFile(abs)
+	}
+	if err != nil {
+		return fail(err.Error())
+	}
+	content := string(contentBytes)
+	return FileReadResult{Path: p, OK: true, Content: &content, Size: info.Size(), Truncated: truncated}
+}
+
 func FSListDirectoryWithSizes(ctx context.Context, wm *workspace.Manager, a ListDirectoryWithSizesRequest) (ListDirectoryWithSizesResponse, error) {
 	abs, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
@@ -507,7 +1771,7 @@ func FSListDirectoryWithSizes(ctx context.Context, wm *workspace.Manager, a List
 	var entries []EntryInfo
 	var totals TotalsInfo
 	for _, f := range files {
-		if isProtectedName(f.Name()) {
+		if wm.Protected().IsProtectedPath(filepath.Join(a.Path, f.Name())) {
 			continue
 		}
 		info, err := f.Info()
@@ -535,6 +1799,9 @@ func FSListDirectoryWithSizes(ctx context.Context, wm *workspace.Manager, a List
 	return ListDirectoryWithSizesResponse{Entries: entries, Totals: totals}, nil
 }
 
+// maxSearchTimeout caps fs_search_files' timeoutMs.
+const maxSearchTimeout = 5 * time.Minute
+
 func FSSearchFiles(ctx context.Context, wm *workspace.Manager, a SearchFilesRequest) (SearchFilesResponse, error) {
 	if a.WorkspaceID == "" || a.Pattern == "" {
 		return SearchFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'pattern' are required")
@@ -543,18 +1810,39 @@ func FSSearchFiles(ctx context.Context, wm *workspace.Manager, a SearchFilesRequ
 	if err != nil {
 		return SearchFilesResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
+	wsRoot, _ := wm.SafePath(a.WorkspaceID, ".")
+	if a.MaxResults < 0 {
+		return SearchFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'maxResults' must not be negative")
+	}
+	if a.TimeoutMs < 0 || time.Duration(a.TimeoutMs)*time.Millisecond > maxSearchTimeout {
+		return SearchFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'timeoutMs' must be between 0 and %d", maxSearchTimeout.Milliseconds())
+	}
+	if a.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(a.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+	ignore, err := gitignoreFor(wm, a.WorkspaceID, a.RespectGitignore)
+	if err != nil {
+		return SearchFilesResponse{}, fmt.Errorf("INTERNAL: failed to read .gitignore: %v", err)
+	}
 	var matches []string
+	truncated := false
 	err = filepath.WalkDir(start, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		// Stop walking as soon as the client gives up or the timeout passes
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if d.IsDir() {
-			if isProtectedName(d.Name()) {
+			if wm.Protected().IsProtectedIn(wsRoot, path) || ignore.ignored(path, true) {
 				return fs.SkipDir
 			}
 			return nil
 		}
-		if isProtectedName(d.Name()) {
+		if wm.Protected().IsProtectedIn(wsRoot, path) || ignore.ignored(path, false) {
 			return nil
 		}
 		// Check main pattern against filename
@@ -576,9 +1864,9 @@ func FSSearchFiles(ctx context.Context, wm *workspace.Manager, a SearchFilesRequ
 				}
 			}
 			if !excluded {
-				wsRoot, err := wm.SafePath(a.WorkspaceID, ".")
-				if err != nil {
-					return err
+				if a.MaxResults > 0 && len(matches) == a.MaxResults {
+					truncated = true
+					return fs.SkipAll
 				}
 				if rel, err := filepath.Rel(wsRoot, path); err == nil {
 					matches = append(matches, rel)
@@ -587,10 +1875,15 @@ func FSSearchFiles(ctx context.Context, wm *workspace.Manager, a SearchFilesRequ
 		}
 		return nil
 	})
-	if err != nil {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return SearchFilesResponse{}, fmt.Errorf("TIMEOUT: search did not finish within the deadline")
+	case errors.Is(err, context.Canceled):
+		return SearchFilesResponse{}, fmt.Errorf("TIMEOUT: search cancelled: %v", err)
+	case err != nil:
 		return SearchFilesResponse{}, fmt.Errorf("INTERNAL: search failed: %v", err)
 	}
-	return SearchFilesResponse{Matches: matches}, nil
+	return SearchFilesResponse{Matches: matches, Truncated: truncated}, nil
 }
 
 func FSDirectoryTree(ctx context.Context, wm *workspace.Manager, a DirectoryTreeRequest) (any, error) {
@@ -598,7 +1891,24 @@ func FSDirectoryTree(ctx context.Context, wm *workspace.Manager, a DirectoryTree
 	if err != nil {
 		return nil, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	tree, err := buildTree(start, a.ExcludePatterns)
+	if a.MaxDepth < 0 {
+		return nil, fmt.Errorf("INVALID_INPUT: 'maxDepth' must not be negative")
+	}
+	ignore, err := gitignoreFor(wm, a.WorkspaceID, a.RespectGitignore)
+	if err != nil {
+		return nil, fmt.Errorf("INTERNAL: failed to read .gitignore: %v", err)
+	}
+	wsRoot, _ := wm.SafePath(a.WorkspaceID, ".")
+	opts := treeOptions{protected: wm.Protected(), wsRoot: wsRoot, excludePatterns: a.ExcludePatterns, maxDepth: a.MaxDepth, dirsOnly: a.DirsOnly, ignore: ignore, stats: a.IncludeStats}
+	if p := progressFromContext(ctx); p != nil {
+		// The total is unknown until the walk completes
+		entries := 0
+		opts.progress = func() {
+			entries++
+			p.report(ctx, entries, 0, "entries scanned")
+		}
+	}
+	tree, err := buildTree(start, opts, 1)
 	if err != nil {
 		return nil, fmt.Errorf("INTERNAL: failed to build directory tree: %v", err)
 	}
@@ -606,23 +1916,26 @@ func FSDirectoryTree(ctx context.Context, wm *workspace.Manager, a DirectoryTree
 }
 
 func FSReadMediaFile(ctx context.Context, wm *workspace.Manager, a ReadMediaFileRequest) (ReadMediaFileResponse, error) {
-	if isProtectedPath(a.Path) {
+	if wm.Protected().IsProtectedPath(a.Path) {
 		return ReadMediaFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 	}
 	abs, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
 		return ReadMediaFileResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	content, err := os.ReadFile(abs)
+	info, err := os.Stat(abs)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return ReadMediaFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 		}
 		return ReadMediaFileResponse{}, fmt.Errorf("INTERNAL: failed to read media file: %v", err)
 	}
-	const maxMediaFileSize = 10 * 1024 * 1024
-	if len(content) > maxMediaFileSize {
-		return ReadMediaFileResponse{}, fmt.Errorf("UNSUPPORTED: media file too large (max 10MB)")
+	if info.Size() > maxMediaBytes {
+		return ReadMediaFileResponse{}, fmt.Errorf("UNSUPPORTED: media file too large (%d bytes, max %d); download it from GET /api/workspaces/%s/raw?path=... instead", info.Size(), maxMediaBytes, a.WorkspaceID)
+	}
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return ReadMediaFileResponse{}, fmt.Errorf("INTERNAL: failed to read media file: %v", err)
 	}
 	mimeType := http.DetectContentType(content)
 	encoded := base64.StdEncoding.EncodeToString(content)
@@ -633,30 +1946,192 @@ func FSReadMediaFile(ctx context.Context, wm *workspace.Manager, a ReadMediaFile
 	}, nil
 }
 
-// Helper used by REST layer to detect EOF in some contexts.
-var _ = io.EOF
-
-func FSDeleteFile(ctx context.Context, wm *workspace.Manager, a DeleteFileRequest) (DeleteFileResponse, error) {
-	if a.WorkspaceID == "" || a.Path == "" {
-		return DeleteFileResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
+// FSSetMtime sets the modification time of a file or directory.
+// Git does not track mtimes, so no commit is made, but a file.updated event is published.
+func FSSetMtime(ctx context.Context, wm *workspace.Manager, a SetMtimeRequest) (SetMtimeResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" || a.Mtime == "" {
+		return SetMtimeResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'path', and 'mtime' are required")
 	}
-	if isProtectedPath(a.Path) {
-		return DeleteFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	mtime, err := time.Parse(time.RFC3339, a.Mtime)
+	if err != nil {
+		return SetMtimeResponse{}, fmt.Errorf("INVALID_INPUT: 'mtime' must be RFC3339: %v", err)
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return SetMtimeResponse{}, fmt.Errorf("NOT_FOUND: file not found")
 	}
 	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
 	if err != nil {
-		return DeleteFileResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+		return SetMtimeResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
 	}
-	// Determine if directory before removal
-	isDir := false
-	if info, statErr := os.Stat(absPath); statErr == nil {
-		isDir = info.IsDir()
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SetMtimeResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+		}
+		return SetMtimeResponse{}, fmt.Errorf("INTERNAL: failed to stat file: %v", err)
 	}
-	if err := os.RemoveAll(absPath); err != nil {
-		return DeleteFileResponse{}, fmt.Errorf("INTERNAL: failed to delete file: %v", err)
+	expectWorkspaceChange(a.WorkspaceID, a.Path, "file.updated")
+	// A zero atime leaves the access time unchanged.
+	if err := os.Chtimes(absPath, time.Time{}, mtime); err != nil {
+		return SetMtimeResponse{}, fmt.Errorf("INTERNAL: failed to set mtime: %v", err)
 	}
-	commit, err := wm.Commit(a.WorkspaceID, fmt.Sprintf("mcp/fs_delete_file: Delete %s", a.Path), "mcp-client")
-	if err != nil {
+
+	mtimeStr := mtime.UTC().Format(time.RFC3339)
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+		Type:  "file.updated",
+		Path:  a.Path,
+		IsDir: info.IsDir(),
+		MTime: &mtimeStr,
+	})
+
+	return SetMtimeResponse{Path: a.Path, Mtime: mtimeStr}, nil
+}
+
+// parseMode parses an octal permission string such as "0755" or "644". Modes
+// must fit in the permission bits (no setuid, setgid or sticky) and leave the
+// owner able to read the entry, and for directories also to enter it.
+func parseMode(mode string, isDir bool) (os.FileMode, error) {
+	n, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("INVALID_INPUT: 'mode' must be an octal string such as \"0755\"")
+	}
+	if n > 0o777 {
+		return 0, fmt.Errorf("INVALID_INPUT: 'mode' %s is outside 0000-0777", mode)
+	}
+	need, what := uint64(0o400), "read"
+	if isDir {
+		need, what = 0o500, "read and execute"
+	}
+	if n&need != need {
+		return 0, fmt.Errorf("INVALID_INPUT: 'mode' %s must keep the owner's %s permission", mode, what)
+	}
+	return os.FileMode(n), nil
+}
+
+// FSChmod changes the permission bits of a file or directory and commits the
+// change. Git only records the executable bit of files, so other changes are
+// applied without a commit; file.updated is published either way.
+func FSChmod(ctx context.Context, wm *workspace.Manager, a ChmodRequest) (ChmodResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" || a.Mode == "" {
+		return ChmodResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId', 'path', and 'mode' are required")
+	}
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return ChmodResponse{}, err
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return ChmodResponse{}, fmt.Errorf("FORBIDDEN: cannot change permissions of protected path %s", a.Path)
+	}
+	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
+	if err != nil {
+		return ChmodResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return ChmodResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	info, err := os.Stat(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ChmodResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+		}
+		return ChmodResponse{}, fmt.Errorf("INTERNAL: failed to stat file: %v", err)
+	}
+	mode, err := parseMode(a.Mode, info.IsDir())
+	if err != nil {
+		return ChmodResponse{}, err
+	}
+	expectWorkspaceChange(a.WorkspaceID, a.Path, "file.updated")
+	if err := os.Chmod(absPath, mode); err != nil {
+		return ChmodResponse{}, fmt.Errorf("INTERNAL: failed to change mode: %v", err)
+	}
+	if info, err = os.Stat(absPath); err != nil {
+		return ChmodResponse{}, fmt.Errorf("INTERNAL: failed to stat file: %v", err)
+	}
+	out := ChmodResponse{Path: a.Path, Mode: fmt.Sprintf("%04o", info.Mode().Perm()), Permissions: info.Mode().String()}
+	commit, err := commitAs(wm, a.WorkspaceID, fmt.Sprintf("mcp/fs_chmod: Set mode %s on %s", out.Mode, a.Path), a.AuthorName, a.AuthorEmail)
+	switch {
+	case err == nil:
+		out.Commit = commit
+	case !errors.Is(err, git.ErrEmptyCommit):
+		return ChmodResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+
+	var commitRef *string
+	if out.Commit != "" {
+		commitRef = &out.Commit
+	}
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+		Type:   "file.updated",
+		Path:   a.Path,
+		IsDir:  info.IsDir(),
+		Commit: commitRef,
+	})
+	return out, nil
+}
+
+// Helper used by REST layer to detect EOF in some contexts.
+var _ = io.EOF
+
+func FSDeleteFile(ctx context.Context, wm *workspace.Manager, a DeleteFileRequest) (DeleteFileResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" {
+		return DeleteFileResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
+	}
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return DeleteFileResponse{}, err
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return DeleteFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	}
+	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
+	if err != nil {
+		return DeleteFileResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return DeleteFileResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	// Determine if directory before removal
+	isDir := false
+	info, statErr := wm.FS().Stat(absPath)
+	if statErr == nil {
+		isDir = info.IsDir()
+	}
+	if isDir {
+		root, err := wm.SafePath(a.WorkspaceID, ".")
+		if err != nil {
+			return DeleteFileResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		}
+		if err := checkProtectedContents(wm, root, absPath, "deleted"); err != nil {
+			return DeleteFileResponse{}, err
+		}
+	}
+	var trashID string
+	message := fmt.Sprintf("mcp/fs_delete_file: Delete %s", a.Path)
+	if a.SoftDelete {
+		if os.IsNotExist(statErr) {
+			return DeleteFileResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+		}
+		if filepath.Clean(a.Path) == "." {
+			return DeleteFileResponse{}, fmt.Errorf("FORBIDDEN: the workspace root cannot be moved to the trash")
+		}
+		expectWorkspaceChange(a.WorkspaceID, a.Path, "file.deleted", "dir.deleted")
+		entry, err := wm.MoveToTrash(a.WorkspaceID, a.Path)
+		if err != nil {
+			return DeleteFileResponse{}, fmt.Errorf("INTERNAL: failed to move to trash: %v", err)
+		}
+		trashID = entry.ID
+		message = fmt.Sprintf("mcp/fs_delete_file: Move %s to trash", a.Path)
+	} else {
+		expectWorkspaceChange(a.WorkspaceID, a.Path, "file.deleted", "dir.deleted")
+		if err := wm.FS().RemoveAll(absPath); err != nil {
+			return DeleteFileResponse{}, fmt.Errorf("INTERNAL: failed to delete file: %v", err)
+		}
+		wm.InvalidateUsage(a.WorkspaceID)
+	}
+	commit, err := commitAs(wm, a.WorkspaceID, message, a.AuthorName, a.AuthorEmail)
+	if err != nil {
 		return DeleteFileResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
 	}
 
@@ -666,12 +2141,678 @@ func FSDeleteFile(ctx context.Context, wm *workspace.Manager, a DeleteFileReques
 		evtType = "dir.deleted"
 	}
 	commitCopy := commit
-	publishWorkspaceEvent(a.WorkspaceID, events.WorkspaceEvent{
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
 		Type:   evtType,
 		Path:   a.Path,
 		IsDir:  isDir,
 		Commit: &commitCopy,
 	})
 
-	return DeleteFileResponse{Path: a.Path, Commit: commit}, nil
+	return DeleteFileResponse{Path: a.Path, Commit: commit, TrashID: trashID}, nil
+}
+
+// FSRestoreFromTrash moves a soft-deleted file or directory back to its
+// original path, commits it and emits file.created or dir.created. The entry
+// is chosen by trashId, or as the latest deletion of path.
+func FSRestoreFromTrash(ctx context.Context, wm *workspace.Manager, a RestoreFromTrashRequest) (RestoreFromTrashResponse, error) {
+	if a.WorkspaceID == "" || (a.TrashID == "") == (a.Path == "") {
+		return RestoreFromTrashResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and exactly one of 'trashId' or 'path' are required")
+	}
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return RestoreFromTrashResponse{}, err
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, "."); err != nil {
+		return RestoreFromTrashResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return RestoreFromTrashResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	id := a.TrashID
+	if id == "" {
+		entries, err := wm.ListTrash(a.WorkspaceID)
+		if err != nil {
+			return RestoreFromTrashResponse{}, fmt.Errorf("INTERNAL: %v", err)
+		}
+		want := filepath.ToSlash(filepath.Clean(a.Path))
+		for _, e := range entries {
+			if e.Path == want {
+				id = e.ID
+				break
+			}
+		}
+		if id == "" {
+			return RestoreFromTrashResponse{}, fmt.Errorf("NOT_FOUND: %s is not in the trash", a.Path)
+		}
+	}
+	entry, err := wm.RestoreFromTrash(a.WorkspaceID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, workspace.ErrTrashEntryNotFound):
+			return RestoreFromTrashResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		case errors.Is(err, workspace.ErrRestoreTargetExists):
+			return RestoreFromTrashResponse{}, fmt.Errorf("ALREADY_EXISTS: %v", err)
+		}
+		return RestoreFromTrashResponse{}, fmt.Errorf("INTERNAL: failed to restore from trash: %v", err)
+	}
+	if root, err := wm.SafePath(a.WorkspaceID, "."); err == nil {
+		if abs, err := wm.SafePath(a.WorkspaceID, entry.Path); err == nil {
+			removeRedundantGitkeeps(wm.FS(), root, abs)
+		}
+	}
+	evtType := "file.created"
+	if entry.IsDir {
+		evtType = "dir.created"
+	}
+	expectWorkspaceChange(a.WorkspaceID, entry.Path, evtType)
+	commit, err := commitAs(wm, a.WorkspaceID, fmt.Sprintf("mcp/fs_restore_from_trash: Restore %s", entry.Path), a.AuthorName, a.AuthorEmail)
+	if err != nil {
+		return RestoreFromTrashResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+		Type:   evtType,
+		Path:   entry.Path,
+		IsDir:  entry.IsDir,
+		Commit: &commit,
+	})
+	return RestoreFromTrashResponse{Path: entry.Path, TrashID: entry.ID, Commit: commit}, nil
+}
+
+// FSDiffFiles returns a unified diff between pathA and either pathB or the inline contentB.
+func FSDiffFiles(ctx context.Context, wm *workspace.Manager, a DiffFilesRequest) (DiffFilesResponse, error) {
+	if a.WorkspaceID == "" || a.PathA == "" {
+		return DiffFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'pathA' are required")
+	}
+	if (a.PathB == "") == (a.ContentB == nil) {
+		return DiffFilesResponse{}, fmt.Errorf("INVALID_INPUT: exactly one of 'pathB' or 'contentB' is required")
+	}
+	readSide := func(rel string) (string, error) {
+		if wm.Protected().IsProtectedPath(rel) {
+			return "", fmt.Errorf("NOT_FOUND: file not found: %s", rel)
+		}
+		abs, err := wm.SafePath(a.WorkspaceID, rel)
+		if err != nil {
+			return "", fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+		}
+		b, err := os.ReadFile(abs)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "", fmt.Errorf("NOT_FOUND: file not found: %s", rel)
+			}
+			return "", fmt.Errorf("INTERNAL: failed to read file: %v", err)
+		}
+		return string(b), nil
+	}
+
+	contentA, err := readSide(a.PathA)
+	if err != nil {
+		return DiffFilesResponse{}, err
+	}
+	toName := "b/" + a.PathA
+	var contentB string
+	if a.ContentB != nil {
+		contentB = *a.ContentB
+	} else {
+		if contentB, err = readSide(a.PathB); err != nil {
+			return DiffFilesResponse{}, err
+		}
+		toName = "b/" + a.PathB
+	}
+
+	diff, added, removed := unifiedDiff("a/"+a.PathA, toName, contentA, contentB)
+	return DiffFilesResponse{Diff: diff, Added: added, Removed: removed}, nil
+}
+
+// FSMergeContent merges a client's edit (base -> theirs) into the file's current
+// content without writing it. The response carries the current etag so the client
+// can write the merged result with ifMatchFileEtag.
+func FSMergeContent(ctx context.Context, wm *workspace.Manager, a MergeContentRequest) (MergeContentResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" {
+		return MergeContentResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return MergeContentResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	}
+	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
+	if err != nil {
+		return MergeContentResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	current, err := os.ReadFile(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return MergeContentResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+		}
+		return MergeContentResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+	}
+	sum := sha256.Sum256(current)
+	out := MergeContentResponse{Path: a.Path, Etag: fmt.Sprintf("%x", sum[:])}
+
+	merged, conflicts := mergeThreeWay(a.Base, a.Theirs, string(current))
+	if len(conflicts) > 0 {
+		out.Conflicts = conflicts
+		return out, nil
+	}
+	out.Clean = true
+	out.Merged = merged
+	return out, nil
+}
+
+// Bounds for fs_wait_for_change.
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 5 * time.Minute
+)
+
+// FSWaitForChange blocks until an event for path (or anything under it) is
+// published, turning the event stream into a synchronous primitive. Presence
+// events are ignored. It requires the event hub, which only the HTTP transport runs.
+func FSWaitForChange(ctx context.Context, wm *workspace.Manager, a WaitForChangeRequest) (WaitForChangeResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" {
+		return WaitForChangeResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
+	}
+	timeout := defaultWaitTimeout
+	if a.TimeoutMs < 0 || time.Duration(a.TimeoutMs)*time.Millisecond > maxWaitTimeout {
+		return WaitForChangeResponse{}, fmt.Errorf("INVALID_INPUT: 'timeoutMs' must be between 1 and %d", maxWaitTimeout.Milliseconds())
+	} else if a.TimeoutMs > 0 {
+		timeout = time.Duration(a.TimeoutMs) * time.Millisecond
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return WaitForChangeResponse{}, fmt.Errorf("NOT_FOUND: path not found")
+	}
+	if _, err := wm.SafePath(a.WorkspaceID, a.Path); err != nil {
+		return WaitForChangeResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	if eventHub == nil {
+		return WaitForChangeResponse{}, fmt.Errorf("UNSUPPORTED: change notifications are only available with the HTTP transport")
+	}
+
+	since := eventHub.LastID(a.WorkspaceID)
+	ch, unsubscribe := eventHub.SubscribeFiltered(a.WorkspaceID, since, 16, events.PathFilter(a.Path))
+	defer unsubscribe()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				return WaitForChangeResponse{}, fmt.Errorf("UNSUPPORTED: event stream closed (server shutting down)")
+			}
+			if strings.HasPrefix(evt.Type, "presence.") {
+				continue
+			}
+			return WaitForChangeResponse{Event: evt}, nil
+		case <-timer.C:
+			return WaitForChangeResponse{}, fmt.Errorf("TIMEOUT: no change to %s within %dms", a.Path, timeout.Milliseconds())
+		case <-ctx.Done():
+			return WaitForChangeResponse{}, ctx.Err()
+		}
+	}
+}
+
+// FSJSONSet sets a value at a JSON pointer inside a JSON file, preserving key order
+// and indentation style, then commits the change.
+func FSJSONSet(ctx context.Context, wm *workspace.Manager, a JSONSetRequest) (JSONSetResponse, error) {
+	if a.WorkspaceID == "" || a.Path == "" {
+		return JSONSetResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'path' are required")
+	}
+	// An explicit null arrives as the literal "null"; only an absent value is empty.
+	if len(a.Value) == 0 {
+		return JSONSetResponse{}, fmt.Errorf("INVALID_INPUT: 'value' is required")
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return JSONSetResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	}
+	absPath, err := wm.SafePath(a.WorkspaceID, a.Path)
+	if err != nil {
+		return JSONSetResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return JSONSetResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	orig, err := os.ReadFile(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return JSONSetResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+		}
+		return JSONSetResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+	}
+	doc, err := parseOrderedJSON(orig)
+	if err != nil {
+		return JSONSetResponse{}, fmt.Errorf("INVALID_INPUT: file is not valid JSON: %v", err)
+	}
+	value, err := parseOrderedJSON(a.Value)
+	if err != nil {
+		return JSONSetResponse{}, fmt.Errorf("INVALID_INPUT: invalid 'value': %v", err)
+	}
+	doc, err = jsonPointerSet(doc, a.Pointer, value)
+	if err != nil {
+		return JSONSetResponse{}, fmt.Errorf("INVALID_INPUT: %v", err)
+	}
+	contentBytes, err := encodeJSONLike(doc, orig)
+	if err != nil {
+		return JSONSetResponse{}, fmt.Errorf("INTERNAL: failed to encode JSON: %v", err)
+	}
+	if string(contentBytes) == string(orig) {
+		return JSONSetResponse{Path: a.Path, Pointer: a.Pointer, BytesWritten: 0, Commit: ""}, nil
+	}
+
+	expectWorkspaceChange(a.WorkspaceID, a.Path, "file.created", "file.updated")
+	if err := wm.WriteFileAtomic(a.WorkspaceID, absPath, contentBytes, 0644); err != nil {
+		return JSONSetResponse{}, writeFailed("failed to write file", err)
+	}
+	commit, err := wm.Commit(a.WorkspaceID, fmt.Sprintf("mcp/fs_json_set: Set %s in %s", a.Pointer, a.Path), "mcp-client")
+	if err != nil {
+		return JSONSetResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+
+	commitCopy := commit
+	publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+		Type:   "file.updated",
+		Path:   a.Path,
+		IsDir:  false,
+		Commit: &commitCopy,
+	})
+
+	return JSONSetResponse{Path: a.Path, Pointer: a.Pointer, BytesWritten: len(contentBytes), Commit: commit}, nil
+}
+
+// estimatedBytesPerToken is the rough bytes-per-token ratio used by fs_estimate_read.
+const estimatedBytesPerToken = 4
+
+func FSEstimateRead(ctx context.Context, wm *workspace.Manager, a EstimateReadRequest) (EstimateReadResponse, error) {
+	if a.WorkspaceID == "" {
+		return EstimateReadResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	if wm.Protected().IsProtectedPath(a.Path) {
+		return EstimateReadResponse{}, fmt.Errorf("NOT_FOUND: path not found")
+	}
+	abs, err := wm.SafePath(a.WorkspaceID, a.Path)
+	if err != nil {
+		return EstimateReadResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return EstimateReadResponse{}, fmt.Errorf("NOT_FOUND: path not found")
+		}
+		return EstimateReadResponse{}, fmt.Errorf("INTERNAL: failed to stat path: %v", err)
+	}
+
+	out := EstimateReadResponse{Path: a.Path}
+	add := func(p string) error {
+		n, lines, err := countBytesAndLines(p)
+		if err != nil {
+			return err
+		}
+		out.Files++
+		out.Bytes += n
+		out.Lines += lines
+		return nil
+	}
+
+	if !info.IsDir() {
+		if err := add(abs); err != nil {
+			return EstimateReadResponse{}, fmt.Errorf("INTERNAL: failed to read file: %v", err)
+		}
+	} else {
+		root, _ := wm.SafePath(a.WorkspaceID, ".")
+		err = filepath.WalkDir(abs, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != abs && (wm.Protected().IsProtectedIn(root, p) || !a.Recursive) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if wm.Protected().IsProtectedIn(root, p) || !d.Type().IsRegular() {
+				return nil
+			}
+			return add(p)
+		})
+		if err != nil {
+			return EstimateReadResponse{}, fmt.Errorf("INTERNAL: failed to scan directory: %v", err)
+		}
+	}
+	out.EstimatedTokens = (out.Bytes + estimatedBytesPerToken - 1) / estimatedBytesPerToken
+	return out, nil
+}
+
+// FSManifest returns every regular file under a path with its size and SHA-256,
+// sorted by path so two manifests can be diffed line by line.
+func FSManifest(ctx context.Context, wm *workspace.Manager, a ManifestRequest) (ManifestResponse, error) {
+	if a.WorkspaceID == "" {
+		return ManifestResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	rel := a.Path
+	if rel == "" {
+		rel = "."
+	}
+	if wm.Protected().IsProtectedPath(rel) {
+		return ManifestResponse{}, fmt.Errorf("NOT_FOUND: path not found")
+	}
+	root, err := wm.SafePath(a.WorkspaceID, ".")
+	if err != nil {
+		return ManifestResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	abs, err := wm.SafePath(a.WorkspaceID, rel)
+	if err != nil {
+		return ManifestResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	if _, err := os.Stat(abs); err != nil {
+		if os.IsNotExist(err) {
+			return ManifestResponse{}, fmt.Errorf("NOT_FOUND: path not found")
+		}
+		return ManifestResponse{}, fmt.Errorf("INTERNAL: failed to stat path: %v", err)
+	}
+
+	out := ManifestResponse{Path: rel, Files: []ManifestEntry{}}
+	err = filepath.WalkDir(abs, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != abs && wm.Protected().IsProtectedIn(root, p) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if wm.Protected().IsProtectedIn(root, p) || !d.Type().IsRegular() {
+			return nil
+		}
+		size, sum, err := hashFile(wm.FS(), p)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		out.Files = append(out.Files, ManifestEntry{Path: filepath.ToSlash(relPath), Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return ManifestResponse{}, fmt.Errorf("INTERNAL: failed to build manifest: %v", err)
+	}
+	sort.Slice(out.Files, func(i, j int) bool { return out.Files[i].Path < out.Files[j].Path })
+	return out, nil
+}
+
+// FSGetDirectorySize sums the sizes of regular files under a directory and counts
+// files and subdirectories, skipping protected names. With MaxDepth > 0 only
+// entries up to that many levels below the directory are counted.
+func FSGetDirectorySize(ctx context.Context, wm *workspace.Manager, a GetDirectorySizeRequest) (GetDirectorySizeResponse, error) {
+	if a.WorkspaceID == "" {
+		return GetDirectorySizeResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	if a.MaxDepth < 0 {
+		return GetDirectorySizeResponse{}, fmt.Errorf("INVALID_INPUT: 'maxDepth' must not be negative")
+	}
+	rel := a.Path
+	if rel == "" {
+		rel = "."
+	}
+	if wm.Protected().IsProtectedPath(rel) {
+		return GetDirectorySizeResponse{}, fmt.Errorf("NOT_FOUND: path not found")
+	}
+	abs, err := wm.SafePath(a.WorkspaceID, rel)
+	if err != nil {
+		return GetDirectorySizeResponse{}, fmt.Errorf("OUT_OF_BOUNDS: %v", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return GetDirectorySizeResponse{}, fmt.Errorf("NOT_FOUND: path not found")
+		}
+		return GetDirectorySizeResponse{}, fmt.Errorf("INTERNAL: failed to stat path: %v", err)
+	}
+	if !info.IsDir() {
+		return GetDirectorySizeResponse{}, fmt.Errorf("INVALID_INPUT: path is not a directory")
+	}
+
+	out := GetDirectorySizeResponse{Path: a.Path}
+	root, _ := wm.SafePath(a.WorkspaceID, ".")
+	err = filepath.WalkDir(abs, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == abs {
+			return nil
+		}
+		if wm.Protected().IsProtectedIn(root, p) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		depth := strings.Count(p[len(abs):], string(os.PathSeparator))
+		if a.MaxDepth > 0 && depth > a.MaxDepth {
+			out.Truncated = true
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			out.Directories++
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out.Files++
+		out.CombinedSize += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return GetDirectorySizeResponse{}, fmt.Errorf("INTERNAL: failed to scan directory: %v", err)
+	}
+	return out, nil
+}
+
+// FSCopyBetweenWorkspaces copies a file or directory tree from one workspace into
+// another and commits the result in the destination workspace. The destination
+// must not exist; .git directories and symlinks are never copied.
+func FSCopyBetweenWorkspaces(ctx context.Context, wm *workspace.Manager, a CopyBetweenWorkspacesRequest) (CopyBetweenWorkspacesResponse, error) {
+	if a.SourceWorkspaceID == "" || a.SourcePath == "" || a.DestWorkspaceID == "" || a.DestPath == "" {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("INVALID_INPUT: 'sourceWorkspaceId', 'sourcePath', 'destWorkspaceId', and 'destPath' are required")
+	}
+	if wm.Protected().IsProtectedPath(a.SourcePath) || wm.Protected().IsProtectedPath(a.DestPath) {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("NOT_FOUND: file not found")
+	}
+	srcRoot, err := wm.SafePath(a.SourceWorkspaceID, ".")
+	if err != nil {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	destRoot, err := wm.SafePath(a.DestWorkspaceID, ".")
+	if err != nil {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	src, err := wm.SafePath(a.SourceWorkspaceID, a.SourcePath)
+	if err != nil {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("OUT_OF_BOUNDS: source path invalid: %v", err)
+	}
+	if info, err := os.Lstat(src); err == nil && info.IsDir() {
+		if err := checkProtectedContents(wm, srcRoot, src, "copied"); err != nil {
+			return CopyBetweenWorkspacesResponse{}, err
+		}
+	}
+	dst, err := wm.SafePath(a.DestWorkspaceID, a.DestPath)
+	if err != nil {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("OUT_OF_BOUNDS: destination path invalid: %v", err)
+	}
+	if dst == destRoot {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("INVALID_INPUT: destination must not be the workspace root")
+	}
+	unlock, err := wm.Lock(a.DestWorkspaceID)
+	if err != nil {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+	expectWorkspaceChange(a.DestWorkspaceID, a.DestPath, "file.created", "dir.created")
+	var progress func(copied, total int)
+	if p := progressFromContext(ctx); p != nil {
+		progress = func(copied, total int) { p.report(ctx, copied, total, "files copied") }
+	}
+	if err := wm.CopyPathWithProgress(a.SourceWorkspaceID, a.SourcePath, a.DestWorkspaceID, a.DestPath, progress); err != nil {
+		switch {
+		case errors.Is(err, workspace.ErrSourceNotFound):
+			return CopyBetweenWorkspacesResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+		case errors.Is(err, workspace.ErrUnsupportedType):
+			return CopyBetweenWorkspacesResponse{}, fmt.Errorf("UNSUPPORTED: %v", err)
+		case errors.Is(err, workspace.ErrCopyIntoSelf):
+			return CopyBetweenWorkspacesResponse{}, fmt.Errorf("INVALID_INPUT: %v", err)
+		case errors.Is(err, workspace.ErrDestinationExists):
+			return CopyBetweenWorkspacesResponse{}, fmt.Errorf("ALREADY_EXISTS: %v", err)
+		}
+		return CopyBetweenWorkspacesResponse{}, writeFailed("copy failed", err)
+	}
+	info, err := os.Lstat(dst)
+	if err != nil {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("INTERNAL: failed to stat copy: %v", err)
+	}
+	removeRedundantGitkeeps(wm.FS(), destRoot, dst)
+	commit, err := wm.Commit(a.DestWorkspaceID, fmt.Sprintf("mcp/fs_copy_between_workspaces: Copy %s:%s to %s", a.SourceWorkspaceID, a.SourcePath, a.DestPath), "mcp-client")
+	if err != nil {
+		return CopyBetweenWorkspacesResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+
+	evtType := "file.created"
+	if info.IsDir() {
+		evtType = "dir.created"
+	}
+	commitCopy := commit
+	publishWorkspaceEvent(ctx, a.DestWorkspaceID, events.WorkspaceEvent{
+		Type:   evtType,
+		Path:   a.DestPath,
+		IsDir:  info.IsDir(),
+		Commit: &commitCopy,
+	})
+
+	return CopyBetweenWorkspacesResponse{DestWorkspaceID: a.DestWorkspaceID, DestPath: a.DestPath, IsDir: info.IsDir(), Commit: commit}, nil
+}
+
+// hashFile streams a file through SHA-256, returning its size and hex digest.
+func hashFile(fsys workspace.FS, p string) (int64, string, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// countBytesAndLines streams a file, returning its size and line count
+// (a final line without a trailing newline still counts).
+func countBytesAndLines(path string) (int64, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 32*1024)
+	var total int64
+	lines := 0
+	var last byte
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			total += int64(n)
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if total > 0 && last != '\n' {
+		lines++
+	}
+	return total, lines, nil
+}
+
+// Caps for fs_search_and_read so a broad pattern cannot produce an unbounded payload.
+const (
+	defaultSearchReadMaxFiles     = 20
+	maxSearchReadMaxFiles         = 100
+	defaultSearchReadBytesPerFile = 64 * 1024
+	maxSearchReadTotalBytes       = 1024 * 1024
+)
+
+func FSSearchAndRead(ctx context.Context, wm *workspace.Manager, a SearchAndReadRequest) (SearchAndReadResponse, error) {
+	if a.MaxFiles < 0 || a.MaxBytesPerFile < 0 {
+		return SearchAndReadResponse{}, fmt.Errorf("INVALID_INPUT: 'maxFiles' and 'maxBytesPerFile' must not be negative")
+	}
+	maxFiles := a.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = defaultSearchReadMaxFiles
+	}
+	if maxFiles > maxSearchReadMaxFiles {
+		maxFiles = maxSearchReadMaxFiles
+	}
+	perFile := a.MaxBytesPerFile
+	if perFile == 0 {
+		perFile = defaultSearchReadBytesPerFile
+	}
+
+	found, err := FSSearchFiles(ctx, wm, SearchFilesRequest{
+		WorkspaceID:     a.WorkspaceID,
+		Path:            a.Path,
+		Pattern:         a.Pattern,
+		ExcludePatterns: a.ExcludePatterns,
+	})
+	if err != nil {
+		return SearchAndReadResponse{}, err
+	}
+	sort.Strings(found.Matches)
+
+	fsys := wm.FS()
+	out := SearchAndReadResponse{Files: []SearchAndReadFile{}}
+	remaining := maxSearchReadTotalBytes
+	for _, rel := range found.Matches {
+		abs, err := wm.SafePath(a.WorkspaceID, rel)
+		if err != nil {
+			continue
+		}
+		info, err := fsys.Stat(abs)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if a.Content != "" {
+			if ok, err := fileContains(fsys, abs, []byte(a.Content)); err != nil || !ok {
+				continue
+			}
+		}
+		if len(out.Files) == maxFiles || remaining <= 0 {
+			out.Truncated = true
+			break
+		}
+		limit := min(perFile, remaining)
+		data, err := readCapped(fsys, abs, info.Size(), int64(limit), false, "")
+		if err != nil {
+			continue
+		}
+		f := SearchAndReadFile{Path: filepath.ToSlash(rel), Size: info.Size(), Truncated: info.Size() > int64(limit)}
+		f.Content = string(data)
+		remaining -= len(data)
+		out.Files = append(out.Files, f)
+	}
+	return out, nil
 }
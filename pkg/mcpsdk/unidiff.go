@@ -0,0 +1,309 @@
+package mcpsdk
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// unifiedContextLines is the number of unchanged lines shown around each hunk.
+const unifiedContextLines = 3
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string // includes the trailing "\n" when present
+}
+
+// unifiedDiff renders a line-based unified diff (with hunk headers) between from and to.
+// It returns the diff text along with the number of added and removed lines.
+// An empty string is returned when the inputs are identical.
+func unifiedDiff(fromName, toName, from, to string) (string, int, int) {
+	dmp := diffmatchpatch.New()
+	c1, c2, lineArray := dmp.DiffLinesToChars(from, to)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(c1, c2, false), lineArray)
+
+	var lines []diffLine
+	added, removed := 0, 0
+	for _, d := range diffs {
+		for _, l := range splitLinesKeepEOL(d.Text) {
+			lines = append(lines, diffLine{op: d.Type, text: l})
+			switch d.Type {
+			case diffmatchpatch.DiffInsert:
+				added++
+			case diffmatchpatch.DiffDelete:
+				removed++
+			}
+		}
+	}
+	if added == 0 && removed == 0 {
+		return "", 0, 0
+	}
+
+	// aNo[i]/bNo[i] hold the number of old/new lines preceding index i.
+	aNo := make([]int, len(lines)+1)
+	bNo := make([]int, len(lines)+1)
+	for i, l := range lines {
+		aNo[i+1], bNo[i+1] = aNo[i], bNo[i]
+		if l.op != diffmatchpatch.DiffInsert {
+			aNo[i+1]++
+		}
+		if l.op != diffmatchpatch.DiffDelete {
+			bNo[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	i := 0
+	for i < len(lines) {
+		for i < len(lines) && lines[i].op == diffmatchpatch.DiffEqual {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+		start := i - unifiedContextLines
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk across change runs separated by at most 2*context equal lines.
+		end := i
+		for {
+			for end < len(lines) && lines[end].op != diffmatchpatch.DiffEqual {
+				end++
+			}
+			gap := 0
+			for end+gap < len(lines) && lines[end+gap].op == diffmatchpatch.DiffEqual {
+				gap++
+			}
+			if end+gap < len(lines) && gap <= 2*unifiedContextLines {
+				end += gap
+				continue
+			}
+			break
+		}
+		stop := end + unifiedContextLines
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(aNo[start], aNo[stop]-aNo[start]),
+			hunkRange(bNo[start], bNo[stop]-bNo[start]))
+		for _, l := range lines[start:stop] {
+			switch l.op {
+			case diffmatchpatch.DiffInsert:
+				b.WriteByte('+')
+			case diffmatchpatch.DiffDelete:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+	return b.String(), added, removed
+}
+
+// hunkRange formats a unified diff range. before is the count of lines preceding the hunk.
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	default:
+		return fmt.Sprintf("%d,%d", before+1, count)
+	}
+}
+
+// splitLinesKeepEOL splits s into lines, keeping the trailing "\n" on each line.
+func splitLinesKeepEOL(s string) []string {
+	var out []string
+	for s != "" {
+		idx := strings.IndexByte(s, '\n')
+		if idx < 0 {
+			out = append(out, s)
+			break
+		}
+		out = append(out, s[:idx+1])
+		s = s[idx+1:]
+	}
+	return out
+}
+
+// patchHunk is one "@@ -a,b +c,d @@" section of a unified diff.
+type patchHunk struct {
+	header   string
+	oldStart int // 1-based; for a pure insertion, the line after which to insert
+	oldLines []string
+	newLines []string
+}
+
+// parseUnifiedDiff parses the hunks of a single-file unified diff. File headers
+// ("--- a", "+++ b") and git extended headers before the first hunk are skipped;
+// a second file header is rejected. Blank lines inside a hunk are treated as
+// empty context lines, since editors often strip the leading space.
+func parseUnifiedDiff(patch string) ([]patchHunk, error) {
+	raw := strings.Split(patch, "\n")
+	if n := len(raw); n > 0 && raw[n-1] == "" {
+		raw = raw[:n-1]
+	}
+	var hunks []patchHunk
+	seenFile := false
+	for i := 0; i < len(raw); i++ {
+		line := raw[i]
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			if seenFile && len(hunks) > 0 {
+				return nil, fmt.Errorf("patch touches more than one file")
+			}
+			seenFile = true
+			continue
+		case !strings.HasPrefix(line, "@@"):
+			continue
+		}
+		var h patchHunk
+		var oldCount, newCount int
+		if err := parseHunkHeader(line, &h.oldStart, &oldCount, &newCount); err != nil {
+			return nil, err
+		}
+		h.header = line
+		lastOp := byte(' ') // for "\ No newline at end of file", which ends the previous line
+		for i+1 < len(raw) && (len(h.oldLines) < oldCount || len(h.newLines) < newCount) {
+			i++
+			l := raw[i]
+			if strings.HasPrefix(l, `\`) {
+				h.trimLastEOL(lastOp)
+				continue
+			}
+			op, text := byte(' '), ""
+			if l != "" {
+				op, text = l[0], l[1:]
+			}
+			text += "\n"
+			switch op {
+			case ' ':
+				h.oldLines = append(h.oldLines, text)
+				h.newLines = append(h.newLines, text)
+			case '-':
+				h.oldLines = append(h.oldLines, text)
+			case '+':
+				h.newLines = append(h.newLines, text)
+			default:
+				return nil, fmt.Errorf("unexpected line in hunk %q: %q", line, l)
+			}
+			lastOp = op
+		}
+		// The marker usually follows the hunk's final line
+		if i+1 < len(raw) && strings.HasPrefix(raw[i+1], `\`) {
+			i++
+			h.trimLastEOL(lastOp)
+		}
+		if len(h.oldLines) != oldCount || len(h.newLines) != newCount {
+			return nil, fmt.Errorf("hunk %q is truncated", line)
+		}
+		hunks = append(hunks, h)
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch contains no hunks")
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader reads "@@ -start[,count] +start[,count] @@"; an omitted count is 1.
+func parseHunkHeader(line string, oldStart, oldCount, newCount *int) error {
+	var newStart int
+	rest := strings.TrimPrefix(line, "@@ ")
+	if end := strings.Index(rest, " @@"); end >= 0 {
+		rest = rest[:end]
+	}
+	oldRange, newRange, ok := strings.Cut(rest, " ")
+	if !ok || !strings.HasPrefix(oldRange, "-") || !strings.HasPrefix(newRange, "+") {
+		return fmt.Errorf("malformed hunk header %q", line)
+	}
+	if err := parseHunkRange(oldRange[1:], oldStart, oldCount); err != nil {
+		return fmt.Errorf("malformed hunk header %q", line)
+	}
+	if err := parseHunkRange(newRange[1:], &newStart, newCount); err != nil {
+		return fmt.Errorf("malformed hunk header %q", line)
+	}
+	return nil
+}
+
+func parseHunkRange(s string, start, count *int) error {
+	*count = 1
+	if a, b, ok := strings.Cut(s, ","); ok {
+		if _, err := fmt.Sscanf(b, "%d", count); err != nil {
+			return err
+		}
+		s = a
+	}
+	_, err := fmt.Sscanf(s, "%d", start)
+	return err
+}
+
+// trimLastEOL drops the newline from the last line on the side(s) op belongs to.
+func (h *patchHunk) trimLastEOL(op byte) {
+	trim := func(lines []string) {
+		if len(lines) > 0 {
+			lines[len(lines)-1] = strings.TrimSuffix(lines[len(lines)-1], "\n")
+		}
+	}
+	if op != '+' {
+		trim(h.oldLines)
+	}
+	if op != '-' {
+		trim(h.newLines)
+	}
+}
+
+// hunkConflictError reports a hunk whose context or removed lines do not match
+// the content at the position given by its header.
+type hunkConflictError struct {
+	header string
+	line   int
+}
+
+func (e *hunkConflictError) Error() string {
+	return fmt.Sprintf("hunk %q does not match the file at line %d", e.header, e.line)
+}
+
+// applyUnifiedDiff applies hunks to content. Each hunk must match exactly at the
+// line its header names; there is no fuzzy matching or offset search, so a file
+// that changed since the diff was made yields a *hunkConflictError.
+func applyUnifiedDiff(content string, hunks []patchHunk) (string, error) {
+	lines := splitLinesKeepEOL(content)
+	var b strings.Builder
+	cursor := 0
+	for _, h := range hunks {
+		start := h.oldStart - 1
+		if len(h.oldLines) == 0 {
+			start = h.oldStart // "-N,0" inserts after line N
+		}
+		if start < cursor || start+len(h.oldLines) > len(lines) {
+			return "", &hunkConflictError{header: h.header, line: start + 1}
+		}
+		for j, want := range h.oldLines {
+			if lines[start+j] != want {
+				return "", &hunkConflictError{header: h.header, line: start + j + 1}
+			}
+		}
+		for _, l := range lines[cursor:start] {
+			b.WriteString(l)
+		}
+		for _, l := range h.newLines {
+			b.WriteString(l)
+		}
+		cursor = start + len(h.oldLines)
+	}
+	for _, l := range lines[cursor:] {
+		b.WriteString(l)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,222 @@
+package mcpsdk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// maxExportInlineBytes caps the compressed archive workspace_export returns as
+// base64; larger workspaces are downloaded from GET /api/workspaces/{id}/archive.
+const maxExportInlineBytes = 8 << 20 // 8 MiB
+
+// errExportTooLarge is returned by cappedBuffer once the cap is exceeded.
+var errExportTooLarge = errors.New("archive exceeds the inline size cap")
+
+// cappedBuffer is a bytes.Buffer that refuses to grow beyond max bytes.
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, errExportTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// exportStats summarises an export.
+type exportStats struct {
+	files       int
+	directories int
+}
+
+// walkArchiveEntries calls fn for every directory and regular file below root
+// that an export includes: .git and .gitkeep are left out unless includeGit is
+// set, other protected paths always; symlinks and other special files are
+// always skipped.
+func walkArchiveEntries(root string, includeGit bool, protected *workspace.ProtectedPaths, fn func(p string, d fs.DirEntry) error) error {
+	builtin := workspace.DefaultProtectedPaths()
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if protected.IsProtectedPath(rel) && !(includeGit && builtin.IsProtectedPath(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(p, d)
+	})
+}
+
+// countArchiveFiles returns the number of files an export of root includes.
+func countArchiveFiles(root string, includeGit bool, protected *workspace.ProtectedPaths) (int, error) {
+	n := 0
+	err := walkArchiveEntries(root, includeGit, protected, func(_ string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// writeWorkspaceArchive writes root as a gzip-compressed tar to w, with
+// slash-separated paths relative to root (see walkArchiveEntries for what is
+// included). progress, if non-nil, is called with the number of files written
+// after each one.
+func writeWorkspaceArchive(w io.Writer, root string, includeGit bool, protected *workspace.ProtectedPaths, progress func(files int)) (exportStats, error) {
+	var st exportStats
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := walkArchiveEntries(root, includeGit, protected, func(p string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		// Owner names from the server's passwd are meaningless to the recipient
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if d.IsDir() {
+			hdr.Name += "/"
+			st.directories++
+			return tw.WriteHeader(hdr)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.CopyN(tw, f, hdr.Size); err != nil {
+			return err
+		}
+		st.files++
+		if progress != nil {
+			progress(st.files)
+		}
+		return nil
+	})
+	if err != nil {
+		return st, err
+	}
+	if err := tw.Close(); err != nil {
+		return st, err
+	}
+	return st, gz.Close()
+}
+
+// WorkspaceExport returns a workspace as a base64-encoded tar.gz, failing with
+// TOO_LARGE when the archive exceeds maxExportInlineBytes.
+func WorkspaceExport(ctx context.Context, wm *workspace.Manager, a ExportWorkspaceRequest) (ExportWorkspaceResponse, error) {
+	if a.WorkspaceID == "" {
+		return ExportWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' is required")
+	}
+	root, err := wm.SafePath(a.WorkspaceID, ".")
+	if err != nil {
+		return ExportWorkspaceResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	var progress func(int)
+	if p := progressFromContext(ctx); p != nil {
+		total, err := countArchiveFiles(root, a.IncludeGit, wm.Protected())
+		if err != nil {
+			return ExportWorkspaceResponse{}, fmt.Errorf("INTERNAL: failed to archive workspace: %v", err)
+		}
+		progress = func(files int) { p.report(ctx, files, total, "files archived") }
+	}
+	buf := &cappedBuffer{max: maxExportInlineBytes}
+	st, err := writeWorkspaceArchive(buf, root, a.IncludeGit, wm.Protected(), progress)
+	if err != nil {
+		if errors.Is(err, errExportTooLarge) {
+			return ExportWorkspaceResponse{}, fmt.Errorf("TOO_LARGE: archive exceeds %d bytes; download it from GET /api/workspaces/%s/archive instead", maxExportInlineBytes, a.WorkspaceID)
+		}
+		return ExportWorkspaceResponse{}, fmt.Errorf("INTERNAL: failed to archive workspace: %v", err)
+	}
+	return ExportWorkspaceResponse{
+		WorkspaceID:   a.WorkspaceID,
+		ArchiveBase64: base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Size:          int64(buf.Len()),
+		Files:         st.files,
+		Directories:   st.directories,
+	}, nil
+}
+
+// workspaceExportHandler serves GET /api/workspaces/{id}/archive, streaming the
+// workspace as a tar.gz (?includeGit=true adds .git). Errors after the first
+// byte cannot change the status, so they abort the stream and are logged.
+func workspaceExportHandler(wm *workspace.Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsID := r.PathValue("id")
+		params, _ := json.Marshal(map[string]string{"workspaceId": wsID})
+		if err := checkToolScope(scopeFromContext(r.Context()), "workspace_export"); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if err := checkShareAccess(r.Context(), "workspace_export", params); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if err := checkToolBackend(wm, "workspace_export"); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		includeGit := false
+		if v := r.URL.Query().Get("includeGit"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeRESTError(w, fmt.Errorf("INVALID_INPUT: 'includeGit' must be a boolean"))
+				return
+			}
+			includeGit = b
+		}
+		root, err := wm.SafePath(wsID, ".")
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("NOT_FOUND: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", wsID+".tar.gz"))
+		if _, err := writeWorkspaceArchive(w, root, includeGit, wm.Protected(), nil); err != nil {
+			slog.Warn("Workspace export aborted", "workspaceId", wsID, "error", err)
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
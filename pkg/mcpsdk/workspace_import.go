@@ -0,0 +1,165 @@
+package mcpsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"mcp-workspace-manager/pkg/events"
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// maxImportUploadBytes bounds the size of an uploaded archive.
+const maxImportUploadBytes = 256 << 20 // 256 MiB
+
+// importWorkspace creates a workspace named name from an archive, detecting the
+// format by its magic bytes, commits the extracted tree and emits
+// workspace.created. If extraction or the commit fails the new workspace is
+// removed again.
+func importWorkspace(ctx context.Context, wm *workspace.Manager, name string, archive io.ReaderAt, size int64) (ImportWorkspaceResponse, error) {
+	if name == "" {
+		return ImportWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: 'name' is required")
+	}
+	header := make([]byte, 4)
+	n, _ := archive.ReadAt(header, 0)
+
+	var ar archiveReader
+	switch detectArchive(header[:n]) {
+	case archiveZip:
+		z, err := newZipArchive(archive, size)
+		if err != nil {
+			return ImportWorkspaceResponse{}, err
+		}
+		ar = z
+	case archiveGzip:
+		t, err := newTarGzArchive(io.NewSectionReader(archive, 0, size))
+		if err != nil {
+			return ImportWorkspaceResponse{}, err
+		}
+		ar = t
+	default:
+		return ImportWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: unrecognised archive format (expected zip or tar.gz)")
+	}
+
+	wsID, wsPath, err := wm.Create(name)
+	if err != nil {
+		return ImportWorkspaceResponse{}, fmt.Errorf("INTERNAL: failed to create workspace: %v", err)
+	}
+	discard := func() {
+		if rmErr := wm.Remove(wsID); rmErr != nil {
+			slog.Warn("Failed to remove workspace after failed import", "workspaceId", wsID, "error", rmErr)
+		}
+	}
+	st, err := extractArchive(wsPath, ar, wm.Protected(), wm.Quota())
+	if err != nil {
+		discard()
+		return ImportWorkspaceResponse{}, err
+	}
+
+	out := ImportWorkspaceResponse{
+		WorkspaceID: wsID,
+		Path:        wsPath,
+		Files:       st.files,
+		Directories: st.directories,
+		Skipped:     st.skipped,
+	}
+	if st.files+st.directories > 0 {
+		commit, err := wm.Commit(wsID, fmt.Sprintf("mcp/workspace_import: import %d files", st.files), "mcp-client")
+		if err != nil {
+			discard()
+			return ImportWorkspaceResponse{}, fmt.Errorf("INTERNAL: failed to commit import: %v", err)
+		}
+		out.Commit = commit
+	}
+	var commitRef *string
+	if out.Commit != "" {
+		commitRef = &out.Commit
+	}
+	publishWorkspaceEvent(ctx, wsID, events.WorkspaceEvent{
+		Type:   "workspace.created",
+		IsDir:  true,
+		Commit: commitRef,
+	})
+	return out, nil
+}
+
+// WorkspaceImport creates a workspace from a base64-encoded archive.
+func WorkspaceImport(ctx context.Context, wm *workspace.Manager, a ImportWorkspaceRequest) (ImportWorkspaceResponse, error) {
+	data, err := base64.StdEncoding.DecodeString(a.ArchiveBase64)
+	if err != nil {
+		return ImportWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: 'archiveBase64' is not valid base64: %v", err)
+	}
+	if len(data) == 0 {
+		return ImportWorkspaceResponse{}, fmt.Errorf("INVALID_INPUT: 'archiveBase64' is required")
+	}
+	return importWorkspace(ctx, wm, a.Name, bytes.NewReader(data), int64(len(data)))
+}
+
+// workspaceImportHandler serves POST /api/workspaces/import: a multipart form
+// with a `name` field and the archive in a `file` field. The upload is spooled to
+// a temporary file (zip needs random access) and imported like workspace_import.
+func workspaceImportHandler(wm *workspace.Manager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			writeRESTError(w, fmt.Errorf("METHOD_NOT_ALLOWED: %s is not allowed for workspace import", r.Method))
+			return
+		}
+		if err := checkToolScope(scopeFromContext(r.Context()), "workspace_import"); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if err := checkShareAccess(r.Context(), "workspace_import", nil); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		if err := checkToolBackend(wm, "workspace_import"); err != nil {
+			writeRESTError(w, err)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportUploadBytes)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeRESTError(w, fmt.Errorf("TOO_LARGE: upload exceeds %d bytes", int64(maxImportUploadBytes)))
+				return
+			}
+			writeRESTError(w, fmt.Errorf("INVALID_INPUT: expected a multipart form with the archive in 'file': %v", err))
+			return
+		}
+		defer file.Close()
+		if r.MultipartForm != nil {
+			defer func() { _ = r.MultipartForm.RemoveAll() }()
+		}
+
+		tmp, err := os.CreateTemp("", "mcp-import-*")
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("INTERNAL: failed to buffer upload: %v", err))
+			return
+		}
+		defer func() {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}()
+		size, err := io.Copy(tmp, file)
+		if err != nil {
+			writeRESTError(w, fmt.Errorf("INTERNAL: failed to buffer upload: %v", err))
+			return
+		}
+
+		out, err := importWorkspace(r.Context(), wm, r.FormValue("name"), tmp, size)
+		if err != nil {
+			writeRESTError(w, err)
+			return
+		}
+		writeRESTJSON(w, out, 0)
+	})
+}
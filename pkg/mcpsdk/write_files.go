@@ -0,0 +1,202 @@
+package mcpsdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mcp-workspace-manager/pkg/events"
+	"mcp-workspace-manager/pkg/workspace"
+)
+
+// maxWriteFiles bounds the number of files one fs_write_files call accepts.
+const maxWriteFiles = 100
+
+// plannedWrite is one file of an fs_write_files call, with what it replaces
+// so the write can be rolled back.
+type plannedWrite struct {
+	path    string
+	abs     string
+	data    []byte
+	existed bool
+	prev    []byte
+}
+
+// FSWriteFiles writes several files and commits them together. Every path is
+// validated before anything is written; if a write then fails, the files
+// already written are restored (or removed, with any directories created for
+// them) and nothing is committed. Unchanged files are reported but neither
+// written nor evented.
+func FSWriteFiles(ctx context.Context, wm *workspace.Manager, a WriteFilesRequest) (WriteFilesResponse, error) {
+	if a.WorkspaceID == "" || len(a.Files) == 0 {
+		return WriteFilesResponse{}, fmt.Errorf("INVALID_INPUT: 'workspaceId' and 'files' are required")
+	}
+	if len(a.Files) > maxWriteFiles {
+		return WriteFilesResponse{}, fmt.Errorf("INVALID_INPUT: at most %d files can be written at once", maxWriteFiles)
+	}
+	if err := checkAuthor(a.AuthorName, a.AuthorEmail); err != nil {
+		return WriteFilesResponse{}, err
+	}
+	root, err := wm.SafePath(a.WorkspaceID, ".")
+	if err != nil {
+		return WriteFilesResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	unlock, err := wm.Lock(a.WorkspaceID)
+	if err != nil {
+		return WriteFilesResponse{}, fmt.Errorf("NOT_FOUND: %v", err)
+	}
+	defer unlock()
+
+	plan := make([]plannedWrite, 0, len(a.Files))
+	seen := map[string]string{} // lower-cased path -> path
+	for i, f := range a.Files {
+		if f.Path == "" {
+			return WriteFilesResponse{}, fmt.Errorf("INVALID_INPUT: files[%d]: 'path' is required", i)
+		}
+		if wm.Protected().IsProtectedPath(f.Path) {
+			return WriteFilesResponse{}, fmt.Errorf("NOT_FOUND: files[%d]: file not found", i)
+		}
+		abs, err := wm.SafePath(a.WorkspaceID, f.Path)
+		if err != nil {
+			return WriteFilesResponse{}, fmt.Errorf("OUT_OF_BOUNDS: files[%d]: %v", i, err)
+		}
+		rel, _ := filepath.Rel(root, abs)
+		key := strings.ToLower(filepath.ToSlash(rel))
+		if prev, ok := seen[key]; ok {
+			if prev == filepath.ToSlash(rel) {
+				return WriteFilesResponse{}, fmt.Errorf("INVALID_INPUT: files[%d]: %s is listed more than once", i, f.Path)
+			}
+			return WriteFilesResponse{}, fmt.Errorf("CONFLICT: files[%d]: %s differs only in case from %s", i, f.Path, prev)
+		}
+		seen[key] = filepath.ToSlash(rel)
+		if err := checkCaseCollision(wm.FS(), root, abs, ""); err != nil {
+			return WriteFilesResponse{}, err
+		}
+		w := plannedWrite{path: f.Path, abs: abs, data: []byte(f.Content)}
+		info, statErr := os.Stat(abs)
+		switch {
+		case statErr == nil && info.IsDir():
+			return WriteFilesResponse{}, fmt.Errorf("CONFLICT: files[%d]: %s is a directory", i, f.Path)
+		case statErr == nil:
+			if w.prev, err = os.ReadFile(abs); err != nil {
+				return WriteFilesResponse{}, fmt.Errorf("INTERNAL: failed to read %s: %v", f.Path, err)
+			}
+			w.existed = true
+		case !os.IsNotExist(statErr):
+			return WriteFilesResponse{}, fmt.Errorf("INTERNAL: failed to stat %s: %v", f.Path, statErr)
+		}
+		plan = append(plan, w)
+	}
+
+	out := WriteFilesResponse{Files: make([]WrittenFile, len(plan))}
+	var changed []int
+	for i, w := range plan {
+		out.Files[i] = WrittenFile{Path: w.path, Overwritten: w.existed}
+		if w.existed && bytes.Equal(w.prev, w.data) {
+			out.Files[i].Unchanged = true
+			continue
+		}
+		out.Files[i].BytesWritten = len(w.data)
+		changed = append(changed, i)
+	}
+	if len(changed) == 0 {
+		return out, nil
+	}
+
+	var written []plannedWrite
+	var createdDirs []string
+	for _, i := range changed {
+		w := plan[i]
+		expectWorkspaceChange(a.WorkspaceID, w.path, "file.created", "file.updated")
+		dirs := missingDirs(root, filepath.Dir(w.abs))
+		err := os.MkdirAll(filepath.Dir(w.abs), 0755)
+		if err == nil {
+			createdDirs = append(createdDirs, dirs...)
+			err = wm.WriteFileAtomic(a.WorkspaceID, w.abs, w.data, 0644)
+		}
+		if err != nil {
+			msg := writeFailed("failed to write "+w.path, err).Error()
+			if rbErr := rollbackWrites(wm, a.WorkspaceID, written, createdDirs); rbErr != nil {
+				return WriteFilesResponse{}, fmt.Errorf("%s; rolling back the %d files written before it failed: %v", msg, len(written), rbErr)
+			}
+			return WriteFilesResponse{}, fmt.Errorf("%s; the %d files written before it were rolled back", msg, len(written))
+		}
+		written = append(written, w)
+	}
+	for _, w := range written {
+		removeRedundantGitkeeps(wm.FS(), root, w.abs)
+	}
+
+	message := fmt.Sprintf("mcp/fs_write_files: Write %s", plan[changed[0]].path)
+	if len(changed) > 1 {
+		var body strings.Builder
+		for _, i := range changed {
+			body.WriteString("\n- " + plan[i].path)
+		}
+		message = fmt.Sprintf("mcp/fs_write_files: Write %d files\n%s", len(changed), body.String())
+	}
+	commit, err := commitAs(wm, a.WorkspaceID, message, a.AuthorName, a.AuthorEmail)
+	if err != nil {
+		return WriteFilesResponse{}, fmt.Errorf("INTERNAL: failed to commit changes: %v", err)
+	}
+	out.Commit = commit
+
+	for _, i := range changed {
+		evtType := "file.created"
+		if plan[i].existed {
+			evtType = "file.updated"
+		}
+		commitCopy := commit
+		publishWorkspaceEvent(ctx, a.WorkspaceID, events.WorkspaceEvent{
+			Type:   evtType,
+			Path:   plan[i].path,
+			Commit: &commitCopy,
+		})
+	}
+	return out, nil
+}
+
+// missingDirs returns the directories between root and dir (inclusive) that do
+// not exist yet, deepest first.
+func missingDirs(root, dir string) []string {
+	var missing []string
+	for d := dir; d != root && strings.HasPrefix(d, root); d = filepath.Dir(d) {
+		if _, err := os.Stat(d); !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, d)
+	}
+	return missing
+}
+
+// rollbackWrites undoes the writes of a failed fs_write_files call: replaced
+// files get their previous content back, new files and the directories created
+// for them are removed.
+func rollbackWrites(wm *workspace.Manager, workspaceID string, written []plannedWrite, createdDirs []string) error {
+	var errs []string
+	for i := len(written) - 1; i >= 0; i-- {
+		w := written[i]
+		var err error
+		if w.existed {
+			err = wm.WriteFileAtomic(workspaceID, w.abs, w.prev, 0644)
+		} else {
+			err = os.Remove(w.abs)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", w.path, err))
+		}
+	}
+	// Deeper paths are longer, so children go before their parents
+	sort.Slice(createdDirs, func(i, j int) bool { return len(createdDirs[i]) > len(createdDirs[j]) })
+	for _, d := range createdDirs {
+		_ = os.Remove(d)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempFilePrefix marks temp files created by WriteFileAtomic so orphans can be
+// recognised and removed on startup.
+const tempFilePrefix = "mcp-write-"
+
+// workspaceTempDir is the per-workspace temp location used when no temp dir is
+// configured. Living under .git keeps it on the workspace's filesystem while
+// staying invisible to commits, listings and the filesystem watcher.
+const workspaceTempDir = ".git/mcp-tmp"
+
+// tempDirFor returns the directory temp files for workspaceID are created in.
+func (m *Manager) tempDirFor(workspaceID string) string {
+	if m.tempDir != "" {
+		return m.tempDir
+	}
+	return filepath.Join(m.rootPath, workspaceID, filepath.FromSlash(workspaceTempDir))
+}
+
+// WriteFileAtomic writes data to absPath (a path inside workspaceID) by writing a
+// temp file and renaming it over the target, so readers never observe a partial
+// file. An existing target keeps its permissions; new files get perm. Writes
+// to filesystems other than OSFS are atomic already and go straight to the FS.
+// With a quota (see WithQuota) a write that would exceed it fails with
+// ErrQuotaExceeded before anything is written.
+func (m *Manager) WriteFileAtomic(workspaceID, absPath string, data []byte, perm os.FileMode) (err error) {
+	var prevSize int64
+	info, statErr := m.fs.Stat(absPath)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+		prevSize = info.Size()
+	}
+	if m.countsTowardQuota(workspaceID, absPath) {
+		if err := m.reserve(workspaceID, int64(len(data))-prevSize); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				m.InvalidateUsage(workspaceID)
+			}
+		}()
+	}
+	if !m.OnDisk() {
+		return m.fs.WriteFile(absPath, data, perm)
+	}
+	dir := m.tempDirFor(workspaceID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, tempFilePrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return fmt.Errorf("failed to set permissions on temp file: %w", err)
+	}
+	if err := os.Rename(tmpName, absPath); err != nil {
+		return fmt.Errorf("failed to move temp file into place: %w", err)
+	}
+	return nil
+}
+
+// checkSameDevice verifies that files created in dir can be renamed into root,
+// which fails when the two are on different filesystems.
+func checkSameDevice(dir, root string) error {
+	probe, err := os.CreateTemp(dir, tempFilePrefix+"probe-*")
+	if err != nil {
+		return fmt.Errorf("temp dir %q is not writable: %w", dir, err)
+	}
+	probe.Close()
+	defer os.Remove(probe.Name())
+
+	target := filepath.Join(root, "."+filepath.Base(probe.Name()))
+	if err := os.Rename(probe.Name(), target); err != nil {
+		var linkErr *os.LinkError
+		if errors.As(err, &linkErr) {
+			return fmt.Errorf("temp dir %q must be on the same filesystem as the workspaces root: %w", dir, err)
+		}
+		return err
+	}
+	return os.Remove(target)
+}
+
+// cleanupOrphanedTempFiles removes temp files left behind by interrupted writes,
+// both in the configured temp dir and in each workspace's default temp dir.
+func (m *Manager) cleanupOrphanedTempFiles() {
+	dirs := []string{}
+	if m.tempDir != "" {
+		dirs = append(dirs, m.tempDir)
+	}
+	if entries, err := m.fs.ReadDir(m.rootPath); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, filepath.Join(m.rootPath, e.Name(), filepath.FromSlash(workspaceTempDir)))
+			}
+		}
+	}
+	for _, dir := range dirs {
+		entries, err := m.fs.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasPrefix(e.Name(), tempFilePrefix) {
+				continue
+			}
+			p := filepath.Join(dir, e.Name())
+			if err := m.fs.RemoveAll(p); err != nil {
+				slog.Warn("Failed to remove orphaned temp file", "path", p, "error", err)
+			} else {
+				slog.Info("Removed orphaned temp file", "path", p)
+			}
+		}
+	}
+}
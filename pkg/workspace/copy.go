@@ -0,0 +1,241 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Errors returned (wrapped) by CopyPath.
+var (
+	ErrSourceNotFound    = errors.New("source not found")
+	ErrDestinationExists = errors.New("destination exists")
+	ErrUnsupportedType   = errors.New("only regular files and directories can be copied")
+	ErrCopyIntoSelf      = errors.New("destination is inside the source directory")
+)
+
+// CopyPath copies a file or directory tree from srcRel in one workspace to
+// dstRel in another (or the same) workspace, creating dstRel's parents. It never
+// overwrites: the destination must not exist. See copyTree for what is copied.
+// Nothing is committed. A copy that would take the destination workspace over
+// its quota fails with ErrQuotaExceeded. Copies are only supported on OSFS.
+func (m *Manager) CopyPath(srcWorkspaceID, srcRel, dstWorkspaceID, dstRel string) error {
+	return m.CopyPathWithProgress(srcWorkspaceID, srcRel, dstWorkspaceID, dstRel, nil)
+}
+
+// CopyPathWithProgress is CopyPath calling progress, if non-nil, after each
+// file with the number of files copied so far and the total to copy.
+func (m *Manager) CopyPathWithProgress(srcWorkspaceID, srcRel, dstWorkspaceID, dstRel string, progress func(copied, total int)) error {
+	if !m.OnDisk() {
+		return fmt.Errorf("copying files: %w", errors.ErrUnsupported)
+	}
+	src, err := m.SafePath(srcWorkspaceID, srcRel)
+	if err != nil {
+		return err
+	}
+	dst, err := m.SafePath(dstWorkspaceID, dstRel)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrSourceNotFound
+		}
+		return fmt.Errorf("failed to stat source: %w", err)
+	}
+	if !info.IsDir() && !info.Mode().IsRegular() {
+		return ErrUnsupportedType
+	}
+	if info.IsDir() && (dst == src || strings.HasPrefix(dst, src+string(os.PathSeparator))) {
+		return ErrCopyIntoSelf
+	}
+	if m.quota > 0 {
+		size := info.Size()
+		if info.IsDir() {
+			if size, err = m.treeSize(src); err != nil {
+				return fmt.Errorf("failed to scan source: %w", err)
+			}
+		}
+		if err := m.reserve(dstWorkspaceID, size); err != nil {
+			return err
+		}
+		defer m.InvalidateUsage(dstWorkspaceID)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	var onFile func()
+	if progress != nil {
+		total, err := countTreeFiles(src)
+		if err != nil {
+			return fmt.Errorf("failed to scan source: %w", err)
+		}
+		copied := 0
+		onFile = func() {
+			copied++
+			progress(copied, total)
+		}
+	}
+	if err := copyTree(src, dst, onFile); err != nil {
+		if !errors.Is(err, ErrDestinationExists) {
+			_ = os.RemoveAll(dst)
+		}
+		return err
+	}
+	return nil
+}
+
+// Clone creates a workspace named newName, with an id chosen as in Create,
+// holding a copy of sourceID: its git repository with the full history, tags
+// and branches, and its working tree including uncommitted changes. Callers
+// should hold the source's Lock so the copy is consistent. A source over the
+// quota fails with ErrQuotaExceeded. Clones are only supported on OSFS.
+func (m *Manager) Clone(sourceID, newName string) (string, string, error) {
+	if !m.OnDisk() {
+		return "", "", fmt.Errorf("cloning workspaces: %w", errors.ErrUnsupported)
+	}
+	if sourceID == "" || sourceID == "." || sourceID == ".." || strings.ContainsAny(sourceID, `/\`) || !m.isWorkspace(sourceID) {
+		return "", "", fmt.Errorf("workspace '%s' not found", sourceID)
+	}
+	src := filepath.Join(m.rootPath, sourceID)
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read workspace: %w", err)
+	}
+	size, err := m.treeSize(src)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to scan workspace: %w", err)
+	}
+	if err := m.checkNewWorkspaceSize(size); err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	id, path, err := m.claimNewWorkspaceDir(newName, now)
+	if err != nil {
+		return "", "", err
+	}
+	for _, e := range entries {
+		// copyTree only skips nested .git directories, so the repository
+		// itself is copied along with the working tree.
+		if err := copyTree(filepath.Join(src, e.Name()), filepath.Join(path, e.Name()), nil); err != nil {
+			if rmErr := os.RemoveAll(path); rmErr != nil {
+				slog.Warn("Failed to remove workspace after failed clone", "workspaceId", id, "error", rmErr)
+			}
+			return "", "", fmt.Errorf("failed to copy workspace: %w", err)
+		}
+	}
+	// Temp files of in-flight writes to the source belong to the source
+	_ = os.RemoveAll(filepath.Join(path, filepath.FromSlash(workspaceTempDir)))
+	if err := m.writeMetadata(id, Metadata{Name: newName, CreatedAt: now.UTC()}); err != nil {
+		slog.Warn("Failed to write workspace metadata", "workspaceId", id, "error", err)
+	}
+	slog.Info("Cloned workspace", "from", sourceID, "id", id, "path", path)
+	return id, path, nil
+}
+
+// copyTree copies a regular file or directory tree from src to dst, preserving
+// permission bits exactly (regardless of umask). .git directories below src and
+// anything that is not a regular file or directory are skipped; symlinks are
+// never followed, so a link cannot pull in content from outside src. dst must
+// not exist. onFile, if non-nil, is called after each file is copied.
+func copyTree(src, dst string, onFile func()) error {
+	if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+		return ErrDestinationExists
+	}
+	// Directories stay writable while being filled; their modes are applied
+	// afterwards, deepest first, so read-only directories can be copied too
+	type dirMode struct {
+		path string
+		perm fs.FileMode
+	}
+	var dirs []dirMode
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			if p != src && d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			if err := os.Mkdir(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{target, info.Mode().Perm()})
+			return nil
+		case info.Mode().IsRegular():
+			if err := copyFile(p, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			if onFile != nil {
+				onFile()
+			}
+			return nil
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countTreeFiles returns the number of regular files copyTree copies from src.
+func countTreeFiles(src string) (int, error) {
+	n := 0
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != src && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		if d.Type().IsRegular() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, perm)
+}
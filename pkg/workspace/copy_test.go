@@ -0,0 +1,132 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyTree_NestedPreservesModes(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "a", "b"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "top.txt"), []byte("top"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a", "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a", "b", "secret"), []byte("s"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, ".git", "HEAD"), []byte("ref"), 0644))
+	require.NoError(t, os.Symlink("/etc/passwd", filepath.Join(src, "a", "link")))
+	require.NoError(t, os.Chmod(filepath.Join(src, "a", "b"), 0750))
+	require.NoError(t, os.Chmod(filepath.Join(src, "a"), 0555))
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(src, "a"), 0755) })
+
+	dst := filepath.Join(t.TempDir(), "dst")
+	require.NoError(t, copyTree(src, dst, nil))
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(dst, "a"), 0755) })
+
+	data, err := os.ReadFile(filepath.Join(dst, "a", "b", "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "s", string(data))
+
+	modes := map[string]os.FileMode{
+		"top.txt":    0644,
+		"a":          0555,
+		"a/run.sh":   0755,
+		"a/b":        0750,
+		"a/b/secret": 0600,
+	}
+	for rel, want := range modes {
+		info, err := os.Stat(filepath.Join(dst, rel))
+		require.NoError(t, err, rel)
+		assert.Equal(t, want, info.Mode().Perm(), rel)
+	}
+
+	_, err = os.Lstat(filepath.Join(dst, "a", "link"))
+	assert.True(t, os.IsNotExist(err), "symlinks must not be copied")
+	_, err = os.Stat(filepath.Join(dst, ".git"))
+	assert.True(t, os.IsNotExist(err), ".git must not be copied")
+}
+
+func TestCopyTree_DestinationExists(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "dst.txt")
+	require.NoError(t, os.WriteFile(src, []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(dst, []byte("old"), 0644))
+
+	err := copyTree(src, dst, nil)
+	assert.True(t, errors.Is(err, ErrDestinationExists), "got %v", err)
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+}
+
+func TestCopyPath_BetweenWorkspaces(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	srcID, srcPath, err := m.Create("source")
+	require.NoError(t, err)
+	dstID, dstPath, err := m.Create("dest")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(srcPath, "pkg", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "pkg", "sub", "f.go"), []byte("package sub\n"), 0644))
+
+	require.NoError(t, m.CopyPath(srcID, "pkg", dstID, "vendor/pkg"))
+	data, err := os.ReadFile(filepath.Join(dstPath, "vendor", "pkg", "sub", "f.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package sub\n", string(data))
+
+	assert.ErrorIs(t, m.CopyPath(srcID, "pkg", dstID, "vendor/pkg"), ErrDestinationExists)
+	assert.ErrorIs(t, m.CopyPath(srcID, "missing", dstID, "x"), ErrSourceNotFound)
+	assert.ErrorIs(t, m.CopyPath(srcID, "pkg", srcID, "pkg/sub/copy"), ErrCopyIntoSelf)
+	_, err = os.Stat(filepath.Join(srcPath, "pkg", "sub", "copy"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestClone_CopiesHistoryAndWorkingTree(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	src, srcPath, err := m.Create("Original")
+	require.NoError(t, err)
+	commitFiles(t, m, src, map[string]string{"a.txt": "one"})
+	head := commitFiles(t, m, src, map[string]string{"a.txt": "two", "dir/b.txt": "b"})
+	_, err = m.CreateTag(src, "v1", "", "")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "draft.txt"), []byte("uncommitted"), 0644))
+
+	id, path, err := m.Clone(src, "Original")
+	require.NoError(t, err)
+	assert.Equal(t, "original-2", id, "colliding ids are suffixed")
+
+	cloneHead, err := m.HeadCommit(id)
+	require.NoError(t, err)
+	assert.Equal(t, head, cloneHead)
+	want, err := m.GetCommitHistory(src, 10, "", TimeWindow{})
+	require.NoError(t, err)
+	got, err := m.GetCommitHistory(id, 10, "", TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].Hash, got[i].Hash)
+	}
+	tags, err := m.ListTags(id)
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "v1", Commit: head}}, tags)
+	data, err := os.ReadFile(filepath.Join(path, "draft.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "uncommitted", string(data))
+
+	// The clone is independent of its source
+	commitFiles(t, m, id, map[string]string{"a.txt": "three"})
+	srcHead, err := m.HeadCommit(src)
+	require.NoError(t, err)
+	assert.Equal(t, head, srcHead)
+
+	_, _, err = m.Clone("missing", "x")
+	assert.Error(t, err)
+	_, _, err = m.Clone("..", "x")
+	assert.Error(t, err)
+}
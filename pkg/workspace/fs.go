@@ -0,0 +1,321 @@
+package workspace
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is the filesystem a Manager keeps workspaces on. Names are absolute paths
+// under the workspaces root; errors satisfy os.IsNotExist and os.IsExist like
+// those of the os package.
+type FS interface {
+	Open(name string) (File, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	ReadDir(name string) ([]os.DirEntry, error)
+	Rename(oldpath, newpath string) error
+	RemoveAll(path string) error
+	Stat(name string) (os.FileInfo, error)
+	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+}
+
+// File is an open file returned by FS.Open.
+type File interface {
+	io.ReadSeekCloser
+	Stat() (os.FileInfo, error)
+}
+
+// OSFS is the default FS, backed by the operating system's filesystem.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (File, error)               { return os.Open(name) }
+func (OSFS) ReadFile(name string) ([]byte, error)         { return os.ReadFile(name) }
+func (OSFS) ReadDir(name string) ([]os.DirEntry, error)   { return os.ReadDir(name) }
+func (OSFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (OSFS) RemoveAll(path string) error                  { return os.RemoveAll(path) }
+func (OSFS) Stat(name string) (os.FileInfo, error)        { return os.Stat(name) }
+func (OSFS) Mkdir(name string, perm os.FileMode) error    { return os.Mkdir(name, perm) }
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// WalkDir walks the tree rooted at root through fsys, calling fn for each file
+// or directory as filepath.WalkDir does (lexical order, SkipDir and SkipAll).
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys FS, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		if err = fn(name, d, err); err != nil {
+			if err == filepath.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := walkDir(fsys, filepath.Join(name, e.Name()), e, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MemFS is an FS held entirely in memory, for tests and ephemeral workspaces
+// that never touch disk. The zero value is not usable; see NewMemFS.
+type MemFS struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode // keyed by cleaned absolute path
+}
+
+type memNode struct {
+	dir     bool
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty in-memory filesystem containing only the root directory.
+func NewMemFS() *MemFS {
+	root := filepath.VolumeName(os.TempDir()) + string(filepath.Separator)
+	return &MemFS{nodes: map[string]*memNode{
+		root: {dir: true, mode: fs.ModeDir | 0755, modTime: time.Now()},
+	}}
+}
+
+func (m *MemFS) Open(name string) (File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name = filepath.Clean(name)
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	// Readers see the content as of Open; later writes replace the slice.
+	return &memFile{Reader: bytes.NewReader(n.data), info: n.info(name)}, nil
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name = filepath.Clean(name)
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if n.dir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+	return bytes.Clone(n.data), nil
+}
+
+// WriteFile creates or truncates name; like os.WriteFile, an existing file
+// keeps its permissions.
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if err := m.checkParent("open", name); err != nil {
+		return err
+	}
+	if n, ok := m.nodes[name]; ok {
+		if n.dir {
+			return &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+		}
+		perm = n.mode.Perm()
+	}
+	m.nodes[name] = &memNode{data: bytes.Clone(data), mode: perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name = filepath.Clean(name)
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if !n.dir {
+		return nil, &fs.PathError{Op: "readdirent", Path: name, Err: errNotDir}
+	}
+	var entries []os.DirEntry
+	for p, child := range m.nodes {
+		if p != name && filepath.Dir(p) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(child.info(p)))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Rename moves a file or directory tree, replacing an existing file (but not
+// a directory) at newpath as os.Rename does on Unix.
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	n, ok := m.nodes[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if err := m.checkParent("rename", newpath); err != nil {
+		return err
+	}
+	if oldpath == newpath {
+		return nil
+	}
+	if n.dir && strings.HasPrefix(newpath, oldpath+string(filepath.Separator)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrInvalid}
+	}
+	if existing, ok := m.nodes[newpath]; ok && (existing.dir || n.dir) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+	prefix := oldpath + string(filepath.Separator)
+	for p, child := range m.nodes {
+		if p == oldpath {
+			delete(m.nodes, p)
+			m.nodes[newpath] = child
+		} else if strings.HasPrefix(p, prefix) {
+			delete(m.nodes, p)
+			m.nodes[newpath+string(filepath.Separator)+strings.TrimPrefix(p, prefix)] = child
+		}
+	}
+	return nil
+}
+
+// RemoveAll removes path and everything below it; a missing path is not an error.
+func (m *MemFS) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	prefix := path + string(filepath.Separator)
+	for p := range m.nodes {
+		if p == path || strings.HasPrefix(p, prefix) {
+			delete(m.nodes, p)
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name = filepath.Clean(name)
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return n.info(name), nil
+}
+
+func (m *MemFS) Mkdir(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.nodes[name]; ok {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if err := m.checkParent("mkdir", name); err != nil {
+		return err
+	}
+	m.nodes[name] = &memNode{dir: true, mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	var missing []string
+	for p := path; ; p = filepath.Dir(p) {
+		if n, ok := m.nodes[p]; ok {
+			if !n.dir {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: errNotDir}
+			}
+			break
+		}
+		missing = append(missing, p)
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		m.nodes[missing[i]] = &memNode{dir: true, mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+// checkParent reports an error unless name's parent is an existing directory.
+// The caller holds mu.
+func (m *MemFS) checkParent(op, name string) error {
+	parent, ok := m.nodes[filepath.Dir(name)]
+	switch {
+	case !ok:
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	case !parent.dir:
+		return &fs.PathError{Op: op, Path: name, Err: errNotDir}
+	}
+	return nil
+}
+
+func (n *memNode) info(name string) os.FileInfo {
+	return memFileInfo{name: filepath.Base(name), size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
+
+type memFile struct {
+	*bytes.Reader
+	info os.FileInfo
+}
+
+func (f *memFile) Close() error               { return nil }
+func (f *memFile) Stat() (os.FileInfo, error) { return f.info, nil }
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memFileInfo) Sys() any           { return nil }
@@ -0,0 +1,130 @@
+package workspace
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS_Conformance(t *testing.T) {
+	for _, b := range []struct {
+		name string
+		fs   FS
+	}{
+		{"os", OSFS{}},
+		{"memory", NewMemFS()},
+	} {
+		t.Run(b.name, func(t *testing.T) {
+			fsys, root := b.fs, t.TempDir()
+			p := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+			require.NoError(t, fsys.MkdirAll(root, 0755))
+
+			require.NoError(t, fsys.MkdirAll(p("a/b"), 0755))
+			require.NoError(t, fsys.WriteFile(p("a/b/one.txt"), []byte("one"), 0600))
+			require.NoError(t, fsys.WriteFile(p("a/two.txt"), []byte("two"), 0644))
+			err := fsys.WriteFile(p("missing/x.txt"), []byte("x"), 0644)
+			assert.True(t, os.IsNotExist(err), "%v", err)
+			assert.True(t, os.IsExist(fsys.Mkdir(p("a"), 0755)))
+
+			data, err := fsys.ReadFile(p("a/b/one.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "one", string(data))
+			require.NoError(t, fsys.WriteFile(p("a/b/one.txt"), []byte("uno!"), 0644))
+			info, err := fsys.Stat(p("a/b/one.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), info.Size())
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "overwrites keep permissions")
+
+			f, err := fsys.Open(p("a/two.txt"))
+			require.NoError(t, err)
+			_, err = f.Seek(1, io.SeekStart)
+			require.NoError(t, err)
+			rest, err := io.ReadAll(f)
+			require.NoError(t, err)
+			assert.Equal(t, "wo", string(rest))
+			require.NoError(t, f.Close())
+
+			entries, err := fsys.ReadDir(p("a"))
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+			assert.Equal(t, "b", entries[0].Name())
+			assert.True(t, entries[0].IsDir())
+			assert.Equal(t, "two.txt", entries[1].Name())
+
+			var walked []string
+			require.NoError(t, WalkDir(fsys, p("a"), func(pth string, d os.DirEntry, err error) error {
+				require.NoError(t, err)
+				rel, _ := filepath.Rel(root, pth)
+				walked = append(walked, filepath.ToSlash(rel))
+				return nil
+			}))
+			assert.Equal(t, []string{"a", "a/b", "a/b/one.txt", "a/two.txt"}, walked)
+
+			require.NoError(t, fsys.Rename(p("a"), p("c")))
+			_, err = fsys.Stat(p("a/b/one.txt"))
+			assert.True(t, os.IsNotExist(err), "%v", err)
+			data, err = fsys.ReadFile(p("c/b/one.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "uno!", string(data))
+
+			require.NoError(t, fsys.RemoveAll(p("c/b")))
+			require.NoError(t, fsys.RemoveAll(p("c/b")), "removing a missing path is not an error")
+			entries, err = fsys.ReadDir(p("c"))
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, "two.txt", entries[0].Name())
+		})
+	}
+}
+
+func TestMemFS_WorkspacesNeverTouchDisk(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	m, err := NewManager(root, WithFS(NewMemFS()))
+	require.NoError(t, err)
+	assert.False(t, m.OnDisk())
+	_, err = os.Stat(root)
+	assert.True(t, os.IsNotExist(err), "the root only exists in memory")
+
+	id, path, err := m.Create("Scratch")
+	require.NoError(t, err)
+	abs, err := m.SafePath(id, "notes/todo.txt")
+	require.NoError(t, err)
+	require.NoError(t, m.FS().MkdirAll(filepath.Dir(abs), 0755))
+	require.NoError(t, m.WriteFileAtomic(id, abs, []byte("buy milk"), 0644))
+	data, err := m.FS().ReadFile(filepath.Join(path, "notes", "todo.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "buy milk", string(data))
+	_, err = m.SafePath("missing", "a.txt")
+	assert.Error(t, err)
+
+	// Commits are skipped and there is no history
+	commit, err := m.Commit(id, "ignored", "test")
+	require.NoError(t, err)
+	assert.Empty(t, commit)
+	head, err := m.HeadCommit(id)
+	require.NoError(t, err)
+	assert.Empty(t, head)
+	history, err := m.GetCommitHistory(id, 10, "", TimeWindow{})
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	_, err = m.DiffCommits(id, "HEAD", "")
+	assert.True(t, errors.Is(err, ErrNoHistory), "%v", err)
+	_, err = m.CreateTag(id, "v1", "", "")
+	assert.True(t, errors.Is(err, ErrNoHistory), "%v", err)
+
+	newID, err := m.Rename(id, "Renamed")
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", newID)
+	list, err := m.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, Workspace{Name: "renamed", Path: filepath.Join(m.RootPath(), "renamed"), DisplayName: "Renamed"}, list[0])
+	data, err = m.FS().ReadFile(filepath.Join(m.RootPath(), "renamed", "notes", "todo.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "buy milk", string(data))
+}
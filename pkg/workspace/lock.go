@@ -0,0 +1,74 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// workspaceLock guards one workspace. Mutations share rw and take turns on mu;
+// destructive operations hold rw exclusively, so they never overlap a write.
+type workspaceLock struct {
+	rw sync.RWMutex
+	mu sync.Mutex
+}
+
+// lockFor returns the lock of workspaceID, creating it on first use.
+func (m *Manager) lockFor(workspaceID string) *workspaceLock {
+	m.locksMu.Lock()
+	defer m.locksMu.Unlock()
+	if m.locks == nil {
+		m.locks = map[string]*workspaceLock{}
+	}
+	l, ok := m.locks[workspaceID]
+	if !ok {
+		l = &workspaceLock{}
+		m.locks[workspaceID] = l
+	}
+	return l
+}
+
+// checkExists reports a workspace that disappeared, typically renamed away,
+// while the caller waited for its lock.
+func (m *Manager) checkExists(workspaceID string) error {
+	if _, err := m.fs.Stat(filepath.Join(m.rootPath, workspaceID)); os.IsNotExist(err) {
+		return fmt.Errorf("workspace '%s' not found", workspaceID)
+	}
+	return nil
+}
+
+// Lock acquires the per-workspace lock that serializes modifications of a
+// workspace's working tree and git index, and returns the function releasing
+// it. Callers hold it across a write and the Commit recording it, so
+// concurrent writers cannot interleave index updates or commit each other's
+// changes. It waits for any LockExclusive holder and fails if the workspace
+// no longer exists once acquired. It is not reentrant.
+func (m *Manager) Lock(workspaceID string) (unlock func(), err error) {
+	l := m.lockFor(workspaceID)
+	l.rw.RLock()
+	l.mu.Lock()
+	unlock = func() {
+		l.mu.Unlock()
+		l.rw.RUnlock()
+	}
+	if err := m.checkExists(workspaceID); err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
+
+// LockExclusive acquires the workspace's lock for an operation that rewrites
+// or moves the whole workspace (revert, reset, rename): it waits for every
+// Lock holder to finish and keeps new ones out until released. Like Lock, it
+// fails if the workspace no longer exists once acquired.
+func (m *Manager) LockExclusive(workspaceID string) (unlock func(), err error) {
+	l := m.lockFor(workspaceID)
+	l.rw.Lock()
+	if err := m.checkExists(workspaceID); err != nil {
+		l.rw.Unlock()
+		return nil, err
+	}
+	return l.rw.Unlock, nil
+}
@@ -0,0 +1,123 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockExclusive_WaitsForWriters(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, _, err := m.Create("Locks")
+	require.NoError(t, err)
+
+	unlock, err := m.Lock(id)
+	require.NoError(t, err)
+	acquired := make(chan struct{})
+	go func() {
+		release, err := m.LockExclusive(id)
+		if assert.NoError(t, err) {
+			release()
+		}
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("LockExclusive acquired while a writer held Lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-acquired
+}
+
+func TestLock_FailsAfterRename(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, _, err := m.Create("Before")
+	require.NoError(t, err)
+
+	exclusive, err := m.LockExclusive(id)
+	require.NoError(t, err)
+	waited := make(chan error, 1)
+	go func() {
+		unlock, err := m.Lock(id)
+		if err == nil {
+			unlock()
+		}
+		waited <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	newID, err := m.Rename(id, "After")
+	require.NoError(t, err)
+	exclusive()
+
+	assert.ErrorContains(t, <-waited, "not found", "a writer queued behind the rename must not proceed")
+	unlock, err := m.Lock(newID)
+	require.NoError(t, err)
+	unlock()
+}
+
+// TestLock_WritesInterleavedWithRename hammers a workspace with committed
+// writes while it is renamed: every write lands in exactly one of the two ids
+// and the moved repository stays consistent.
+func TestLock_WritesInterleavedWithRename(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, _, err := m.Create("Stress")
+	require.NoError(t, err)
+
+	const writers, writes = 8, 15
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	committed := 0
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				abs, err := m.SafePath(id, fmt.Sprintf("w%d-%d.txt", w, i))
+				if err != nil {
+					return
+				}
+				unlock, err := m.Lock(id)
+				if err != nil {
+					return
+				}
+				err = m.WriteFileAtomic(id, abs, []byte("data"), 0644)
+				if err == nil {
+					_, err = m.Commit(id, "write", "test")
+				}
+				unlock()
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				committed++
+				mu.Unlock()
+			}
+		}(w)
+	}
+	time.Sleep(10 * time.Millisecond)
+	unlock, err := m.LockExclusive(id)
+	require.NoError(t, err)
+	newID, err := m.Rename(id, "Stress Renamed")
+	unlock()
+	require.NoError(t, err)
+	wg.Wait()
+
+	_, err = os.Stat(filepath.Join(m.RootPath(), id))
+	assert.True(t, os.IsNotExist(err), "no write recreated the old workspace")
+	changes, err := m.Status(newID)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "every write was committed")
+	history, err := m.GetCommitHistory(newID, writers*writes+10, "", TimeWindow{})
+	require.NoError(t, err)
+	assert.Len(t, history, committed+1, "one commit per write on top of the initial one")
+}
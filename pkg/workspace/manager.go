@@ -1,44 +1,126 @@
 package workspace
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
+// ErrWorkspaceExists is returned (wrapped) by Rename when the target id is taken.
+var ErrWorkspaceExists = errors.New("workspace already exists")
+
+// ErrNoHistory is returned (wrapped) by git-backed methods for workspaces kept
+// on a filesystem other than OSFS, which have no repository.
+var ErrNoHistory = errors.New("workspace has no git history")
+
+// ErrCommitNotFound is returned (wrapped) when a revision names no commit.
+var ErrCommitNotFound = errors.New("commit not found")
+
 // Manager handles all operations related to workspaces.
 type Manager struct {
-	rootPath string
+	rootPath     string
+	fs           FS     // see WithFS
+	tempDir      string // optional; see WithTempDir
+	templatesDir string // optional; see WithTemplatesDir
+	slugs        SlugStrategy
+	quota        int64 // optional; see WithQuota
+	protected    *ProtectedPaths
+
+	usageMu sync.Mutex
+	usage   map[string]*usageEntry // cached workspace sizes; see Usage
+
+	locksMu sync.Mutex
+	locks   map[string]*workspaceLock // per-workspace locks; see Lock
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithSlugStrategy selects how new workspace ids are generated (default SlugStrategySlug).
+func WithSlugStrategy(s SlugStrategy) Option {
+	return func(m *Manager) { m.slugs = s }
+}
+
+// WithTempDir sets the directory used for temp files during atomic writes.
+// It must be on the same filesystem as the workspaces root; by default each
+// workspace uses a directory inside its own .git folder.
+func WithTempDir(dir string) Option {
+	return func(m *Manager) { m.tempDir = dir }
+}
+
+// WithFS keeps workspaces on fsys instead of the OS filesystem. Workspaces on
+// any FS other than OSFS have no git repository: commits are skipped (Commit
+// returns an empty hash), history is empty and the other git-backed methods
+// return ErrNoHistory. The mcpsdk tools that still need the OS filesystem or
+// history refuse such a Manager with UNSUPPORTED (see OnDisk).
+func WithFS(fsys FS) Option {
+	return func(m *Manager) { m.fs = fsys }
 }
 
 type Workspace struct {
-	Name string
-	Path string
+	Name        string // workspace id (directory name)
+	Path        string
+	DisplayName string // name given at creation, from metadata
+}
+
+// FileChange describes a single file that differs between two commits.
+type FileChange struct {
+	Path   string
+	Action string // "added" | "modified" | "deleted"
+	Patch  string // unified diff for this file (empty for binary files)
 }
 
 // NewManager creates a new Workspace Manager.
-// It ensures the root directory for workspaces exists.
-func NewManager(rootPath string) (*Manager, error) {
+// It ensures the root directory for workspaces exists and removes temp files
+// orphaned by interrupted writes.
+func NewManager(rootPath string, opts ...Option) (*Manager, error) {
 	if rootPath == "" {
 		return nil, fmt.Errorf("workspaces root path cannot be empty")
 	}
-	// Ensure the root directory exists
-	if err := os.MkdirAll(rootPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create workspaces root directory: %w", err)
-	}
 	absRoot, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path for workspaces root: %w", err)
 	}
-	return &Manager{rootPath: absRoot}, nil
+	m := &Manager{rootPath: absRoot, fs: OSFS{}, protected: DefaultProtectedPaths()}
+	for _, opt := range opts {
+		opt(m)
+	}
+	// Ensure the root directory exists
+	if err := m.fs.MkdirAll(absRoot, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workspaces root directory: %w", err)
+	}
+	if m.tempDir != "" {
+		if m.tempDir, err = filepath.Abs(m.tempDir); err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for temp dir: %w", err)
+		}
+		if err := m.fs.MkdirAll(m.tempDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		if m.OnDisk() {
+			if err := checkSameDevice(m.tempDir, m.rootPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.templatesDir != "" {
+		if m.templatesDir, err = filepath.Abs(m.templatesDir); err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for templates dir: %w", err)
+		}
+	}
+	m.cleanupOrphanedTempFiles()
+	return m, nil
 }
 
 // RootPath returns the absolute root path for all workspaces.
@@ -46,38 +128,67 @@ func (m *Manager) RootPath() string {
 	return m.rootPath
 }
 
+// FS returns the filesystem workspaces are kept on.
+func (m *Manager) FS() FS {
+	return m.fs
+}
+
+// OnDisk reports whether workspaces live on the OS filesystem and so have git
+// repositories.
+func (m *Manager) OnDisk() bool {
+	_, ok := m.fs.(OSFS)
+	return ok
+}
+
+// openRepo opens the git repository of a workspace, failing with ErrNoHistory
+// when workspaces are not on disk.
+func (m *Manager) openRepo(workspaceID string) (*git.Repository, error) {
+	if !m.OnDisk() {
+		return nil, ErrNoHistory
+	}
+	return git.PlainOpen(filepath.Join(m.rootPath, workspaceID))
+}
+
+// isWorkspace reports whether the directory id under the root is a workspace:
+// a git repository on disk, or a directory with a .git folder on other
+// filesystems.
+func (m *Manager) isWorkspace(id string) bool {
+	if m.OnDisk() {
+		_, err := git.PlainOpen(filepath.Join(m.rootPath, id))
+		return err == nil
+	}
+	info, err := m.fs.Stat(filepath.Join(m.rootPath, id, ".git"))
+	return err == nil && info.IsDir()
+}
+
 // Create initializes a new workspace.
-// It generates a slug, creates a directory, and initializes a git repository.
+// It generates an id using the configured slug strategy (suffixed with -2, -3,
+// ... when taken), creates a directory, initializes a git repository and
+// records the display name in metadata.
 func (m *Manager) Create(name string) (string, string, error) {
-	slug := GenerateSlug(name)
-	workspacePath := filepath.Join(m.rootPath, slug)
-
-	// Ensure uniqueness by appending a short hash if the directory already exists.
-	// This is a simple approach; more robust strategies could be used in a real app.
-	if _, err := os.Stat(workspacePath); !os.IsNotExist(err) {
-		slog.Warn("Workspace with this slug already exists, generating a unique name", "slug", slug)
-		// Simple disambiguation using a timestamp hash.
-		hash := time.Now().Format("20060102150405")
-		slug = fmt.Sprintf("%s-%s", slug, hash)
-		workspacePath = filepath.Join(m.rootPath, slug)
+	now := time.Now()
+	slug, workspacePath, err := m.claimNewWorkspaceDir(name, now)
+	if err != nil {
+		return "", "", err
 	}
 
-	// Create the workspace directory
-	if err := os.MkdirAll(workspacePath, 0755); err != nil {
+	// Initialize a new git repository; elsewhere only the .git directory
+	// holding metadata and temp files is created
+	if m.OnDisk() {
+		if _, err := git.PlainInit(workspacePath, false); err != nil {
+			return "", "", fmt.Errorf("failed to initialize git repository: %w", err)
+		}
+	} else if err := m.fs.Mkdir(filepath.Join(workspacePath, ".git"), 0755); err != nil {
 		return "", "", fmt.Errorf("failed to create workspace directory: %w", err)
 	}
 
-	// Initialize a new git repository
-	_, err := git.PlainInit(workspacePath, false)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to initialize git repository: %w", err)
+	if err := m.writeMetadata(slug, Metadata{Name: name, CreatedAt: now.UTC()}); err != nil {
+		slog.Warn("Failed to write workspace metadata", "workspaceId", slug, "error", err)
 	}
 
 	// Create a .gitkeep file to allow for an initial commit
 	gitkeepPath := filepath.Join(workspacePath, ".gitkeep")
-	if f, err := os.Create(gitkeepPath); err == nil {
-		f.Close()
-	}
+	_ = m.fs.WriteFile(gitkeepPath, nil, 0644)
 
 	slog.Info("Successfully created and initialized workspace", "id", slug, "path", workspacePath)
 
@@ -90,11 +201,149 @@ func (m *Manager) Create(name string) (string, string, error) {
 	return slug, workspacePath, nil
 }
 
+// claimNewWorkspaceDir generates the id for a workspace named name with the
+// configured slug strategy and claims its directory (see claimWorkspaceDir).
+func (m *Manager) claimNewWorkspaceDir(name string, now time.Time) (string, string, error) {
+	slug := m.slugs.generateID(name, now)
+	if slug == "" || slug == "." || slug == ".." || strings.ContainsAny(slug, `/\`) {
+		return "", "", fmt.Errorf("invalid workspace id %q generated for name %q", slug, name)
+	}
+	return m.claimWorkspaceDir(slug)
+}
+
+// maxSlugSuffix bounds the "-N" suffixes tried by claimWorkspaceDir.
+const maxSlugSuffix = 1000
+
+// claimWorkspaceDir creates the directory for a new workspace with id slug,
+// or, when that is taken, slug-2, slug-3, ... (shortening slug to keep ids
+// within maxSlugLength). Each candidate is claimed with a single Mkdir, so
+// concurrent creates of the same name never share a directory.
+func (m *Manager) claimWorkspaceDir(slug string) (string, string, error) {
+	for n := 1; n <= maxSlugSuffix; n++ {
+		id := slug
+		if n > 1 {
+			suffix := fmt.Sprintf("-%d", n)
+			base := slug
+			if len(base)+len(suffix) > maxSlugLength {
+				base = strings.TrimRight(base[:maxSlugLength-len(suffix)], "-")
+			}
+			id = base + suffix
+		}
+		p := filepath.Join(m.rootPath, id)
+		err := m.fs.Mkdir(p, 0755)
+		if err == nil {
+			if n > 1 {
+				slog.Info("Workspace id taken, using a suffixed id", "slug", slug, "id", id)
+			}
+			return id, p, nil
+		}
+		if !os.IsExist(err) {
+			return "", "", fmt.Errorf("failed to create workspace directory: %w", err)
+		}
+	}
+	return "", "", fmt.Errorf("%w: '%s' and its first %d suffixed ids", ErrWorkspaceExists, slug, maxSlugSuffix)
+}
+
+// Remove deletes a workspace completely: its directory, with its history and
+// metadata, and the Manager's cached usage and lock for it. It waits for
+// writes in progress (see LockExclusive). Callers use it to undo a workspace
+// they just created and failed to fill.
+func (m *Manager) Remove(workspaceID string) error {
+	if workspaceID == "" || workspaceID == "." || workspaceID == ".." || filepath.Base(workspaceID) != workspaceID {
+		return fmt.Errorf("invalid workspace id %q", workspaceID)
+	}
+	unlock, err := m.LockExclusive(workspaceID)
+	if err != nil {
+		return err
+	}
+	err = m.fs.RemoveAll(filepath.Join(m.rootPath, workspaceID))
+	unlock()
+	m.InvalidateUsage(workspaceID)
+	m.locksMu.Lock()
+	delete(m.locks, workspaceID)
+	m.locksMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace directory: %w", err)
+	}
+	slog.Info("Removed workspace", "workspaceId", workspaceID)
+	return nil
+}
+
+// Rename records newName as a workspace's display name and moves it to the id
+// the slug strategy derives from newName (see WithSlugStrategy), keeping its
+// files and git history: uuid ids never change and slug-date ids keep their
+// date. It returns the new id, which equals oldID when the id is unchanged.
+// The target directory is claimed with a single Mkdir and the workspace's
+// entries are moved into it, so concurrent creates and renames never share it. Callers hold
+// LockExclusive(oldID), so writers waiting for it fail rather than recreate
+// the old directory.
+func (m *Manager) Rename(oldID, newName string) (string, error) {
+	oldPath := filepath.Join(m.rootPath, oldID)
+	if oldID == "" || oldID == "." || oldID == ".." || strings.ContainsAny(oldID, `/\`) {
+		return "", fmt.Errorf("workspace '%s' not found", oldID)
+	}
+	if !m.isWorkspace(oldID) {
+		return "", fmt.Errorf("workspace '%s' not found", oldID)
+	}
+	md, err := m.Metadata(oldID)
+	if err != nil {
+		return "", err
+	}
+	// Unlike Create, a rename does not fall back to DefaultSlug: a name without
+	// usable characters is more likely a mistake than a request for that id
+	newID := m.slugs.renameID(oldID, newName, md.CreatedAt)
+	if newID == "" {
+		return "", fmt.Errorf("name %q has no characters usable in a workspace id", newName)
+	}
+	if newID != oldID {
+		newPath := filepath.Join(m.rootPath, newID)
+		if err := m.fs.Mkdir(newPath, 0755); err != nil {
+			if os.IsExist(err) {
+				return "", fmt.Errorf("%w: '%s'", ErrWorkspaceExists, newID)
+			}
+			return "", fmt.Errorf("failed to create workspace directory: %w", err)
+		}
+		if err := m.moveWorkspaceEntries(oldPath, newPath); err != nil {
+			_ = m.fs.RemoveAll(newPath)
+			return "", fmt.Errorf("failed to rename workspace directory: %w", err)
+		}
+		if err := m.fs.RemoveAll(oldPath); err != nil {
+			slog.Warn("Failed to remove renamed workspace directory", "workspaceId", oldID, "error", err)
+		}
+	}
+	md.Name = newName
+	if err := m.writeMetadata(newID, md); err != nil {
+		slog.Warn("Failed to write workspace metadata", "workspaceId", newID, "error", err)
+	}
+	m.InvalidateUsage(oldID)
+	slog.Info("Renamed workspace", "from", oldID, "to", newID)
+	return newID, nil
+}
+
+// moveWorkspaceEntries moves every entry of the directory from into the empty
+// directory to, which Rename has claimed (a directory cannot be renamed over
+// another). On failure the entries already moved are moved back.
+func (m *Manager) moveWorkspaceEntries(from, to string) error {
+	entries, err := m.fs.ReadDir(from)
+	if err != nil {
+		return err
+	}
+	for i, e := range entries {
+		if err := m.fs.Rename(filepath.Join(from, e.Name()), filepath.Join(to, e.Name())); err != nil {
+			for _, moved := range entries[:i] {
+				_ = m.fs.Rename(filepath.Join(to, moved.Name()), filepath.Join(from, moved.Name()))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 // SafePath resolves a relative path from within a workspace and ensures it does not escape the workspace root.
 // It returns the absolute, cleaned path.
 func (m *Manager) SafePath(workspaceID, relativePath string) (string, error) {
 	workspaceRoot := filepath.Join(m.rootPath, workspaceID)
-	if _, err := os.Stat(workspaceRoot); os.IsNotExist(err) {
+	if _, err := m.fs.Stat(workspaceRoot); os.IsNotExist(err) {
 		return "", fmt.Errorf("workspace '%s' not found", workspaceID)
 	}
 
@@ -120,15 +369,31 @@ func (m *Manager) SafePath(workspaceID, relativePath string) (string, error) {
 	return absPath, nil
 }
 
-// GetCommitHistory returns the commit log for a workspace.
-func (m *Manager) GetCommitHistory(workspaceID string, limit int) ([]object.Commit, error) {
-	workspacePath := filepath.Join(m.rootPath, workspaceID)
-	repo, err := git.PlainOpen(workspacePath)
+// TimeWindow restricts a history to commits authored between Since and Until,
+// both inclusive. A zero bound leaves that side open.
+type TimeWindow struct {
+	Since, Until time.Time
+}
+
+// Contains reports whether t falls inside the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	return (w.Since.IsZero() || !t.Before(w.Since)) && (w.Until.IsZero() || !t.After(w.Until))
+}
+
+// GetCommitHistory returns the commit log for a workspace, keeping only commits
+// whose author time is inside window.
+// If before is a commit hash, the log starts at that commit's parents (the commit
+// itself is excluded), which lets callers page through history.
+func (m *Manager) GetCommitHistory(workspaceID string, limit int, before string, window TimeWindow) ([]object.Commit, error) {
+	if !m.OnDisk() {
+		return nil, nil
+	}
+	repo, err := m.openRepo(workspaceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open git repository: %w", err)
 	}
 
-	cIter, err := repo.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
+	cIter, err := logBefore(repo, before)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commit iterator: %w", err)
 	}
@@ -146,20 +411,25 @@ func (m *Manager) GetCommitHistory(workspaceID string, limit int) ([]object.Comm
 			}
 			return nil, err
 		}
-		commits = append(commits, *commit)
+		if window.Contains(commit.Author.When) {
+			commits = append(commits, *commit)
+		}
 	}
 	return commits, nil
 }
 
 // GetFileCommitHistory returns commits that modified the specified file path within a workspace.
-func (m *Manager) GetFileCommitHistory(workspaceID, relPath string, limit int) ([]object.Commit, error) {
-	workspacePath := filepath.Join(m.rootPath, workspaceID)
-	repo, err := git.PlainOpen(workspacePath)
+// before and window behave as in GetCommitHistory.
+func (m *Manager) GetFileCommitHistory(workspaceID, relPath string, limit int, before string, window TimeWindow) ([]object.Commit, error) {
+	if !m.OnDisk() {
+		return nil, nil
+	}
+	repo, err := m.openRepo(workspaceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open git repository: %w", err)
 	}
 
-	cIter, err := repo.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
+	cIter, err := logBefore(repo, before)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commit iterator: %w", err)
 	}
@@ -177,6 +447,9 @@ func (m *Manager) GetFileCommitHistory(workspaceID, relPath string, limit int) (
 			}
 			return nil, err
 		}
+		if !window.Contains(commit.Author.When) {
+			continue
+		}
 
 		// Compare with first parent if any; if none (root), include if file exists in this commit.
 		if parent, err := commit.Parents().Next(); err == nil && parent != nil {
@@ -215,10 +488,30 @@ func (m *Manager) GetFileCommitHistory(workspaceID, relPath string, limit int) (
 	return commits, nil
 }
 
+// logBefore returns a log iterator in committer-time order. With an empty before it
+// starts at HEAD; otherwise it starts at the given commit and skips that commit.
+func logBefore(repo *git.Repository, before string) (object.CommitIter, error) {
+	if before == "" {
+		return repo.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
+	}
+	from := plumbing.NewHash(before)
+	if _, err := repo.CommitObject(from); err != nil {
+		return nil, fmt.Errorf("failed to resolve commit %q: %w", before, err)
+	}
+	cIter, err := repo.Log(&git.LogOptions{From: from, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cIter.Next(); err != nil && err != io.EOF {
+		cIter.Close()
+		return nil, err
+	}
+	return cIter, nil
+}
+
 // ReadFileAtCommit returns the file content at a given commit hash.
 func (m *Manager) ReadFileAtCommit(workspaceID, relPath, commitHash string) (string, error) {
-	workspacePath := filepath.Join(m.rootPath, workspaceID)
-	repo, err := git.PlainOpen(workspacePath)
+	repo, err := m.openRepo(workspaceID)
 	if err != nil {
 		return "", fmt.Errorf("failed to open git repository: %w", err)
 	}
@@ -247,11 +540,27 @@ func (m *Manager) ReadFileAtCommit(workspaceID, relPath, commitHash string) (str
 	return string(b), nil
 }
 
+// DefaultAuthorEmail is the commit author email used when none is given.
+const DefaultAuthorEmail = "mcp-server@localhost"
+
 // Commit creates a new commit in the specified workspace's git repository.
 // It stages all changes before committing and returns the commit hash.
+// Callers that modify the working tree should hold Lock across the
+// modification and the commit.
 func (m *Manager) Commit(workspaceID, message, authorName string) (string, error) {
-	workspacePath := filepath.Join(m.rootPath, workspaceID)
-	repo, err := git.PlainOpen(workspacePath)
+	return m.CommitAs(workspaceID, message, authorName, "")
+}
+
+// CommitAs is Commit with an explicit author email; an empty email falls back
+// to DefaultAuthorEmail.
+func (m *Manager) CommitAs(workspaceID, message, name, email string) (string, error) {
+	if !m.OnDisk() {
+		return "", nil
+	}
+	if email == "" {
+		email = DefaultAuthorEmail
+	}
+	repo, err := m.openRepo(workspaceID)
 	if err != nil {
 		return "", fmt.Errorf("failed to open git repository: %w", err)
 	}
@@ -269,8 +578,8 @@ func (m *Manager) Commit(workspaceID, message, authorName string) (string, error
 	// Commit the changes
 	commitHash, err := worktree.Commit(message, &git.CommitOptions{
 		Author: &object.Signature{
-			Name:  authorName,
-			Email: "mcp-server@localhost", // Placeholder email
+			Name:  name,
+			Email: email,
 			When:  time.Now(),
 		},
 	})
@@ -284,7 +593,7 @@ func (m *Manager) Commit(workspaceID, message, authorName string) (string, error
 
 // List returns a slice of all workspaces.
 func (m *Manager) List() ([]Workspace, error) {
-	entries, err := os.ReadDir(m.rootPath)
+	entries, err := m.fs.ReadDir(m.rootPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read workspaces root directory: %w", err)
 	}
@@ -292,13 +601,16 @@ func (m *Manager) List() ([]Workspace, error) {
 	var workspaces []Workspace
 	for _, entry := range entries {
 		if entry.IsDir() {
-			// Basic check to see if it's a git repository
-			_, err := git.PlainOpen(filepath.Join(m.rootPath, entry.Name()))
-			if err == nil {
-				workspaces = append(workspaces, Workspace{
-					Name: entry.Name(),
-					Path: filepath.Join(m.rootPath, entry.Name()),
-				})
+			if m.isWorkspace(entry.Name()) {
+				ws := Workspace{
+					Name:        entry.Name(),
+					Path:        filepath.Join(m.rootPath, entry.Name()),
+					DisplayName: entry.Name(),
+				}
+				if md, err := m.Metadata(entry.Name()); err == nil && md.Name != "" {
+					ws.DisplayName = md.Name
+				}
+				workspaces = append(workspaces, ws)
 			}
 		}
 	}
@@ -308,8 +620,10 @@ func (m *Manager) List() ([]Workspace, error) {
 // HeadCommit returns the current HEAD commit hash for the workspace repository.
 // If the repository has no commits yet, it returns an empty string without error.
 func (m *Manager) HeadCommit(workspaceID string) (string, error) {
-	workspacePath := filepath.Join(m.rootPath, workspaceID)
-	repo, err := git.PlainOpen(workspacePath)
+	if !m.OnDisk() {
+		return "", nil
+	}
+	repo, err := m.openRepo(workspaceID)
 	if err != nil {
 		return "", err
 	}
@@ -320,3 +634,280 @@ func (m *Manager) HeadCommit(workspaceID string) (string, error) {
 	}
 	return ref.Hash().String(), nil
 }
+
+// HeadCommitObject returns the commit HEAD points at, or nil (without error)
+// if the repository has no commits yet.
+func (m *Manager) HeadCommitObject(workspaceID string) (*object.Commit, error) {
+	if !m.OnDisk() {
+		return nil, nil
+	}
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, nil
+	}
+	return repo.CommitObject(ref.Hash())
+}
+
+// RepoInfo summarises a workspace repository.
+type RepoInfo struct {
+	Branch  string // short name of the branch HEAD points at; empty when detached
+	Head    string // HEAD commit hash; empty when there are no commits
+	Commits int    // commits reachable from HEAD
+}
+
+// RepoInfo reports the current branch, HEAD and number of commits. A
+// repository without commits yields a zero Head and Commits without error.
+func (m *Manager) RepoInfo(workspaceID string) (RepoInfo, error) {
+	if !m.OnDisk() {
+		return RepoInfo{}, nil
+	}
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return RepoInfo{}, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	var info RepoInfo
+	if ref, err := repo.Reference(plumbing.HEAD, false); err == nil && ref.Type() == plumbing.SymbolicReference {
+		info.Branch = ref.Target().Short()
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return info, nil
+	}
+	info.Head = head.Hash().String()
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return RepoInfo{}, fmt.Errorf("failed to read commit log: %w", err)
+	}
+	defer iter.Close()
+	if err := iter.ForEach(func(*object.Commit) error {
+		info.Commits++
+		return nil
+	}); err != nil {
+		return RepoInfo{}, fmt.Errorf("failed to count commits: %w", err)
+	}
+	return info, nil
+}
+
+// ResolveRef resolves a snapshot name (tag, branch, or full/abbreviated commit hash)
+// to the full hash of the commit it points at. Annotated tags are peeled.
+func (m *Manager) ResolveRef(workspaceID, ref string) (string, error) {
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to open git repository: %w", err)
+	}
+	h, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve ref %q: %w", ref, err)
+	}
+	return h.String(), nil
+}
+
+// DiffCommits returns the files changed between two commits, with a unified patch per file.
+// Revisions may be full or abbreviated hashes (or any revision go-git can resolve).
+// If toHash is empty, HEAD is used.
+func (m *Manager) DiffCommits(workspaceID, fromHash, toHash string) ([]FileChange, error) {
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	if toHash == "" {
+		toHash = "HEAD"
+	}
+	fromCommit, err := resolveCommit(repo, fromHash)
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := resolveCommit(repo, toHash)
+	if err != nil {
+		return nil, err
+	}
+	fromTree, err := fromCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	toTree, err := toCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	patch, err := fromTree.Patch(toTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute patch: %w", err)
+	}
+
+	var changes []FileChange
+	for _, fp := range patch.FilePatches() {
+		from, to := fp.Files()
+		change := FileChange{Action: "modified"}
+		switch {
+		case from == nil:
+			change.Action = "added"
+			change.Path = to.Path()
+		case to == nil:
+			change.Action = "deleted"
+			change.Path = from.Path()
+		default:
+			change.Path = to.Path()
+		}
+		var sb strings.Builder
+		if err := diff.NewUnifiedEncoder(&sb, diff.DefaultContextLines).Encode(singleFilePatch{fp}); err == nil {
+			change.Patch = sb.String()
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// singleFilePatch adapts one FilePatch to the diff.Patch interface for encoding.
+type singleFilePatch struct{ fp diff.FilePatch }
+
+func (p singleFilePatch) FilePatches() []diff.FilePatch { return []diff.FilePatch{p.fp} }
+func (p singleFilePatch) Message() string               { return "" }
+
+// resolveCommit resolves a revision (hash, abbreviated hash, HEAD, tag...) to a
+// commit. Only a missing reference or object is reported as ErrCommitNotFound;
+// other failures (e.g. a corrupt repository) are returned as they are.
+func resolveCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	h, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err == nil {
+		var c *object.Commit
+		if c, err = repo.CommitObject(*h); err == nil {
+			return c, nil
+		}
+	}
+	if errors.Is(err, plumbing.ErrReferenceNotFound) || errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, fmt.Errorf("%w: %q", ErrCommitNotFound, rev)
+	}
+	return nil, fmt.Errorf("failed to resolve %q: %w", rev, err)
+}
+
+// RevertToCommit restores the working tree to the tree of a prior commit (leaving .git intact)
+// and records the restoration as a new commit. Paths are compared against the working tree,
+// so uncommitted edits and untracked files (but not ignored ones) are reverted as well. It
+// returns the new HEAD hash and the files that changed on disk. If HEAD already has the
+// commit's tree, no commit is made and HEAD is returned unchanged.
+func (m *Manager) RevertToCommit(workspaceID, commitHash string) (string, []FileChange, error) {
+	workspacePath := filepath.Join(m.rootPath, workspaceID)
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	target, err := resolveCommit(repo, commitHash)
+	if err != nil {
+		return "", nil, err
+	}
+	head, err := resolveCommit(repo, "HEAD")
+	if err != nil {
+		return "", nil, err
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	targetTree, err := target.Tree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+
+	// Candidates are the paths that differ between HEAD and the target, plus
+	// those that differ between HEAD and the working tree.
+	paths := map[string]bool{}
+	treeChanges, err := headTree.Diff(targetTree)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to diff trees: %w", err)
+	}
+	for _, tc := range treeChanges {
+		paths[tc.From.Name] = true
+		paths[tc.To.Name] = true
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read worktree status: %w", err)
+	}
+	for p, st := range status {
+		if st.Worktree != git.Unmodified || st.Staging != git.Unmodified {
+			paths[p] = true
+		}
+	}
+	delete(paths, "")
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	var changes []FileChange
+	for _, rel := range sorted {
+		abs := filepath.Join(workspacePath, filepath.FromSlash(rel))
+		current, readErr := os.ReadFile(abs)
+		if readErr != nil && !os.IsNotExist(readErr) {
+			return "", nil, fmt.Errorf("failed to read %s: %w", rel, readErr)
+		}
+		want, err := targetTree.File(rel)
+		if errors.Is(err, object.ErrFileNotFound) {
+			if os.IsNotExist(readErr) {
+				continue
+			}
+			if err := os.Remove(abs); err != nil && !os.IsNotExist(err) {
+				return "", nil, fmt.Errorf("failed to remove %s: %w", rel, err)
+			}
+			m.InvalidateUsage(workspaceID)
+			removeEmptyParents(filepath.Dir(abs), workspacePath)
+			changes = append(changes, FileChange{Path: rel, Action: "deleted"})
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s at commit: %w", rel, err)
+		}
+		content, err := want.Contents()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %s at commit: %w", rel, err)
+		}
+		if readErr == nil && string(current) == content {
+			continue
+		}
+		mode, err := want.Mode.ToOSFileMode()
+		if err != nil {
+			mode = 0644
+		}
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return "", nil, fmt.Errorf("failed to create parent directories: %w", err)
+		}
+		if err := m.WriteFileAtomic(workspaceID, abs, []byte(content), mode.Perm()); err != nil {
+			return "", nil, fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		_ = os.Chmod(abs, mode.Perm())
+		action := "modified"
+		if os.IsNotExist(readErr) {
+			action = "added"
+		}
+		changes = append(changes, FileChange{Path: rel, Action: action})
+	}
+	if head.TreeHash == target.TreeHash {
+		// Only uncommitted changes were undone; HEAD already records the tree
+		return head.Hash.String(), changes, nil
+	}
+
+	commit, err := m.Commit(workspaceID, fmt.Sprintf("mcp/workspace_revert: Revert to %s", target.Hash.String()), "mcp-client")
+	if err != nil {
+		return "", nil, err
+	}
+	return commit, changes, nil
+}
+
+// removeEmptyParents removes now-empty directories from dir upwards, stopping at stop.
+func removeEmptyParents(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
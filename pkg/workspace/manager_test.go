@@ -0,0 +1,238 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var validIDRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// forEachBackend runs test once with workspaces on disk and once in memory;
+// newManager creates a Manager on a fresh root of that backend.
+func forEachBackend(t *testing.T, test func(t *testing.T, newManager func(opts ...Option) *Manager)) {
+	backends := []struct {
+		name string
+		fs   func() FS
+	}{
+		{"os", func() FS { return OSFS{} }},
+		{"memory", func() FS { return NewMemFS() }},
+	}
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, func(opts ...Option) *Manager {
+				m, err := NewManager(t.TempDir(), append(opts, WithFS(b.fs()))...)
+				require.NoError(t, err)
+				return m
+			})
+		})
+	}
+}
+
+func TestCreate_SlugStrategies(t *testing.T) {
+	today := time.Now().Format("20060102")
+	cases := []struct {
+		strategy SlugStrategy
+		check    func(t *testing.T, id string)
+	}{
+		{SlugStrategySlug, func(t *testing.T, id string) {
+			assert.True(t, strings.HasPrefix(id, "my-project"), id)
+		}},
+		{SlugStrategySlugDate, func(t *testing.T, id string) {
+			assert.True(t, strings.HasPrefix(id, today+"-my-project"), id)
+		}},
+		{SlugStrategyUUID, func(t *testing.T, id string) {
+			assert.Len(t, id, 36)
+			assert.NotContains(t, id, "my-project")
+		}},
+	}
+	forEachBackend(t, func(t *testing.T, newManager func(opts ...Option) *Manager) {
+		for _, tc := range cases {
+			t.Run(string(tc.strategy), func(t *testing.T) {
+				m := newManager(WithSlugStrategy(tc.strategy))
+
+				id1, _, err := m.Create("My Project")
+				require.NoError(t, err)
+				id2, _, err := m.Create("My Project")
+				require.NoError(t, err)
+
+				for _, id := range []string{id1, id2} {
+					assert.Regexp(t, validIDRegex, id)
+					tc.check(t, id)
+					md, err := m.Metadata(id)
+					require.NoError(t, err)
+					assert.Equal(t, "My Project", md.Name)
+					assert.False(t, md.CreatedAt.IsZero())
+				}
+				assert.NotEqual(t, id1, id2)
+
+				list, err := m.List()
+				require.NoError(t, err)
+				require.Len(t, list, 2)
+				for _, ws := range list {
+					assert.Equal(t, "My Project", ws.DisplayName)
+				}
+			})
+		}
+	})
+}
+
+func TestRename_SlugStrategies(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, newManager func(opts ...Option) *Manager) {
+		for strategy, want := range map[SlugStrategy]func(oldID string) string{
+			SlugStrategySlug:     func(string) string { return "renamed-project" },
+			SlugStrategySlugDate: func(oldID string) string { return oldID[:9] + "renamed-project" },
+			SlugStrategyUUID:     func(oldID string) string { return oldID },
+		} {
+			t.Run(string(strategy), func(t *testing.T) {
+				m := newManager(WithSlugStrategy(strategy))
+				oldID, _, err := m.Create("My Project")
+				require.NoError(t, err)
+
+				newID, err := m.Rename(oldID, "Renamed Project")
+				require.NoError(t, err)
+				assert.Equal(t, want(oldID), newID)
+				md, err := m.Metadata(newID)
+				require.NoError(t, err)
+				assert.Equal(t, "Renamed Project", md.Name)
+				list, err := m.List()
+				require.NoError(t, err)
+				assert.Len(t, list, 1)
+			})
+		}
+	})
+}
+
+func TestRename_TakenIDIsLeftAlone(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, newManager func(opts ...Option) *Manager) {
+		m := newManager()
+		id, _, err := m.Create("Alpha")
+		require.NoError(t, err)
+		taken, _, err := m.Create("Beta")
+		require.NoError(t, err)
+
+		_, err = m.Rename(id, "Beta")
+		assert.ErrorIs(t, err, ErrWorkspaceExists)
+		md, err := m.Metadata(taken)
+		require.NoError(t, err)
+		assert.Equal(t, "Beta", md.Name, "the existing workspace is untouched")
+		md, err = m.Metadata(id)
+		require.NoError(t, err)
+		assert.Equal(t, "Alpha", md.Name)
+	})
+}
+
+func TestRemove(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, newManager func(opts ...Option) *Manager) {
+		m := newManager(WithQuota(1 << 20))
+		id, path, err := m.Create("Doomed")
+		require.NoError(t, err)
+		require.NoError(t, m.WriteFileAtomic(id, filepath.Join(path, "a.txt"), []byte("abc"), 0644))
+		used, err := m.Usage(id)
+		require.NoError(t, err)
+		require.Positive(t, used)
+
+		require.NoError(t, m.Remove(id))
+		_, err = m.SafePath(id, ".")
+		assert.Error(t, err)
+		_, err = m.Lock(id)
+		assert.Error(t, err)
+		assert.Error(t, m.Remove(".."))
+
+		// The id is free again, and nothing of the old workspace carries over
+		again, path, err := m.Create("Doomed")
+		require.NoError(t, err)
+		assert.Equal(t, id, again)
+		_, err = m.FS().Stat(filepath.Join(path, "a.txt"))
+		assert.True(t, os.IsNotExist(err))
+		used, err = m.Usage(again)
+		require.NoError(t, err)
+		assert.Zero(t, used)
+	})
+}
+
+func TestParseSlugStrategy(t *testing.T) {
+	st, err := ParseSlugStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, SlugStrategySlug, st)
+
+	st, err = ParseSlugStrategy("UUID")
+	require.NoError(t, err)
+	assert.Equal(t, SlugStrategyUUID, st)
+
+	_, err = ParseSlugStrategy("hash")
+	assert.Error(t, err)
+}
+
+func TestCreate_DisambiguatesCollidingSlugs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, newManager func(opts ...Option) *Manager) {
+		m := newManager()
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, _, err := m.Create("Same Name")
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+		assert.Equal(t, []string{"same-name", "same-name-2", "same-name-3"}, ids)
+
+		// Concurrent creates each claim their own directory
+		var wg sync.WaitGroup
+		got := make([]string, 3)
+		for i := range got {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				id, _, err := m.Create("Racing")
+				assert.NoError(t, err)
+				got[i] = id
+			}(i)
+		}
+		wg.Wait()
+		assert.ElementsMatch(t, []string{"racing", "racing-2", "racing-3"}, got)
+
+		list, err := m.List()
+		require.NoError(t, err)
+		assert.Len(t, list, 6)
+
+		// Suffixed ids stay within the length limit
+		long := strings.Repeat("a", maxSlugLength)
+		_, _, err = m.Create(long)
+		require.NoError(t, err)
+		id, _, err := m.Create(long)
+		require.NoError(t, err)
+		assert.Len(t, id, maxSlugLength)
+		assert.True(t, strings.HasSuffix(id, "-2"), id)
+	})
+}
+
+func TestDiffCommits_MissingAndBrokenCommits(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, path, err := m.Create("Diffs")
+	require.NoError(t, err)
+	require.NoError(t, m.WriteFileAtomic(id, filepath.Join(path, "a.txt"), []byte("a\n"), 0644))
+	head, err := m.Commit(id, "add a.txt", "test")
+	require.NoError(t, err)
+
+	for _, rev := range []string{"deadbeef", "0123456789abcdef0123456789abcdef01234567", "no-such-tag"} {
+		_, err = m.DiffCommits(id, rev, "HEAD")
+		assert.ErrorIs(t, err, ErrCommitNotFound, rev)
+	}
+
+	// A commit whose tree cannot be read is a broken repository, not a missing commit
+	c, err := m.HeadCommitObject(id)
+	require.NoError(t, err)
+	tree := c.TreeHash.String()
+	require.NoError(t, os.Remove(filepath.Join(path, ".git", "objects", tree[:2], tree[2:])))
+	_, err = m.DiffCommits(id, head, "HEAD")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCommitNotFound)
+}
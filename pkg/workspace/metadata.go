@@ -0,0 +1,49 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// metadataFile holds per-workspace metadata. It lives inside .git so it is
+// never committed or shown in listings.
+const metadataFile = ".git/mcp-workspace.json"
+
+// Metadata is descriptive information about a workspace that is not derivable
+// from its id (which may be a slug, date-prefixed slug or UUID).
+type Metadata struct {
+	Name      string    `json:"name"`      // display name given at creation
+	CreatedAt time.Time `json:"createdAt"` // creation time (UTC)
+}
+
+func (m *Manager) metadataPath(workspaceID string) string {
+	return filepath.Join(m.rootPath, workspaceID, filepath.FromSlash(metadataFile))
+}
+
+// Metadata returns the stored metadata for a workspace. Workspaces created before
+// metadata was recorded fall back to their id as the name.
+func (m *Manager) Metadata(workspaceID string) (Metadata, error) {
+	data, err := m.fs.ReadFile(m.metadataPath(workspaceID))
+	if os.IsNotExist(err) {
+		return Metadata{Name: workspaceID}, nil
+	}
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to read workspace metadata: %w", err)
+	}
+	var md Metadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return Metadata{}, fmt.Errorf("failed to parse workspace metadata: %w", err)
+	}
+	return md, nil
+}
+
+func (m *Manager) writeMetadata(workspaceID string, md Metadata) error {
+	data, err := json.MarshalIndent(md, "", "  ")
+	if err != nil {
+		return err
+	}
+	return m.WriteFileAtomic(workspaceID, m.metadataPath(workspaceID), data, 0644)
+}
@@ -0,0 +1,152 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ProtectedPaths decides which workspace paths the tools refuse to read or
+// modify and leave out of listings and events. .git, .gitkeep and TrashDir
+// are always protected; deployments can add names, matched against every path
+// segment, and globs (see IsProtectedPath), e.g. to hide credentials.
+type ProtectedPaths struct {
+	names map[string]bool
+	globs []string // slash-separated path.Match patterns
+}
+
+// builtinProtectedNames are protected whatever the configuration.
+var builtinProtectedNames = []string{".git", ".gitkeep", TrashDir}
+
+// DefaultProtectedPaths protects only the built-in names.
+func DefaultProtectedPaths() *ProtectedPaths {
+	p, _ := NewProtectedPaths(nil, nil)
+	return p
+}
+
+// WithProtectedPaths replaces the default protected set (DefaultProtectedPaths).
+func WithProtectedPaths(p *ProtectedPaths) Option {
+	return func(m *Manager) {
+		if p != nil {
+			m.protected = p
+		}
+	}
+}
+
+// Protected returns the paths tools must refuse and hide in this Manager's
+// workspaces.
+func (m *Manager) Protected() *ProtectedPaths {
+	return m.protected
+}
+
+// NewProtectedPaths protects the built-in names plus names and globs. Names
+// must be single path segments; globs use path.Match syntax and are rejected
+// if malformed.
+func NewProtectedPaths(names, globs []string) (*ProtectedPaths, error) {
+	p := &ProtectedPaths{names: map[string]bool{}}
+	for _, n := range builtinProtectedNames {
+		p.names[n] = true
+	}
+	for _, n := range names {
+		if n == "" || n == "." || n == ".." || strings.ContainsAny(n, `/\`) {
+			return nil, fmt.Errorf("invalid protected name %q: must be a single path segment", n)
+		}
+		p.names[n] = true
+	}
+	for _, g := range globs {
+		g = strings.TrimPrefix(filepath.ToSlash(g), "./")
+		if g == "" || strings.HasPrefix(g, "/") {
+			return nil, fmt.Errorf("invalid protected glob %q: must be a relative pattern", g)
+		}
+		if _, err := path.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("invalid protected glob %q: %w", g, err)
+		}
+		p.globs = append(p.globs, g)
+	}
+	return p, nil
+}
+
+// IsProtectedName reports whether a single directory entry name is protected:
+// it is a protected name or matches a glob without '/'.
+func (p *ProtectedPaths) IsProtectedName(name string) bool {
+	if p.names[name] {
+		return true
+	}
+	for _, g := range p.globs {
+		if strings.Contains(g, "/") {
+			continue
+		}
+		if ok, _ := path.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtectedPath reports whether rel, relative to the workspace root, is
+// protected or lies below something protected: one of its segments is a
+// protected name, or it or one of its parents matches a glob containing '/'
+// (so "secrets/*" covers everything below secrets/).
+func (p *ProtectedPaths) IsProtectedPath(rel string) bool {
+	segs := strings.Split(filepath.ToSlash(filepath.Clean(rel)), "/")
+	for i, seg := range segs {
+		if seg == "" || seg == "." {
+			continue
+		}
+		if p.IsProtectedName(seg) {
+			return true
+		}
+		prefix := strings.Join(segs[:i+1], "/")
+		for _, g := range p.globs {
+			if !strings.Contains(g, "/") {
+				continue
+			}
+			if ok, _ := path.Match(g, prefix); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsProtectedIn is IsProtectedPath for abs, a path inside the workspace
+// directory root, as met while walking a workspace.
+func (p *ProtectedPaths) IsProtectedIn(root, abs string) bool {
+	rel, err := filepath.Rel(root, abs)
+	return err == nil && p.IsProtectedPath(rel)
+}
+
+// ProtectedWithin returns the first path, relative to the workspace directory
+// root, below the directory abs on fsys that a configured name or glob
+// protects, or "" if there is none. Built-in names are skipped: a .gitkeep travels with its
+// directory. Tools moving, copying or deleting a whole directory use it so a
+// parent cannot carry protected entries along.
+func (p *ProtectedPaths) ProtectedWithin(fsys FS, root, abs string) (string, error) {
+	if len(p.names) == len(builtinProtectedNames) && len(p.globs) == 0 {
+		return "", nil
+	}
+	builtin := DefaultProtectedPaths()
+	found := ""
+	err := WalkDir(fsys, abs, func(pth string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if pth == abs {
+			return nil
+		}
+		if builtin.IsProtectedName(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if p.IsProtectedIn(root, pth) {
+			found, _ = filepath.Rel(root, pth)
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return filepath.ToSlash(found), err
+}
@@ -0,0 +1,63 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtectedPaths_NamesAndGlobs(t *testing.T) {
+	p, err := NewProtectedPaths([]string{".github"}, []string{"*.env", "secrets/*"})
+	require.NoError(t, err)
+
+	for _, rel := range []string{
+		".git", ".git/config", "sub/.gitkeep", TrashDir + "/x", // built-in
+		".github/workflows/ci.yml",       // custom name in any segment
+		"prod.env", "config/staging.env", // name glob in any segment
+		"secrets/key.pem", "secrets/dir/x", // path glob and everything below it
+	} {
+		assert.True(t, p.IsProtectedPath(rel), rel)
+	}
+	for _, rel := range []string{".", "README.md", "env", "config/app.yaml", "secrets", "other/secrets/key.pem"} {
+		assert.False(t, p.IsProtectedPath(rel), rel)
+	}
+	assert.True(t, p.IsProtectedName("local.env"))
+	assert.False(t, p.IsProtectedName("key.pem"), "path globs do not apply to bare names")
+
+	assert.False(t, DefaultProtectedPaths().IsProtectedPath("prod.env"))
+}
+
+func TestNewProtectedPaths_RejectsInvalidEntries(t *testing.T) {
+	_, err := NewProtectedPaths([]string{"a/b"}, nil)
+	assert.Error(t, err)
+	_, err = NewProtectedPaths(nil, []string{"[unclosed"})
+	assert.Error(t, err)
+	_, err = NewProtectedPaths(nil, []string{"/etc/*"})
+	assert.Error(t, err)
+}
+
+func TestProtectedPaths_ProtectedWithin(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"public/a.txt", "public/.gitkeep", "secrets/key.pem", "config/prod.env"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(rel)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, rel), []byte("x"), 0644))
+	}
+	p, err := NewProtectedPaths(nil, []string{"*.env", "secrets/*"})
+	require.NoError(t, err)
+
+	for dir, want := range map[string]string{
+		"public":  "",
+		"secrets": "secrets/key.pem",
+		"config":  "config/prod.env",
+	} {
+		got, err := p.ProtectedWithin(OSFS{}, root, filepath.Join(root, dir))
+		require.NoError(t, err)
+		assert.Equal(t, want, got, dir)
+	}
+	got, err := DefaultProtectedPaths().ProtectedWithin(OSFS{}, root, filepath.Join(root, "secrets"))
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
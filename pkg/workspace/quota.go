@@ -0,0 +1,133 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrQuotaExceeded is returned (wrapped) by WriteFileAtomic, CopyPath, Clone
+// and CreateFromTemplate when the change would take a workspace over the quota
+// set with WithQuota.
+var ErrQuotaExceeded = errors.New("workspace quota exceeded")
+
+// usageTTL bounds how long a cached usage is trusted, so changes made outside
+// the Manager (editors, git commands, deletes) are picked up again.
+const usageTTL = time.Minute
+
+type usageEntry struct {
+	bytes     int64
+	scannedAt time.Time
+}
+
+// WithQuota limits the size of each workspace's files (everything but .git)
+// to bytes; 0, the default, means unlimited.
+func WithQuota(bytes int64) Option {
+	return func(m *Manager) { m.quota = bytes }
+}
+
+// Quota returns the per-workspace quota in bytes, 0 when unlimited.
+func (m *Manager) Quota() int64 {
+	return m.quota
+}
+
+// Usage returns the bytes taken by workspaceID's files, .git excluded. The
+// result is cached and kept up to date by the Manager's own writes.
+func (m *Manager) Usage(workspaceID string) (int64, error) {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	return m.usageLocked(workspaceID)
+}
+
+func (m *Manager) usageLocked(workspaceID string) (int64, error) {
+	if e, ok := m.usage[workspaceID]; ok && time.Since(e.scannedAt) < usageTTL {
+		return e.bytes, nil
+	}
+	n, err := m.treeSize(filepath.Join(m.rootPath, workspaceID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute workspace usage: %w", err)
+	}
+	if m.usage == nil {
+		m.usage = map[string]*usageEntry{}
+	}
+	m.usage[workspaceID] = &usageEntry{bytes: n, scannedAt: time.Now()}
+	return n, nil
+}
+
+// reserve records that workspaceID grows by delta bytes (which may be
+// negative), failing with ErrQuotaExceeded instead when that would take it
+// over the quota. Without a quota it does nothing.
+func (m *Manager) reserve(workspaceID string, delta int64) error {
+	if m.quota <= 0 {
+		return nil
+	}
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	used, err := m.usageLocked(workspaceID)
+	if err != nil {
+		return err
+	}
+	if delta > 0 && used+delta > m.quota {
+		return fmt.Errorf("%w: %d bytes used, %d more would exceed the %d-byte quota", ErrQuotaExceeded, used, delta, m.quota)
+	}
+	m.usage[workspaceID].bytes = max(used+delta, 0)
+	return nil
+}
+
+// checkNewWorkspaceSize fails with ErrQuotaExceeded when a workspace created
+// with size bytes of files would already be over the quota.
+func (m *Manager) checkNewWorkspaceSize(size int64) error {
+	if m.quota > 0 && size > m.quota {
+		return fmt.Errorf("%w: %d bytes would exceed the %d-byte quota", ErrQuotaExceeded, size, m.quota)
+	}
+	return nil
+}
+
+// countsTowardQuota reports whether absPath is part of workspaceID's usage,
+// which excludes .git and its metadata and temp files.
+func (m *Manager) countsTowardQuota(workspaceID, absPath string) bool {
+	gitDir := filepath.Join(m.rootPath, workspaceID, ".git")
+	return absPath != gitDir && !strings.HasPrefix(absPath, gitDir+string(filepath.Separator))
+}
+
+// InvalidateUsage drops the cached usage of workspaceID, so the next quota
+// check rescans it. Callers that remove files without the Manager use it.
+func (m *Manager) InvalidateUsage(workspaceID string) {
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+	delete(m.usage, workspaceID)
+}
+
+// treeSize returns the total size of the regular files below dir, skipping
+// .git directories.
+func (m *Manager) treeSize(dir string) (int64, error) {
+	entries, err := m.fs.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var n int64
+	for _, e := range entries {
+		switch {
+		case e.IsDir() && e.Name() == ".git":
+		case e.IsDir():
+			sub, err := m.treeSize(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return 0, err
+			}
+			n += sub
+		case e.Type().IsRegular():
+			info, err := e.Info()
+			if err != nil {
+				return 0, err
+			}
+			n += info.Size()
+		}
+	}
+	return n, nil
+}
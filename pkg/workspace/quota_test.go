@@ -0,0 +1,87 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic_Quota(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, newManager func(opts ...Option) *Manager) {
+		m := newManager(WithQuota(100))
+		id, _, err := m.Create("Quota")
+		require.NoError(t, err)
+		write := func(rel string, n int) error {
+			abs, err := m.SafePath(id, rel)
+			require.NoError(t, err)
+			return m.WriteFileAtomic(id, abs, []byte(strings.Repeat("x", n)), 0644)
+		}
+		base, err := m.Usage(id)
+		require.NoError(t, err)
+
+		require.NoError(t, write("a.txt", 60))
+		require.NoError(t, write("b.txt", int(100-60-base)), "writing up to the quota is allowed")
+		err = write("c.txt", 1)
+		assert.True(t, errors.Is(err, ErrQuotaExceeded), "%v", err)
+		_, err = m.FS().Stat(filepath.Join(m.RootPath(), id, "c.txt"))
+		assert.True(t, os.IsNotExist(err), "a rejected write leaves nothing behind")
+
+		// Overwrites only count the growth, and shrinking frees space
+		require.NoError(t, write("a.txt", 60))
+		require.NoError(t, write("a.txt", 10))
+		require.NoError(t, write("c.txt", 50))
+		used, err := m.Usage(id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), used)
+	})
+}
+
+func TestCopyPath_Quota(t *testing.T) {
+	m, err := NewManager(t.TempDir(), WithQuota(100))
+	require.NoError(t, err)
+	src, srcPath, err := m.Create("Source")
+	require.NoError(t, err)
+	dst, _, err := m.Create("Dest")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(srcPath, "dir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "dir", "one.txt"), []byte(strings.Repeat("1", 40)), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "dir", "two.txt"), []byte(strings.Repeat("2", 40)), 0644))
+
+	require.NoError(t, m.CopyPath(src, "dir", dst, "first"))
+	err = m.CopyPath(src, "dir", dst, "second")
+	assert.True(t, errors.Is(err, ErrQuotaExceeded), "%v", err)
+	_, err = os.Stat(filepath.Join(m.RootPath(), dst, "second"))
+	assert.True(t, os.IsNotExist(err), "a rejected copy leaves nothing behind")
+
+	// Removing files outside the Manager is picked up once the usage is invalidated
+	require.NoError(t, os.RemoveAll(filepath.Join(m.RootPath(), dst, "first")))
+	m.InvalidateUsage(dst)
+	require.NoError(t, m.CopyPath(src, "dir", dst, "second"))
+}
+
+func TestCloneAndTemplate_Quota(t *testing.T) {
+	templates := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(templates, "big"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templates, "big", "a.txt"), []byte(strings.Repeat("t", 150)), 0644))
+	m, err := NewManager(t.TempDir(), WithQuota(100), WithTemplatesDir(templates))
+	require.NoError(t, err)
+
+	_, _, err = m.CreateFromTemplate("From Template", "big")
+	assert.True(t, errors.Is(err, ErrQuotaExceeded), "%v", err)
+
+	// A source pushed over the quota from outside the Manager cannot be cloned
+	src, srcPath, err := m.Create("Source")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "a.txt"), []byte(strings.Repeat("s", 150)), 0644))
+	_, _, err = m.Clone(src, "Copy")
+	assert.True(t, errors.Is(err, ErrQuotaExceeded), "%v", err)
+
+	list, err := m.List()
+	require.NoError(t, err)
+	assert.Len(t, list, 1, "rejected clones and templates leave no workspace behind")
+}
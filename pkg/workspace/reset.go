@@ -0,0 +1,106 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrRootCommit is returned when undoing the first commit of a workspace,
+// which has no parent to reset to.
+var ErrRootCommit = errors.New("cannot reset past the root commit")
+
+// ResetSoft moves HEAD back to the parent of the current HEAD commit, keeping
+// the working tree (and index) as they are, so the next commit records the
+// undone changes again. It returns the new HEAD hash.
+func (m *Manager) ResetSoft(workspaceID string) (string, error) {
+	repo, parent, _, err := m.resetTarget(workspaceID)
+	if err != nil {
+		return "", err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: parent.Hash, Mode: git.SoftReset}); err != nil {
+		return "", fmt.Errorf("failed to reset: %w", err)
+	}
+	return parent.Hash.String(), nil
+}
+
+// ResetHard moves HEAD back to the parent of the current HEAD commit and
+// restores the working tree to it, discarding the undone commit's changes.
+// It returns the new HEAD hash and the files that changed on disk relative
+// to the previous HEAD.
+func (m *Manager) ResetHard(workspaceID string) (string, []FileChange, error) {
+	repo, parent, head, err := m.resetTarget(workspaceID)
+	if err != nil {
+		return "", nil, err
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	treeChanges, err := headTree.Diff(parentTree)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to diff trees: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: parent.Hash, Mode: git.HardReset}); err != nil {
+		return "", nil, fmt.Errorf("failed to reset: %w", err)
+	}
+	m.InvalidateUsage(workspaceID)
+
+	workspacePath := filepath.Join(m.rootPath, workspaceID)
+	var changes []FileChange
+	for _, tc := range treeChanges {
+		switch {
+		case tc.To.Name == "":
+			// Files the undone commit added are no longer tracked; remove
+			// them (and directories left empty) as git reset --hard does.
+			abs := filepath.Join(workspacePath, filepath.FromSlash(tc.From.Name))
+			if err := os.Remove(abs); err != nil && !os.IsNotExist(err) {
+				return "", nil, fmt.Errorf("failed to remove %s: %w", tc.From.Name, err)
+			}
+			removeEmptyParents(filepath.Dir(abs), workspacePath)
+			changes = append(changes, FileChange{Path: tc.From.Name, Action: "deleted"})
+		case tc.From.Name == "":
+			changes = append(changes, FileChange{Path: tc.To.Name, Action: "added"})
+		default:
+			changes = append(changes, FileChange{Path: tc.To.Name, Action: "modified"})
+		}
+	}
+	return parent.Hash.String(), changes, nil
+}
+
+// resetTarget opens the workspace repository and returns its HEAD commit and
+// that commit's first parent, or ErrRootCommit when HEAD has none.
+func (m *Manager) resetTarget(workspaceID string) (*git.Repository, *object.Commit, *object.Commit, error) {
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	head, err := resolveCommit(repo, "HEAD")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if head.NumParents() == 0 {
+		return nil, nil, nil, ErrRootCommit
+	}
+	parent, err := head.Parent(0)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read parent commit: %w", err)
+	}
+	return repo, parent, head, nil
+}
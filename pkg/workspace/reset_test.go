@@ -0,0 +1,93 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitFiles writes files (path -> content) into the workspace and commits them.
+func commitFiles(t *testing.T, m *Manager, id string, files map[string]string) string {
+	t.Helper()
+	for rel, content := range files {
+		abs := filepath.Join(m.RootPath(), id, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(abs), 0755))
+		require.NoError(t, os.WriteFile(abs, []byte(content), 0644))
+	}
+	hash, err := m.Commit(id, "test commit", "test")
+	require.NoError(t, err)
+	return hash
+}
+
+func TestResetSoft_KeepsWorkingTree(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, path, err := m.Create("reset soft")
+	require.NoError(t, err)
+	base := commitFiles(t, m, id, map[string]string{"a.txt": "one"})
+	commitFiles(t, m, id, map[string]string{"a.txt": "two", "dir/b.txt": "new"})
+
+	head, err := m.ResetSoft(id)
+	require.NoError(t, err)
+	assert.Equal(t, base, head)
+	current, err := m.HeadCommit(id)
+	require.NoError(t, err)
+	assert.Equal(t, base, current)
+
+	data, err := os.ReadFile(filepath.Join(path, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(data))
+	_, err = os.Stat(filepath.Join(path, "dir", "b.txt"))
+	assert.NoError(t, err)
+
+	// The kept changes are committed again by the next commit.
+	redo, err := m.Commit(id, "redo", "test")
+	require.NoError(t, err)
+	changes, err := m.DiffCommits(id, base, redo)
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+}
+
+func TestResetHard_RestoresParent(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, path, err := m.Create("reset hard")
+	require.NoError(t, err)
+	base := commitFiles(t, m, id, map[string]string{"a.txt": "one", "gone.txt": "bye"})
+	require.NoError(t, os.Remove(filepath.Join(path, "gone.txt")))
+	commitFiles(t, m, id, map[string]string{"a.txt": "two", "dir/sub/b.txt": "new"})
+
+	head, changes, err := m.ResetHard(id)
+	require.NoError(t, err)
+	assert.Equal(t, base, head)
+	assert.ElementsMatch(t, []FileChange{
+		{Path: "a.txt", Action: "modified"},
+		{Path: "dir/sub/b.txt", Action: "deleted"},
+		{Path: "gone.txt", Action: "added"},
+	}, changes)
+
+	data, err := os.ReadFile(filepath.Join(path, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(data))
+	data, err = os.ReadFile(filepath.Join(path, "gone.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "bye", string(data))
+	_, err = os.Stat(filepath.Join(path, "dir"))
+	assert.True(t, os.IsNotExist(err), "directories left empty are removed")
+}
+
+func TestReset_RefusesRootCommit(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, _, err := m.Create("root only")
+	require.NoError(t, err)
+
+	_, err = m.ResetSoft(id)
+	assert.True(t, errors.Is(err, ErrRootCommit), "%v", err)
+	_, _, err = m.ResetHard(id)
+	assert.True(t, errors.Is(err, ErrRootCommit), "%v", err)
+}
@@ -1,8 +1,12 @@
 package workspace
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 var (
@@ -16,6 +20,10 @@ var (
 
 const maxSlugLength = 64
 
+// DefaultSlug is the slug used for names with no characters usable in an id
+// (e.g. "***", only whitespace, or only non-ASCII letters).
+const DefaultSlug = "workspace"
+
 // GenerateSlug creates a filesystem-safe, unique-enough slug from a given name.
 // It follows the rules in the PRD:
 // 1. Lowercase normalization.
@@ -23,7 +31,17 @@ const maxSlugLength = 64
 // 3. Collapse repeated hyphens.
 // 4. Trim leading/trailing hyphens.
 // 5. Truncate to a safe length.
+// When nothing is left it returns DefaultSlug, so the result is never empty.
 func GenerateSlug(name string) string {
+	if slug := slugify(name); slug != "" {
+		return slug
+	}
+	return DefaultSlug
+}
+
+// slugify applies the GenerateSlug rules without the fallback; the result may
+// be empty.
+func slugify(name string) string {
 	// 1. Lowercase normalization
 	slug := strings.ToLower(name)
 
@@ -45,4 +63,68 @@ func GenerateSlug(name string) string {
 	}
 
 	return slug
-}
\ No newline at end of file
+}
+
+// SlugStrategy selects how workspace ids are derived when a workspace is created.
+type SlugStrategy string
+
+const (
+	// SlugStrategySlug derives the id from the name (the default), e.g. "my-project".
+	SlugStrategySlug SlugStrategy = "slug"
+	// SlugStrategySlugDate prefixes the slug with the creation date for sortability, e.g. "20240102-my-project".
+	SlugStrategySlugDate SlugStrategy = "slug-date"
+	// SlugStrategyUUID uses a random UUID so ids reveal nothing about the name.
+	SlugStrategyUUID SlugStrategy = "uuid"
+)
+
+// ParseSlugStrategy validates a strategy name; an empty string selects the default.
+func ParseSlugStrategy(s string) (SlugStrategy, error) {
+	switch st := SlugStrategy(strings.ToLower(strings.TrimSpace(s))); st {
+	case "":
+		return SlugStrategySlug, nil
+	case SlugStrategySlug, SlugStrategySlugDate, SlugStrategyUUID:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown slug strategy %q (want slug, slug-date or uuid)", s)
+	}
+}
+
+// generateID returns the candidate workspace id for name under the strategy.
+func (s SlugStrategy) generateID(name string, now time.Time) string {
+	switch s {
+	case SlugStrategySlugDate:
+		return now.Format("20060102") + "-" + GenerateSlug(name)
+	case SlugStrategyUUID:
+		return uuid.NewString()
+	default:
+		return GenerateSlug(name)
+	}
+}
+
+// slugDatePrefix matches the date prefix SlugStrategySlugDate gives ids.
+var slugDatePrefix = regexp.MustCompile(`^[0-9]{8}-`)
+
+// renameID returns the id a workspace with id oldID, created at created, takes
+// when renamed to name: uuid ids never change, slug-date ids keep their date,
+// and slugs follow the name. Unlike generateID there is no DefaultSlug
+// fallback; "" means name has no usable characters.
+func (s SlugStrategy) renameID(oldID, name string, created time.Time) string {
+	switch s {
+	case SlugStrategyUUID:
+		return oldID
+	case SlugStrategySlugDate:
+		slug := slugify(name)
+		if slug == "" {
+			return ""
+		}
+		if prefix := slugDatePrefix.FindString(oldID); prefix != "" {
+			return prefix + slug
+		}
+		if created.IsZero() {
+			created = time.Now().UTC()
+		}
+		return created.Format("20060102") + "-" + slug
+	default:
+		return slugify(name)
+	}
+}
@@ -0,0 +1,59 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSlug_FallsBackWhenEmpty(t *testing.T) {
+	cases := map[string]string{
+		"invalid characters only": "***",
+		"punctuation and dashes":  "--!?--",
+		"whitespace only":         " \t\n ",
+		"empty":                   "",
+		"emoji only":              "🚀🎉",
+		"non-ASCII letters only":  "日本語のプロジェクト",
+	}
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, DefaultSlug, GenerateSlug(in))
+		})
+	}
+}
+
+func TestGenerateSlug_LongUnicodeNames(t *testing.T) {
+	// Non-ASCII characters are dropped, so mixed names keep their ASCII words
+	assert.Equal(t, "caf-rsum", GenerateSlug("Café Résumé"))
+
+	long := GenerateSlug(strings.Repeat("Ünïcödé wörd ", 40))
+	assert.LessOrEqual(t, len(long), maxSlugLength)
+	assert.Regexp(t, validIDRegex, long)
+	assert.False(t, strings.HasSuffix(long, "-"))
+
+	assert.Equal(t, DefaultSlug, GenerateSlug(strings.Repeat("漢字", 200)))
+}
+
+func TestCreate_NamesWithoutUsableCharacters(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+
+	id1, path1, err := m.Create("***")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSlug, id1)
+	assert.NotEqual(t, m.RootPath(), path1)
+
+	id2, _, err := m.Create("   ")
+	require.NoError(t, err)
+	assert.NotEqual(t, id1, id2, "a second unusable name must not reuse the first workspace")
+	assert.True(t, strings.HasPrefix(id2, DefaultSlug+"-"), id2)
+
+	md, err := m.Metadata(id1)
+	require.NoError(t, err)
+	assert.Equal(t, "***", md.Name)
+
+	_, err = m.Rename(id1, "!!!")
+	assert.ErrorContains(t, err, "no characters usable")
+}
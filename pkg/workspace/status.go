@@ -0,0 +1,60 @@
+package workspace
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// PendingChange is a file whose working-tree content differs from HEAD.
+type PendingChange struct {
+	Path   string // slash-separated
+	Status string // "untracked" | "added" | "modified" | "deleted" | "renamed" | "copied" | "unmerged"
+	Staged bool   // some of the change is staged in the index
+}
+
+// statusNames names the git status codes Status reports.
+var statusNames = map[git.StatusCode]string{
+	git.Untracked:          "untracked",
+	git.Added:              "added",
+	git.Modified:           "modified",
+	git.Deleted:            "deleted",
+	git.Renamed:            "renamed",
+	git.Copied:             "copied",
+	git.UpdatedButUnmerged: "unmerged",
+}
+
+// Status returns the uncommitted changes of a workspace, sorted by path:
+// files edited outside the Manager, or written without a commit. Ignored and
+// excluded files (such as TrashDir) are left out.
+func (m *Manager) Status(workspaceID string) ([]PendingChange, error) {
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	st, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	changes := []PendingChange{}
+	for path, fs := range st {
+		// The working tree's state wins over the index's
+		code := fs.Worktree
+		if code == git.Unmodified {
+			code = fs.Staging
+		}
+		name, ok := statusNames[code]
+		if !ok {
+			continue
+		}
+		staged := fs.Staging != git.Unmodified && fs.Staging != git.Untracked
+		changes = append(changes, PendingChange{Path: path, Status: name, Staged: staged})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
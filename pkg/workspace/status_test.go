@@ -0,0 +1,53 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus_ReportsUncommittedChanges(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, path, err := m.Create("status")
+	require.NoError(t, err)
+	commitFiles(t, m, id, map[string]string{"kept.txt": "one", "edited.txt": "one", "gone.txt": "one"})
+
+	changes, err := m.Status(id)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "a freshly committed workspace is clean")
+
+	require.NoError(t, os.WriteFile(filepath.Join(path, "edited.txt"), []byte("two"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(path, "gone.txt")))
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "new"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "new", "file.txt"), []byte("new"), 0644))
+	_, err = m.MoveToTrash(id, "kept.txt")
+	require.NoError(t, err)
+
+	changes, err = m.Status(id)
+	require.NoError(t, err)
+	assert.Equal(t, []PendingChange{
+		{Path: "edited.txt", Status: "modified"},
+		{Path: "gone.txt", Status: "deleted"},
+		{Path: "kept.txt", Status: "deleted"},
+		{Path: "new/file.txt", Status: "untracked"},
+	}, changes, "the trash is excluded")
+
+	commitFiles(t, m, id, nil)
+	changes, err = m.Status(id)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestStatus_NoHistoryInMemory(t *testing.T) {
+	m, err := NewManager(t.TempDir(), WithFS(NewMemFS()))
+	require.NoError(t, err)
+	id, _, err := m.Create("status")
+	require.NoError(t, err)
+	_, err = m.Status(id)
+	assert.True(t, errors.Is(err, ErrNoHistory), "%v", err)
+}
@@ -0,0 +1,90 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var (
+	ErrInvalidTagName = errors.New("invalid tag name")
+	ErrTagExists      = errors.New("tag already exists")
+)
+
+// Tag is a named checkpoint of a workspace.
+type Tag struct {
+	Name    string
+	Commit  string // hash of the tagged commit
+	Message string // annotation; empty for lightweight tags
+}
+
+// CreateTag tags commit (any revision; HEAD when empty) as name. A non-empty
+// message creates an annotated tag, otherwise the tag is lightweight.
+func (m *Manager) CreateTag(workspaceID, name, message, commit string) (Tag, error) {
+	if name == "" || plumbing.NewTagReferenceName(name).Validate() != nil {
+		return Tag{}, fmt.Errorf("%w: %q", ErrInvalidTagName, name)
+	}
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return Tag{}, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	if commit == "" {
+		commit = "HEAD"
+	}
+	target, err := resolveCommit(repo, commit)
+	if err != nil {
+		return Tag{}, err
+	}
+	var opts *git.CreateTagOptions
+	if message != "" {
+		opts = &git.CreateTagOptions{
+			Message: message,
+			Tagger:  &object.Signature{Name: "mcp-client", Email: DefaultAuthorEmail, When: time.Now()},
+		}
+	}
+	if _, err := repo.CreateTag(name, target.Hash, opts); err != nil {
+		if errors.Is(err, git.ErrTagExists) {
+			return Tag{}, fmt.Errorf("%w: %q", ErrTagExists, name)
+		}
+		return Tag{}, fmt.Errorf("failed to create tag: %w", err)
+	}
+	return Tag{Name: name, Commit: target.Hash.String(), Message: strings.TrimSuffix(message, "\n")}, nil
+}
+
+// ListTags returns the workspace's tags sorted by name, annotated tags being
+// peeled to the commit they point at.
+func (m *Manager) ListTags(workspaceID string) ([]Tag, error) {
+	repo, err := m.openRepo(workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository: %w", err)
+	}
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	tags := []Tag{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		tag := Tag{Name: ref.Name().Short(), Commit: ref.Hash().String()}
+		if obj, err := repo.TagObject(ref.Hash()); err == nil {
+			c, err := obj.Commit()
+			if err != nil {
+				return fmt.Errorf("failed to peel tag %s: %w", tag.Name, err)
+			}
+			tag.Commit = c.Hash.String()
+			tag.Message = strings.TrimSuffix(obj.Message, "\n")
+		}
+		tags = append(tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
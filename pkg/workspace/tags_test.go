@@ -0,0 +1,55 @@
+package workspace
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTag_ListsAnnotatedAndLightweight(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, _, err := m.Create("tags")
+	require.NoError(t, err)
+	first := commitFiles(t, m, id, map[string]string{"a.txt": "one"})
+	head := commitFiles(t, m, id, map[string]string{"a.txt": "two"})
+
+	tag, err := m.CreateTag(id, "v1.0", "First release", first[:8])
+	require.NoError(t, err)
+	assert.Equal(t, Tag{Name: "v1.0", Commit: first, Message: "First release"}, tag)
+	_, err = m.CreateTag(id, "latest", "", "")
+	require.NoError(t, err)
+
+	tags, err := m.ListTags(id)
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{
+		{Name: "latest", Commit: head},
+		{Name: "v1.0", Commit: first, Message: "First release"},
+	}, tags)
+
+	resolved, err := m.ResolveRef(id, "v1.0")
+	require.NoError(t, err)
+	assert.Equal(t, first, resolved)
+}
+
+func TestCreateTag_RejectsInvalidAndDuplicateNames(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, _, err := m.Create("tags")
+	require.NoError(t, err)
+
+	for _, name := range []string{"", "has space", "a..b", "ends.lock", "-dash", "a:b"} {
+		_, err := m.CreateTag(id, name, "", "")
+		assert.True(t, errors.Is(err, ErrInvalidTagName), "%q: %v", name, err)
+	}
+	_, err = m.CreateTag(id, "v1", "", "")
+	require.NoError(t, err)
+	_, err = m.CreateTag(id, "v1", "again", "")
+	assert.True(t, errors.Is(err, ErrTagExists), "%v", err)
+
+	tags, err := m.ListTags(id)
+	require.NoError(t, err)
+	assert.Len(t, tags, 1)
+}
@@ -0,0 +1,84 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Errors returned (wrapped) by CreateFromTemplate.
+var (
+	ErrTemplatesDisabled = errors.New("no templates directory is configured")
+	ErrTemplateNotFound  = errors.New("template not found")
+	ErrInvalidTemplate   = errors.New("invalid template name")
+)
+
+// WithTemplatesDir sets the directory whose subdirectories are the templates
+// available to CreateFromTemplate.
+func WithTemplatesDir(dir string) Option {
+	return func(m *Manager) { m.templatesDir = dir }
+}
+
+// templatePath resolves a template name to its directory. Names are a single
+// path element, so they cannot reach outside the templates directory.
+func (m *Manager) templatePath(template string) (string, error) {
+	if m.templatesDir == "" {
+		return "", ErrTemplatesDisabled
+	}
+	if template == "" || template == "." || template == ".." || strings.HasPrefix(template, ".") ||
+		strings.ContainsAny(template, `/\`) || template != filepath.Base(template) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTemplate, template)
+	}
+	p := filepath.Join(m.templatesDir, template)
+	info, err := os.Stat(p)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w: %q", ErrTemplateNotFound, template)
+	}
+	return p, nil
+}
+
+// CreateFromTemplate creates a workspace like Create and copies the contents of
+// the named template into it (without any .git, or the root .gitkeep, which the
+// workspace already has). Nothing is committed; if the
+// copy fails the new workspace is removed again. A template over the quota
+// fails with ErrQuotaExceeded. Templates are only supported on OSFS.
+func (m *Manager) CreateFromTemplate(name, template string) (string, string, error) {
+	if !m.OnDisk() {
+		return "", "", fmt.Errorf("copying templates: %w", errors.ErrUnsupported)
+	}
+	src, err := m.templatePath(template)
+	if err != nil {
+		return "", "", err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read template: %w", err)
+	}
+	size, err := m.treeSize(src)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to scan template: %w", err)
+	}
+	if err := m.checkNewWorkspaceSize(size); err != nil {
+		return "", "", err
+	}
+	id, path, err := m.Create(name)
+	if err != nil {
+		return "", "", err
+	}
+	for _, e := range entries {
+		// Create already wrote the root .gitkeep
+		if e.Name() == ".git" || e.Name() == ".gitkeep" {
+			continue
+		}
+		if err := copyTree(filepath.Join(src, e.Name()), filepath.Join(path, e.Name()), nil); err != nil {
+			if rmErr := os.RemoveAll(path); rmErr != nil {
+				slog.Warn("Failed to remove workspace after failed template copy", "workspaceId", id, "error", rmErr)
+			}
+			return "", "", fmt.Errorf("failed to copy template: %w", err)
+		}
+	}
+	return id, path, nil
+}
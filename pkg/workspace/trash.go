@@ -0,0 +1,199 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrashDir is the workspace directory soft-deleted files are moved to. Each
+// entry keeps the file or directory at its original relative path under
+// TrashDir/<id>/, with its details in TrashDir/<id>.json. The directory is
+// excluded from commits through .git/info/exclude.
+const TrashDir = ".trash"
+
+// Errors returned (wrapped) by the trash methods.
+var (
+	ErrTrashEntryNotFound  = errors.New("trash entry not found")
+	ErrRestoreTargetExists = errors.New("restore target exists")
+)
+
+// TrashEntry describes one soft-deleted file or directory.
+type TrashEntry struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"` // original path, slash-separated
+	IsDir     bool      `json:"isDir"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// trashIDLayout names trash entries by their UTC deletion time, so they sort
+// chronologically.
+const trashIDLayout = "20060102T150405.000000000Z"
+
+// MoveToTrash moves rel, a slash-separated path inside the workspace, into a
+// new trash entry instead of deleting it. Callers hold Lock; the move is not
+// committed.
+func (m *Manager) MoveToTrash(workspaceID, rel string) (TrashEntry, error) {
+	if filepath.Clean(rel) == "." {
+		return TrashEntry{}, fmt.Errorf("cannot move the workspace root to the trash")
+	}
+	abs, err := m.SafePath(workspaceID, rel)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	info, err := m.fs.Stat(abs)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	trashRoot := filepath.Join(m.rootPath, workspaceID, TrashDir)
+	if err := m.fs.MkdirAll(trashRoot, 0755); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := m.excludeTrash(workspaceID); err != nil {
+		return TrashEntry{}, err
+	}
+
+	now := time.Now().UTC()
+	entry := TrashEntry{Path: filepath.ToSlash(filepath.Clean(rel)), IsDir: info.IsDir(), DeletedAt: now}
+	base := now.Format(trashIDLayout)
+	for n := 1; ; n++ {
+		entry.ID = base
+		if n > 1 {
+			entry.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		if err := m.fs.Mkdir(filepath.Join(trashRoot, entry.ID), 0755); err == nil {
+			break
+		} else if !os.IsExist(err) {
+			return TrashEntry{}, fmt.Errorf("failed to create trash entry: %w", err)
+		}
+	}
+	entryDir := filepath.Join(trashRoot, entry.ID)
+	target := filepath.Join(entryDir, filepath.FromSlash(entry.Path))
+	if err := m.fs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to create trash entry: %w", err)
+	}
+	if err := m.fs.Rename(abs, target); err != nil {
+		_ = m.fs.RemoveAll(entryDir)
+		return TrashEntry{}, fmt.Errorf("failed to move to trash: %w", err)
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	if err := m.fs.WriteFile(entryDir+".json", data, 0644); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to record trash entry: %w", err)
+	}
+	return entry, nil
+}
+
+// ListTrash returns the workspace's trash entries, most recent first.
+func (m *Manager) ListTrash(workspaceID string) ([]TrashEntry, error) {
+	if _, err := m.SafePath(workspaceID, "."); err != nil {
+		return nil, err
+	}
+	trashRoot := filepath.Join(m.rootPath, workspaceID, TrashDir)
+	files, err := m.fs.ReadDir(trashRoot)
+	if os.IsNotExist(err) {
+		return []TrashEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+	entries := []TrashEntry{}
+	for _, f := range files {
+		id, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() {
+			continue
+		}
+		entry, err := m.trashEntry(workspaceID, id)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	return entries, nil
+}
+
+// RestoreFromTrash moves the trash entry id back to its original path and
+// removes the entry. An existing file or directory at that path is never
+// overwritten. Callers hold Lock; the restore is not committed.
+func (m *Manager) RestoreFromTrash(workspaceID, id string) (TrashEntry, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return TrashEntry{}, fmt.Errorf("%w: %q", ErrTrashEntryNotFound, id)
+	}
+	entry, err := m.trashEntry(workspaceID, id)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	dst, err := m.SafePath(workspaceID, entry.Path)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	if _, err := m.fs.Stat(dst); !os.IsNotExist(err) {
+		return TrashEntry{}, fmt.Errorf("%w: %s", ErrRestoreTargetExists, entry.Path)
+	}
+	entryDir := filepath.Join(m.rootPath, workspaceID, TrashDir, id)
+	if err := m.fs.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	if err := m.fs.Rename(filepath.Join(entryDir, filepath.FromSlash(entry.Path)), dst); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to restore from trash: %w", err)
+	}
+	_ = m.fs.RemoveAll(entryDir)
+	_ = m.fs.RemoveAll(entryDir + ".json")
+	return entry, nil
+}
+
+// trashEntry reads the details of trash entry id.
+func (m *Manager) trashEntry(workspaceID, id string) (TrashEntry, error) {
+	data, err := m.fs.ReadFile(filepath.Join(m.rootPath, workspaceID, TrashDir, id+".json"))
+	if os.IsNotExist(err) {
+		return TrashEntry{}, fmt.Errorf("%w: %q", ErrTrashEntryNotFound, id)
+	}
+	if err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to read trash entry: %w", err)
+	}
+	var entry TrashEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.ID != id {
+		return TrashEntry{}, fmt.Errorf("%w: %q is corrupt", ErrTrashEntryNotFound, id)
+	}
+	return entry, nil
+}
+
+// trashExcludePattern keeps TrashDir out of commits.
+const trashExcludePattern = "/" + TrashDir + "/"
+
+// excludeTrash adds trashExcludePattern to the workspace's .git/info/exclude
+// unless it is already listed. Workspaces without a repository need nothing.
+func (m *Manager) excludeTrash(workspaceID string) error {
+	if !m.OnDisk() {
+		return nil
+	}
+	p := filepath.Join(m.rootPath, workspaceID, ".git", "info", "exclude")
+	data, err := m.fs.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read git excludes: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == trashExcludePattern {
+			return nil
+		}
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, trashExcludePattern+"\n"...)
+	if err := m.fs.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to update git excludes: %w", err)
+	}
+	if err := m.fs.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("failed to update git excludes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash_MoveAndRestore(t *testing.T) {
+	m, err := NewManager(t.TempDir())
+	require.NoError(t, err)
+	id, path, err := m.Create("trash")
+	require.NoError(t, err)
+	commitFiles(t, m, id, map[string]string{"docs/a.txt": "a", "docs/sub/b.txt": "b"})
+
+	file, err := m.MoveToTrash(id, "docs/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "docs/a.txt", file.Path)
+	assert.False(t, file.IsDir)
+	dir, err := m.MoveToTrash(id, "docs/sub")
+	require.NoError(t, err)
+	assert.True(t, dir.IsDir)
+	assert.NotEqual(t, file.ID, dir.ID)
+	_, err = os.Stat(filepath.Join(path, "docs", "a.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// The trash is never committed
+	commit, err := m.Commit(id, "delete", "test")
+	require.NoError(t, err)
+	head, err := m.HeadCommitObject(id)
+	require.NoError(t, err)
+	assert.Equal(t, commit, head.Hash.String())
+	tree, err := head.Tree()
+	require.NoError(t, err)
+	_, err = tree.FindEntry(TrashDir)
+	assert.Error(t, err, ".trash must not be committed")
+
+	entries, err := m.ListTrash(id)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, dir.ID, entries[0].ID, "most recent first")
+
+	restored, err := m.RestoreFromTrash(id, dir.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "docs/sub", restored.Path)
+	data, err := os.ReadFile(filepath.Join(path, "docs", "sub", "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+
+	_, err = m.RestoreFromTrash(id, dir.ID)
+	assert.True(t, errors.Is(err, ErrTrashEntryNotFound), "%v", err)
+	_, err = m.RestoreFromTrash(id, "../x")
+	assert.True(t, errors.Is(err, ErrTrashEntryNotFound), "%v", err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(path, "docs", "a.txt"), []byte("new"), 0644))
+	_, err = m.RestoreFromTrash(id, file.ID)
+	assert.True(t, errors.Is(err, ErrRestoreTargetExists), "%v", err)
+	entries, err = m.ListTrash(id)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "a failed restore keeps the entry")
+}